@@ -54,10 +54,10 @@ type Config struct {
 		PullerCleanerCsvURL        string `yaml:"pullerCleanerCsvURL"`
 	} `yaml:"functions"`
 
-	Protoparam struct {
-		TmpFilePath string `yaml:"tmpFilePath"`
-		TmpFileName string `yaml:"tmpFileName"`
-	} `yaml:"protoparam"`
+	Protobuf struct {
+		DescriptorCacheSize int  `yaml:"descriptorCacheSize"`
+		StrictUnknownFields bool `yaml:"strictUnknownFields"`
+	} `yaml:"protobuf"`
 
 	PullerCleanerJSON struct {
 		TimeDurationSeconds time.Duration `yaml:"timeDurationSeconds"`
@@ -71,12 +71,87 @@ type Config struct {
 		MaxThroughput       int           `yaml:"maxThroughput"`
 	} `yaml:"pullercleanercsv"`
 
+	Metrics struct {
+		PushgatewayURL      string        `yaml:"pushgatewayURL"`
+		PushIntervalSeconds time.Duration `yaml:"pushIntervalSeconds"`
+	} `yaml:"metrics"`
+
+	Tracing struct {
+		Endpoint    string  `yaml:"endpoint"`
+		Insecure    bool    `yaml:"insecure"`
+		SampleRatio float64 `yaml:"sampleRatio"`
+	} `yaml:"tracing"`
+
+	SchemaCache struct {
+		MaxEntries         int           `yaml:"maxEntries"`
+		MaxBytes           int           `yaml:"maxBytes"`
+		TTLSeconds         time.Duration `yaml:"ttlSeconds"`
+		NegativeTTLSeconds time.Duration `yaml:"negativeTTLSeconds"`
+		Warm               bool          `yaml:"warm"`
+		WatchChanges       bool          `yaml:"watchChanges"`
+	} `yaml:"schemaCache"`
+
+	Publisher struct {
+		DelayThresholdMilliseconds time.Duration `yaml:"delayThresholdMilliseconds"`
+		CountThreshold             int           `yaml:"countThreshold"`
+		ByteThreshold              int           `yaml:"byteThreshold"`
+		TimeoutSeconds             time.Duration `yaml:"timeoutSeconds"`
+		MaxOutstandingMessages     int64         `yaml:"maxOutstandingMessages"`
+		MaxOutstandingBytes        int64         `yaml:"maxOutstandingBytes"`
+	} `yaml:"publisher"`
+
+	Retry struct {
+		MaxAttempts                int           `yaml:"maxAttempts"`
+		InitialBackoffMilliseconds time.Duration `yaml:"initialBackoffMilliseconds"`
+		MaxBackoffMilliseconds     time.Duration `yaml:"maxBackoffMilliseconds"`
+	} `yaml:"retry"`
+
+	Validators map[string]map[string]string `yaml:"validators"`
+
+	Routing struct {
+		Rules []RoutingRuleConfig `yaml:"rules"`
+	} `yaml:"routing"`
+
+	Transformations []TransformationConfig `yaml:"transformations"`
+
 	PullerCleanerTaskQueue  string      `yaml:"pullerCleanerTaskQueue"`
 	ContentType             string      `yaml:"contentType"`
 	FileMode                os.FileMode `yaml:"fileMode"`
 	FirestoreCollectionName string      `yaml:"firestoreCollectionName"`
 }
 
+// MatchConfig matches messages by their attributes. Empty fields match any value, and the fields are shell patterns,
+// e.g. "orders-*". Version matches the versionId attribute.
+type MatchConfig struct {
+	Format     string            `yaml:"format"`
+	Namespace  string            `yaml:"namespace"`
+	SchemaId   string            `yaml:"schemaId"`
+	Version    string            `yaml:"version"`
+	Attributes map[string]string `yaml:"attributes"`
+}
+
+// RoutingRuleConfig routes the messages it matches to its topics. Outcome matches the validation outcome ("valid",
+// "invalid" or "dead-letter"), also by a shell pattern.
+type RoutingRuleConfig struct {
+	Match struct {
+		MatchConfig `yaml:",inline"`
+		Outcome     string `yaml:"outcome"`
+	} `yaml:"match"`
+	Topics []string `yaml:"topics"`
+}
+
+// TransformationConfig transforms the valid messages it matches before they're published. Output is the format
+// messages are converted into, only "json" is supported. Defaults fills in the default values of the schema and
+// StripUnknown removes the members the schema doesn't declare. Rename maps the paths of members, e.g.
+// "/customer/name", to their new names.
+type TransformationConfig struct {
+	Match        MatchConfig       `yaml:"match"`
+	Output       string            `yaml:"output"`
+	Defaults     bool              `yaml:"defaults"`
+	StripUnknown bool              `yaml:"stripUnknown"`
+	Rename       map[string]string `yaml:"rename"`
+}
+
 // RetrieveConfig obtains configuration parameters from a
 // config file (which is in GCS bucket) into an object.
 //
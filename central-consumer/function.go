@@ -19,22 +19,68 @@ package central_consumer
 import (
 	"context"
 	"encoding/base64"
+	"fmt"
 	"log"
 	"strings"
+	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/syntio/central-consumer/metrics"
 	"github.com/syntio/central-consumer/pubsub"
 	"github.com/syntio/central-consumer/registry"
-	"github.com/syntio/central-consumer/validator"
+	"github.com/syntio/central-consumer/retry"
+	"github.com/syntio/central-consumer/routing"
+	"github.com/syntio/central-consumer/transform"
+	"github.com/syntio/janitor-common/envelope"
+	"github.com/syntio/janitor-common/publisher"
+	"github.com/syntio/janitor-common/tracing"
+	"github.com/syntio/janitor-common/validator"
+	"github.com/syntio/janitor-common/validator/convert"
+	"github.com/syntio/janitor-common/validator/impl"
 )
 
 var b64coder *base64.Encoding
-// Location of the specific validator implementations.
-var baseLocation string
+
+// Routing table of the config file.
+var routes *routing.Table
+
+// Policy of retrying transient failures.
+var retryPolicy retry.Policy
+
+// Transformations of the valid messages of the config file.
+var pipeline *transform.Pipeline
 
 // Init function.
 func init() {
 	b64coder = base64.StdEncoding
-	baseLocation = "github.com/syntio/central-consumer/validator/impl."
+
+	tracing.Configure("central-consumer", registry.Cfg.Tracing.Endpoint, registry.Cfg.Tracing.Insecure,
+		registry.Cfg.Tracing.SampleRatio)
+
+	var err error
+	if routes, err = routing.New(registry.Cfg.Routing.Rules); err != nil {
+		log.Printf("ERROR: invalid routing table, messages go to the default topics. %v.\n", err)
+		routes, _ = routing.New(nil)
+	}
+
+	if pipeline, err = transform.New(registry.Cfg.Transformations); err != nil {
+		log.Printf("ERROR: invalid transformations, valid messages are published as they are. %v.\n", err)
+		pipeline, _ = transform.New(nil)
+	}
+
+	validator.Configure(registry.Cfg.Validators)
+	impl.ConfigureProtobuf(registry.Cfg.Protobuf.DescriptorCacheSize, registry.Cfg.Protobuf.StrictUnknownFields)
+
+	publisher.Configure(publisher.Settings(registry.Cfg.Publisher.DelayThresholdMilliseconds*time.Millisecond,
+		registry.Cfg.Publisher.CountThreshold, registry.Cfg.Publisher.ByteThreshold,
+		registry.Cfg.Publisher.TimeoutSeconds*time.Second),
+		registry.Cfg.Publisher.MaxOutstandingMessages, registry.Cfg.Publisher.MaxOutstandingBytes)
+
+	retryPolicy = retry.NewPolicy(registry.Cfg.Retry.MaxAttempts,
+		registry.Cfg.Retry.InitialBackoffMilliseconds*time.Millisecond,
+		registry.Cfg.Retry.MaxBackoffMilliseconds*time.Millisecond)
 }
 
 // CentralConsumerHandler represents a consumer handler function working with Schema Registry (Janitor).
@@ -43,110 +89,265 @@ func init() {
 // metadata validity, retrieving the required message schema from the Schema Registry by the ID and Version metadata
 // and validating the input message with the retrieved schema.
 //
-// An error is returned if any errors occur during the function execution.
+// The handling is traced in a span continuing the trace context carried by the message attributes, if any.
+//
+// Transient failures, such as an unavailable Schema Registry or Pub/Sub, are retried by the retry policy of the
+// config file. If they persist, an error is returned, so Pub/Sub redelivers the message. Messages which can't be
+// handled for any other reason go to the dead-letter topic.
 func CentralConsumerHandler(ctx context.Context, message pubsub.Message) error {
-	valid, id, version, format := retrieveMetadata(message.Attributes)
+	defer metrics.Push()
+	defer tracing.Flush(ctx)
+
+	ctx, span := tracing.Start(tracing.Extract(ctx, message.Attributes), "CentralConsumerHandler",
+		trace.WithSpanKind(trace.SpanKindConsumer))
+	defer span.End()
+
+	valid, namespace, id, version, format := retrieveMetadata(message.Attributes)
+	span.SetAttributes(
+		attribute.String("schema.namespace", namespace),
+		attribute.String("schema.id", id),
+		attribute.String("schema.version", version),
+		attribute.String("message.format", format),
+	)
 
 	if !valid {
 		log.Printf("Message metadata invalid, required fields are NOT set. ID = %v, Version = %v, Format = %v.\n",
 			id, version, format)
-		handleTransmission(message, pubsub.DeadLetterTopic)
-		return nil
+		return reroute(ctx, message, pubsub.DeadLetterTopic, metrics.OutcomeDeadLetter,
+			envelope.ReasonMissingMetadata, envelope.StageMetadata, missingMetadata(message.Attributes))
 	}
 
 	// Retrieve the message schema from the Schema Registry
-	schemaInfo, found, err := registry.GetSchemaByIDAndVersion(id, version)
+	var schemaInfo *registry.Schema
+	var found bool
+	err := retryPolicy.Do(ctx, func() (err error) {
+		start := time.Now()
+		schemaInfo, found, err = registry.GetSchemaByIDAndVersion(ctx, namespace, id, version)
+		metrics.RegistryFetchDuration.WithLabelValues(fetchResult(found, err)).Observe(time.Since(start).Seconds())
+		return err
+	})
 
-	if err != nil {
+	if retry.IsTransient(err) {
+		log.Printf("ERROR: during schema registry request, the message will be redelivered. %v.\n", err)
+		return redeliver(message, err)
+	} else if err != nil {
 		log.Printf("ERROR: during schema registry request. %v.\n", err)
-		handleTransmission(message, pubsub.DeadLetterTopic)
-		return nil
+		return reroute(ctx, message, pubsub.DeadLetterTopic, metrics.OutcomeDeadLetter,
+			envelope.ReasonRegistryError, envelope.StageSchemaRetrieval, err)
 	}
 
 	// Validate and transmit the message depending on the retrieved schema
 	if !found {
 		_, invalidTopic, _, _ := chooseTopic(format)
-		handleTransmission(message, invalidTopic)
-	} else {
-		transmitValidMessage(format, message, schemaInfo)
-
+		return reroute(ctx, message, invalidTopic, metrics.OutcomeInvalid, envelope.ReasonSchemaNotFound,
+			envelope.StageSchemaRetrieval, fmt.Errorf("schema ID = %s, Version = %s wasn't found", id, version))
 	}
+	return transmitValidMessage(ctx, format, message, schemaInfo)
+}
 
-	return nil
+// missingMetadata returns an error naming the required metadata a message lacks.
+func missingMetadata(metadata map[string]string) error {
+	var missing []string
+	for _, name := range []string{"schemaId", "versionId", "format"} {
+		if len(metadata[name]) == 0 {
+			missing = append(missing, name)
+		}
+	}
+	return fmt.Errorf("required metadata isn't set: %s", strings.Join(missing, ", "))
 }
 
 // retrieveMetadata returns if the metadata is valid and corresponding metadata values.
-// Invalid metadata is logged with the corresponding description.
-func retrieveMetadata(metadata map[string]string) (bool, string, string, string) {
+// Invalid metadata is logged with the corresponding description. The namespace is optional.
+func retrieveMetadata(metadata map[string]string) (bool, string, string, string, string) {
+	namespace := metadata["namespace"]
 	id := metadata["schemaId"]
 	version := metadata["versionId"]
 	format := metadata["format"]
 
 	valid := len(id) != 0 && len(version) != 0 && len(format) != 0
 
-	return valid, id, version, format
+	return valid, namespace, id, version, format
 }
 
 // handleValidationAndTransmission validates the input message with the retrieved message schema from the Schema Registry.
 // Depending on the validation result, the message is transmitted to the validated, invalidated or error topic.
+// Valid messages are transformed by the transformations of the config file first, and go to the error topic if
+// they can't be.
 // Also, depending on the message format, the function receives as a parameter a validator for the specific message format.
-func handleValidationAndTransmission(message pubsub.Message, schemaInfo *registry.Schema, validatedTopic, invalidatedTopic,
-	errorTopic string, validator func([]byte, []byte) (bool, error)) {
+// Validators failing transiently are retried, and an error is returned if they keep failing.
+func handleValidationAndTransmission(ctx context.Context, message pubsub.Message, schemaInfo *registry.Schema,
+	validatedTopic, invalidatedTopic, errorTopic string, messageValidator validator.Validator) error {
 	content := message.Data
 	schema, err := b64coder.DecodeString(schemaInfo.SchemaDetails[0].Specification)
 
 	if err != nil {
 		log.Printf("ERROR: during base64 decoding. %v.\n", err)
-		handleTransmission(message, errorTopic)
-		return
+		return reroute(ctx, message, errorTopic, metrics.OutcomeDeadLetter, envelope.ReasonSchemaDecoding,
+			envelope.StageSchemaDecoding, err)
 	}
 
-	valid, err := validator(content, schema)
+	validator.UseAttributes(messageValidator, message.Attributes)
+	format := formatLabel(message.Attributes["format"])
+	validationCtx, span := tracing.Start(ctx, "validator.Validate",
+		trace.WithAttributes(attribute.String("message.format", format)))
+	start := time.Now()
+	var result *impl.Result
+	err = retryPolicy.Do(validationCtx, func() (err error) {
+		result, err = messageValidator.Validate(content, schema)
+		return err
+	})
+	metrics.ValidationDuration.WithLabelValues(format).Observe(time.Since(start).Seconds())
+	span.SetAttributes(attribute.Bool("message.valid", err == nil && result.Valid))
+	if err != nil {
+		span.RecordError(err)
+	}
+	span.End()
 
 	switch {
+	case retry.IsTransient(err):
+		log.Printf("ERROR: during message validation, the message will be redelivered. %v\n", err)
+		return redeliver(message, err)
 	case err != nil:
 		log.Printf("ERROR: during message validation. %v\n", err)
-		handleTransmission(message, errorTopic)
-	case valid:
-		handleTransmission(message, validatedTopic)
+		return reroute(ctx, message, errorTopic, metrics.OutcomeDeadLetter, envelope.ReasonValidatorError,
+			envelope.StageValidation, err)
+	case result.Valid:
+		transformed, err := pipeline.Apply(message, schema)
+		if err != nil {
+			log.Printf("ERROR: during message transformation. %v\n", err)
+			return reroute(ctx, message, errorTopic, metrics.OutcomeDeadLetter, envelope.ReasonTransformation,
+				envelope.StageTransformation, err)
+		}
+		return handleTransmission(ctx, transformed, validatedTopic, metrics.OutcomeValid)
 	default:
-		handleTransmission(message, invalidatedTopic)
+		stamped := pubsub.Message{Data: message.Data, Attributes: envelope.StampViolations(message.Attributes,
+			envelope.ComponentCentralConsumer, envelope.StageValidation, result.Violations)}
+		return handleTransmission(ctx, stamped, invalidatedTopic, metrics.OutcomeInvalid)
 	}
 }
 
-// handleTransmission transmits the input message to a specific topic.
-func handleTransmission(message pubsub.Message, topicName string) {
-	if err := pubsub.Transmit(message, topicName); err != nil {
-		log.Printf("ERROR: during message transmission to pubsub. %v.\n", err)
+// reroute stamps a message with the reason and the stage it is rerouted for and at, and the error which caused it,
+// and transmits it to the topic of its outcome.
+func reroute(ctx context.Context, message pubsub.Message, topicName, outcome, reason, stage string,
+	err error) error {
+	return handleTransmission(ctx, stamp(message, reason, stage, err), topicName, outcome)
+}
+
+// stamp returns a copy of a message stamped as rerouted by the central consumer for a reason at a stage, see
+// envelope.Stamp.
+func stamp(message pubsub.Message, reason, stage string, err error) pubsub.Message {
+	return pubsub.Message{Data: message.Data,
+		Attributes: envelope.Stamp(message.Attributes, envelope.ComponentCentralConsumer, reason, stage, err)}
+}
+
+// handleTransmission transmits the input message to the topics the routing table chooses for it, or to the default
+// topic if no routing rule matches. The message is counted with its outcome: valid, invalid (no schema or failed
+// validation) or dead-letter (couldn't be processed).
+//
+// Transmissions failing transiently are retried, and an error is returned if they keep failing, so the message is
+// redelivered. A message which can't be transmitted to a topic at all goes to the dead-letter topic instead. An error
+// is also returned if the message can't be transmitted to the dead-letter topic, so it isn't lost.
+func handleTransmission(ctx context.Context, message pubsub.Message, topicName, outcome string) error {
+	deadLettered := false
+	for _, topic := range routes.Topics(message.Attributes, outcome, topicName) {
+		err := transmit(ctx, message, topic)
+		if err != nil && !retry.IsTransient(err) && topic != pubsub.DeadLetterTopic && !deadLettered {
+			log.Printf("ERROR: during message transmission to pubsub topic %s, the message goes to the "+
+				"dead-letter topic. %v.\n", topic, err)
+			outcome, deadLettered = metrics.OutcomeDeadLetter, true
+			err = transmit(ctx, stamp(message, envelope.ReasonPublishFailed, envelope.StageTransmission, err),
+				pubsub.DeadLetterTopic)
+			topic = pubsub.DeadLetterTopic
+		}
+		if err != nil {
+			log.Printf("ERROR: during message transmission to pubsub topic %s, the message will be redelivered. "+
+				"%v.\n", topic, err)
+			return redeliver(message, err)
+		}
+	}
+	metrics.RecordMessage(formatLabel(message.Attributes["format"]), outcome)
+	return nil
+}
+
+// transmit transmits a message to a topic, retrying transient failures.
+func transmit(ctx context.Context, message pubsub.Message, topicName string) error {
+	return retryPolicy.Do(ctx, func() error {
+		return pubsub.Transmit(ctx, message, topicName)
+	})
+}
+
+// redeliver counts a message Pub/Sub has to redeliver and returns the error of its handling, so the invocation fails.
+func redeliver(message pubsub.Message, err error) error {
+	metrics.RecordMessage(formatLabel(message.Attributes["format"]), metrics.OutcomeRedelivered)
+	return err
+}
+
+// formatLabel returns the format of a message as a metric label. Unregistered formats share the label "other", so
+// arbitrary message attributes can't inflate the number of series.
+func formatLabel(format string) string {
+	if validator.Supports(format) {
+		return strings.ToLower(format)
+	}
+	return "other"
+}
+
+// fetchResult returns the result of a schema fetch from the Schema Registry as a metric label.
+func fetchResult(found bool, err error) string {
+	switch {
+	case err != nil:
+		return "error"
+	case found:
+		return "found"
+	default:
+		return "not-found"
 	}
 }
 
 // transmitValidMessage transmits a valid message to a specific topic.
-func transmitValidMessage(format string, message pubsub.Message, schemaInfo *registry.Schema) {
-	validator := validator.ValidatorFactory(baseLocation + strings.Title(format) + "Validator")
+func transmitValidMessage(ctx context.Context, format string, message pubsub.Message,
+	schemaInfo *registry.Schema) error {
 	validTopic, invalidTopic, errorTopic, err := chooseTopic(format)
 
 	if err {
-		handleTransmission(message, errorTopic)
+		return reroute(ctx, message, errorTopic, metrics.OutcomeDeadLetter, envelope.ReasonUnsupportedFormat,
+			envelope.StageMetadata, fmt.Errorf("unsupported message format %q", format))
 	} else {
-		handleValidationAndTransmission(message, schemaInfo, validTopic, invalidTopic, errorTopic, validator.Validate)
+		messageValidator, err := validator.ForFormat(format)
+		if retry.IsTransient(err) {
+			log.Printf("ERROR: during validator creation, the message will be redelivered. %v.\n", err)
+			return redeliver(message, err)
+		} else if err != nil {
+			log.Printf("ERROR: during validator creation. %v.\n", err)
+			return reroute(ctx, message, errorTopic, metrics.OutcomeDeadLetter, envelope.ReasonValidatorError,
+				envelope.StageValidation, err)
+		}
+		defer func() {
+			if err := validator.Close(messageValidator); err != nil {
+				log.Printf("ERROR: during validator closing. %v.\n", err)
+			}
+		}()
+		return handleValidationAndTransmission(ctx, message, schemaInfo, validTopic, invalidTopic, errorTopic,
+			messageValidator)
 	}
- }
+}
 
 // chooseTopic returns corresponding formats based on a format of a message. For invalid input format, err is set to true.
+// Messages of the formats which are validated with a JSON Schema (YAML, MessagePack, CBOR and BSON) share the invalid
+// topic of JSON messages, so their schemas evolve by the JSON inference. The topics are the defaults for messages no
+// rule of the routing table matches.
 func chooseTopic(format string) (validTopic, invalidTopic, errorTopic string, err bool) {
 	validTopic = pubsub.ValidTopic
 	invalidTopic = pubsub.DeadLetterTopic
 	errorTopic = pubsub.DeadLetterTopic
 	err = false
 
-	if format == "json" {
+	if format == "json" || convert.IsConvertible(format) {
 		invalidTopic = pubsub.InvalidTopicJSON
 		errorTopic = pubsub.InvalidTopicJSON
 	} else if format == "csv" {
 		invalidTopic = pubsub.InvalidTopicCSV
 		errorTopic = pubsub.InvalidTopicCSV
-	} else if !(format == "avro" || format == "protobuf" || format == "xml") {
+	} else if !validator.Supports(format) {
 		err = true
 	}
 
@@ -1,12 +1,17 @@
 module github.com/syntio/central-consumer
 
-go 1.13
+go 1.16
 
 require (
 	cloud.google.com/go/pubsub v1.3.1
 	cloud.google.com/go/storage v1.12.0
-	github.com/hamba/avro v1.0.0
-	github.com/jhump/protoreflect v1.6.1
-	github.com/xeipuuv/gojsonschema v1.2.0
+	github.com/prometheus/client_golang v1.7.1
+	github.com/syntio/janitor-common v0.0.0
+	go.mongodb.org/mongo-driver v1.7.5 // indirect
+	go.opentelemetry.io/otel v1.0.1
+	go.opentelemetry.io/otel/trace v1.0.1
+	google.golang.org/grpc v1.41.0
 	gopkg.in/yaml.v3 v3.0.0-20200615113413-eeeca48fe776
 )
+
+replace github.com/syntio/janitor-common => ../janitor-common
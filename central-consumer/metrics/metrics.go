@@ -0,0 +1,149 @@
+// Copyright 2020 Syntio Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package metrics holds the Prometheus metrics of the central consumer. Cloud Function instances can't be scraped,
+// so the metrics are pushed to a Prometheus Pushgateway, grouped by instance. Counters of an instance keep their last
+// pushed value after the instance is gone, so summing them over the instances gives the totals.
+package metrics
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+	"github.com/syntio/central-consumer/registry"
+)
+
+// Outcomes of a consumed message.
+const (
+	OutcomeValid       = "valid"
+	OutcomeInvalid     = "invalid"
+	OutcomeDeadLetter  = "dead-letter"
+	OutcomeRedelivered = "redelivered"
+)
+
+// jobName is the Pushgateway job the metrics are pushed under.
+const jobName = "central-consumer"
+
+// defaultPushInterval is used when the config file doesn't define how often the metrics are pushed.
+const defaultPushInterval = 10 * time.Second
+
+var (
+	// Messages counts the consumed messages per format and outcome.
+	Messages = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "central_consumer_messages_total",
+		Help: "Consumed messages per format and outcome (valid, invalid, dead-letter, redelivered).",
+	}, []string{"format", "outcome"})
+
+	// ValidationDuration observes how long validating a message takes per format.
+	ValidationDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "central_consumer_validation_duration_seconds",
+		Help:    "Duration of message validations per format.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"format"})
+
+	// RegistryFetchDuration observes how long fetching a schema from the Schema Registry takes per result (found,
+	// not-found, error).
+	RegistryFetchDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "central_consumer_registry_fetch_duration_seconds",
+		Help:    "Duration of schema fetches from the Schema Registry per result.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"result"})
+)
+
+// Descriptions of the schema cache metrics, which are read from the cache when the metrics are gathered.
+var (
+	cacheLookupsDesc = prometheus.NewDesc("central_consumer_schema_cache_lookups_total",
+		"Schema cache lookups per result (hit, negative-hit, miss).", []string{"result"}, nil)
+	cacheEntriesDesc = prometheus.NewDesc("central_consumer_schema_cache_entries",
+		"Schema versions held by the schema cache.", nil, nil)
+)
+
+var (
+	gatherer   = prometheus.NewRegistry()
+	pusher     *push.Pusher
+	interval   time.Duration
+	lastPush   time.Time
+	pushLock   sync.Mutex
+	instanceID = newInstanceID()
+)
+
+func init() {
+	gatherer.MustRegister(Messages, ValidationDuration, RegistryFetchDuration, schemaCacheCollector{})
+
+	cfg := registry.Cfg.Metrics
+	if cfg.PushgatewayURL == "" {
+		return
+	}
+	interval = cfg.PushIntervalSeconds * time.Second
+	if interval <= 0 {
+		interval = defaultPushInterval
+	}
+	pusher = push.New(cfg.PushgatewayURL, jobName).Gatherer(gatherer).Grouping("instance", instanceID)
+}
+
+// RecordMessage counts a consumed message with its outcome.
+func RecordMessage(format, outcome string) {
+	Messages.WithLabelValues(format, outcome).Inc()
+}
+
+// Push pushes the metrics to the Pushgateway, at most once per push interval. Nothing is pushed if no Pushgateway
+// is configured. Pushing is done within the invocation, since Cloud Functions get no CPU between invocations.
+func Push() {
+	if pusher == nil {
+		return
+	}
+	pushLock.Lock()
+	defer pushLock.Unlock()
+	if time.Since(lastPush) < interval {
+		return
+	}
+	lastPush = time.Now()
+	if err := pusher.Push(); err != nil {
+		log.Printf("ERROR: metrics couldn't be pushed. %v.\n", err)
+	}
+}
+
+// schemaCacheCollector collects the metrics of the schema cache. Nothing is collected if the cache is disabled.
+type schemaCacheCollector struct{}
+
+func (schemaCacheCollector) Describe(descs chan<- *prometheus.Desc) {
+	descs <- cacheLookupsDesc
+	descs <- cacheEntriesDesc
+}
+
+func (schemaCacheCollector) Collect(metrics chan<- prometheus.Metric) {
+	stats, enabled := registry.SchemaCacheStats()
+	if !enabled {
+		return
+	}
+	metrics <- prometheus.MustNewConstMetric(cacheLookupsDesc, prometheus.CounterValue, float64(stats.Hits), "hit")
+	metrics <- prometheus.MustNewConstMetric(cacheLookupsDesc, prometheus.CounterValue, float64(stats.NegativeHits),
+		"negative-hit")
+	metrics <- prometheus.MustNewConstMetric(cacheLookupsDesc, prometheus.CounterValue, float64(stats.Misses), "miss")
+	metrics <- prometheus.MustNewConstMetric(cacheEntriesDesc, prometheus.GaugeValue, float64(stats.Entries))
+}
+
+// newInstanceID returns a random ID telling the metrics of function instances apart.
+func newInstanceID() string {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(id)
+}
@@ -18,9 +18,19 @@ package pubsub
 import (
 	lib "cloud.google.com/go/pubsub"
 	"context"
+	"errors"
 	"os"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	grpccodes "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
 	"github.com/syntio/central-consumer/registry"
+	"github.com/syntio/central-consumer/retry"
+	"github.com/syntio/janitor-common/publisher"
+	"github.com/syntio/janitor-common/tracing"
 )
 
 var projectID = os.Getenv("PROJECT_ID")
@@ -29,6 +39,17 @@ var InvalidTopicJSON = registry.Cfg.Topics.InvalidTopicJSON
 var InvalidTopicCSV = registry.Cfg.Topics.InvalidTopicCSV
 var DeadLetterTopic = registry.Cfg.Topics.DeadLetterTopic
 
+// permanentCodes are the gRPC status codes of publish errors which recur however often the message is published,
+// e.g. a missing topic or a message over the size limit.
+var permanentCodes = map[grpccodes.Code]bool{
+	grpccodes.InvalidArgument:    true,
+	grpccodes.NotFound:           true,
+	grpccodes.PermissionDenied:   true,
+	grpccodes.FailedPrecondition: true,
+	grpccodes.Unauthenticated:    true,
+	grpccodes.Unimplemented:      true,
+}
+
 // Message represents a standard message which contains a payload and metadata. Structure is used for message
 // re-transmission after its validation.
 type Message struct {
@@ -36,29 +57,49 @@ type Message struct {
 	Attributes map[string]string `json:"attributes"`
 }
 
-// Transmit transmits a message to the required topic.
+// Transmit transmits a message to the required topic. The transmission is traced as a child of the span in the
+// context, and its trace context is set into the attributes of the transmitted message, so whoever consumes the
+// message next continues the trace.
 //
-// An error is returned if any errors occur during the function execution.
-func Transmit(message Message, topicName string) error {
-	ctx := context.Background()
-	client, err := lib.NewClient(ctx, projectID)
+// An error is returned if any errors occur during the function execution. Errors are transient, unless the message
+// can't be published to the topic at all.
+func Transmit(ctx context.Context, message Message, topicName string) error {
+	ctx, span := tracing.Start(ctx, "pubsub.Transmit", trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(attribute.String("messaging.destination", topicName)))
+	defer span.End()
+
+	err := publish(ctx, message, topicName)
 	if err != nil {
-		return err
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		if !errors.Is(err, lib.ErrOversizedMessage) && !permanentCodes[status.Code(err)] {
+			err = retry.Transient(err)
+		}
 	}
-	defer client.Close()
+	return err
+}
 
-	topic := client.Topic(topicName)
+// publish publishes a message with the trace context of the context to the topic, and waits for the result, since
+// the function instance may get no CPU after the invocation returns. An invocation publishes its message alone, so the
+// publisher's batching can't gather messages of several invocations: the delay threshold of the publisher settings
+// only delays every message by as much, and it is best kept low.
+func publish(ctx context.Context, message Message, topicName string) error {
+	attributes := make(map[string]string, len(message.Attributes)+1)
+	for key, value := range message.Attributes {
+		attributes[key] = value
+	}
+	tracing.Inject(ctx, attributes)
 
 	libMessage := lib.Message{
 		Data:       message.Data,
-		Attributes: message.Attributes,
+		Attributes: attributes,
 	}
 
-	result := topic.Publish(ctx, &libMessage)
-	_, err = result.Get(ctx)
+	result, err := publisher.Publish(ctx, projectID, topicName, &libMessage)
 	if err != nil {
 		return err
 	}
 
-	return nil
-}
\ No newline at end of file
+	_, err = result.Get(ctx)
+	return err
+}
@@ -0,0 +1,187 @@
+// Copyright 2020 Syntio Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registry
+
+import (
+	"container/list"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// cacheKey identifies a schema version in a namespace. An empty namespace stands for any namespace, as in the
+// Schema Registry.
+type cacheKey struct {
+	namespace string
+	id        string
+	version   string
+}
+
+// cacheEntry is a cached schema version, or a missing one if schema is nil. Missing versions expire after the
+// negative TTL, since they may be registered later, and registered ones after the TTL, since they may be removed by
+// the retention of the Schema Registry. An entry without an expiry never expires.
+type cacheEntry struct {
+	key     cacheKey
+	schema  *Schema
+	size    int
+	expires time.Time
+}
+
+// CacheStats counts the lookups of the schema cache.
+type CacheStats struct {
+	Hits         uint64
+	NegativeHits uint64
+	Misses       uint64
+	Entries      int
+}
+
+// schemaCache is an LRU cache of schema versions, limited by the number of entries and by their size. Schema versions
+// are immutable, but the retention of the Schema Registry removes versions, so cached versions expire after the TTL
+// and are invalidated by the changes of the registry, if the instance watches them.
+type schemaCache struct {
+	maxEntries  int
+	maxBytes    int
+	ttl         time.Duration
+	negativeTTL time.Duration
+
+	lock    sync.Mutex
+	order   *list.List // most recently used first
+	entries map[cacheKey]*list.Element
+	size    int
+
+	hits         uint64
+	negativeHits uint64
+	misses       uint64
+}
+
+// newSchemaCache creates a cache of at most maxEntries entries, which must be positive, and maxBytes bytes of
+// specifications (unlimited if not positive), keeping registered versions for ttl (forever if not positive) and
+// missing versions for negativeTTL.
+func newSchemaCache(maxEntries, maxBytes int, ttl, negativeTTL time.Duration) *schemaCache {
+	return &schemaCache{
+		maxEntries:  maxEntries,
+		maxBytes:    maxBytes,
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+		order:       list.New(),
+		entries:     make(map[cacheKey]*list.Element),
+	}
+}
+
+// get looks a schema version up. The output is the schema (nil for a cached missing version) and whether the
+// version is cached at all.
+func (c *schemaCache) get(key cacheKey) (*Schema, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		atomic.AddUint64(&c.misses, 1)
+		return nil, false
+	}
+	entry := element.Value.(*cacheEntry)
+	if !entry.expires.IsZero() && time.Now().After(entry.expires) {
+		c.remove(element)
+		atomic.AddUint64(&c.misses, 1)
+		return nil, false
+	}
+
+	c.order.MoveToFront(element)
+	if entry.schema == nil {
+		atomic.AddUint64(&c.negativeHits, 1)
+	} else {
+		atomic.AddUint64(&c.hits, 1)
+	}
+	return entry.schema, true
+}
+
+// add caches a schema version.
+func (c *schemaCache) add(key cacheKey, schema *Schema) {
+	size := len(key.namespace) + len(key.id) + len(key.version)
+	for _, details := range schema.SchemaDetails {
+		size += len(details.Specification)
+	}
+	entry := &cacheEntry{key: key, schema: schema, size: size}
+	if c.ttl > 0 {
+		entry.expires = time.Now().Add(c.ttl)
+	}
+	c.put(entry)
+}
+
+// addMissing caches a schema version which isn't registered.
+func (c *schemaCache) addMissing(key cacheKey) {
+	if c.negativeTTL <= 0 {
+		return
+	}
+	c.put(&cacheEntry{
+		key:     key,
+		size:    len(key.namespace) + len(key.id) + len(key.version),
+		expires: time.Now().Add(c.negativeTTL),
+	})
+}
+
+// put caches an entry and evicts the least recently used entries beyond the limits. Entries larger than the size
+// limit aren't cached.
+func (c *schemaCache) put(entry *cacheEntry) {
+	if c.maxBytes > 0 && entry.size > c.maxBytes {
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if element, ok := c.entries[entry.key]; ok {
+		c.remove(element)
+	}
+	c.entries[entry.key] = c.order.PushFront(entry)
+	c.size += entry.size
+
+	for c.order.Len() > c.maxEntries || (c.maxBytes > 0 && c.size > c.maxBytes) {
+		c.remove(c.order.Back())
+	}
+}
+
+// invalidate removes every cached version of a schema, in any namespace.
+func (c *schemaCache) invalidate(id string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for key, element := range c.entries {
+		if key.id == id {
+			c.remove(element)
+		}
+	}
+}
+
+// remove removes an entry. The lock must be held.
+func (c *schemaCache) remove(element *list.Element) {
+	entry := c.order.Remove(element).(*cacheEntry)
+	delete(c.entries, entry.key)
+	c.size -= entry.size
+}
+
+// stats returns the lookup counts and the number of entries.
+func (c *schemaCache) stats() CacheStats {
+	c.lock.Lock()
+	entries := c.order.Len()
+	c.lock.Unlock()
+
+	return CacheStats{
+		Hits:         atomic.LoadUint64(&c.hits),
+		NegativeHits: atomic.LoadUint64(&c.negativeHits),
+		Misses:       atomic.LoadUint64(&c.misses),
+		Entries:      entries,
+	}
+}
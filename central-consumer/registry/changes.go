@@ -0,0 +1,80 @@
+// Copyright 2020 Syntio Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// Backoff of reconnecting to the change stream of the Schema Registry.
+const (
+	minWatchBackoff = time.Second
+	maxWatchBackoff = 30 * time.Second
+)
+
+// Change is a change of a schema streamed by the Schema Registry.
+type Change struct {
+	Kind      string `json:"kind"`
+	Id        string `json:"identification"`
+	Namespace string `json:"namespace"`
+	Version   int32  `json:"version"`
+}
+
+// watchChanges follows the change stream of the Schema Registry and removes the cached versions of every changed
+// schema, e.g. the versions removed by the retention of the registry. The stream only carries the changes made through
+// the registry instance it is connected to, and changes made while it is down are lost, so the TTL of the cache
+// bounds how long a missed change is served.
+func watchChanges() {
+	backoff := minWatchBackoff
+	for {
+		err := streamChanges(func() {
+			backoff = minWatchBackoff
+		})
+		log.Printf("Schema change stream interrupted, reconnecting in %v. %v.\n", backoff, err)
+
+		time.Sleep(backoff)
+		if backoff *= 2; backoff > maxWatchBackoff {
+			backoff = maxWatchBackoff
+		}
+	}
+}
+
+// streamChanges connects to the change stream of the Schema Registry, calls onConnect once connected and invalidates
+// the cache for every streamed change. The output is the error which ended the stream.
+func streamChanges(onConnect func()) error {
+	response, err := http.Get(schemaRegistryURL + "/changes")
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("error: status code [%v]", response.StatusCode)
+	}
+	onConnect()
+
+	decoder := json.NewDecoder(response.Body)
+	for {
+		var change Change
+		if err := decoder.Decode(&change); err != nil {
+			return err
+		}
+		cache.invalidate(change.Id)
+	}
+}
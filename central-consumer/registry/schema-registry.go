@@ -16,29 +16,65 @@
 package registry
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
+	"strconv"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
 	"github.com/syntio/central-consumer/configuration"
+	"github.com/syntio/central-consumer/retry"
+	"github.com/syntio/janitor-common/tracing"
 )
 
 var Cfg configuration.Config = configuration.RetrieveConfig()
 var schemaRegistryURL = os.Getenv("SCHEMA_REGISTRY_URL")
 
+// cache holds the schema versions retrieved from the Schema Registry. It is nil if caching is disabled.
+var cache *schemaCache
+
+// warmTimeout bounds warming the schema cache when the function instance starts.
+const warmTimeout = 10 * time.Second
+
+// Init function sets up the schema cache, if the config file enables it with a positive maxEntries. Processes which
+// don't know the Schema Registry URL don't cache. The cache is warmed in the background, so the first messages of a
+// function instance don't wait for it, and the changes of the registry are watched in the background as well.
+func init() {
+	cacheCfg := Cfg.SchemaCache
+	if cacheCfg.MaxEntries <= 0 || schemaRegistryURL == "" {
+		return
+	}
+	cache = newSchemaCache(cacheCfg.MaxEntries, cacheCfg.MaxBytes, cacheCfg.TTLSeconds*time.Second,
+		cacheCfg.NegativeTTLSeconds*time.Second)
+
+	if cacheCfg.Warm {
+		go warmCache()
+	}
+	if cacheCfg.WatchChanges {
+		go watchChanges()
+	}
+}
+
 // Schema represents a message schema from Schema Registry.
 type Schema struct {
 	Id            string           `json:"id,omitempty"`
+	Namespace     string           `json:"namespace"`
 	SchemaType    string           `json:"schema-type"`
 	Autogenerated bool             `json:"autogenerated"`
 	Description   string           `json:"description"`
 	CreationDate  time.Time        `json:"creation-date"`
 	Name          string           `json:"name"`
 	SchemaDetails []*SchemaDetails `json:"schemas"`
+	LatestVersion int32            `json:"latest-version"`
 }
 
 // SchemaDetails represents details of a Schema.
@@ -53,45 +89,164 @@ type Report struct {
 	Message string `json:"message"`
 }
 
-// GetSchemaByIDAndVersion retrieves a message schema from the Schema Registry using the schema ID and version.
-// HTTP is used as the method of communication with the Schema Registry service.
+// GetSchemaByIDAndVersion retrieves a message schema from the Schema Registry using the schema namespace, ID and
+// version. HTTP is used as the method of communication with the Schema Registry service. An empty namespace
+// retrieves the schema regardless of its namespace. The request is traced as a child of the span in the context.
+//
+// Retrieved schema versions are kept in the schema cache, if it is enabled, and versions the Schema Registry doesn't
+// know are kept for the configured negative TTL. Cached versions are served without a request.
 //
 // Function returns the SchemaInfo structure if the required schema is found (boolean indicator). An error is returned
-// if any errors occur during the function execution.
-func GetSchemaByIDAndVersion(id, version string) (*Schema, bool, error) {
-	var schemaInfo *Schema
-	var found bool = false
-	var err error
+// if any errors occur during the function execution. Failed requests and the responses of an unavailable or
+// overloaded Schema Registry (5xx, 408 and 429 status codes) are transient errors.
+func GetSchemaByIDAndVersion(ctx context.Context, namespace, id, version string) (schemaInfo *Schema, found bool,
+	err error) {
+	ctx, span := tracing.Start(ctx, "registry.GetSchemaByIDAndVersion", trace.WithAttributes(
+		attribute.String("schema.namespace", namespace),
+		attribute.String("schema.id", id),
+		attribute.String("schema.version", version),
+	))
+	defer func() {
+		span.SetAttributes(attribute.Bool("schema.found", found))
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
 
-	getURL := fmt.Sprintf("%s/schema/%s/version/%s", schemaRegistryURL, id, version)
+	key := cacheKey{namespace: namespace, id: id, version: version}
+	if cache != nil {
+		cached, ok := cache.get(key)
+		span.SetAttributes(attribute.Bool("cache.hit", ok))
+		if ok {
+			return cached, cached != nil, nil
+		}
+	}
 
-	response, err := http.Get(getURL)
+	getURL := fmt.Sprintf("%s%s/schema/%s/version/%s", schemaRegistryURL, namespacePath(namespace), id, version)
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, getURL, nil)
 	if err != nil {
 		return schemaInfo, found, err
 	}
+	response, err := tracing.HTTPClient.Do(request)
+	if err != nil {
+		return schemaInfo, found, retry.Transient(err)
+	}
 
 	responseBody, err := ioutil.ReadAll(response.Body)
 	if err != nil {
-		return schemaInfo, found, err
+		return schemaInfo, found, retry.Transient(err)
 	}
 	defer response.Body.Close()
 
+	if isTransientStatus(response.StatusCode) {
+		return schemaInfo, found, retry.Transient(fmt.Errorf("schema registry responded with %s",
+			response.Status))
+	}
 	if response.StatusCode == http.StatusOK {
 		schemaInfo, err = JSONToSchema(responseBody)
 		if err != nil {
 			return schemaInfo, found, err
 		}
 		found = true
+		if cache != nil {
+			cache.add(key, schemaInfo)
+		}
 	} else {
+		if response.StatusCode == http.StatusNotFound && cache != nil {
+			cache.addMissing(key)
+		}
 		report, err := JSONToReport(responseBody)
 		if err != nil {
 			return schemaInfo, found, err
 		}
-		log.Printf("Schema couldn't be retrieved for ID = %s and Version = %s. Message: %s.\n", id, version, report.Message)
+		log.Printf("Schema couldn't be retrieved for Namespace = %s, ID = %s and Version = %s. Message: %s.\n",
+			namespace, id, version, report.Message)
 	}
 	return schemaInfo, found, err
 }
 
+// isTransientStatus reports whether a response status code means the request may succeed if it is retried.
+func isTransientStatus(statusCode int) bool {
+	return statusCode >= http.StatusInternalServerError || statusCode == http.StatusRequestTimeout ||
+		statusCode == http.StatusTooManyRequests
+}
+
+// SchemaCacheStats returns the lookup counts and the size of the schema cache, and whether the cache is enabled.
+func SchemaCacheStats() (CacheStats, bool) {
+	if cache == nil {
+		return CacheStats{}, false
+	}
+	return cache.stats(), true
+}
+
+// warmCache caches the latest versions of the registered schemas, as long as they fit into half of the cache. Every
+// version is cached under its namespace and under the empty namespace, which messages without a namespace look
+// schemas up with.
+func warmCache() {
+	ctx, cancel := context.WithTimeout(context.Background(), warmTimeout)
+	defer cancel()
+
+	schemas, err := listSchemas(ctx)
+	if err != nil {
+		log.Printf("ERROR: schema cache couldn't be warmed. %v.\n", err)
+		return
+	}
+
+	warmed := 0
+	for _, schema := range schemas {
+		if warmed >= cache.maxEntries/2 {
+			break
+		}
+		if schema.LatestVersion == 0 {
+			continue
+		}
+		version := strconv.Itoa(int(schema.LatestVersion))
+		latest, found, err := GetSchemaByIDAndVersion(ctx, schema.Namespace, schema.Id, version)
+		if err != nil {
+			log.Printf("ERROR: schema cache couldn't be warmed. %v.\n", err)
+			break
+		}
+		if found {
+			cache.add(cacheKey{id: schema.Id, version: version}, latest)
+			warmed++
+		}
+	}
+	log.Printf("Schema cache warmed with %d schemas.\n", warmed)
+}
+
+// listSchemas retrieves the registered schemas of every namespace from the Schema Registry, without their versions.
+func listSchemas(ctx context.Context) ([]*Schema, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, schemaRegistryURL+"/schema/", nil)
+	if err != nil {
+		return nil, err
+	}
+	response, err := tracing.HTTPClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error: status code [%v]", response.StatusCode)
+	}
+	var schemas []*Schema
+	if err := json.NewDecoder(response.Body).Decode(&schemas); err != nil {
+		return nil, err
+	}
+	return schemas, nil
+}
+
+// namespacePath returns the Schema Registry URL path prefix of a namespace.
+func namespacePath(namespace string) string {
+	if namespace == "" {
+		return ""
+	}
+	return "/ns/" + url.PathEscape(namespace)
+}
+
 // JSONToSchema converts response body to a Schema structure and returns it. Error is set to nil if the conversion was successful.
 func JSONToSchema(responseBody []byte) (*Schema, error) {
 	schemaInfo := Schema{}
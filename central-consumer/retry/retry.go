@@ -0,0 +1,110 @@
+// Copyright 2020 Syntio Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package retry tells transient failures, such as an unavailable service, from permanent ones, such as invalid data,
+// and retries operations failing transiently with an exponential backoff. Errors are permanent unless they are
+// marked as transient with Transient.
+package retry
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+)
+
+// Default policy settings, used when the config file doesn't set them.
+const (
+	defaultMaxAttempts    = 3
+	defaultInitialBackoff = 100 * time.Millisecond
+	defaultMaxBackoff     = 2 * time.Second
+)
+
+// transientError marks an error as transient.
+type transientError struct {
+	err error
+}
+
+func (te *transientError) Error() string {
+	return te.err.Error()
+}
+
+func (te *transientError) Unwrap() error {
+	return te.err
+}
+
+// Transient marks an error as transient, i.e. the operation which failed with it may succeed if it is retried.
+// Transient returns nil for a nil error.
+func Transient(err error) error {
+	if err == nil || IsTransient(err) {
+		return err
+	}
+	return &transientError{err: err}
+}
+
+// IsTransient reports whether an error, or any error it wraps, is transient. Context cancellations and deadlines
+// are transient as well, since the operation didn't fail on its own.
+func IsTransient(err error) bool {
+	var te *transientError
+	return errors.As(err, &te) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled)
+}
+
+// Policy defines how often and how long apart failing operations are retried.
+type Policy struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// NewPolicy creates a policy, using the defaults for the settings which aren't positive.
+func NewPolicy(maxAttempts int, initialBackoff, maxBackoff time.Duration) Policy {
+	if maxAttempts <= 0 {
+		maxAttempts = defaultMaxAttempts
+	}
+	if initialBackoff <= 0 {
+		initialBackoff = defaultInitialBackoff
+	}
+	if maxBackoff <= 0 {
+		maxBackoff = defaultMaxBackoff
+	}
+	return Policy{MaxAttempts: maxAttempts, InitialBackoff: initialBackoff, MaxBackoff: maxBackoff}
+}
+
+// Do runs an operation until it succeeds, fails permanently or has been attempted MaxAttempts times. Between the
+// attempts it waits for a backoff which doubles with each attempt up to MaxBackoff, with a random jitter of up to
+// half of it.
+//
+// The error of the last attempt is returned, or the error of the context if it is done while waiting.
+func (p Policy) Do(ctx context.Context, operation func() error) error {
+	backoff := p.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		err := operation()
+		if err == nil || !IsTransient(err) || attempt >= p.MaxAttempts {
+			return err
+		}
+
+		wait := backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		if backoff *= 2; backoff > p.MaxBackoff {
+			backoff = p.MaxBackoff
+		}
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright 2020 Syntio Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package routing chooses the topics a message is transmitted to by the routing table of the config file. The rules
+// of the table are evaluated in order, and the first rule matching a message chooses its topics. Messages no rule
+// matches go to the default topic of their outcome.
+package routing
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/syntio/central-consumer/configuration"
+)
+
+// Table is a routing table.
+type Table struct {
+	rules []rule
+}
+
+// rule is a rule of a routing table. Outcome is the pattern of the validation outcome.
+type rule struct {
+	matcher *Matcher
+	outcome string
+	topics  []string
+}
+
+// New creates a routing table from the routing rules of the config file.
+//
+// An error is returned if a rule has no topics or an invalid pattern.
+func New(rules []configuration.RoutingRuleConfig) (*Table, error) {
+	table := &Table{}
+	for i, ruleCfg := range rules {
+		if len(ruleCfg.Topics) == 0 {
+			return nil, fmt.Errorf("routing rule %d has no topics", i+1)
+		}
+
+		matcher, err := NewMatcher(ruleCfg.Match.MatchConfig)
+		if err != nil {
+			return nil, fmt.Errorf("routing rule %d: %v", i+1, err)
+		}
+		outcome := ruleCfg.Match.Outcome
+		if outcome == "" {
+			outcome = "*"
+		}
+		if _, err := path.Match(outcome, ""); err != nil {
+			return nil, fmt.Errorf("routing rule %d: invalid pattern %q of the outcome: %v", i+1, outcome, err)
+		}
+
+		table.rules = append(table.rules, rule{matcher: matcher, outcome: outcome, topics: ruleCfg.Topics})
+	}
+	return table, nil
+}
+
+// Topics returns the topics of a message with the given attributes and validation outcome: the topics of the first
+// matching rule, or the default topic if no rule matches.
+func (t *Table) Topics(attributes map[string]string, outcome, defaultTopic string) []string {
+	for _, r := range t.rules {
+		if matched, _ := path.Match(r.outcome, outcome); matched && r.matcher.Matches(attributes) {
+			return r.topics
+		}
+	}
+	return []string{defaultTopic}
+}
+
+// Matcher matches messages by their attributes, e.g. for the routing rules and the transformations of the config
+// file. Its conditions map message attributes to the patterns their values have to match.
+type Matcher struct {
+	conditions map[string]string
+}
+
+// NewMatcher creates a matcher of the match fields of the config file.
+//
+// An error is returned if a pattern is invalid.
+func NewMatcher(match configuration.MatchConfig) (*Matcher, error) {
+	conditions := map[string]string{}
+	for key, pattern := range match.Attributes {
+		conditions[key] = pattern
+	}
+	for key, pattern := range map[string]string{
+		"format":    match.Format,
+		"namespace": match.Namespace,
+		"schemaId":  match.SchemaId,
+		"versionId": match.Version,
+	} {
+		if pattern != "" {
+			conditions[key] = pattern
+		}
+	}
+
+	for key, pattern := range conditions {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q of %s: %v", pattern, key, err)
+		}
+	}
+	return &Matcher{conditions: conditions}, nil
+}
+
+// Matches reports whether a message with the given attributes meets every condition of the matcher. A missing
+// attribute only matches the pattern "*".
+func (m *Matcher) Matches(attributes map[string]string) bool {
+	for key, pattern := range m.conditions {
+		if matched, _ := path.Match(pattern, attributes[key]); !matched {
+			return false
+		}
+	}
+	return true
+}
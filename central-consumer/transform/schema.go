@@ -0,0 +1,208 @@
+// Copyright 2020 Syntio Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transform
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// maxReferences is the number of references followed to resolve a schema, which stops cyclic references.
+const maxReferences = 32
+
+// schemaDocument is a decoded JSON Schema document.
+type schemaDocument struct {
+	root interface{}
+}
+
+// resolve returns the object of a schema, following its references into the document, e.g.
+// "#/definitions/address". Boolean schemas and references to other documents resolve to nil.
+func (d *schemaDocument) resolve(schema interface{}) map[string]interface{} {
+	for i := 0; i < maxReferences; i++ {
+		object, ok := schema.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		ref, ok := object["$ref"].(string)
+		if !ok {
+			return object
+		}
+		schema = d.pointer(ref)
+	}
+	return nil
+}
+
+// pointer returns the value a reference into the document points to, or nil.
+func (d *schemaDocument) pointer(ref string) interface{} {
+	if ref == "#" {
+		return d.root
+	}
+	if !strings.HasPrefix(ref, "#/") {
+		return nil
+	}
+
+	value := d.root
+	for _, segment := range strings.Split(ref[2:], "/") {
+		segment = strings.NewReplacer("~1", "/", "~0", "~").Replace(segment)
+		switch parent := value.(type) {
+		case map[string]interface{}:
+			value = parent[segment]
+		case []interface{}:
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index >= len(parent) {
+				return nil
+			}
+			value = parent[index]
+		default:
+			return nil
+		}
+	}
+	return value
+}
+
+// subschemas returns the object of a schema and the objects of the schemas it combines with allOf, which a value
+// has to conform to as well.
+func (d *schemaDocument) subschemas(schema interface{}) []map[string]interface{} {
+	var objects []map[string]interface{}
+	var collect func(schema interface{}, depth int)
+	collect = func(schema interface{}, depth int) {
+		object := d.resolve(schema)
+		if object == nil || depth > maxReferences {
+			return
+		}
+		objects = append(objects, object)
+		allOf, _ := object["allOf"].([]interface{})
+		for _, member := range allOf {
+			collect(member, depth+1)
+		}
+	}
+	collect(schema, 0)
+	return objects
+}
+
+// fillDefaults fills in the default values of the properties an object of a JSON value lacks, in the value and in
+// its members and items.
+func (d *schemaDocument) fillDefaults(value, schema interface{}) {
+	for _, object := range d.subschemas(schema) {
+		switch value := value.(type) {
+		case map[string]interface{}:
+			properties, _ := object["properties"].(map[string]interface{})
+			for name, property := range properties {
+				if _, ok := value[name]; !ok {
+					if propertyObject := d.resolve(property); propertyObject != nil {
+						if def, ok := propertyObject["default"]; ok {
+							value[name] = copyValue(def)
+						}
+					}
+				}
+				if member, ok := value[name]; ok {
+					d.fillDefaults(member, property)
+				}
+			}
+		case []interface{}:
+			d.eachItem(value, object, d.fillDefaults)
+		}
+	}
+}
+
+// stripUnknown removes the members of the objects of a JSON value which the properties and pattern properties of
+// their schemas don't declare, in the value and in its members and items. Objects whose schemas declare neither are
+// kept as they are.
+func (d *schemaDocument) stripUnknown(value, schema interface{}) {
+	objects := d.subschemas(schema)
+	switch value := value.(type) {
+	case map[string]interface{}:
+		declared := map[string][]interface{}{}
+		var patterns []*regexp.Regexp
+		closed := false
+		for _, object := range objects {
+			if properties, ok := object["properties"].(map[string]interface{}); ok {
+				closed = true
+				for name, property := range properties {
+					declared[name] = append(declared[name], property)
+				}
+			}
+			if patternProperties, ok := object["patternProperties"].(map[string]interface{}); ok {
+				closed = true
+				for pattern := range patternProperties {
+					if re, err := regexp.Compile(pattern); err == nil {
+						patterns = append(patterns, re)
+					}
+				}
+			}
+		}
+
+		for name, member := range value {
+			if properties, ok := declared[name]; ok {
+				for _, property := range properties {
+					d.stripUnknown(member, property)
+				}
+			} else if closed && !matchesAny(patterns, name) {
+				delete(value, name)
+			}
+		}
+	case []interface{}:
+		for _, object := range objects {
+			d.eachItem(value, object, d.stripUnknown)
+		}
+	}
+}
+
+// eachItem calls a function with the items of an array and their schemas, by the items keyword of the schema of the
+// array.
+func (d *schemaDocument) eachItem(array []interface{}, object map[string]interface{},
+	f func(value, schema interface{})) {
+	switch items := object["items"].(type) {
+	case []interface{}:
+		for i := 0; i < len(array) && i < len(items); i++ {
+			f(array[i], items[i])
+		}
+	case map[string]interface{}:
+		for _, item := range array {
+			f(item, items)
+		}
+	}
+}
+
+// matchesAny reports whether a name matches any of the patterns.
+func matchesAny(patterns []*regexp.Regexp, name string) bool {
+	for _, pattern := range patterns {
+		if pattern.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// copyValue returns a deep copy of a JSON value, so default values aren't shared by the values they're filled into.
+func copyValue(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		object := make(map[string]interface{}, len(value))
+		for name, member := range value {
+			object[name] = copyValue(member)
+		}
+		return object
+	case []interface{}:
+		array := make([]interface{}, len(value))
+		for i, item := range value {
+			array[i] = copyValue(item)
+		}
+		return array
+	default:
+		return value
+	}
+}
@@ -0,0 +1,296 @@
+// Copyright 2020 Syntio Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package transform transforms the valid messages before they're published, by the transformations of the config
+// file. The transformations are evaluated in order, and the first transformation matching a message transforms it.
+// Messages no transformation matches are published as they are.
+//
+// A transformation converts a message into JSON by the schema it was validated with: CSV rows become objects keyed
+// by the columns of the CSV Schema, XML documents are converted by their XML Schema, and YAML, MessagePack, CBOR and
+// BSON messages like they are for validation. It can then fill in the default values of a JSON Schema, strip the
+// members a JSON Schema doesn't declare and rename members.
+package transform
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/syntio/central-consumer/configuration"
+	"github.com/syntio/central-consumer/pubsub"
+	"github.com/syntio/central-consumer/routing"
+	"github.com/syntio/janitor-common/validator/convert"
+	"github.com/syntio/janitor-common/validator/csvschema"
+	"github.com/syntio/janitor-common/validator/xsd"
+)
+
+// Attributes of a transformed message. The format attribute keeps the format the message was validated in, so the
+// routing rules still match it by that format, and consumers have to read the format of the data from
+// AttributeOutputFormat.
+const (
+	AttributeOutputFormat = "janitor.outputFormat"
+	// AttributeTransformations lists the steps applied to the message, e.g. "convert,defaults".
+	AttributeTransformations = "janitor.transformations"
+)
+
+// Steps of a transformation.
+const (
+	StepConvert      = "convert"
+	StepDefaults     = "defaults"
+	StepStripUnknown = "strip-unknown"
+	StepRename       = "rename"
+)
+
+// OutputJSON is the output format of the transformations.
+const OutputJSON = "json"
+
+// Pipeline is a list of transformations.
+type Pipeline struct {
+	transformations []transformation
+}
+
+// transformation is a transformation of a pipeline, which transforms the messages its matcher matches.
+type transformation struct {
+	matcher      *routing.Matcher
+	defaults     bool
+	stripUnknown bool
+	renames      []rename
+}
+
+// rename renames the member at the path to the name.
+type rename struct {
+	path []string
+	name string
+}
+
+// New creates a pipeline from the transformations of the config file.
+//
+// An error is returned if a transformation has an unsupported output format, an invalid pattern or an invalid
+// rename.
+func New(transformations []configuration.TransformationConfig) (*Pipeline, error) {
+	pipeline := &Pipeline{}
+	for i, cfg := range transformations {
+		if cfg.Output != OutputJSON {
+			return nil, fmt.Errorf("transformation %d: unsupported output format %q, only %q is supported", i+1,
+				cfg.Output, OutputJSON)
+		}
+
+		matcher, err := routing.NewMatcher(cfg.Match)
+		if err != nil {
+			return nil, fmt.Errorf("transformation %d: %v", i+1, err)
+		}
+
+		var renames []rename
+		for from, to := range cfg.Rename {
+			segments, err := parsePointer(from)
+			if err != nil {
+				return nil, fmt.Errorf("transformation %d: invalid rename of %q: %v", i+1, from, err)
+			}
+			if to == "" {
+				return nil, fmt.Errorf("transformation %d: invalid rename of %q: the new name is empty", i+1, from)
+			}
+			renames = append(renames, rename{path: segments, name: to})
+		}
+		// Deeper members are renamed first, so the paths of the others still name their parents.
+		sort.Slice(renames, func(a, b int) bool {
+			if len(renames[a].path) != len(renames[b].path) {
+				return len(renames[a].path) > len(renames[b].path)
+			}
+			return strings.Join(renames[a].path, "/") < strings.Join(renames[b].path, "/")
+		})
+
+		pipeline.transformations = append(pipeline.transformations, transformation{matcher: matcher,
+			defaults: cfg.Defaults, stripUnknown: cfg.StripUnknown, renames: renames})
+	}
+	return pipeline, nil
+}
+
+// parsePointer parses the path of a member, a JSON Pointer such as "/customer/name", into its segments.
+func parsePointer(pointer string) ([]string, error) {
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("the path has to start with /")
+	}
+	segments := strings.Split(pointer[1:], "/")
+	for i, segment := range segments {
+		segments[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(segment)
+	}
+	return segments, nil
+}
+
+// Apply transforms a valid message with the first matching transformation of the pipeline, using the schema the
+// message was validated with. Messages no transformation matches are returned as they are. The attributes of the
+// original message aren't changed.
+//
+// The default values and the unknown members need a JSON Schema, so they're only handled for the formats which are
+// validated with one. Arrays on the path of a renamed member are looked into, so "/name" renames the member of each
+// row of a CSV message.
+//
+// An error is returned if the message can't be converted into JSON, e.g. because of its format, or the schema can't
+// be parsed.
+func (p *Pipeline) Apply(message pubsub.Message, schema []byte) (pubsub.Message, error) {
+	t := p.match(message.Attributes)
+	if t == nil {
+		return message, nil
+	}
+
+	format := strings.ToLower(message.Attributes["format"])
+	document, converted, err := toJSON(format, message.Data, schema)
+	if err != nil {
+		return message, err
+	}
+	var steps []string
+	if converted {
+		steps = append(steps, StepConvert)
+	}
+
+	jsonSchema := format == "json" || convert.IsConvertible(format)
+	if (jsonSchema && (t.defaults || t.stripUnknown)) || len(t.renames) > 0 {
+		value, err := decode(document)
+		if err != nil {
+			return message, err
+		}
+
+		if jsonSchema && (t.defaults || t.stripUnknown) {
+			root, err := decode(schema)
+			if err != nil {
+				return message, fmt.Errorf("the JSON Schema can't be parsed: %v", err)
+			}
+			d := &schemaDocument{root: root}
+			if t.defaults {
+				d.fillDefaults(value, root)
+				steps = append(steps, StepDefaults)
+			}
+			if t.stripUnknown {
+				d.stripUnknown(value, root)
+				steps = append(steps, StepStripUnknown)
+			}
+		}
+		for _, r := range t.renames {
+			renameMember(value, r.path, r.name)
+		}
+		if len(t.renames) > 0 {
+			steps = append(steps, StepRename)
+		}
+
+		if document, err = encode(value); err != nil {
+			return message, err
+		}
+	}
+
+	attributes := make(map[string]string, len(message.Attributes)+2)
+	for key, value := range message.Attributes {
+		attributes[key] = value
+	}
+	attributes[AttributeOutputFormat] = OutputJSON
+	if len(steps) > 0 {
+		attributes[AttributeTransformations] = strings.Join(steps, ",")
+	} else {
+		delete(attributes, AttributeTransformations)
+	}
+	return pubsub.Message{Data: document, Attributes: attributes}, nil
+}
+
+// match returns the first transformation matching a message with the given attributes, if any.
+func (p *Pipeline) match(attributes map[string]string) *transformation {
+	for i := range p.transformations {
+		if p.transformations[i].matcher.Matches(attributes) {
+			return &p.transformations[i]
+		}
+	}
+	return nil
+}
+
+// toJSON converts a message of a format into a JSON document by its schema, and reports whether it was converted.
+// JSON messages are returned as they are.
+func toJSON(format string, message, schema []byte) ([]byte, bool, error) {
+	switch {
+	case format == "json":
+		return message, false, nil
+
+	case format == "csv":
+		s, err := csvschema.Parse(schema)
+		if err != nil {
+			return nil, false, fmt.Errorf("the CSV Schema can't be parsed: %v", err)
+		}
+		records, err := s.Records(message)
+		if err != nil {
+			return nil, false, err
+		}
+		document, err := encode(records)
+		return document, true, err
+
+	case format == "xml":
+		s, err := xsd.Parse(schema)
+		if err != nil {
+			return nil, false, fmt.Errorf("the XML Schema can't be parsed: %v", err)
+		}
+		document, err := s.ToJSON(message)
+		return document, true, err
+
+	case convert.IsConvertible(format):
+		document, err := convert.ToJSON(format, message)
+		return document, true, err
+
+	default:
+		return nil, false, fmt.Errorf("messages of the format %q can't be converted into JSON", format)
+	}
+}
+
+// decode decodes a JSON document, keeping its numbers as they are written.
+func decode(document []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(document))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// encode encodes a JSON value without escaping HTML characters, which the consumers of the messages needn't be
+// protected from.
+func encode(value interface{}) ([]byte, error) {
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buffer.Bytes(), []byte("\n")), nil
+}
+
+// renameMember renames the member at a path of a JSON value, looking into arrays on the path. A member which already
+// has the new name is replaced.
+func renameMember(value interface{}, path []string, name string) {
+	switch value := value.(type) {
+	case []interface{}:
+		for _, item := range value {
+			renameMember(item, path, name)
+		}
+	case map[string]interface{}:
+		member, ok := value[path[0]]
+		if !ok {
+			return
+		}
+		if len(path) > 1 {
+			renameMember(member, path[1:], name)
+			return
+		}
+		delete(value, path[0])
+		value[name] = member
+	}
+}
@@ -0,0 +1,239 @@
+// Copyright 2020 Syntio Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transform
+
+import (
+	"testing"
+
+	"github.com/syntio/central-consumer/configuration"
+	"github.com/syntio/central-consumer/pubsub"
+)
+
+// jsonTransformation returns a transformation of the JSON messages.
+func jsonTransformation() configuration.TransformationConfig {
+	return configuration.TransformationConfig{Match: configuration.MatchConfig{Format: "json"}, Output: OutputJSON}
+}
+
+// apply creates a pipeline of a transformation and applies it to a JSON message.
+func apply(t *testing.T, cfg configuration.TransformationConfig, document, schema string) pubsub.Message {
+	t.Helper()
+	pipeline, err := New([]configuration.TransformationConfig{cfg})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	transformed, err := pipeline.Apply(pubsub.Message{Data: []byte(document),
+		Attributes: map[string]string{"format": "json"}}, []byte(schema))
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	return transformed
+}
+
+func TestApplyRenames(t *testing.T) {
+	tests := []struct {
+		name     string
+		rename   map[string]string
+		document string
+		want     string
+	}{
+		{
+			name:     "member",
+			rename:   map[string]string{"/qty": "quantity"},
+			document: `{"qty":2}`,
+			want:     `{"quantity":2}`,
+		},
+		{
+			name:     "member and its parent, deepest first",
+			rename:   map[string]string{"/customer": "buyer", "/customer/name": "fullName"},
+			document: `{"customer":{"name":"Ann"}}`,
+			want:     `{"buyer":{"fullName":"Ann"}}`,
+		},
+		{
+			name:     "members of three levels",
+			rename:   map[string]string{"/a": "x", "/a/b": "y", "/a/b/c": "z"},
+			document: `{"a":{"b":{"c":1}}}`,
+			want:     `{"x":{"y":{"z":1}}}`,
+		},
+		{
+			name:     "member of each item",
+			rename:   map[string]string{"/items/sku": "code"},
+			document: `{"items":[{"sku":"A"},{"sku":"B"}]}`,
+			want:     `{"items":[{"code":"A"},{"code":"B"}]}`,
+		},
+		{
+			name:     "member replacing another",
+			rename:   map[string]string{"/old": "new"},
+			document: `{"new":1,"old":2}`,
+			want:     `{"new":2}`,
+		},
+		{
+			name:     "missing member",
+			rename:   map[string]string{"/missing/name": "fullName"},
+			document: `{"name":"Ann"}`,
+			want:     `{"name":"Ann"}`,
+		},
+		{
+			name:     "escaped path",
+			rename:   map[string]string{"/a~1b": "ab"},
+			document: `{"a/b":1}`,
+			want:     `{"ab":1}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := jsonTransformation()
+			cfg.Rename = test.rename
+			transformed := apply(t, cfg, test.document, `{}`)
+			if got := string(transformed.Data); got != test.want {
+				t.Errorf("Apply() = %s, want %s", got, test.want)
+			}
+			if got := transformed.Attributes[AttributeTransformations]; got != StepRename {
+				t.Errorf("%s = %q, want %q", AttributeTransformations, got, StepRename)
+			}
+		})
+	}
+}
+
+func TestApplyDefaultsAndStripUnknown(t *testing.T) {
+	schema := `{
+		"type": "object",
+		"properties": {
+			"status": {"type": "string", "default": "new"},
+			"customer": {"$ref": "#/definitions/customer"},
+			"items": {"type": "array", "items": {"properties": {"qty": {"default": 1}}}},
+			"extra": {"type": "object"}
+		},
+		"patternProperties": {"^x-": {}},
+		"allOf": [{"properties": {"priority": {"default": false}}}],
+		"definitions": {
+			"customer": {"properties": {"country": {"default": "HR"}, "tags": {"default": []}}}
+		}
+	}`
+
+	tests := []struct {
+		name         string
+		defaults     bool
+		stripUnknown bool
+		document     string
+		want         string
+		steps        string
+	}{
+		{
+			name:     "defaults",
+			defaults: true,
+			document: `{"customer":{},"items":[{},{"qty":3}]}`,
+			want: `{"customer":{"country":"HR","tags":[]},"items":[{"qty":1},{"qty":3}],"priority":false,` +
+				`"status":"new"}`,
+			steps: StepDefaults,
+		},
+		{
+			name:     "defaults keep the given values",
+			defaults: true,
+			document: `{"status":"paid","priority":true,"customer":{"country":"DE","tags":["vip"]}}`,
+			want:     `{"customer":{"country":"DE","tags":["vip"]},"priority":true,"status":"paid"}`,
+			steps:    StepDefaults,
+		},
+		{
+			name:         "strip unknown",
+			stripUnknown: true,
+			document: `{"status":"new","note":"x","x-trace":"1","customer":{"country":"HR","vip":true},` +
+				`"items":[{"qty":1,"sku":"A"}],"extra":{"any":1},"priority":true}`,
+			want: `{"customer":{"country":"HR"},"extra":{"any":1},"items":[{"qty":1}],"priority":true,` +
+				`"status":"new","x-trace":"1"}`,
+			steps: StepStripUnknown,
+		},
+		{
+			name:         "defaults and strip unknown",
+			defaults:     true,
+			stripUnknown: true,
+			document:     `{"note":"x"}`,
+			want:         `{"priority":false,"status":"new"}`,
+			steps:        StepDefaults + "," + StepStripUnknown,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := jsonTransformation()
+			cfg.Defaults = test.defaults
+			cfg.StripUnknown = test.stripUnknown
+			transformed := apply(t, cfg, test.document, schema)
+			if got := string(transformed.Data); got != test.want {
+				t.Errorf("Apply() = %s, want %s", got, test.want)
+			}
+			if got := transformed.Attributes[AttributeTransformations]; got != test.steps {
+				t.Errorf("%s = %q, want %q", AttributeTransformations, got, test.steps)
+			}
+		})
+	}
+}
+
+func TestApplyAttributes(t *testing.T) {
+	pipeline, err := New([]configuration.TransformationConfig{{
+		Match:  configuration.MatchConfig{Format: "yaml", SchemaId: "orders-*"},
+		Output: OutputJSON,
+	}})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	attributes := map[string]string{"format": "yaml", "schemaId": "orders-1"}
+	transformed, err := pipeline.Apply(pubsub.Message{Data: []byte("qty: 2\n"), Attributes: attributes}, []byte(`{}`))
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if got, want := string(transformed.Data), `{"qty":2}`; got != want {
+		t.Errorf("Apply() = %s, want %s", got, want)
+	}
+	for key, want := range map[string]string{"format": "yaml", AttributeOutputFormat: OutputJSON,
+		AttributeTransformations: StepConvert} {
+		if got := transformed.Attributes[key]; got != want {
+			t.Errorf("%s = %q, want %q", key, got, want)
+		}
+	}
+	if _, ok := attributes[AttributeOutputFormat]; ok {
+		t.Errorf("the attributes of the original message were changed: %v", attributes)
+	}
+
+	other := pubsub.Message{Data: []byte("qty: 2\n"), Attributes: map[string]string{"format": "yaml",
+		"schemaId": "invoices-1"}}
+	if unmatched, err := pipeline.Apply(other, []byte(`{}`)); err != nil || string(unmatched.Data) != "qty: 2\n" ||
+		unmatched.Attributes[AttributeOutputFormat] != "" {
+		t.Errorf("Apply() of an unmatched message = %v, %v, want it as it is", unmatched, err)
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  configuration.TransformationConfig
+	}{
+		{"unsupported output", configuration.TransformationConfig{Output: "xml"}},
+		{"invalid pattern", configuration.TransformationConfig{Output: OutputJSON,
+			Match: configuration.MatchConfig{SchemaId: "orders-["}}},
+		{"relative rename", configuration.TransformationConfig{Output: OutputJSON,
+			Rename: map[string]string{"qty": "quantity"}}},
+		{"empty name", configuration.TransformationConfig{Output: OutputJSON, Rename: map[string]string{"/qty": ""}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := New([]configuration.TransformationConfig{test.cfg}); err == nil {
+				t.Error("New() error = nil, want an error")
+			}
+		})
+	}
+}
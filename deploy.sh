@@ -84,22 +84,32 @@ gcloud iam service-accounts keys create $PWD/key.json --iam-account=$AppServiceA
 
 echo "Stroing the iam key on the GCP bucket"
 gsutil cp $PWD/key.json gs://$PROJECT_ID-$BUCKET_NAME
+
+# Copy a component into a staging directory together with the shared janitor-common module, and point its go.mod to
+# the copy, so the component builds without the rest of the repository
+stage() {
+	rm -rf /tmp/staging/$1
+	mkdir -p /tmp/staging/$1
+	cp -r /workspace/$1/. /tmp/staging/$1
+	cp -r /workspace/janitor-common /tmp/staging/$1/janitor-common
+	(cd /tmp/staging/$1 && go mod edit -replace github.com/syntio/janitor-common=./janitor-common)
+}
+
 # Create .zip source files for Cloud Functions
 echo "Creating .zip source files.."
-cd central-consumer;
-zip -r ../central-consumer.zip *
-cd ../puller-cleaner/puller-cleaner-json;
+stage central-consumer
+cd /tmp/staging/central-consumer;
+zip -r /workspace/central-consumer.zip *
+stage puller-cleaner/puller-cleaner-json
+cd /tmp/staging/puller-cleaner/puller-cleaner-json;
 zip -r /workspace/puller-cleaner-json.zip *
-cd ../puller-cleaner-csv;
+stage puller-cleaner/puller-cleaner-csv
+cd /tmp/staging/puller-cleaner/puller-cleaner-csv;
 zip -r /workspace/puller-cleaner-csv.zip *
-cd  ../../puller-tasks/puller-tasks-json;
+cd /workspace/puller-tasks/puller-tasks-json;
 zip -r /workspace/puller-tasks-json.zip *
 cd ../puller-tasks-csv
 zip -r /workspace/puller-tasks-csv.zip *
-cd ../../helper-functions/xml-validator;
-zip -r /workspace/xml-validator.zip *
-cd ../csv-validator
-zip -r /workspace/csv-validator.zip *
 
 cd /workspace
 
@@ -110,7 +120,8 @@ do
 	gsutil cp $zip gs://$PROJECT_ID-$BUCKET_NAME
 done
 
-cd schema-registry
+stage schema-registry
+cd /tmp/staging/schema-registry
 
 # Put the REST server application inside a docker container and deploy the image to the Container Registry
 echo "Deploying Schema Registry component image in the Container Registry.."
@@ -118,36 +129,24 @@ gcloud builds submit --tag gcr.io/$PROJECT_ID/schema-registry
 
 # Deploy the created image to Cloud Run
 echo "Deploying the created image to Cloud Run.."
-gcloud run deploy schema-registry --image gcr.io/$PROJECT_ID/schema-registry --platform managed --allow-unauthenticated --region $REGION --set-env-vars PROJECT_ID=$PROJECT_ID,BUCKET_NAME=$PROJECT_ID-$BUCKET_NAME,CONFIG_FILE=$CONFIG_FILE,SERVICE_ACCOUNT_KEY_FILE=key.json
+gcloud run deploy schema-registry --image gcr.io/$PROJECT_ID/schema-registry --platform managed --use-http2 --allow-unauthenticated --region $REGION --set-env-vars PROJECT_ID=$PROJECT_ID,BUCKET_NAME=$PROJECT_ID-$BUCKET_NAME,CONFIG_FILE=$CONFIG_FILE,SERVICE_ACCOUNT_KEY_FILE=key.json
 
-cd ..
+cd /workspace
 
 echo "Getting schema-registry URL"
 SCHEMA_REGISTRY_URL=$(gcloud run services list --platform managed | awk 'NR==2 {print $4}')
-
-# Deploy the helper functions for XML and CSV validation
-echo "Deploying the helper Cloud Functions.."
 echo $SCHEMA_REGISTRY_URL
-gcloud functions deploy xml-validator --runtime python37 --timeout=540s --allow-unauthenticated --entry-point http_validation_handler --source=gs://$PROJECT_ID-$BUCKET_NAME/xml-validator.zip --trigger-http --region $REGION
-
-gcloud functions deploy csv-validator --runtime java11 --timeout=540 --allow-unauthenticated --entry-point hr.syntio.handler.HttpHandler --source=gs://$PROJECT_ID-$BUCKET_NAME/csv-validator.zip --trigger-http --region $REGION
-
-echo "Getting helper-functions URLs"
-XML_VALIDATOR_URL=https://$REGION-$PROJECT_ID.cloudfunctions.net/xml-validator
-CSV_VALIDATOR_URL=https://$REGION-$PROJECT_ID.cloudfunctions.net/csv-validator
-echo $XML_VALIDATOR_URL
-echo $CSV_VALIDATOR_URL
 
 # Deploy the Central Consumer to Cloud Functions
 echo "Deploying the Central Consumer component.."
-gcloud functions deploy central-consumer --runtime go113 --timeout=540s --allow-unauthenticated --entry-point CentralConsumerHandler --set-env-vars SCHEMA_REGISTRY_URL=$SCHEMA_REGISTRY_URL,XML_VALIDATOR_URL=$XML_VALIDATOR_URL,CSV_VALIDATOR_URL=$CSV_VALIDATOR_URL --source=gs://$PROJECT_ID-$BUCKET_NAME/central-consumer.zip --trigger-topic $INPUT_TOPIC --region $REGION --set-env-vars PROJECT_ID=$PROJECT_ID,BUCKET_NAME=$PROJECT_ID-$BUCKET_NAME,CONFIG_FILE=$CONFIG_FILE
+gcloud functions deploy central-consumer --runtime go116 --timeout=540s --allow-unauthenticated --retry --entry-point CentralConsumerHandler --set-env-vars SCHEMA_REGISTRY_URL=$SCHEMA_REGISTRY_URL --source=gs://$PROJECT_ID-$BUCKET_NAME/central-consumer.zip --trigger-topic $INPUT_TOPIC --region $REGION --set-env-vars PROJECT_ID=$PROJECT_ID,BUCKET_NAME=$PROJECT_ID-$BUCKET_NAME,CONFIG_FILE=$CONFIG_FILE
 
 EVOLUTION_PATH=/schema/%s/evolution
 # Deploy the Puller & Cleaner (JSON and CSV) to Cloud Functions
 echo "Deploying the Puller & Cleaner component.."
-gcloud functions deploy puller-cleaner-json --runtime go113 --timeout=540s --allow-unauthenticated --entry-point PullerCleaner --set-env-vars SCHEMA_REGISTRY_URL=$SCHEMA_REGISTRY_URL,EVOLUTION_PATH=$EVOLUTION_PATH   --source=gs://$PROJECT_ID-$BUCKET_NAME/puller-cleaner-json.zip --trigger-http --region $REGION --set-env-vars PROJECT_ID=$PROJECT_ID,BUCKET_NAME=$PROJECT_ID-$BUCKET_NAME,CONFIG_FILE=$CONFIG_FILE
+gcloud functions deploy puller-cleaner-json --runtime go116 --timeout=540s --allow-unauthenticated --entry-point PullerCleaner --set-env-vars SCHEMA_REGISTRY_URL=$SCHEMA_REGISTRY_URL,EVOLUTION_PATH=$EVOLUTION_PATH   --source=gs://$PROJECT_ID-$BUCKET_NAME/puller-cleaner-json.zip --trigger-http --region $REGION --set-env-vars PROJECT_ID=$PROJECT_ID,BUCKET_NAME=$PROJECT_ID-$BUCKET_NAME,CONFIG_FILE=$CONFIG_FILE
 
-gcloud functions deploy puller-cleaner-csv --runtime go113 --timeout=540s --allow-unauthenticated --entry-point PullerCleaner --set-env-vars SCHEMA_REGISTRY_URL=$SCHEMA_REGISTRY_URL,CSV_VALIDATOR_URL=$CSV_VALIDATOR_URL,EVOLUTION_PATH=$EVOLUTION_PATH  --source=gs://$PROJECT_ID-$BUCKET_NAME/puller-cleaner-csv.zip --trigger-http --region $REGION --set-env-vars PROJECT_ID=$PROJECT_ID,BUCKET_NAME=$PROJECT_ID-$BUCKET_NAME,CONFIG_FILE=$CONFIG_FILE
+gcloud functions deploy puller-cleaner-csv --runtime go116 --timeout=540s --allow-unauthenticated --entry-point PullerCleaner --set-env-vars SCHEMA_REGISTRY_URL=$SCHEMA_REGISTRY_URL,EVOLUTION_PATH=$EVOLUTION_PATH  --source=gs://$PROJECT_ID-$BUCKET_NAME/puller-cleaner-csv.zip --trigger-http --region $REGION --set-env-vars PROJECT_ID=$PROJECT_ID,BUCKET_NAME=$PROJECT_ID-$BUCKET_NAME,CONFIG_FILE=$CONFIG_FILE
 
 echo "Getting puller&cleaner URLs"
 PULLER_CLEANER_JSON_URL=https://$REGION-$PROJECT_ID.cloudfunctions.net/puller-cleaner-json
@@ -156,9 +155,9 @@ PULLER_CLEANER_CSV_URL=https://$REGION-$PROJECT_ID.cloudfunctions.net/puller-cle
 # Deploy the function that creates Cloud Tasks to invoke Cloud Functions
 echo "Deploying the Puller Tasks functions.."
 
-gcloud functions deploy puller-tasks-json --runtime go113 --timeout=540s --allow-unauthenticated --entry-point HTTPPullerTasks --set-env-vars PULLER_CLEANER_JSON_URL=$PULLER_CLEANER_JSON_URL --source=gs://$PROJECT_ID-$BUCKET_NAME/puller-tasks-json.zip --trigger-http --region $REGION --set-env-vars PROJECT_ID=$PROJECT_ID,REGION=$REGION,BUCKET_NAME=$PROJECT_ID-$BUCKET_NAME,CONFIG_FILE=$CONFIG_FILE
+gcloud functions deploy puller-tasks-json --runtime go116 --timeout=540s --allow-unauthenticated --entry-point HTTPPullerTasks --set-env-vars PULLER_CLEANER_JSON_URL=$PULLER_CLEANER_JSON_URL --source=gs://$PROJECT_ID-$BUCKET_NAME/puller-tasks-json.zip --trigger-http --region $REGION --set-env-vars PROJECT_ID=$PROJECT_ID,REGION=$REGION,BUCKET_NAME=$PROJECT_ID-$BUCKET_NAME,CONFIG_FILE=$CONFIG_FILE
 
-gcloud functions deploy puller-tasks-csv --runtime go113 --timeout=540s --allow-unauthenticated --entry-point HTTPPullerTasks --set-env-vars PULLER_CLEANER_CSV_URL=$PULLER_CLEANER_CSV_URL --source=gs://$PROJECT_ID-$BUCKET_NAME/puller-tasks-csv.zip --trigger-http --region $REGION --set-env-vars PROJECT_ID=$PROJECT_ID,REGION=$REGION,BUCKET_NAME=$PROJECT_ID-$BUCKET_NAME,CONFIG_FILE=$CONFIG_FILE
+gcloud functions deploy puller-tasks-csv --runtime go116 --timeout=540s --allow-unauthenticated --entry-point HTTPPullerTasks --set-env-vars PULLER_CLEANER_CSV_URL=$PULLER_CLEANER_CSV_URL --source=gs://$PROJECT_ID-$BUCKET_NAME/puller-tasks-csv.zip --trigger-http --region $REGION --set-env-vars PROJECT_ID=$PROJECT_ID,REGION=$REGION,BUCKET_NAME=$PROJECT_ID-$BUCKET_NAME,CONFIG_FILE=$CONFIG_FILE
 
 # Create Cloud Tasks queue
 echo "Creating Cloud Tasks Queue.."
@@ -177,4 +176,9 @@ gcloud scheduler jobs create http puller-cleaner-invoker-json --schedule "* * *
 gcloud scheduler jobs create http puller-cleaner-invoker-csv --schedule "* * * * *" \
 	--uri $SCHEDULER_URI_CSV --message-body "{\"puller-number\":10}"
 
+# Compact the schemas of every namespace daily. The registry runs on Cloud Run, whose instances get no CPU between
+# requests, so compaction is triggered by a request instead of a timer
+gcloud scheduler jobs create http schema-registry-compaction --schedule "0 3 * * *" \
+	--uri $SCHEMA_REGISTRY_URL/compaction --http-method POST
+
 echo "Deployment complete :)"
@@ -1,36 +0,0 @@
-// Copyright 2020 Syntio Inc.
-
-// Licensed under the Apache License, Version 2.0 (the "License");
-// you may not use this file except in compliance with the License.
-// You may obtain a copy of the License at
-
-//     http://www.apache.org/licenses/LICENSE-2.0
-
-// Unless required by applicable law or agreed to in writing, software
-// distributed under the License is distributed on an "AS IS" BASIS,
-// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
-// See the License for the specific language governing permissions and
-// limitations under the License.
-
-// Package handles csv document validation
-package hr.syntio.csv;
-
-import uk.gov.nationalarchives.csv.validator.api.java.CsvValidator;
-import uk.gov.nationalarchives.csv.validator.api.java.FailMessage;
-
-import java.io.Reader;
-import java.io.StringReader;
-import java.util.ArrayList;
-import java.util.List;
-
-public class Validator {
-
-    public static boolean validate(String data, String schema) {
-        Reader dataReader = new StringReader(data);
-        Reader schemaReader = new StringReader(schema);
-        List<FailMessage> messages = CsvValidator.validate(dataReader, schemaReader, false, new ArrayList<>(),
-                true, false);
-
-        return messages.isEmpty();
-    }
-}
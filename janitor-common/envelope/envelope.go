@@ -0,0 +1,160 @@
+// Copyright 2020 Syntio Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package envelope stamps the messages which are rerouted to the invalid and dead-letter topics with the janitor
+// attributes, which tell operators why, where and when a message was rerouted, and by which component. The Central
+// Consumer and the puller-cleaners stamp the messages they reroute with the same attributes.
+package envelope
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/syntio/janitor-common/validator/impl"
+)
+
+// Attributes of a rerouted message.
+const (
+	AttributeReason    = "janitor.reason"
+	AttributeStage     = "janitor.stage"
+	AttributeError     = "janitor.error"
+	AttributeTimestamp = "janitor.timestamp"
+	AttributeComponent = "janitor.component"
+	AttributeAttempt   = "janitor.attempt"
+	// AttributeViolations holds the violations of an invalid message as a JSON array.
+	AttributeViolations = "janitor.violations"
+)
+
+// Components which stamp messages.
+const (
+	ComponentCentralConsumer   = "central-consumer"
+	ComponentPullerCleanerJSON = "puller-cleaner-json"
+	ComponentPullerCleanerCSV  = "puller-cleaner-csv"
+)
+
+// Stages of the message handling a message is rerouted at.
+const (
+	StageMetadata        = "metadata"
+	StageFiltering       = "filtering"
+	StageSchemaInference = "schema-inference"
+	StageSchemaRetrieval = "schema-retrieval"
+	StageSchemaDecoding  = "schema-decoding"
+	StageValidation      = "validation"
+	StageTransformation  = "transformation"
+	StageTransmission    = "transmission"
+)
+
+// Reasons a message is rerouted for.
+const (
+	ReasonMissingMetadata   = "missing-metadata"
+	ReasonUnsupportedFormat = "unsupported-format"
+	ReasonWrongFormat       = "wrong-format"
+	ReasonInvalidMessage    = "invalid-message"
+	ReasonInferenceFailed   = "inference-failed"
+	ReasonEvolutionPending  = "evolution-pending"
+	ReasonEvolutionLimited  = "evolution-limited"
+	ReasonEvolutionRefused  = "evolution-refused"
+	ReasonSchemaNotFound    = "schema-not-found"
+	ReasonRegistryError     = "registry-error"
+	ReasonSchemaDecoding    = "schema-decoding-failed"
+	ReasonValidatorError    = "validator-error"
+	ReasonValidationFailed  = "validation-failed"
+	ReasonTransformation    = "transformation-failed"
+	ReasonPublishFailed     = "publish-failed"
+)
+
+// maxValueBytes is the size limit of a Pub/Sub attribute value.
+const maxValueBytes = 1024
+
+// Stamp returns a copy of the attributes of a message stamped with the component which reroutes it, the reason and
+// the stage it is rerouted for and at, and the error which caused it, if any. The attempt counts how often the message
+// has been rerouted by any component, this time included. The attributes passed in aren't changed.
+func Stamp(attributes map[string]string, component, reason, stage string, err error) map[string]string {
+	stamped := make(map[string]string, len(attributes)+6)
+	for key, value := range attributes {
+		stamped[key] = value
+	}
+
+	attempt := Attempt(stamped)
+	stamped[AttributeReason] = reason
+	stamped[AttributeStage] = stage
+	stamped[AttributeTimestamp] = time.Now().UTC().Format(time.RFC3339Nano)
+	stamped[AttributeComponent] = component
+	stamped[AttributeAttempt] = strconv.Itoa(attempt + 1)
+	if err != nil {
+		stamped[AttributeError] = truncate(err.Error(), maxValueBytes)
+	} else {
+		delete(stamped, AttributeError)
+	}
+	return stamped
+}
+
+// Attempt returns how often a message with the given attributes has been rerouted, zero if it never was.
+func Attempt(attributes map[string]string) int {
+	attempt, _ := strconv.Atoi(attributes[AttributeAttempt])
+	return attempt
+}
+
+// StampViolations returns a copy of the attributes of an invalid message stamped like Stamp does, with the error
+// listing the violations of the message and the violations themselves as a JSON array. The array holds as many
+// violations as fit into an attribute, in the order they were found.
+func StampViolations(attributes map[string]string, component, stage string,
+	violations []impl.Violation) map[string]string {
+	var err error
+	if len(violations) > 0 {
+		descriptions := make([]string, len(violations))
+		for i, violation := range violations {
+			descriptions[i] = violation.Location + ": " + violation.Description
+		}
+		err = errors.New(strings.Join(descriptions, "; "))
+	}
+
+	stamped := Stamp(attributes, component, ReasonValidationFailed, stage, err)
+	delete(stamped, AttributeViolations)
+	// The encoding only grows with the number of violations, so the most violations which fit are searched for.
+	n := sort.Search(len(violations), func(i int) bool {
+		encoded, err := json.Marshal(violations[:i+1])
+		return err != nil || len(encoded) > maxValueBytes
+	})
+	if n > 0 {
+		encoded, _ := json.Marshal(violations[:n])
+		stamped[AttributeViolations] = string(encoded)
+	}
+	return stamped
+}
+
+// Clear removes the janitor attributes from the attributes of a message which is cleaned, so it doesn't arrive at the
+// valid topic with the reason it was rerouted for before.
+func Clear(attributes map[string]string) {
+	for _, attribute := range []string{AttributeReason, AttributeStage, AttributeError, AttributeTimestamp,
+		AttributeComponent, AttributeAttempt, AttributeViolations} {
+		delete(attributes, attribute)
+	}
+}
+
+// truncate truncates a string to at most n bytes, without splitting a UTF-8 encoded character.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
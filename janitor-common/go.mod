@@ -0,0 +1,21 @@
+module github.com/syntio/janitor-common
+
+go 1.16
+
+require (
+	cloud.google.com/go/pubsub v1.3.1
+	github.com/fxamacker/cbor/v2 v2.4.0
+	github.com/hamba/avro v1.0.0
+	github.com/jhump/protoreflect v1.6.1
+	github.com/prometheus/client_golang v1.7.1
+	github.com/vmihailenco/msgpack/v5 v5.3.5
+	github.com/xeipuuv/gojsonschema v1.2.0
+	go.mongodb.org/mongo-driver v1.4.2
+	go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp v0.25.0
+	go.opentelemetry.io/otel v1.0.1
+	go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp v1.0.1
+	go.opentelemetry.io/otel/sdk v1.0.1
+	go.opentelemetry.io/otel/trace v1.0.1
+	golang.org/x/sync v0.0.0-20201008141435-b3e1573b7520
+	gopkg.in/yaml.v3 v3.0.0-20200313102051-9f266ea9e77c
+)
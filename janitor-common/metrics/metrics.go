@@ -0,0 +1,118 @@
+// Copyright 2020 Syntio Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package metrics holds the Prometheus metrics of the puller-cleaners. Every invocation cleans one pulled batch, whose
+// counts are collected in the invocation's context and pushed to a Prometheus Pushgateway when the batch is done,
+// since Cloud Function instances can't be scraped. The puller-cleaners are told apart by the job they push under.
+package metrics
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+)
+
+// Stages a pulled message can end up in.
+const (
+	StagePulled       = "pulled"
+	StageCleaned      = "cleaned"
+	StageRerouted     = "rerouted"
+	StageDeadLettered = "dead-lettered"
+)
+
+var stages = []string{StagePulled, StageCleaned, StageRerouted, StageDeadLettered}
+
+var (
+	// Messages counts the messages per stage over all batches.
+	Messages = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "puller_cleaner_messages_total",
+		Help: "Messages pulled, cleaned, rerouted to another format's topic and dead-lettered.",
+	}, []string{"stage"})
+
+	// BatchMessages observes how many messages of a batch ended up in each stage.
+	BatchMessages = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "puller_cleaner_batch_messages",
+		Help:    "Messages per batch pulled, cleaned, rerouted to another format's topic and dead-lettered.",
+		Buckets: prometheus.ExponentialBuckets(1, 4, 7),
+	}, []string{"stage"})
+)
+
+var (
+	gatherer   = prometheus.NewRegistry()
+	pusher     *push.Pusher
+	instanceID = newInstanceID()
+)
+
+func init() {
+	gatherer.MustRegister(Messages, BatchMessages)
+}
+
+// Configure sets the Pushgateway the metrics are pushed to under the named job. Nothing is pushed while it isn't set.
+func Configure(jobName, pushgatewayURL string) {
+	if pushgatewayURL == "" {
+		return
+	}
+	pusher = push.New(pushgatewayURL, jobName).Gatherer(gatherer).Grouping("instance", instanceID)
+}
+
+type batchKey struct{}
+
+// Batch collects the counts of one pulled batch.
+type Batch struct {
+	counts map[string]int
+}
+
+// NewBatch starts collecting the counts of a batch of pulled messages. The returned context carries the batch, so
+// the messages passed along with it are counted by Count.
+func NewBatch(ctx context.Context, pulled int) (context.Context, *Batch) {
+	batch := &Batch{counts: map[string]int{StagePulled: pulled}}
+	return context.WithValue(ctx, batchKey{}, batch), batch
+}
+
+// Count counts a message of the batch carried by the context in the given stage. Messages are handled one at a
+// time within a batch, so no locking is needed.
+func Count(ctx context.Context, stage string) {
+	if batch, ok := ctx.Value(batchKey{}).(*Batch); ok {
+		batch.counts[stage]++
+	}
+}
+
+// Finish observes the counts of the batch and pushes the metrics to the Pushgateway. Pushing is done within the
+// invocation, since Cloud Functions get no CPU between invocations.
+func (b *Batch) Finish() {
+	for _, stage := range stages {
+		Messages.WithLabelValues(stage).Add(float64(b.counts[stage]))
+		BatchMessages.WithLabelValues(stage).Observe(float64(b.counts[stage]))
+	}
+
+	if pusher == nil {
+		return
+	}
+	if err := pusher.Push(); err != nil {
+		log.Printf("ERROR: metrics couldn't be pushed. %v", err)
+	}
+}
+
+// newInstanceID returns a random ID telling the metrics of function instances apart.
+func newInstanceID() string {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(id)
+}
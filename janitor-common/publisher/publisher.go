@@ -0,0 +1,223 @@
+// Copyright 2020 Syntio Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package publisher publishes messages to Pub/Sub topics for the Central Consumer and the puller-cleaners. Publishing
+// goes through one client and one publisher per topic, which live as long as the function instance, so only the first
+// message an instance publishes to a topic pays for the connection. The publishers batch messages by the configured
+// publish settings, and flow control bounds the messages and bytes being published at once.
+package publisher
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/sync/semaphore"
+)
+
+var (
+	publishersMu    sync.Mutex
+	client          *pubsub.Client
+	publishers      = map[string]*pubsub.Topic{}
+	publishSettings = pubsub.DefaultPublishSettings
+
+	outstandingMessages limit
+	outstandingBytes    limit
+)
+
+// Configure sets the publish settings of the publishers, and the limits of the messages and bytes being published
+// at once (a limit ≤0 doesn't limit). It must be called before anything is published.
+func Configure(settings pubsub.PublishSettings, maxOutstandingMessages, maxOutstandingBytes int64) {
+	publishSettings = settings
+	outstandingMessages = newLimit(maxOutstandingMessages)
+	outstandingBytes = newLimit(maxOutstandingBytes)
+}
+
+// Settings returns the publish settings of a config file, defaulting to the Pub/Sub client's settings for the ones
+// which aren't positive.
+func Settings(delayThreshold time.Duration, countThreshold, byteThreshold int,
+	timeout time.Duration) pubsub.PublishSettings {
+	settings := pubsub.DefaultPublishSettings
+	if delayThreshold > 0 {
+		settings.DelayThreshold = delayThreshold
+	}
+	if countThreshold > 0 {
+		settings.CountThreshold = countThreshold
+	}
+	if byteThreshold > 0 {
+		settings.ByteThreshold = byteThreshold
+	}
+	if timeout > 0 {
+		settings.Timeout = timeout
+	}
+	return settings
+}
+
+// limit is a flow control limit. A zero limit doesn't limit anything.
+type limit struct {
+	size int64
+	sem  *semaphore.Weighted
+}
+
+// newLimit creates a limit of the given size, a size ≤0 doesn't limit.
+func newLimit(size int64) limit {
+	if size <= 0 {
+		return limit{}
+	}
+	return limit{size: size, sem: semaphore.NewWeighted(size)}
+}
+
+// acquire waits until n more units fit into the limit or the context is done. A single request larger than the
+// limit waits for the whole limit, so it isn't refused.
+func (l limit) acquire(ctx context.Context, n int64) (int64, error) {
+	if l.sem == nil {
+		return 0, nil
+	}
+	if n > l.size {
+		n = l.size
+	}
+	return n, l.sem.Acquire(ctx, n)
+}
+
+// release releases n units acquired from the limit.
+func (l limit) release(n int64) {
+	if l.sem != nil && n > 0 {
+		l.sem.Release(n)
+	}
+}
+
+// publisher returns the publisher of a topic, creating the client and the publisher on first use.
+func publisher(projectID, topicName string) (*pubsub.Topic, error) {
+	publishersMu.Lock()
+	defer publishersMu.Unlock()
+
+	if topic, ok := publishers[topicName]; ok {
+		return topic, nil
+	}
+
+	if client == nil {
+		// The client outlives the invocation it's created in, so it mustn't use the invocation's context.
+		newClient, err := pubsub.NewClient(context.Background(), projectID)
+		if err != nil {
+			return nil, err
+		}
+		client = newClient
+	}
+
+	topic := client.Topic(topicName)
+	topic.PublishSettings = publishSettings
+	publishers[topicName] = topic
+
+	return topic, nil
+}
+
+// Publish hands a message over to the publisher of the topic and returns its publish result without waiting for it.
+// It waits while the outstanding messages are over the flow control limits, and returns an error if the context is
+// done before the message could be handed over.
+func Publish(ctx context.Context, projectID, topicName string, message *pubsub.Message) (*pubsub.PublishResult,
+	error) {
+	topic, err := publisher(projectID, topicName)
+	if err != nil {
+		return nil, err
+	}
+
+	messages, err := outstandingMessages.acquire(ctx, 1)
+	if err != nil {
+		return nil, err
+	}
+	bytes, err := outstandingBytes.acquire(ctx, int64(len(message.Data)))
+	if err != nil {
+		outstandingMessages.release(messages)
+		return nil, err
+	}
+
+	result := topic.Publish(ctx, message)
+	go func() {
+		<-result.Ready()
+		outstandingBytes.release(bytes)
+		outstandingMessages.release(messages)
+	}()
+
+	return result, nil
+}
+
+type publishingKey struct{}
+
+// Publishing collects the publish results of the messages published within a batch, so the batch doesn't wait for
+// every message before handling the next one.
+type Publishing struct {
+	pending []pendingMessage
+}
+
+// pendingMessage is a published message whose publish result hasn't been checked yet.
+type pendingMessage struct {
+	message   pubsub.Message
+	topicName string
+	result    *pubsub.PublishResult
+	span      trace.Span
+}
+
+// PublishError is the error of a message that couldn't be published. Message is the message as it was published,
+// so it can be published elsewhere instead.
+type PublishError struct {
+	Message pubsub.Message
+	Topic   string
+	Err     error
+}
+
+func (e PublishError) Error() string {
+	return fmt.Sprintf("ERROR: Couldn't publish the message %s to the topic %s: %v", e.Message.ID, e.Topic, e.Err)
+}
+
+// StartPublishing starts collecting the publish results of a batch. The returned context carries the collection,
+// so the messages tracked with it are checked when Wait is called.
+func StartPublishing(ctx context.Context) (context.Context, *Publishing) {
+	publishing := &Publishing{}
+	return context.WithValue(ctx, publishingKey{}, publishing), publishing
+}
+
+// Track adds a published message, its topic, its publish result and the span of its publishing to the publishing
+// carried by the context, which ends the span once the result is checked. Messages are published one at a time
+// within a batch, so no locking is needed. It returns false if the context carries no publishing.
+func Track(ctx context.Context, message pubsub.Message, topicName string, result *pubsub.PublishResult,
+	span trace.Span) bool {
+	publishing, ok := ctx.Value(publishingKey{}).(*Publishing)
+	if ok {
+		publishing.pending = append(publishing.pending,
+			pendingMessage{message: message, topicName: topicName, result: result, span: span})
+	}
+	return ok
+}
+
+// Wait waits for the publish results of all the messages tracked so far and returns the errors of the messages that
+// couldn't be published, one per message.
+func (p *Publishing) Wait(ctx context.Context) []PublishError {
+	var errs []PublishError
+
+	for _, pending := range p.pending {
+		if _, err := pending.result.Get(ctx); err != nil {
+			pending.span.RecordError(err)
+			pending.span.SetStatus(codes.Error, err.Error())
+			errs = append(errs, PublishError{Message: pending.message, Topic: pending.topicName, Err: err})
+		}
+		pending.span.End()
+	}
+	p.pending = nil
+
+	return errs
+}
@@ -0,0 +1,143 @@
+// Copyright 2020 Syntio Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package tracing holds the OpenTelemetry tracing of the central consumer, the Schema Registry and the puller-cleaners.
+// Spans are exported over OTLP/HTTP to the collector set in the config file, and the W3C trace context is propagated
+// over HTTP headers and Pub/Sub message attributes, so a message keeps its trace from the producer through the
+// consumer, the Schema Registry and the validators to the puller-cleaners.
+package tracing
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName names the instrumentation creating the spans.
+const instrumentationName = "github.com/syntio/janitor-common/tracing"
+
+// HTTPClient sends HTTP requests in client spans and propagates the trace context of the request context in their
+// headers.
+var HTTPClient = &http.Client{Transport: otelhttp.NewTransport(http.DefaultTransport)}
+
+var provider *sdktrace.TracerProvider
+
+func init() {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+}
+
+// Configure exports the spans of the named service to the OTLP/HTTP collector on the endpoint (host:port), over plain
+// HTTP if insecure is set. New traces are sampled by the ratio (all of them if it isn't set), continued traces follow
+// the sampling decision of their parent. Nothing is exported while no endpoint is set, but the trace context is still
+// propagated.
+func Configure(serviceName, endpoint string, insecure bool, sampleRatio float64) {
+	if endpoint == "" {
+		return
+	}
+	options := []otlptracehttp.Option{otlptracehttp.WithEndpoint(endpoint)}
+	if insecure {
+		options = append(options, otlptracehttp.WithInsecure())
+	}
+	exporter, err := otlptracehttp.New(context.Background(), options...)
+	if err != nil {
+		log.Printf("ERROR: trace exporter couldn't be created. %v.\n", err)
+		return
+	}
+
+	if sampleRatio <= 0 {
+		sampleRatio = 1
+	}
+	provider = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceNameKey.String(serviceName))),
+	)
+	otel.SetTracerProvider(provider)
+}
+
+// Start starts a span as a child of the span in the context.
+func Start(ctx context.Context, name string, options ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, options...)
+}
+
+// StartMessage starts a span in the trace carried by the attributes of a message, linked to the span in the context.
+// Messages without a trace context are traced as a child of the span in the context.
+func StartMessage(ctx context.Context, name string, attributes map[string]string,
+	options ...trace.SpanStartOption) (context.Context, trace.Span) {
+	parent := trace.SpanContextFromContext(ctx)
+	ctx = Extract(ctx, attributes)
+	if !trace.SpanContextFromContext(ctx).Equal(parent) {
+		options = append(options, trace.WithLinks(trace.Link{SpanContext: parent}))
+	}
+	return Start(ctx, name, options...)
+}
+
+// ExtractHeaders returns the context with the trace context carried by the headers of a request. The context is
+// returned unchanged if the headers carry no trace context.
+func ExtractHeaders(ctx context.Context, header http.Header) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(header))
+}
+
+// Extract returns the context with the trace context carried by the attributes of a message. The context is returned
+// unchanged if the attributes carry no trace context.
+func Extract(ctx context.Context, attributes map[string]string) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, AttributeCarrier(attributes))
+}
+
+// Inject sets the trace context of the context into the attributes of a message.
+func Inject(ctx context.Context, attributes map[string]string) {
+	otel.GetTextMapPropagator().Inject(ctx, AttributeCarrier(attributes))
+}
+
+// Flush exports the finished spans. Cloud Functions flush within the invocation, since they get no CPU between
+// invocations, and servers flush when they shut down.
+func Flush(ctx context.Context) {
+	if provider == nil {
+		return
+	}
+	if err := provider.ForceFlush(ctx); err != nil {
+		log.Printf("ERROR: spans couldn't be exported. %v.\n", err)
+	}
+}
+
+// AttributeCarrier carries the trace context in the attributes of a Pub/Sub message.
+type AttributeCarrier map[string]string
+
+// Get returns the value of an attribute.
+func (c AttributeCarrier) Get(key string) string {
+	return c[key]
+}
+
+// Set sets the value of an attribute.
+func (c AttributeCarrier) Set(key, value string) {
+	c[key] = value
+}
+
+// Keys lists the attributes.
+func (c AttributeCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	return keys
+}
@@ -0,0 +1,159 @@
+// Copyright 2020 Syntio Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package convert converts messages of the formats which are validated with a JSON Schema, YAML, MessagePack, CBOR
+// and BSON, into JSON documents.
+package convert
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+	"go.mongodb.org/mongo-driver/bson"
+	"gopkg.in/yaml.v3"
+)
+
+// Formats are the message formats converted into JSON.
+var Formats = []string{"yaml", "msgpack", "cbor", "bson"}
+
+// IsConvertible reports whether messages of a format, matched case-insensitively, are converted into JSON.
+func IsConvertible(format string) bool {
+	format = strings.ToLower(format)
+	for _, convertible := range Formats {
+		if format == convertible {
+			return true
+		}
+	}
+	return false
+}
+
+// ToJSON converts a message of a format into a JSON document. Byte strings become base64 strings and timestamps
+// RFC 3339 strings. BSON documents become relaxed MongoDB Extended JSON, e.g. {"$oid": "..."} for object IDs.
+//
+// An error is returned if the message can't be decoded, holds more than one value or a value without a JSON
+// representation, e.g. NaN.
+func ToJSON(format string, message []byte) ([]byte, error) {
+	format = strings.ToLower(format)
+	if format == "bson" {
+		if err := bson.Raw(message).Validate(); err != nil {
+			return nil, err
+		}
+		return bson.MarshalExtJSON(bson.Raw(message), false, false)
+	}
+
+	var value interface{}
+	switch format {
+	case "yaml":
+		decoder := yaml.NewDecoder(bytes.NewReader(message))
+		if err := decoder.Decode(&value); err != nil {
+			return nil, err
+		}
+		var next interface{}
+		if err := decoder.Decode(&next); err != io.EOF {
+			return nil, fmt.Errorf("the message holds more than one YAML document")
+		}
+	case "msgpack":
+		decoder := msgpack.NewDecoder(bytes.NewReader(message))
+		decoder.SetMapDecoder(func(d *msgpack.Decoder) (interface{}, error) {
+			return d.DecodeUntypedMap()
+		})
+		if err := decoder.Decode(&value); err != nil {
+			return nil, err
+		}
+		if _, err := decoder.PeekCode(); err != io.EOF {
+			return nil, fmt.Errorf("the message holds more than one MessagePack value")
+		}
+	case "cbor":
+		decoder := cbor.NewDecoder(bytes.NewReader(message))
+		if err := decoder.Decode(&value); err != nil {
+			return nil, err
+		}
+		if decoder.NumBytesRead() != len(message) {
+			return nil, fmt.Errorf("the message holds more than one CBOR value")
+		}
+	default:
+		return nil, fmt.Errorf("messages in the format %s aren't converted into JSON", format)
+	}
+
+	normalized, err := normalize(value)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(normalized)
+}
+
+// normalize turns a decoded value into a value with a JSON representation.
+func normalize(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		object := make(map[string]interface{}, len(v))
+		for key, element := range v {
+			normalized, err := normalize(element)
+			if err != nil {
+				return nil, err
+			}
+			object[key] = normalized
+		}
+		return object, nil
+
+	case map[interface{}]interface{}:
+		object := make(map[string]interface{}, len(v))
+		for key, element := range v {
+			normalized, err := normalize(element)
+			if err != nil {
+				return nil, err
+			}
+			object[fmt.Sprint(key)] = normalized
+		}
+		return object, nil
+
+	case []interface{}:
+		array := make([]interface{}, len(v))
+		for i, element := range v {
+			normalized, err := normalize(element)
+			if err != nil {
+				return nil, err
+			}
+			array[i] = normalized
+		}
+		return array, nil
+
+	case []byte:
+		return base64.StdEncoding.EncodeToString(v), nil
+
+	case time.Time:
+		return v.Format(time.RFC3339Nano), nil
+
+	case float32:
+		return normalize(float64(v))
+
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, fmt.Errorf("the number %v has no JSON representation", v)
+		}
+		return v, nil
+
+	case cbor.Tag:
+		return normalize(v.Content)
+	}
+	return value, nil
+}
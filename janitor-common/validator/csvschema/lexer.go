@@ -0,0 +1,177 @@
+// Copyright 2020 Syntio Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csvschema
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenWord
+	tokenString
+	tokenChar
+	tokenDirective
+	tokenReference
+	tokenLeftParen
+	tokenRightParen
+	tokenComma
+	tokenColon
+	tokenSlash
+)
+
+// token is a token of a CSV Schema. The text of words, directives and column references leaves out their @ and $
+// prefixes, and the text of strings and characters their quotes.
+type token struct {
+	kind tokenKind
+	text string
+	line int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokenEOF:
+		return "the end of the schema"
+	case tokenString:
+		return fmt.Sprintf("%q", t.text)
+	case tokenDirective:
+		return "@" + t.text
+	case tokenReference:
+		return "$" + t.text
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+// isWordRune reports whether a rune is part of a word: a rule name, a column name or a number.
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-' || r == '.' || r == '*'
+}
+
+// tokenize splits a CSV Schema into tokens, leaving out whitespace and comments.
+func tokenize(schema string) ([]token, error) {
+	var tokens []token
+	runes := []rune(schema)
+	line := 1
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case r == '\n':
+			line++
+			i++
+		case unicode.IsSpace(r):
+			i++
+
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '/':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			start := line
+			for i += 2; i < len(runes) && !(runes[i] == '*' && i+1 < len(runes) && runes[i+1] == '/'); i++ {
+				if runes[i] == '\n' {
+					line++
+				}
+			}
+			if i == len(runes) {
+				return nil, fmt.Errorf("line %d: unterminated comment", start)
+			}
+			i += 2
+
+		case r == '"':
+			// Only escaped quotes are unescaped, other backslashes belong to regular expressions
+			var text strings.Builder
+			i++
+			for ; i < len(runes) && runes[i] != '"'; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) && runes[i+1] == '"' {
+					i++
+				}
+				if runes[i] == '\n' {
+					line++
+				}
+				text.WriteRune(runes[i])
+			}
+			if i == len(runes) {
+				return nil, fmt.Errorf("line %d: unterminated string", line)
+			}
+			i++
+			tokens = append(tokens, token{kind: tokenString, text: text.String(), line: line})
+
+		case r == '\'':
+			end := i + 1
+			for end < len(runes) && runes[end] != '\'' && runes[end] != '\n' {
+				end++
+			}
+			if end == len(runes) || runes[end] != '\'' {
+				return nil, fmt.Errorf("line %d: unterminated character", line)
+			}
+			text := string(runes[i+1 : end])
+			if text == `\t` {
+				text = "\t"
+			}
+			tokens = append(tokens, token{kind: tokenChar, text: text, line: line})
+			i = end + 1
+
+		case r == '@' || r == '$' || isWordRune(r):
+			kind := tokenWord
+			start := i
+			if r == '@' {
+				kind, start = tokenDirective, i+1
+			} else if r == '$' {
+				kind, start = tokenReference, i+1
+			}
+			i = start
+			if kind == tokenReference && i < len(runes) && runes[i] == '"' {
+				end := i + 1
+				for end < len(runes) && runes[end] != '"' {
+					end++
+				}
+				if end == len(runes) {
+					return nil, fmt.Errorf("line %d: unterminated column reference", line)
+				}
+				tokens = append(tokens, token{kind: kind, text: string(runes[i+1 : end]), line: line})
+				i = end + 1
+				continue
+			}
+			for i < len(runes) && isWordRune(runes[i]) {
+				i++
+			}
+			if i == start {
+				return nil, fmt.Errorf("line %d: unexpected %q", line, r)
+			}
+			tokens = append(tokens, token{kind: kind, text: string(runes[start:i]), line: line})
+
+		default:
+			kind, ok := map[rune]tokenKind{
+				'(': tokenLeftParen,
+				')': tokenRightParen,
+				',': tokenComma,
+				':': tokenColon,
+				'/': tokenSlash,
+			}[r]
+			if !ok {
+				return nil, fmt.Errorf("line %d: unexpected %q", line, r)
+			}
+			tokens = append(tokens, token{kind: kind, text: string(r), line: line})
+			i++
+		}
+	}
+
+	return append(tokens, token{kind: tokenEOF, line: line}), nil
+}
@@ -0,0 +1,492 @@
+// Copyright 2020 Syntio Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csvschema
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// expr is a column rule, or a combination of column rules.
+type expr interface {
+	// holds reports whether the rule holds for a value of the current row.
+	holds(value string, e *evaluation) bool
+	String() string
+}
+
+// evaluation is the state of the validation of a document which rules evaluate in.
+type evaluation struct {
+	row        []string
+	ignoreCase bool
+	// seen holds the values of the unique and identical rules found in the previous rows.
+	seen map[*rule]map[string]bool
+}
+
+// all reports whether all the rules of a sequence hold.
+func all(rules []expr, value string, e *evaluation) bool {
+	for _, rule := range rules {
+		if !rule.holds(value, e) {
+			return false
+		}
+	}
+	return true
+}
+
+func sequenceString(rules []expr) string {
+	texts := make([]string, len(rules))
+	for i, rule := range rules {
+		texts[i] = rule.String()
+	}
+	return strings.Join(texts, " ")
+}
+
+type andExpr struct {
+	left, right expr
+}
+
+func (a *andExpr) holds(value string, e *evaluation) bool {
+	return a.left.holds(value, e) && a.right.holds(value, e)
+}
+
+func (a *andExpr) String() string {
+	return a.left.String() + " and " + a.right.String()
+}
+
+type orExpr struct {
+	left, right expr
+}
+
+func (o *orExpr) holds(value string, e *evaluation) bool {
+	return o.left.holds(value, e) || o.right.holds(value, e)
+}
+
+func (o *orExpr) String() string {
+	return o.left.String() + " or " + o.right.String()
+}
+
+type groupExpr struct {
+	rules []expr
+}
+
+func (g *groupExpr) holds(value string, e *evaluation) bool {
+	return all(g.rules, value, e)
+}
+
+func (g *groupExpr) String() string {
+	return "(" + sequenceString(g.rules) + ")"
+}
+
+type ifExpr struct {
+	condition []expr
+	then      []expr
+	otherwise []expr
+}
+
+func (i *ifExpr) holds(value string, e *evaluation) bool {
+	if all(i.condition, value, e) {
+		return all(i.then, value, e)
+	}
+	return all(i.otherwise, value, e)
+}
+
+func (i *ifExpr) String() string {
+	text := "if(" + sequenceString(i.condition) + ", " + sequenceString(i.then)
+	if i.otherwise != nil {
+		text += ", " + sequenceString(i.otherwise)
+	}
+	return text + ")"
+}
+
+// contextExpr checks a rule on the value of another column of the row.
+type contextExpr struct {
+	column *reference
+	rule   expr
+}
+
+func (c *contextExpr) holds(_ string, e *evaluation) bool {
+	return c.rule.holds(c.column.value(e), e)
+}
+
+func (c *contextExpr) String() string {
+	return c.column.String() + "/" + c.rule.String()
+}
+
+// arg is an argument of a rule, which provides a string.
+type arg interface {
+	value(e *evaluation) string
+	String() string
+}
+
+// literal is a string or a word argument, e.g. a number or the wildcard *.
+type literal struct {
+	text   string
+	quoted bool
+}
+
+func (l literal) value(*evaluation) string {
+	return l.text
+}
+
+func (l literal) String() string {
+	if l.quoted {
+		return `"` + strings.ReplaceAll(l.text, `"`, `\"`) + `"`
+	}
+	return l.text
+}
+
+// reference is the value of another column of the row.
+type reference struct {
+	name  string
+	index int
+}
+
+func (r *reference) value(e *evaluation) string {
+	if r.index < len(e.row) {
+		return e.row[r.index]
+	}
+	return ""
+}
+
+func (r *reference) String() string {
+	return "$" + r.name
+}
+
+// concat concatenates its arguments.
+type concat []arg
+
+func (c concat) value(e *evaluation) string {
+	var text strings.Builder
+	for _, a := range c {
+		text.WriteString(a.value(e))
+	}
+	return text.String()
+}
+
+func (c concat) String() string {
+	return "concat(" + quote(c) + ")"
+}
+
+// rule is a single column rule with its arguments.
+type rule struct {
+	name  string
+	args  []arg
+	check func(r *rule, value string, e *evaluation) bool
+	// re and reIgnoreCase are the compiled expressions of regex rules.
+	re           *regexp.Regexp
+	reIgnoreCase *regexp.Regexp
+}
+
+func (r *rule) holds(value string, e *evaluation) bool {
+	return r.check(r, value, e)
+}
+
+func (r *rule) String() string {
+	if r.args == nil {
+		return r.name
+	}
+	return r.name + "(" + quote(r.args) + ")"
+}
+
+// equal compares two strings, ignoring the case if the column has the @ignoreCase directive.
+func (e *evaluation) equal(a, b string) bool {
+	if e.ignoreCase {
+		return strings.EqualFold(a, b)
+	}
+	return a == b
+}
+
+// fold returns a string for comparisons, lower-cased if the column has the @ignoreCase directive.
+func (e *evaluation) fold(s string) string {
+	if e.ignoreCase {
+		return strings.ToLower(s)
+	}
+	return s
+}
+
+var (
+	positiveIntegerPattern = regexp.MustCompile(`^[0-9]+$`)
+	integerPattern         = regexp.MustCompile(`^-?[0-9]+$`)
+	numberPattern          = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+	uuid4Pattern           = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-4[0-9a-fA-F]{3}-[89abAB][0-9a-fA-F]{3}-[0-9a-fA-F]{12}$`)
+	partUkDatePattern      = regexp.MustCompile(`^(\d{2}|\*|\?)/(January|February|March|April|May|June|July|August|` +
+		`September|October|November|December|Jan|Feb|Mar|Apr|May|Jun|Jul|Aug|Sep|Oct|Nov|Dec|\d{2}|\*|\?)/(\d{4}|\*|\?)$`)
+)
+
+// simpleRules are the rules without arguments.
+var simpleRules = map[string]func(value string) bool{
+	"notEmpty":        func(value string) bool { return value != "" },
+	"empty":           func(value string) bool { return value == "" },
+	"positiveInteger": positiveIntegerPattern.MatchString,
+	"integer":         integerPattern.MatchString,
+	"uuid4":           uuid4Pattern.MatchString,
+	"partUkDate":      partUkDatePattern.MatchString,
+	"upperCase":       func(value string) bool { return value == strings.ToUpper(value) },
+	"lowerCase":       func(value string) bool { return value == strings.ToLower(value) },
+	"uri": func(value string) bool {
+		u, err := url.Parse(value)
+		return err == nil && u.Scheme != ""
+	},
+}
+
+// comparisonRules are the rules comparing a value with a string argument.
+var comparisonRules = map[string]func(e *evaluation, value, argument string) bool{
+	"is":    func(e *evaluation, value, argument string) bool { return e.equal(value, argument) },
+	"isNot": func(e *evaluation, value, argument string) bool { return !e.equal(value, argument) },
+	"not":   func(e *evaluation, value, argument string) bool { return !e.equal(value, argument) },
+	"in": func(e *evaluation, value, argument string) bool {
+		return strings.Contains(e.fold(argument), e.fold(value))
+	},
+	"starts": func(e *evaluation, value, argument string) bool {
+		return strings.HasPrefix(e.fold(value), e.fold(argument))
+	},
+	"ends": func(e *evaluation, value, argument string) bool {
+		return strings.HasSuffix(e.fold(value), e.fold(argument))
+	},
+}
+
+// dateRules are the date and time rules, which parse a value into an instant. Values without a timezone are taken
+// as UTC.
+var dateRules = map[string]func(value string) (time.Time, bool){
+	"xDateTime":   dateParser(`^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:\d{2})?$`, "2006-01-02T15:04:05.999999999"),
+	"xDateTimeTz": dateParser(`^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:\d{2})$`, "2006-01-02T15:04:05.999999999"),
+	"xDate":       dateParser(`^\d{4}-\d{2}-\d{2}(Z|[+-]\d{2}:\d{2})?$`, "2006-01-02"),
+	"xDateTz":     dateParser(`^\d{4}-\d{2}-\d{2}(Z|[+-]\d{2}:\d{2})$`, "2006-01-02"),
+	"xTime":       dateParser(`^\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:\d{2})?$`, "15:04:05.999999999"),
+	"xTimeTz":     dateParser(`^\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:\d{2})$`, "15:04:05.999999999"),
+	"ukDate":      dateParser(`^\d{2}/\d{2}/\d{4}$`, "02/01/2006"),
+}
+
+// timezoneSuffix matches the timezone of a date or time.
+var timezoneSuffix = regexp.MustCompile(`(Z|[+-]\d{2}:\d{2})$`)
+
+// dateParser returns a parser of the dates matching a pattern, which are parsed by a layout after their timezone
+// is taken off.
+func dateParser(pattern, layout string) func(value string) (time.Time, bool) {
+	re := regexp.MustCompile(pattern)
+	return func(value string) (time.Time, bool) {
+		if !re.MatchString(value) {
+			return time.Time{}, false
+		}
+
+		local := value
+		offset := time.Duration(0)
+		if tz := timezoneSuffix.FindString(value); tz != "" {
+			local = strings.TrimSuffix(value, tz)
+			if tz != "Z" {
+				hours, _ := strconv.Atoi(tz[1:3])
+				minutes, _ := strconv.Atoi(tz[4:6])
+				offset = time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute
+				if tz[0] == '-' {
+					offset = -offset
+				}
+			}
+		}
+
+		instant, err := time.Parse(layout, local)
+		if err != nil {
+			return time.Time{}, false
+		}
+		return instant.Add(-offset), true
+	}
+}
+
+// newRule creates a rule from its name and arguments. An error is returned for unknown or unsupported rules and
+// for wrong arguments.
+func newRule(name string, args []arg) (expr, error) {
+	r := &rule{name: name, args: args}
+
+	arity := func(min, max int) error {
+		if len(args) < min || (max >= 0 && len(args) > max) {
+			return fmt.Errorf("wrong number of arguments of the %s rule", name)
+		}
+		return nil
+	}
+
+	if check, ok := simpleRules[name]; ok {
+		r.check = func(r *rule, value string, e *evaluation) bool { return check(value) }
+		return r, arity(0, 0)
+	}
+
+	if compare, ok := comparisonRules[name]; ok {
+		r.check = func(r *rule, value string, e *evaluation) bool {
+			return compare(e, value, r.args[0].value(e))
+		}
+		return r, arity(1, 1)
+	}
+
+	if parse, ok := dateRules[name]; ok {
+		r.check = func(r *rule, value string, e *evaluation) bool {
+			instant, ok := parse(value)
+			if !ok || len(r.args) == 0 {
+				return ok
+			}
+			from, fromOK := parse(r.args[0].value(e))
+			to, toOK := parse(r.args[1].value(e))
+			return fromOK && toOK && !instant.Before(from) && !instant.After(to)
+		}
+		if len(args) == 1 {
+			return nil, fmt.Errorf("the %s rule takes no arguments or a range of two", name)
+		}
+		return r, arity(0, 2)
+	}
+
+	switch name {
+	case "regex", "range", "length":
+		for _, a := range args {
+			if _, ok := a.(literal); !ok {
+				return nil, fmt.Errorf("the %s rule takes literal arguments", name)
+			}
+		}
+	}
+
+	switch name {
+	case "regex":
+		if err := arity(1, 1); err != nil {
+			return nil, err
+		}
+		source := args[0].value(nil)
+		if _, err := regexp.Compile(source); err != nil {
+			return nil, fmt.Errorf("the regex %q is invalid: %v", source, err)
+		}
+		r.re = regexp.MustCompile(`^(?:` + source + `)$`)
+		r.reIgnoreCase = regexp.MustCompile(`(?i)^(?:` + source + `)$`)
+		r.check = func(r *rule, value string, e *evaluation) bool {
+			if e.ignoreCase {
+				return r.reIgnoreCase.MatchString(value)
+			}
+			return r.re.MatchString(value)
+		}
+		return r, nil
+
+	case "any":
+		r.check = func(r *rule, value string, e *evaluation) bool {
+			for _, a := range r.args {
+				if e.equal(value, a.value(e)) {
+					return true
+				}
+			}
+			return false
+		}
+		return r, arity(1, -1)
+
+	case "range":
+		if err := arity(2, 2); err != nil {
+			return nil, err
+		}
+		min, max, err := numericBounds(args[0].value(nil), args[1].value(nil), strconv.ParseFloat)
+		if err != nil {
+			return nil, fmt.Errorf("range: %v", err)
+		}
+		r.check = func(r *rule, value string, e *evaluation) bool {
+			if !numberPattern.MatchString(value) {
+				return false
+			}
+			number, _ := strconv.ParseFloat(value, 64)
+			return (min == nil || number >= *min) && (max == nil || number <= *max)
+		}
+		return r, nil
+
+	case "length":
+		if err := arity(1, 2); err != nil {
+			return nil, err
+		}
+		lower, upper := args[0].value(nil), args[0].value(nil)
+		if len(args) == 2 {
+			upper = args[1].value(nil)
+		}
+		min, max, err := numericBounds(lower, upper, func(s string, _ int) (float64, error) {
+			n, err := strconv.Atoi(s)
+			return float64(n), err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("length: %v", err)
+		}
+		r.check = func(r *rule, value string, e *evaluation) bool {
+			length := float64(utf8.RuneCountInString(value))
+			return (min == nil || length >= *min) && (max == nil || length <= *max)
+		}
+		return r, nil
+
+	case "unique":
+		for _, a := range args {
+			if _, ok := a.(*reference); !ok {
+				return nil, fmt.Errorf("the unique rule takes column references")
+			}
+		}
+		r.check = func(r *rule, value string, e *evaluation) bool {
+			key := value
+			if len(r.args) > 0 {
+				values := make([]string, len(r.args))
+				for i, a := range r.args {
+					values[i] = a.value(e)
+				}
+				key = strings.Join(values, "\x00")
+			}
+			key = e.fold(key)
+			seen := e.values(r)
+			if seen[key] {
+				return false
+			}
+			seen[key] = true
+			return true
+		}
+		return r, nil
+
+	case "identical":
+		r.check = func(r *rule, value string, e *evaluation) bool {
+			seen := e.values(r)
+			if len(seen) == 0 {
+				seen[value] = true
+			}
+			return seen[value]
+		}
+		return r, arity(0, 0)
+
+	case "fileExists", "checksum", "fileCount", "switch", "noExt", "caseSensitive", "integrityCheck":
+		return nil, fmt.Errorf("the %s rule isn't supported", name)
+	}
+	return nil, fmt.Errorf("unknown rule %s", name)
+}
+
+// values returns the values a stateful rule has seen in the previous rows.
+func (e *evaluation) values(r *rule) map[string]bool {
+	seen, ok := e.seen[r]
+	if !ok {
+		seen = map[string]bool{}
+		e.seen[r] = seen
+	}
+	return seen
+}
+
+// numericBounds parses the bounds of a range, where the wildcard * leaves a bound open.
+func numericBounds(lower, upper string, parse func(string, int) (float64, error)) (*float64, *float64, error) {
+	var bounds [2]*float64
+	for i, text := range []string{lower, upper} {
+		if text == "*" {
+			continue
+		}
+		bound, err := parse(text, 64)
+		if err != nil {
+			return nil, nil, fmt.Errorf("the bound %q isn't a number", text)
+		}
+		bounds[i] = &bound
+	}
+	return bounds[0], bounds[1], nil
+}
@@ -0,0 +1,386 @@
+// Copyright 2020 Syntio Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package csvschema validates CSV documents with CSV Schema 1.1 in-process. It covers the subset of the language
+// the schemas created by the Schema Registry and the hand-written schemas use: the version declaration, the global
+// directives (@separator, @quoted, @totalColumns, @permitEmpty, @noHeader and @ignoreColumnNameCase), column
+// definitions with their directives (@optional, @matchIsFalse, @ignoreCase and @warning), and column rules combined
+// with and, or, parentheses and if. External rules, such as fileExists and checksum, aren't supported.
+package csvschema
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Schema is a parsed CSV Schema.
+type Schema struct {
+	separator            rune
+	totalColumns         int
+	noHeader             bool
+	permitEmpty          bool
+	ignoreColumnNameCase bool
+	columns              []*column
+}
+
+// column is a column definition.
+type column struct {
+	name  string
+	rules []expr
+	// Column directives
+	optional     bool
+	matchIsFalse bool
+	ignoreCase   bool
+	warning      bool
+}
+
+// Parse parses a CSV Schema. An error is returned if the schema isn't valid or uses unsupported rules.
+func Parse(schema []byte) (*Schema, error) {
+	tokens, err := tokenize(string(schema))
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	s, err := p.schema()
+	if err != nil {
+		return nil, fmt.Errorf("line %d: %v", p.peek().line, err)
+	}
+	return s, nil
+}
+
+// parser parses the tokens of a CSV Schema.
+type parser struct {
+	tokens   []token
+	position int
+	// references collects the column references of the rules, which are resolved once every column is known.
+	references []*reference
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.position]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.position]
+	if t.kind != tokenEOF {
+		p.position++
+	}
+	return t
+}
+
+func (p *parser) expect(kind tokenKind, what string) (token, error) {
+	t := p.next()
+	if t.kind != kind {
+		return t, fmt.Errorf("expected %s, found %v", what, t)
+	}
+	return t, nil
+}
+
+// atColumn reports whether the next tokens start a column definition, a column name followed by a colon.
+func (p *parser) atColumn() bool {
+	t := p.peek()
+	return (t.kind == tokenWord || t.kind == tokenString) && p.tokens[p.position+1].kind == tokenColon
+}
+
+// schema parses the prolog and the column definitions of the schema.
+func (p *parser) schema() (*Schema, error) {
+	s := &Schema{separator: ',', totalColumns: -1}
+
+	if t := p.next(); t.kind != tokenWord || t.text != "version" {
+		return nil, fmt.Errorf("the schema has to start with the version declaration")
+	}
+	version := p.next()
+	if version.text != "1.0" && version.text != "1.1" {
+		return nil, fmt.Errorf("unsupported version %v", version)
+	}
+
+	for p.peek().kind == tokenDirective {
+		directive := p.next()
+		switch directive.text {
+		case "separator":
+			t := p.next()
+			switch {
+			case t.kind == tokenChar && len([]rune(t.text)) == 1:
+				s.separator = []rune(t.text)[0]
+			case t.kind == tokenWord && t.text == "TAB":
+				s.separator = '\t'
+			default:
+				return nil, fmt.Errorf("the separator %v isn't a single character", t)
+			}
+		case "quoted":
+		case "totalColumns":
+			t := p.next()
+			n, err := strconv.Atoi(t.text)
+			if t.kind != tokenWord || err != nil || n < 0 {
+				return nil, fmt.Errorf("@totalColumns %v isn't a number", t)
+			}
+			s.totalColumns = n
+		case "permitEmpty":
+			s.permitEmpty = true
+		case "noHeader":
+			s.noHeader = true
+		case "ignoreColumnNameCase":
+			s.ignoreColumnNameCase = true
+		default:
+			return nil, fmt.Errorf("unsupported global directive @%s", directive.text)
+		}
+	}
+
+	for p.peek().kind != tokenEOF {
+		c, err := p.column()
+		if err != nil {
+			return nil, err
+		}
+		s.columns = append(s.columns, c)
+	}
+
+	if len(s.columns) == 0 {
+		return nil, fmt.Errorf("the schema defines no columns")
+	}
+	if s.totalColumns >= 0 && s.totalColumns != len(s.columns) {
+		return nil, fmt.Errorf("@totalColumns is %d, but the schema defines %d columns", s.totalColumns,
+			len(s.columns))
+	}
+	for _, ref := range p.references {
+		ref.index = -1
+		for i, c := range s.columns {
+			if c.name == ref.name {
+				ref.index = i
+			}
+		}
+		if ref.index < 0 {
+			return nil, fmt.Errorf("the column reference $%s refers to an undefined column", ref.name)
+		}
+	}
+	return s, nil
+}
+
+// column parses a column definition: the column name, its rules and its directives.
+func (p *parser) column() (*column, error) {
+	if !p.atColumn() {
+		return nil, fmt.Errorf("expected a column definition, found %v", p.peek())
+	}
+	c := &column{name: p.next().text}
+	p.next()
+
+	rules, err := p.sequence()
+	if err != nil {
+		return nil, fmt.Errorf("column %s: %v", c.name, err)
+	}
+	c.rules = rules
+
+	for p.peek().kind == tokenDirective {
+		switch directive := p.next(); directive.text {
+		case "optional":
+			c.optional = true
+		case "matchIsFalse":
+			c.matchIsFalse = true
+		case "ignoreCase":
+			c.ignoreCase = true
+		case "warning", "warningDirective":
+			c.warning = true
+		default:
+			return nil, fmt.Errorf("column %s: unsupported column directive @%s", c.name, directive.text)
+		}
+	}
+	return c, nil
+}
+
+// sequence parses rules which all have to hold, up to the end of the rules of a column or of an argument.
+func (p *parser) sequence() ([]expr, error) {
+	var exprs []expr
+	for {
+		switch p.peek().kind {
+		case tokenEOF, tokenDirective, tokenRightParen, tokenComma:
+			return exprs, nil
+		}
+		if p.atColumn() {
+			return exprs, nil
+		}
+
+		e, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, e)
+	}
+}
+
+// or parses rules combined with or, which binds weaker than and.
+func (p *parser) or() (expr, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for t := p.peek(); t.kind == tokenWord && t.text == "or"; t = p.peek() {
+		p.next()
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = &orExpr{left: left, right: right}
+	}
+	return left, nil
+}
+
+// and parses rules combined with and.
+func (p *parser) and() (expr, error) {
+	left, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	for t := p.peek(); t.kind == tokenWord && t.text == "and"; t = p.peek() {
+		p.next()
+		right, err := p.primary()
+		if err != nil {
+			return nil, err
+		}
+		left = &andExpr{left: left, right: right}
+	}
+	return left, nil
+}
+
+// primary parses a parenthesized sequence of rules, an if rule or a single rule with its arguments. A rule can be
+// prefixed by a column reference and a slash to check the value of another column, e.g. $kind/is("A").
+func (p *parser) primary() (expr, error) {
+	t := p.next()
+
+	if t.kind == tokenReference && p.peek().kind == tokenSlash {
+		p.next()
+		ref := &reference{name: t.text}
+		p.references = append(p.references, ref)
+		rule, err := p.primary()
+		if err != nil {
+			return nil, err
+		}
+		return &contextExpr{column: ref, rule: rule}, nil
+	}
+
+	if t.kind == tokenLeftParen {
+		rules, err := p.sequence()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(tokenRightParen, ")"); err != nil {
+			return nil, err
+		}
+		return &groupExpr{rules: rules}, nil
+	}
+	if t.kind != tokenWord {
+		return nil, fmt.Errorf("expected a rule, found %v", t)
+	}
+
+	if t.text == "if" {
+		return p.conditional()
+	}
+
+	var args []arg
+	if p.peek().kind == tokenLeftParen {
+		p.next()
+		var err error
+		if args, err = p.arguments(); err != nil {
+			return nil, fmt.Errorf("%s: %v", t.text, err)
+		}
+	}
+	return newRule(t.text, args)
+}
+
+// conditional parses the condition and the rules of an if rule.
+func (p *parser) conditional() (expr, error) {
+	if _, err := p.expect(tokenLeftParen, "( after if"); err != nil {
+		return nil, err
+	}
+
+	var parts [][]expr
+	for {
+		rules, err := p.sequence()
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, rules)
+		if t := p.next(); t.kind == tokenRightParen {
+			break
+		} else if t.kind != tokenComma {
+			return nil, fmt.Errorf("expected , or ) in if, found %v", t)
+		}
+	}
+
+	if len(parts) != 2 && len(parts) != 3 {
+		return nil, fmt.Errorf("if takes a condition, the rules if it holds and optionally the rules if it doesn't")
+	}
+	e := &ifExpr{condition: parts[0], then: parts[1]}
+	if len(parts) == 3 {
+		e.otherwise = parts[2]
+	}
+	return e, nil
+}
+
+// arguments parses the arguments of a rule up to the closing parenthesis: strings, numbers, wildcards, column
+// references and concatenations of them.
+func (p *parser) arguments() ([]arg, error) {
+	var args []arg
+	if p.peek().kind == tokenRightParen {
+		p.next()
+		return args, nil
+	}
+
+	for {
+		a, err := p.argument()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, a)
+
+		if t := p.next(); t.kind == tokenRightParen {
+			return args, nil
+		} else if t.kind != tokenComma {
+			return nil, fmt.Errorf("expected , or ), found %v", t)
+		}
+	}
+}
+
+// argument parses a single argument of a rule.
+func (p *parser) argument() (arg, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenString:
+		return literal{text: t.text, quoted: true}, nil
+	case tokenReference:
+		ref := &reference{name: t.text}
+		p.references = append(p.references, ref)
+		return ref, nil
+	case tokenWord:
+		if t.text == "concat" && p.peek().kind == tokenLeftParen {
+			p.next()
+			parts, err := p.arguments()
+			if err != nil {
+				return nil, err
+			}
+			return concat(parts), nil
+		}
+		return literal{text: t.text}, nil
+	}
+	return nil, fmt.Errorf("expected an argument, found %v", t)
+}
+
+// quote returns the source text of a rule's arguments.
+func quote(args []arg) string {
+	texts := make([]string, len(args))
+	for i, a := range args {
+		texts[i] = a.String()
+	}
+	return strings.Join(texts, ", ")
+}
@@ -0,0 +1,160 @@
+// Copyright 2020 Syntio Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csvschema
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Violation is a value of a document which doesn't conform to the schema. Rows are counted from 1, including the
+// header. Column is empty for violations of a whole row or of the document. Rule is the failing rule or directive,
+// and Expected and Actual are the values compared, if any.
+type Violation struct {
+	Row         int
+	Column      string
+	Rule        string
+	Expected    string
+	Actual      string
+	Description string
+}
+
+// Validate validates a CSV document with the schema and returns every violation found. The rules of the columns
+// with the @warning directive are checked, but their failures aren't violations. An error is returned if the
+// document isn't well-formed CSV.
+func (s *Schema) Validate(document []byte) ([]Violation, error) {
+	reader := csv.NewReader(bytes.NewReader(document))
+	reader.Comma = s.separator
+	reader.FieldsPerRecord = -1
+
+	v := &validation{schema: s, evaluation: &evaluation{seen: map[*rule]map[string]bool{}}}
+	row := 0
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		row++
+
+		if len(record) != len(s.columns) {
+			v.mismatch(row, "", "@totalColumns", strconv.Itoa(len(s.columns)), strconv.Itoa(len(record)),
+				"the row has %d columns, expected %d", len(record), len(s.columns))
+			continue
+		}
+		if row == 1 && !s.noHeader {
+			v.header(record)
+			continue
+		}
+		v.row(row, record)
+	}
+
+	if !s.permitEmpty && (row == 0 || (row == 1 && !s.noHeader)) {
+		v.report(0, "", "@permitEmpty", "the document has no rows")
+	}
+	return v.violations, nil
+}
+
+// Records returns the rows of a document as maps of the names of the columns of the schema to the values, without
+// the header. An error is returned if the document isn't well-formed CSV or a row hasn't as many columns as the
+// schema.
+func (s *Schema) Records(document []byte) ([]map[string]string, error) {
+	reader := csv.NewReader(bytes.NewReader(document))
+	reader.Comma = s.separator
+	reader.FieldsPerRecord = -1
+
+	records := []map[string]string{}
+	for row := 1; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(record) != len(s.columns) {
+			return nil, fmt.Errorf("row %d has %d columns, expected %d", row, len(record), len(s.columns))
+		}
+		if row == 1 && !s.noHeader {
+			continue
+		}
+
+		values := make(map[string]string, len(record))
+		for i, c := range s.columns {
+			values[c.name] = record[i]
+		}
+		records = append(records, values)
+	}
+	return records, nil
+}
+
+// validation collects the violations of a document.
+type validation struct {
+	schema     *Schema
+	evaluation *evaluation
+	violations []Violation
+}
+
+// report reports a violation of a rule or a directive of the schema.
+func (v *validation) report(row int, column, rule, format string, args ...interface{}) {
+	v.mismatch(row, column, rule, "", "", format, args...)
+}
+
+// mismatch reports a violation of a rule or a directive of the schema with the expected and the actual value.
+func (v *validation) mismatch(row int, column, rule, expected, actual, format string, args ...interface{}) {
+	v.violations = append(v.violations, Violation{Row: row, Column: column, Rule: rule, Expected: expected,
+		Actual: actual, Description: fmt.Sprintf(format, args...)})
+}
+
+// header checks the names of the header against the column definitions.
+func (v *validation) header(record []string) {
+	for i, c := range v.schema.columns {
+		name := strings.TrimSpace(record[i])
+		if name == c.name || (v.schema.ignoreColumnNameCase && strings.EqualFold(name, c.name)) {
+			continue
+		}
+		v.mismatch(1, c.name, "header", c.name, name, "the header names the column %q, expected %q", name, c.name)
+	}
+}
+
+// row checks the values of a row with the rules of their columns.
+func (v *validation) row(row int, record []string) {
+	v.evaluation.row = record
+	for i, c := range v.schema.columns {
+		value := record[i]
+		if c.optional && value == "" {
+			continue
+		}
+
+		v.evaluation.ignoreCase = c.ignoreCase
+		for _, rule := range c.rules {
+			if rule.holds(value, v.evaluation) != c.matchIsFalse || c.warning {
+				continue
+			}
+			if c.matchIsFalse {
+				v.mismatch(row, c.name, rule.String(), "", value,
+					"%s holds for value %q, but the column has @matchIsFalse", rule, value)
+			} else {
+				v.mismatch(row, c.name, rule.String(), "", value, "%s fails for value %q", rule, value)
+			}
+		}
+	}
+}
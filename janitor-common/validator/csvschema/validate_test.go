@@ -0,0 +1,298 @@
+// Copyright 2020 Syntio Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csvschema
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// located returns the row, column and rule of each violation, e.g. "2/id/unique".
+func located(violations []Violation) []string {
+	locations := []string{}
+	for _, v := range violations {
+		locations = append(locations, fmt.Sprintf("%d/%s/%s", v.Row, v.Column, v.Rule))
+	}
+	return locations
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		schema   string
+		document string
+		want     []string
+	}{
+		{
+			name:     "valid document",
+			schema:   "version 1.1\nid: positiveInteger\nname: notEmpty\n",
+			document: "id,name\n1,Ann\n2,Bob\n",
+			want:     []string{},
+		},
+		{
+			name:     "header names another column",
+			schema:   "version 1.1\nid: positiveInteger\nname: notEmpty\n",
+			document: "id,title\n1,Ann\n",
+			want:     []string{"1/name/header"},
+		},
+		{
+			name:     "header of another case",
+			schema:   "version 1.1\n@ignoreColumnNameCase\nid: positiveInteger\nname: notEmpty\n",
+			document: "ID,Name\n1,Ann\n",
+			want:     []string{},
+		},
+		{
+			name:     "no header",
+			schema:   "version 1.1\n@noHeader\nid: positiveInteger\n",
+			document: "1\nx\n",
+			want:     []string{"2/id/positiveInteger"},
+		},
+		{
+			name:     "row with too few columns",
+			schema:   "version 1.1\n@totalColumns 2\nid: positiveInteger\nname: notEmpty\n",
+			document: "id,name\n1\n2,Bob\n",
+			want:     []string{"2//@totalColumns"},
+		},
+		{
+			name:     "row with too many columns",
+			schema:   "version 1.1\nid: positiveInteger\n",
+			document: "id\n1,2\n",
+			want:     []string{"2//@totalColumns"},
+		},
+		{
+			name:     "empty document",
+			schema:   "version 1.1\nid: positiveInteger\n",
+			document: "id\n",
+			want:     []string{"0//@permitEmpty"},
+		},
+		{
+			name:     "empty document permitted",
+			schema:   "version 1.1\n@permitEmpty\nid: positiveInteger\n",
+			document: "id\n",
+			want:     []string{},
+		},
+		{
+			name:     "xDate",
+			schema:   "version 1.1\nday: xDate\n",
+			document: "day\n2021-02-28\n2021-02-30\n28/02/2021\n2021-02-28+01:00\n",
+			want:     []string{"3/day/xDate", "4/day/xDate"},
+		},
+		{
+			name:     "xDate range",
+			schema:   "version 1.1\nday: xDate(\"2021-01-01\", \"2021-12-31\")\n",
+			document: "day\n2021-06-01\n2022-01-01\n",
+			want:     []string{"3/day/xDate(\"2021-01-01\", \"2021-12-31\")"},
+		},
+		{
+			name:     "unique",
+			schema:   "version 1.1\nid: unique\n",
+			document: "id\n1\n2\n1\n",
+			want:     []string{"4/id/unique"},
+		},
+		{
+			name:     "unique over columns",
+			schema:   "version 1.1\nfirst: unique($first, $last)\nlast: notEmpty\n",
+			document: "first,last\nAnn,Lee\nAnn,Ray\nAnn,Lee\n",
+			want:     []string{"4/first/unique($first, $last)"},
+		},
+		{
+			name:     "if with otherwise",
+			schema:   "version 1.1\nkind: any(\"A\", \"B\")\ncode: if($kind/is(\"A\"), positiveInteger, empty)\n",
+			document: "kind,code\nA,12\nB,\nA,x\nB,12\n",
+			want: []string{
+				"4/code/if($kind/is(\"A\"), positiveInteger, empty)",
+				"5/code/if($kind/is(\"A\"), positiveInteger, empty)",
+			},
+		},
+		{
+			name:     "if without otherwise",
+			schema:   "version 1.1\nkind: notEmpty\ncode: if($kind/is(\"A\"), notEmpty)\n",
+			document: "kind,code\nA,\nB,\n",
+			want:     []string{"2/code/if($kind/is(\"A\"), notEmpty)"},
+		},
+		{
+			name:     "optional",
+			schema:   "version 1.1\nid: positiveInteger\nemail: regex(\"[^@]+@[^@]+\") @optional\n",
+			document: "id,email\n1,\n2,ann@example.com\n3,ann\n",
+			want:     []string{"4/email/regex(\"[^@]+@[^@]+\")"},
+		},
+		{
+			name:     "matchIsFalse",
+			schema:   "version 1.1\nname: is(\"admin\") @matchIsFalse\n",
+			document: "name\nann\nadmin\n",
+			want:     []string{"3/name/is(\"admin\")"},
+		},
+		{
+			name:     "ignoreCase",
+			schema:   "version 1.1\nname: is(\"ANN\") @ignoreCase\n",
+			document: "name\nann\nbob\n",
+			want:     []string{"3/name/is(\"ANN\")"},
+		},
+		{
+			name:     "warning",
+			schema:   "version 1.1\nname: upperCase @warning\n",
+			document: "name\nann\n",
+			want:     []string{},
+		},
+		{
+			name:     "or and and",
+			schema:   "version 1.1\ncode: length(2) and upperCase or is(\"-\")\n",
+			document: "code\nAB\n-\nab\nABC\n",
+			want:     []string{"4/code/length(2) and upperCase or is(\"-\")", "5/code/length(2) and upperCase or is(\"-\")"},
+		},
+		{
+			name:     "range",
+			schema:   "version 1.1\nprice: range(0, 10.5)\n",
+			document: "price\n0\n10.5\n11\n-1\nx\n",
+			want:     []string{"4/price/range(0, 10.5)", "5/price/range(0, 10.5)", "6/price/range(0, 10.5)"},
+		},
+		{
+			name:     "separator",
+			schema:   "version 1.1\n@separator ';'\nid: positiveInteger\nname: notEmpty\n",
+			document: "id;name\n1;Ann\n2;\n",
+			want:     []string{"3/name/notEmpty"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s, err := Parse([]byte(test.schema))
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			violations, err := s.Validate([]byte(test.document))
+			if err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+			if got := located(violations); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Validate() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestValidateValues(t *testing.T) {
+	s, err := Parse([]byte("version 1.1\nid: positiveInteger\nname: notEmpty\n"))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	violations, err := s.Validate([]byte("id,title\n1,Ann\n1,2,3\nx,Bob\n"))
+	if err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	want := []Violation{
+		{Row: 1, Column: "name", Rule: "header", Expected: "name", Actual: "title",
+			Description: `the header names the column "title", expected "name"`},
+		{Row: 3, Rule: "@totalColumns", Expected: "2", Actual: "3", Description: "the row has 3 columns, expected 2"},
+		{Row: 4, Column: "id", Rule: "positiveInteger", Actual: "x",
+			Description: `positiveInteger fails for value "x"`},
+	}
+	if !reflect.DeepEqual(violations, want) {
+		t.Errorf("Validate() = %+v, want %+v", violations, want)
+	}
+}
+
+func TestValidateMalformed(t *testing.T) {
+	s, err := Parse([]byte("version 1.1\nname: notEmpty\n"))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if _, err := s.Validate([]byte("name\n\"Ann\n")); err == nil {
+		t.Error("Validate() of a document with an unterminated quote succeeded")
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		want   string
+	}{
+		{"missing version", "id: notEmpty\n", "version declaration"},
+		{"unsupported version", "version 2.0\nid: notEmpty\n", "unsupported version"},
+		{"no columns", "version 1.1\n", "no columns"},
+		{"total columns mismatch", "version 1.1\n@totalColumns 3\nid: notEmpty\n", "@totalColumns is 3"},
+		{"unknown rule", "version 1.1\nid: shiny\n", "unknown rule shiny"},
+		{"unsupported rule", "version 1.1\nid: fileExists\n", "isn't supported"},
+		{"unsupported directive", "version 1.1\n@lenient\nid: notEmpty\n", "unsupported global directive"},
+		{"undefined reference", "version 1.1\nid: unique($key)\n", "undefined column"},
+		{"invalid regex", "version 1.1\nid: regex(\"[\")\n", "is invalid"},
+		{"single date bound", "version 1.1\nday: xDate(\"2021-01-01\")\n", "range of two"},
+		{"if without rules", "version 1.1\nid: if(notEmpty)\n", "if takes a condition"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Parse([]byte(test.schema))
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("Parse() error = %v, want an error containing %q", err, test.want)
+			}
+		})
+	}
+}
+
+func TestRecords(t *testing.T) {
+	tests := []struct {
+		name     string
+		schema   string
+		document string
+		want     []map[string]string
+		wantErr  bool
+	}{
+		{
+			name:     "header",
+			schema:   "version 1.1\nid: positiveInteger\nname: notEmpty\n",
+			document: "ID,Name\n1,Ann\n2,Bob\n",
+			want:     []map[string]string{{"id": "1", "name": "Ann"}, {"id": "2", "name": "Bob"}},
+		},
+		{
+			name:     "no header",
+			schema:   "version 1.1\n@noHeader\nid: positiveInteger\n",
+			document: "1\n2\n",
+			want:     []map[string]string{{"id": "1"}, {"id": "2"}},
+		},
+		{
+			name:     "only a header",
+			schema:   "version 1.1\nid: positiveInteger\n",
+			document: "id\n",
+			want:     []map[string]string{},
+		},
+		{
+			name:     "row with too few columns",
+			schema:   "version 1.1\nid: positiveInteger\nname: notEmpty\n",
+			document: "id,name\n1\n",
+			wantErr:  true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s, err := Parse([]byte(test.schema))
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			records, err := s.Records([]byte(test.document))
+			if (err != nil) != test.wantErr {
+				t.Fatalf("Records() error = %v, want an error %v", err, test.wantErr)
+			}
+			if !test.wantErr && !reflect.DeepEqual(records, test.want) {
+				t.Errorf("Records() = %v, want %v", records, test.want)
+			}
+		})
+	}
+}
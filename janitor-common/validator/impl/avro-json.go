@@ -0,0 +1,260 @@
+// Copyright 2020 Syntio Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package impl represents implementation of message validation process.
+package impl
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	lib "github.com/hamba/avro"
+)
+
+// explainAvroJSON validates a message in the Avro JSON encoding with a schema. Records may leave out the fields
+// which have a default value.
+func explainAvroJSON(message []byte, schema lib.Schema) []Violation {
+	decoder := json.NewDecoder(bytes.NewReader(message))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return []Violation{{Rule: "encoding", Description: fmt.Sprintf("the message isn't JSON: %v", err)}}
+	}
+	if decoder.More() {
+		return []Violation{{Rule: "encoding", Description: "JSON values follow the datum"}}
+	}
+
+	v := &avroJSONValidation{}
+	v.value(value, schema, "")
+	return v.violations
+}
+
+// avroJSONValidation collects the violations of a JSON encoded datum.
+type avroJSONValidation struct {
+	violations []Violation
+}
+
+// report reports a violation of a rule of the Avro JSON encoding, e.g. "type" or "union".
+func (v *avroJSONValidation) report(path, rule, format string, args ...interface{}) {
+	v.mismatch(path, rule, "", "", format, args...)
+}
+
+// mismatch reports a violation of a rule of the Avro JSON encoding with the expected and the actual value.
+func (v *avroJSONValidation) mismatch(path, rule, expected, actual, format string, args ...interface{}) {
+	v.violations = append(v.violations, Violation{Location: path, Rule: rule, Expected: expected, Actual: actual,
+		Description: fmt.Sprintf(format, args...)})
+}
+
+// value validates a JSON value with a schema. Path is the JSON pointer of the value.
+func (v *avroJSONValidation) value(value interface{}, schema lib.Schema, path string) {
+	if ref, ok := schema.(*lib.RefSchema); ok {
+		schema = ref.Schema()
+	}
+
+	switch s := schema.(type) {
+	case *lib.NullSchema:
+		if value != nil {
+			v.mismatch(path, "type", string(lib.Null), "", "expected null")
+		}
+
+	case *lib.PrimitiveSchema:
+		v.primitive(value, s.Type(), path)
+
+	case *lib.EnumSchema:
+		symbol, ok := value.(string)
+		if !ok {
+			v.mismatch(path, "type", s.FullName(), "", "expected a symbol of the enum %s", s.FullName())
+			return
+		}
+		for _, known := range s.Symbols() {
+			if symbol == known {
+				return
+			}
+		}
+		v.mismatch(path, "enum", strings.Join(s.Symbols(), ", "), symbol, "%q isn't a symbol of the enum %s", symbol,
+			s.FullName())
+
+	case *lib.FixedSchema:
+		text, ok := value.(string)
+		if !ok || !isAvroJSONBytes(text) {
+			v.mismatch(path, "type", s.FullName(), "", "expected a string of bytes")
+		} else if size := utf8.RuneCountInString(text); size != s.Size() {
+			v.mismatch(path, "size", strconv.Itoa(s.Size()), strconv.Itoa(size),
+				"expected %d bytes of the fixed %s, found %d", s.Size(), s.FullName(), size)
+		}
+
+	case *lib.ArraySchema:
+		items, ok := value.([]interface{})
+		if !ok {
+			v.mismatch(path, "type", string(lib.Array), "", "expected an array")
+			return
+		}
+		for i, item := range items {
+			v.value(item, s.Items(), path+"/"+strconv.Itoa(i))
+		}
+
+	case *lib.MapSchema:
+		entries, ok := value.(map[string]interface{})
+		if !ok {
+			v.mismatch(path, "type", string(lib.Map), "", "expected an object")
+			return
+		}
+		for _, key := range sortedKeys(entries) {
+			v.value(entries[key], s.Values(), path+"/"+escapePointer(key))
+		}
+
+	case *lib.RecordSchema:
+		v.record(value, s, path)
+
+	case *lib.UnionSchema:
+		v.union(value, s, path)
+
+	default:
+		v.report(path, "type", "unsupported schema type %s", schema.Type())
+	}
+}
+
+// primitive validates a JSON value with a primitive type.
+func (v *avroJSONValidation) primitive(value interface{}, typ lib.Type, path string) {
+	valid := false
+	switch typ {
+	case lib.Boolean:
+		_, valid = value.(bool)
+	case lib.String:
+		_, valid = value.(string)
+	case lib.Bytes:
+		text, ok := value.(string)
+		valid = ok && isAvroJSONBytes(text)
+	case lib.Int, lib.Long:
+		if number, ok := value.(json.Number); ok {
+			n, err := strconv.ParseInt(number.String(), 10, 64)
+			valid = err == nil && (typ == lib.Long || (n >= math.MinInt32 && n <= math.MaxInt32))
+		}
+	case lib.Float, lib.Double:
+		_, valid = value.(json.Number)
+	}
+
+	if !valid {
+		v.mismatch(path, "type", string(typ), "", "expected a value of type %s", typ)
+	}
+}
+
+// record validates a JSON object with the fields of a record.
+func (v *avroJSONValidation) record(value interface{}, s *lib.RecordSchema, path string) {
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		v.mismatch(path, "type", s.FullName(), "", "expected an object of the record %s", s.FullName())
+		return
+	}
+
+	declared := make(map[string]bool, len(s.Fields()))
+	for _, field := range s.Fields() {
+		declared[field.Name()] = true
+		fieldValue, present := object[field.Name()]
+		switch {
+		case present:
+			v.value(fieldValue, field.Type(), path+"/"+escapePointer(field.Name()))
+		case !field.HasDefault():
+			v.mismatch(path, "required", field.Name(), "", "missing field %s of the record %s", field.Name(),
+				s.FullName())
+		}
+	}
+	for _, key := range sortedKeys(object) {
+		if !declared[key] {
+			v.report(path+"/"+escapePointer(key), "unknown-field", "the record %s has no field %s", s.FullName(),
+				key)
+		}
+	}
+}
+
+// union validates a JSON value with a union. Values other than null are wrapped in an object whose only key names
+// the branch of the union, e.g. {"string": "a"} or {"com.example.Order": {...}}.
+func (v *avroJSONValidation) union(value interface{}, s *lib.UnionSchema, path string) {
+	branches := make([]string, 0, len(s.Types()))
+	for _, branch := range s.Types() {
+		branches = append(branches, avroBranchName(branch))
+	}
+
+	if value == nil {
+		for _, branch := range branches {
+			if branch == string(lib.Null) {
+				return
+			}
+		}
+		v.mismatch(path, "union", strings.Join(branches, ", "), string(lib.Null), "the union [%s] doesn't have null",
+			strings.Join(branches, ", "))
+		return
+	}
+
+	wrapper, ok := value.(map[string]interface{})
+	if !ok || len(wrapper) != 1 {
+		v.mismatch(path, "union", strings.Join(branches, ", "), "",
+			"expected an object naming a branch of the union [%s]", strings.Join(branches, ", "))
+		return
+	}
+	for name, branchValue := range wrapper {
+		for i, branch := range branches {
+			if name == branch {
+				v.value(branchValue, s.Types()[i], path+"/"+escapePointer(name))
+				return
+			}
+		}
+		v.mismatch(path, "union", strings.Join(branches, ", "), name, "%s isn't a branch of the union [%s]", name,
+			strings.Join(branches, ", "))
+	}
+}
+
+// avroBranchName returns the name of a union branch in the JSON encoding: the full name of named types, otherwise
+// the type name.
+func avroBranchName(schema lib.Schema) string {
+	if ref, ok := schema.(*lib.RefSchema); ok {
+		schema = ref.Schema()
+	}
+	if named, ok := schema.(lib.NamedSchema); ok {
+		return named.FullName()
+	}
+	return string(schema.Type())
+}
+
+// isAvroJSONBytes reports whether a string encodes bytes, i.e. all its code points are below 256.
+func isAvroJSONBytes(text string) bool {
+	for _, r := range text {
+		if r > 0xFF {
+			return false
+		}
+	}
+	return true
+}
+
+// escapePointer escapes a reference token of a JSON pointer.
+func escapePointer(token string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(token)
+}
+
+// sortedKeys returns the keys of a JSON object in order, so violations are listed in a stable order.
+func sortedKeys(object map[string]interface{}) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,146 @@
+// Copyright 2020 Syntio Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package impl represents implementation of message validation process.
+package impl
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strconv"
+
+	lib "github.com/hamba/avro"
+	"github.com/hamba/avro/pkg/crc64"
+)
+
+// Framing of binary Avro messages. The Confluent wire format prefixes the datum with a zero magic byte and the
+// 4-byte schema ID of a Confluent Schema Registry, and the Avro single-object encoding with a 2-byte marker and the
+// 8-byte little-endian CRC-64-AVRO fingerprint of the schema.
+const (
+	confluentMagic          = 0x00
+	confluentHeaderSize     = 5
+	singleObjectHeaderSize  = 10
+	singleObjectFingerprint = 2
+)
+
+var singleObjectMarker = []byte{0xC3, 0x01}
+
+// AvroValidator is a validator structure for Avro format.
+type AvroValidator struct {
+	// Encoding is "json" for messages in the Avro JSON encoding. Other messages are binary encoded, either raw or
+	// framed by the Confluent wire format or the Avro single-object encoding.
+	Encoding string
+}
+
+// UseAttributes takes the encoding of the messages from the encoding attribute of a message.
+func (av *AvroValidator) UseAttributes(attributes map[string]string) {
+	av.Encoding = attributes["encoding"]
+}
+
+// Validate validates an Avro message with a schema and lists the violations found: the datum can't be decoded,
+// bytes follow the datum, or the fingerprint of a single-object encoded message isn't the fingerprint of the schema.
+// JSON encoded messages are located by a JSON pointer, e.g. "/items/2/price".
+//
+// A binary message starting with the Confluent magic byte may also be a raw datum, so it is valid if either
+// reading of it is, and the violations of both readings are listed otherwise. The schema ID of a Confluent framed
+// message isn't checked, since it refers to another registry.
+//
+// An error is returned if the schema can't be parsed.
+func (av *AvroValidator) Validate(message, schema []byte) (*Result, error) {
+	libSchema, err := lib.Parse(string(schema))
+	if err != nil {
+		return nil, err
+	}
+
+	if av.Encoding == "json" {
+		return newResult(explainAvroJSON(message, libSchema)), nil
+	}
+	return newResult(explainAvroBinaryMessage(message, libSchema)), nil
+}
+
+// explainAvroBinaryMessage validates a binary Avro message, which is a raw datum, a Confluent framed datum or a
+// single-object encoded datum.
+func explainAvroBinaryMessage(message []byte, libSchema lib.Schema) []Violation {
+	switch {
+	case len(message) >= singleObjectHeaderSize && bytes.HasPrefix(message, singleObjectMarker):
+		fingerprint := binary.LittleEndian.Uint64(message[singleObjectFingerprint:singleObjectHeaderSize])
+		if expected := avroFingerprint(libSchema); fingerprint != expected {
+			return []Violation{{
+				Rule:     "fingerprint",
+				Expected: fmt.Sprintf("%016x", expected),
+				Actual:   fmt.Sprintf("%016x", fingerprint),
+				Description: fmt.Sprintf("the fingerprint %016x of the message isn't the fingerprint %016x of the "+
+					"schema", fingerprint, expected),
+			}}
+		}
+		return explainAvroBinary(message[singleObjectHeaderSize:], libSchema)
+
+	case len(message) >= confluentHeaderSize && message[0] == confluentMagic:
+		framed := explainAvroBinary(message[confluentHeaderSize:], libSchema)
+		if len(framed) == 0 {
+			return nil
+		}
+		raw := explainAvroBinary(message, libSchema)
+		if len(raw) == 0 {
+			return nil
+		}
+
+		violations := make([]Violation, 0, len(framed)+len(raw))
+		for _, violation := range framed {
+			violation.Description = "as a Confluent framed message: " + violation.Description
+			violations = append(violations, violation)
+		}
+		for _, violation := range raw {
+			violation.Description = "as a raw datum: " + violation.Description
+			violations = append(violations, violation)
+		}
+		return violations
+	}
+	return explainAvroBinary(message, libSchema)
+}
+
+// avroFingerprint returns the CRC-64-AVRO fingerprint of the Parsing Canonical Form of a schema.
+func avroFingerprint(schema lib.Schema) uint64 {
+	digest := crc64.New()
+	digest.Write([]byte(schema.String()))
+	return digest.Sum64()
+}
+
+// explainAvroBinary decodes a binary encoded datum, which has to take up the whole message.
+func explainAvroBinary(message []byte, schema lib.Schema) []Violation {
+	reader := lib.NewReader(nil, 0).Reset(message)
+
+	var data interface{}
+	reader.ReadVal(schema, &data)
+	switch {
+	case reader.Error == io.EOF:
+		return []Violation{{Rule: "truncated", Description: "the message ends before the end of the datum"}}
+	case reader.Error != nil:
+		return []Violation{{Rule: "encoding", Description: reader.Error.Error()}}
+	}
+
+	trailing := 0
+	for next := make([]byte, 1); ; trailing++ {
+		if reader.Read(next); reader.Error != nil {
+			break
+		}
+	}
+	if trailing > 0 {
+		return []Violation{{Rule: "trailing-bytes", Actual: strconv.Itoa(trailing),
+			Description: fmt.Sprintf("%d bytes follow the datum", trailing)}}
+	}
+	return nil
+}
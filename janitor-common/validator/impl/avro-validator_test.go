@@ -0,0 +1,128 @@
+// Copyright 2020 Syntio Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package impl
+
+import (
+	"encoding/binary"
+	"reflect"
+	"strings"
+	"testing"
+
+	lib "github.com/hamba/avro"
+)
+
+// orderSchema is the schema of the Avro test messages.
+const orderSchema = `{"type": "record", "name": "Order", "fields": [` +
+	`{"name": "name", "type": "string"}, {"name": "qty", "type": "int"}]}`
+
+// orderDatum is the binary encoding of {"name": "ab", "qty": 3} by orderSchema.
+var orderDatum = []byte{0x04, 'a', 'b', 0x06}
+
+// located returns the location and rule of each violation, e.g. "/qty/type".
+func located(violations []Violation) []string {
+	locations := []string{}
+	for _, v := range violations {
+		locations = append(locations, v.Location+"/"+v.Rule)
+	}
+	return locations
+}
+
+// concat joins byte slices into a new message.
+func concat(parts ...[]byte) []byte {
+	message := []byte{}
+	for _, part := range parts {
+		message = append(message, part...)
+	}
+	return message
+}
+
+// singleObjectHeader returns the single-object encoding header with the fingerprint.
+func singleObjectHeader(fingerprint uint64) []byte {
+	header := make([]byte, singleObjectHeaderSize)
+	copy(header, singleObjectMarker)
+	binary.LittleEndian.PutUint64(header[singleObjectFingerprint:], fingerprint)
+	return header
+}
+
+func TestAvroValidate(t *testing.T) {
+	schema, err := lib.Parse(orderSchema)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	fingerprint := avroFingerprint(schema)
+
+	tests := []struct {
+		name     string
+		encoding string
+		message  []byte
+		want     []string
+	}{
+		{"raw datum", "", orderDatum, []string{}},
+		{"raw datum truncated", "", orderDatum[:3], []string{"/truncated"}},
+		{"trailing bytes", "", concat(orderDatum, []byte{0x00, 0x00}), []string{"/trailing-bytes"}},
+		{"confluent header", "", concat([]byte{confluentMagic, 0, 0, 0, 42}, orderDatum), []string{}},
+		{"confluent header with trailing bytes", "", concat([]byte{confluentMagic, 0, 0, 0, 42}, orderDatum,
+			[]byte{0x00}), []string{"/trailing-bytes", "/trailing-bytes"}},
+		{"single object", "", concat(singleObjectHeader(fingerprint), orderDatum), []string{}},
+		{"single object fingerprint mismatch", "", concat(singleObjectHeader(fingerprint+1), orderDatum),
+			[]string{"/fingerprint"}},
+		{"single object with trailing bytes", "", concat(singleObjectHeader(fingerprint), orderDatum, []byte{0x00}),
+			[]string{"/trailing-bytes"}},
+		{"truncated single object header", "", singleObjectHeader(fingerprint)[:6], []string{"/encoding"}},
+		{"json", "json", []byte(`{"name": "ab", "qty": 3}`), []string{}},
+		{"json type mismatch", "json", []byte(`{"name": "ab", "qty": "3"}`), []string{"/qty/type"}},
+		{"json trailing values", "json", []byte(`{"name": "ab", "qty": 3} {}`), []string{"/encoding"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			validator := &AvroValidator{Encoding: test.encoding}
+			result, err := validator.Validate(test.message, []byte(orderSchema))
+			if err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+			if got := located(result.Violations); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Validate() violations = %v, want %v", got, test.want)
+			}
+			if result.Valid != (len(test.want) == 0) {
+				t.Errorf("Validate() valid = %v, want %v", result.Valid, len(test.want) == 0)
+			}
+		})
+	}
+}
+
+func TestAvroValidateConfluentReadings(t *testing.T) {
+	// A message which is neither a valid Confluent framed datum nor a valid raw datum lists the violations of both
+	// readings.
+	message := concat([]byte{confluentMagic, 0, 0, 0, 42}, orderDatum[:3])
+	result, err := (&AvroValidator{}).Validate(message, []byte(orderSchema))
+	if err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if len(result.Violations) != 2 {
+		t.Fatalf("Validate() violations = %v, want one per reading", result.Violations)
+	}
+	for i, prefix := range []string{"as a Confluent framed message: ", "as a raw datum: "} {
+		if description := result.Violations[i].Description; !strings.HasPrefix(description, prefix) {
+			t.Errorf("Validate() violation %d = %q, want the prefix %q", i, description, prefix)
+		}
+	}
+}
+
+func TestAvroValidateSchemaError(t *testing.T) {
+	if _, err := (&AvroValidator{}).Validate(orderDatum, []byte(`{"type": "record"}`)); err == nil {
+		t.Error("Validate() error = nil, want the schema parse error")
+	}
+}
@@ -15,28 +15,13 @@
 // Package impl represents implementation of message validation process.
 package impl
 
+// BsonValidator is a validator structure for BSON format. Messages are converted into JSON and
+// validated with a JSON Schema.
+type BsonValidator struct{}
 
-import lib "github.com/hamba/avro"
-
-// AvroValidator is a validator structure for Avro format.
-type AvroValidator struct{}
-
-// ValidateMessageWithAVROSchema validates an Avro message with a schema.
+// Validate validates a BSON message with a JSON Schema and lists every violation found, located by a JSON pointer.
 //
-// Function returns the validation boolean result. An error is returned if any errors occur during the
-// function execution.
-func (av *AvroValidator) Validate(message, schema []byte) (bool, error) {
-	var valid bool = false
-
-	libSchema, err := lib.Parse(string(schema))
-	if err != nil {
-		return valid, err
-	}
-
-	var data interface{}
-	if err = lib.Unmarshal(libSchema, message, &data); err != nil {
-		return valid, err
-	}
-
-	return !valid, err
+// An error is returned if any errors occur during the function execution.
+func (bv *BsonValidator) Validate(message, schema []byte) (*Result, error) {
+	return validateConverted("bson", message, schema)
 }
@@ -0,0 +1,27 @@
+// Copyright 2020 Syntio Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package impl represents implementation of message validation process.
+package impl
+
+// CborValidator is a validator structure for CBOR format. Messages are converted into JSON and
+// validated with a JSON Schema.
+type CborValidator struct{}
+
+// Validate validates a CBOR message with a JSON Schema and lists every violation found, located by a JSON pointer.
+//
+// An error is returned if any errors occur during the function execution.
+func (cv *CborValidator) Validate(message, schema []byte) (*Result, error) {
+	return validateConverted("cbor", message, schema)
+}
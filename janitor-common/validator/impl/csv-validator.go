@@ -0,0 +1,55 @@
+// Copyright 2020 Syntio Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package impl represents implementation of message validation process.
+package impl
+
+import (
+	"fmt"
+
+	"github.com/syntio/janitor-common/validator/csvschema"
+)
+
+// CsvValidator is a validator structure for CSV format. Messages are validated with the CSV Schema in-process.
+type CsvValidator struct{}
+
+// Validate validates a CSV message with a schema and lists every violation found, located by the row and the column,
+// e.g. "/3/email". Violations of a whole row leave out the column, and those of the whole message are located by "/".
+//
+// An error is returned if any errors occur during the function execution.
+func (cv *CsvValidator) Validate(message, schema []byte) (*Result, error) {
+	csvSchema, err := csvschema.Parse(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	found, err := csvSchema.Validate(message)
+	if err != nil {
+		return nil, err
+	}
+
+	violations := make([]Violation, 0, len(found))
+	for _, violation := range found {
+		location := "/"
+		if violation.Row > 0 {
+			location = fmt.Sprintf("/%d", violation.Row)
+			if violation.Column != "" {
+				location += "/" + violation.Column
+			}
+		}
+		violations = append(violations, Violation{Location: location, Rule: violation.Rule,
+			Expected: violation.Expected, Actual: violation.Actual, Description: violation.Description})
+	}
+	return newResult(violations), nil
+}
@@ -0,0 +1,96 @@
+/// Copyright 2020 Syntio Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package impl represents implementation of message validation process.
+package impl
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	lib "github.com/xeipuuv/gojsonschema"
+
+	"github.com/syntio/janitor-common/validator/convert"
+)
+
+// JsonValidator is a validator structure for JSON format.
+type JsonValidator struct{}
+
+// Validate validates a JSON message with a schema and lists every violation found, located by a JSON pointer and
+// named by the JSON Schema keyword it violates.
+//
+// An error is returned if any errors occur during the function execution.
+func (jv *JsonValidator) Validate(message, schema []byte) (*Result, error) {
+	documentLoader := lib.NewBytesLoader(message)
+	schemaLoader := lib.NewBytesLoader(schema)
+
+	result, err := lib.Validate(schemaLoader, documentLoader)
+	if err != nil {
+		return nil, err
+	}
+
+	violations := make([]Violation, 0, len(result.Errors()))
+	for _, resultError := range result.Errors() {
+		expected, actual := jsonComparison(resultError)
+		violations = append(violations, Violation{
+			Location:    strings.TrimPrefix(resultError.Context().String("/"), lib.STRING_CONTEXT_ROOT),
+			Rule:        resultError.Type(),
+			Expected:    expected,
+			Actual:      actual,
+			Description: resultError.Description(),
+		})
+	}
+	return newResult(violations), nil
+}
+
+// expectedDetails are the details of JSON Schema errors which hold the expected value, in the order of preference.
+var expectedDetails = []string{"expected", "min", "max", "pattern", "allowed", "format", "property"}
+
+// jsonComparison returns the expected and the actual value of a JSON Schema error. The actual value is only given
+// for scalar values, so it doesn't repeat whole objects and arrays.
+func jsonComparison(resultError lib.ResultError) (expected, actual string) {
+	details := resultError.Details()
+	for _, key := range expectedDetails {
+		if value, ok := details[key]; ok {
+			expected = fmt.Sprint(value)
+			break
+		}
+	}
+
+	switch value := resultError.Value().(type) {
+	case map[string]interface{}, []interface{}:
+		if given, ok := details["given"]; ok {
+			actual = fmt.Sprint(given)
+		}
+	case nil:
+		actual = "null"
+	default:
+		if encoded, err := json.Marshal(value); err == nil {
+			actual = string(encoded)
+		}
+	}
+	return expected, actual
+}
+
+// validateConverted converts a message of a format into JSON and validates it with a JSON Schema.
+//
+// An error is returned if the message can't be converted or the schema isn't valid.
+func validateConverted(format string, message, schema []byte) (*Result, error) {
+	document, err := convert.ToJSON(format, message)
+	if err != nil {
+		return nil, err
+	}
+	return (&JsonValidator{}).Validate(document, schema)
+}
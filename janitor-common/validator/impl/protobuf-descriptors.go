@@ -0,0 +1,81 @@
+// Copyright 2020 Syntio Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package impl represents implementation of message validation process.
+package impl
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"sync"
+)
+
+// descriptorCache is an LRU cache of compiled protobuf schemas by the hash of their source. Schemas which don't
+// compile are cached too, so an invalid schema isn't compiled for every message.
+type descriptorCache struct {
+	maxEntries int
+
+	lock    sync.Mutex
+	order   *list.List // most recently used first
+	entries map[[sha256.Size]byte]*list.Element
+}
+
+// descriptorEntry is a cached schema compilation.
+type descriptorEntry struct {
+	hash     [sha256.Size]byte
+	compiled *compiledSchema
+	err      error
+}
+
+// newDescriptorCache creates a cache of at most maxEntries compiled schemas. Schemas aren't cached if maxEntries
+// isn't positive.
+func newDescriptorCache(maxEntries int) *descriptorCache {
+	return &descriptorCache{
+		maxEntries: maxEntries,
+		order:      list.New(),
+		entries:    make(map[[sha256.Size]byte]*list.Element),
+	}
+}
+
+// get returns the compiled schema, compiling and caching it if it isn't cached.
+func (c *descriptorCache) get(schema []byte) (*compiledSchema, error) {
+	if c.maxEntries <= 0 {
+		return compileSchema(schema)
+	}
+	hash := sha256.Sum256(schema)
+
+	c.lock.Lock()
+	if element, ok := c.entries[hash]; ok {
+		c.order.MoveToFront(element)
+		entry := element.Value.(*descriptorEntry)
+		c.lock.Unlock()
+		return entry.compiled, entry.err
+	}
+	c.lock.Unlock()
+
+	// Compile outside of the lock, a schema compiled twice concurrently is cached once
+	compiled, err := compileSchema(schema)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if _, ok := c.entries[hash]; !ok {
+		c.entries[hash] = c.order.PushFront(&descriptorEntry{hash: hash, compiled: compiled, err: err})
+		for c.order.Len() > c.maxEntries {
+			oldest := c.order.Back()
+			c.order.Remove(oldest)
+			delete(c.entries, oldest.Value.(*descriptorEntry).hash)
+		}
+	}
+	return compiled, err
+}
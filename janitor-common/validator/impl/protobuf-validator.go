@@ -0,0 +1,197 @@
+// Copyright 2020 Syntio Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package impl represents implementation of message validation process.
+package impl
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/jhump/protoreflect/dynamic"
+)
+
+// strictUnknownFields makes messages with fields their message type doesn't declare invalid.
+var strictUnknownFields bool
+
+// descriptors caches the compiled schemas, so a schema is only compiled once per function instance. Schemas aren't
+// cached until ConfigureProtobuf sets the size of the cache.
+var descriptors = newDescriptorCache(0)
+
+// ConfigureProtobuf sets the number of compiled schemas the Protobuf validators cache, no caching if not positive,
+// and whether fields a message type doesn't declare make a message invalid. It has to be called before any message
+// is validated.
+func ConfigureProtobuf(descriptorCacheSize int, strictUnknown bool) {
+	descriptors = newDescriptorCache(descriptorCacheSize)
+	strictUnknownFields = strictUnknown
+}
+
+// schemaFileName is the name a schema is compiled under, as the compiler works with named files.
+const schemaFileName = "schema.proto"
+
+// messageTypeDirective is the comment naming the default message type of a schema, e.g. "// @messageType Order".
+var messageTypeDirective = regexp.MustCompile(`(?m)^\s*//\s*@messageType\s+([\w.]+)\s*$`)
+
+// ProtobufValidator is a validator structure for protobuf format.
+type ProtobufValidator struct {
+	// MessageType names the message of the schema the messages are decoded as, either fully qualified or relative to
+	// the package of the schema. It defaults to the message type named by the @messageType comment of the schema,
+	// or to the first message type of the schema.
+	MessageType string
+}
+
+// UseAttributes takes the message type from the messageType attribute of a message.
+func (proto *ProtobufValidator) UseAttributes(attributes map[string]string) {
+	proto.MessageType = attributes["messageType"]
+}
+
+// Validate validates a protobuf message with a schema and lists the missing required fields and, if unknown fields
+// are treated strictly, the fields the message type doesn't declare, located by their field path, e.g.
+// "/items/2/price".
+//
+// An error is returned if the schema can't be compiled, doesn't have the message type or the message can't be
+// decoded.
+func (proto *ProtobufValidator) Validate(message, schema []byte) (*Result, error) {
+	compiled, err := descriptors.get(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	messageDescriptor, err := compiled.messageType(proto.MessageType)
+	if err != nil {
+		return nil, err
+	}
+
+	decoded := dynamic.NewMessage(messageDescriptor)
+	if err = decoded.Unmarshal(message); err != nil {
+		return nil, err
+	}
+
+	var violations []Violation
+	if strictUnknownFields {
+		violations = unknownFields(decoded, "")
+	}
+	if err := decoded.ValidateRecursive(); err != nil {
+		violations = append(violations, Violation{Rule: "required", Description: err.Error()})
+	}
+	return newResult(violations), nil
+}
+
+// compiledSchema is a compiled protobuf schema with its default message type.
+type compiledSchema struct {
+	file        *desc.FileDescriptor
+	defaultType *desc.MessageDescriptor
+}
+
+// compileSchema compiles a protobuf schema in memory. The schema can import the well-known types, e.g.
+// google/protobuf/timestamp.proto.
+//
+// An error is returned if the schema isn't valid or its @messageType comment names an unknown message type.
+func compileSchema(schema []byte) (*compiledSchema, error) {
+	parser := protoparse.Parser{
+		Accessor: protoparse.FileContentsFromMap(map[string]string{schemaFileName: string(schema)}),
+	}
+
+	fileDescriptors, err := parser.ParseFiles(schemaFileName)
+	if err != nil {
+		return nil, err
+	}
+
+	compiled := &compiledSchema{file: fileDescriptors[0]}
+	if messageTypes := compiled.file.GetMessageTypes(); len(messageTypes) > 0 {
+		compiled.defaultType = messageTypes[0]
+	}
+	if directive := messageTypeDirective.FindSubmatch(schema); directive != nil {
+		if compiled.defaultType = compiled.find(string(directive[1])); compiled.defaultType == nil {
+			return nil, fmt.Errorf("the @messageType %s of the schema isn't a message type of the schema",
+				directive[1])
+		}
+	}
+	return compiled, nil
+}
+
+// messageType returns the message type with a name, or the default message type of the schema if the name is empty.
+//
+// An error is returned if the schema doesn't have the message type.
+func (s *compiledSchema) messageType(name string) (*desc.MessageDescriptor, error) {
+	if name == "" {
+		if s.defaultType == nil {
+			return nil, fmt.Errorf("the schema has no message types")
+		}
+		return s.defaultType, nil
+	}
+
+	if messageDescriptor := s.find(name); messageDescriptor != nil {
+		return messageDescriptor, nil
+	}
+	return nil, fmt.Errorf("the schema has no message type %s", name)
+}
+
+// find finds a message type by its fully qualified name or its name relative to the package of the schema.
+func (s *compiledSchema) find(name string) *desc.MessageDescriptor {
+	if messageDescriptor := s.file.FindMessage(name); messageDescriptor != nil {
+		return messageDescriptor
+	}
+	if pkg := s.file.GetPackage(); pkg != "" {
+		return s.file.FindMessage(pkg + "." + name)
+	}
+	return nil
+}
+
+// unknownFields lists the fields of a decoded message, and of the messages nested in it, which their message types
+// don't declare.
+func unknownFields(message *dynamic.Message, path string) []Violation {
+	var violations []Violation
+
+	unknown := message.GetUnknownFields()
+	sort.Slice(unknown, func(i, j int) bool { return unknown[i] < unknown[j] })
+	for _, number := range unknown {
+		violations = append(violations, Violation{
+			Location: path,
+			Rule:     "unknown-field",
+			Actual:   strconv.Itoa(int(number)),
+			Description: fmt.Sprintf("field number %d isn't declared by %s", number,
+				message.GetMessageDescriptor().GetFullyQualifiedName()),
+		})
+	}
+
+	for _, field := range message.GetKnownFields() {
+		if field.GetMessageType() == nil {
+			continue
+		}
+		fieldPath := path + "/" + field.GetName()
+
+		switch value := message.GetField(field).(type) {
+		case *dynamic.Message:
+			violations = append(violations, unknownFields(value, fieldPath)...)
+		case []interface{}:
+			for i, element := range value {
+				if nested, ok := element.(*dynamic.Message); ok {
+					violations = append(violations, unknownFields(nested, fieldPath+"/"+strconv.Itoa(i))...)
+				}
+			}
+		case map[interface{}]interface{}:
+			for key, element := range value {
+				if nested, ok := element.(*dynamic.Message); ok {
+					violations = append(violations, unknownFields(nested, fmt.Sprintf("%s/%v", fieldPath, key))...)
+				}
+			}
+		}
+	}
+	return violations
+}
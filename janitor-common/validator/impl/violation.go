@@ -0,0 +1,39 @@
+// Copyright 2020 Syntio Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package impl represents implementation of message validation process.
+package impl
+
+// Violation describes a part of a message which doesn't conform to the message schema. Location points to the
+// violating part of the message, e.g. a JSON pointer, an XPath-like element path or a CSV row and column, and is
+// empty if the violation can't be located. Rule names the violated schema rule, e.g. "required", and Expected and
+// Actual are the values compared by the rule, if any.
+type Violation struct {
+	Location    string `json:"location"`
+	Rule        string `json:"rule,omitempty"`
+	Expected    string `json:"expected,omitempty"`
+	Actual      string `json:"actual,omitempty"`
+	Description string `json:"description"`
+}
+
+// Result is the result of a validation. An invalid message has at least one violation.
+type Result struct {
+	Valid      bool        `json:"valid"`
+	Violations []Violation `json:"violations,omitempty"`
+}
+
+// newResult returns the result of a validation which found the given violations.
+func newResult(violations []Violation) *Result {
+	return &Result{Valid: len(violations) == 0, Violations: violations}
+}
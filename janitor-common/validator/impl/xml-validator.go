@@ -0,0 +1,44 @@
+// Copyright 2020 Syntio Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package impl represents implementation of message validation process.
+package impl
+
+import "github.com/syntio/janitor-common/validator/xsd"
+
+// XmlValidator is a validator structure for xml format. Messages are validated with the XML Schema in-process.
+type XmlValidator struct{}
+
+// Validate validates a XML message with a schema and lists every violation found, located by the path of the
+// element or attribute, e.g. "/order/item[2]/@sku".
+//
+// An error is returned if any errors occur during the function execution.
+func (xv *XmlValidator) Validate(message, schema []byte) (*Result, error) {
+	xmlSchema, err := xsd.Parse(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	found, err := xmlSchema.Validate(message)
+	if err != nil {
+		return nil, err
+	}
+
+	violations := make([]Violation, 0, len(found))
+	for _, violation := range found {
+		violations = append(violations, Violation{Location: violation.Path, Rule: violation.Rule,
+			Expected: violation.Expected, Actual: violation.Actual, Description: violation.Description})
+	}
+	return newResult(violations), nil
+}
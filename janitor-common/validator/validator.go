@@ -0,0 +1,211 @@
+// Copyright 2020 Syntio Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Package validator is used for message validation.
+package validator
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/syntio/janitor-common/validator/impl"
+)
+
+// Init function is used to register the validators of the supported message formats.
+func init() {
+	Register("avro", func() Validator { return &impl.AvroValidator{} }, UsesAttributes)
+	Register("bson", func() Validator { return &impl.BsonValidator{} }, 0)
+	Register("cbor", func() Validator { return &impl.CborValidator{} }, 0)
+	Register("csv", func() Validator { return &impl.CsvValidator{} }, 0)
+	Register("json", func() Validator { return &impl.JsonValidator{} }, 0)
+	Register("msgpack", func() Validator { return &impl.MsgpackValidator{} }, 0)
+	Register("protobuf", func() Validator { return &impl.ProtobufValidator{} }, UsesAttributes)
+	Register("xml", func() Validator { return &impl.XmlValidator{} }, 0)
+	Register("yaml", func() Validator { return &impl.YamlValidator{} }, 0)
+}
+
+// Validator interface provides method Validate. Method is used for message validation and returns the result of the
+// validation, which lists where and why an invalid message doesn't conform to its schema.
+type Validator interface {
+	Validate(message, schema []byte) (*impl.Result, error)
+}
+
+// Factory creates a validator of a message format. A validator is created for each message, so a factory shouldn't
+// do any expensive work; that belongs to the Init method of the validator.
+type Factory func() Validator
+
+// Capabilities describe what the validators of a message format support beyond Validate.
+type Capabilities uint
+
+const (
+	// UsesAttributes is set for the validators which implement AttributeValidator.
+	UsesAttributes Capabilities = 1 << iota
+)
+
+// Has reports whether all the given capabilities are set.
+func (c Capabilities) Has(capabilities Capabilities) bool {
+	return c&capabilities == capabilities
+}
+
+// ErrUnknownFormat is returned for message formats no validator is registered for.
+var ErrUnknownFormat = errors.New("unsupported message format")
+
+// plugin is a registered message format.
+type plugin struct {
+	factory      Factory
+	capabilities Capabilities
+}
+
+var (
+	pluginsMu sync.RWMutex
+	plugins   = make(map[string]plugin)
+	// settings holds the settings of the validators by their formats.
+	settings map[string]map[string]string
+)
+
+// Register makes the validators of a message format available by the format, which is case insensitive. Validators
+// shipped out of this tree register their formats in an init function of their package, so importing the package
+// is enough to support the format.
+//
+// Register panics if the format is empty or already registered, if the factory is nil, or if the validators it
+// creates don't implement the interfaces of the declared capabilities.
+func Register(format string, factory Factory, capabilities Capabilities) {
+	format = strings.ToLower(format)
+	if format == "" {
+		panic("validator: Register with an empty format")
+	}
+	if factory == nil {
+		panic("validator: Register with a nil factory for format " + format)
+	}
+
+	v := factory()
+	for _, check := range []struct {
+		capability  Capabilities
+		implemented bool
+		name        string
+	}{
+		{UsesAttributes, isAttributeValidator(v), "AttributeValidator"},
+	} {
+		if capabilities.Has(check.capability) && !check.implemented {
+			panic(fmt.Sprintf("validator: the validator of format %s doesn't implement %s", format, check.name))
+		}
+	}
+
+	pluginsMu.Lock()
+	defer pluginsMu.Unlock()
+	if _, dup := plugins[format]; dup {
+		panic("validator: Register called twice for format " + format)
+	}
+	plugins[format] = plugin{factory: factory, capabilities: capabilities}
+}
+
+// Formats returns the registered message formats, sorted.
+func Formats() []string {
+	pluginsMu.RLock()
+	defer pluginsMu.RUnlock()
+
+	formats := make([]string, 0, len(plugins))
+	for format := range plugins {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+// Supports reports whether a message format is registered.
+func Supports(format string) bool {
+	_, ok := CapabilitiesOf(format)
+	return ok
+}
+
+// CapabilitiesOf returns the capabilities of the validators of a message format, and false if the format isn't
+// registered.
+func CapabilitiesOf(format string) (Capabilities, bool) {
+	pluginsMu.RLock()
+	defer pluginsMu.RUnlock()
+
+	p, ok := plugins[strings.ToLower(format)]
+	return p.capabilities, ok
+}
+
+// Initializer interface is implemented by validators which need to be set up before validating, e.g. with their
+// settings under validators in the config file.
+type Initializer interface {
+	Init(config map[string]string) error
+}
+
+// Configure sets the settings the validators are initialized with, by their formats, e.g. those under validators in
+// the config file of the Central Consumer.
+func Configure(validatorSettings map[string]map[string]string) {
+	pluginsMu.Lock()
+	defer pluginsMu.Unlock()
+
+	settings = make(map[string]map[string]string, len(validatorSettings))
+	for format, formatSettings := range validatorSettings {
+		settings[strings.ToLower(format)] = formatSettings
+	}
+}
+
+// ForFormat returns an initialized validator of a message format, e.g. "json" or "avro". The validator is passed
+// the settings of its format set by Configure, and has to be closed with Close once it isn't needed anymore.
+//
+// An error is returned if the format isn't registered or the validator can't be initialized.
+func ForFormat(format string) (Validator, error) {
+	pluginsMu.RLock()
+	p, ok := plugins[strings.ToLower(format)]
+	formatSettings := settings[strings.ToLower(format)]
+	pluginsMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownFormat, format)
+	}
+
+	v := p.factory()
+	if initializer, ok := v.(Initializer); ok {
+		if err := initializer.Init(formatSettings); err != nil {
+			return nil, fmt.Errorf("initializing the validator of format %s: %w", format, err)
+		}
+	}
+	return v, nil
+}
+
+// Close releases the resources of a validator which holds any, i.e. implements io.Closer.
+func Close(v Validator) error {
+	if closer, ok := v.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// AttributeValidator interface is implemented by validators whose validation depends on the attributes of a
+// message, e.g. the Protobuf validator decodes the message type named by the messageType attribute.
+type AttributeValidator interface {
+	UseAttributes(attributes map[string]string)
+}
+
+// UseAttributes passes the attributes of a message on to a validator which depends on them.
+func UseAttributes(v Validator, attributes map[string]string) {
+	if attributeValidator, ok := v.(AttributeValidator); ok {
+		attributeValidator.UseAttributes(attributes)
+	}
+}
+
+// isAttributeValidator reports whether a validator implements AttributeValidator.
+func isAttributeValidator(v Validator) bool {
+	_, ok := v.(AttributeValidator)
+	return ok
+}
@@ -0,0 +1,247 @@
+// Copyright 2020 Syntio Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xsd
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// ToJSON converts a valid XML document into a JSON document by the schema. The root element becomes the only member
+// of the document. Elements are named by their local names and become the members of the object of their parent,
+// arrays if the content model of the parent allows them more than once. Elements with simple content become the
+// values of their types: numbers, booleans, arrays for lists and strings for the other types. Elements with
+// attributes or child elements become objects, whose attributes are members prefixed with "@", and whose text is the
+// member "#text". Absent attributes and empty elements with a default value get it, and nil elements become null.
+// Elements the schema doesn't declare, e.g. those matched by a wildcard, are converted without types.
+//
+// An error is returned if the document isn't well-formed or its root element isn't declared.
+func (s *Schema) ToJSON(document []byte) ([]byte, error) {
+	root, err := parseDocument(document)
+	if err != nil {
+		return nil, err
+	}
+
+	decl, ok := s.elements[root.name]
+	if !ok {
+		return nil, fmt.Errorf("element %s isn't declared as a global element of the schema", root.name.Local)
+	}
+	return json.Marshal(map[string]interface{}{root.name.Local: s.jsonElement(root, decl)})
+}
+
+// jsonElement returns the JSON value of an element with its declaration, if any.
+func (s *Schema) jsonElement(n *node, decl *element) interface{} {
+	if nilled, ok := instanceAttr(n, "nil"); ok && (nilled == "true" || nilled == "1") {
+		return nil
+	}
+	if decl == nil || (decl.complex != nil && decl.complex.anyType) {
+		return untypedElement(n)
+	}
+
+	t, members := decl.simple, map[string]interface{}{}
+	if decl.complex != nil {
+		t = decl.complex.simple
+		s.jsonAttributes(n, decl.complex, members)
+	}
+
+	if t != nil {
+		text := n.text
+		if text == "" && decl.def != nil {
+			text = *decl.def
+		}
+		if len(members) == 0 {
+			return jsonValue(text, t)
+		}
+		members["#text"] = jsonValue(text, t)
+		return members
+	}
+
+	decls, repeated := declarations(decl.complex.content)
+	arrays := map[string]bool{}
+	for _, child := range n.children {
+		childDecl, ok := decls[child.name]
+		if !ok {
+			childDecl = s.elements[child.name]
+		}
+		addMember(members, arrays, child.name.Local, s.jsonElement(child, childDecl), repeated[child.name])
+	}
+	if n.mixed {
+		members["#text"] = strings.TrimSpace(n.text)
+	}
+	return members
+}
+
+// jsonAttributes adds the attributes of an element, and the default values of its absent attributes, to the members
+// of its object.
+func (s *Schema) jsonAttributes(n *node, t *complexType, members map[string]interface{}) {
+	seen := map[xml.Name]bool{}
+	for _, attr := range n.attrs {
+		if attr.Name.Space == instanceNamespace {
+			continue
+		}
+		seen[attr.Name] = true
+
+		use, ok := t.attributes[attr.Name]
+		if !ok {
+			use, ok = s.attributes[attr.Name]
+		}
+		if ok {
+			members["@"+attr.Name.Local] = jsonValue(attr.Value, use.typ)
+		} else {
+			members["@"+attr.Name.Local] = attr.Value
+		}
+	}
+
+	for name, use := range t.attributes {
+		if !seen[name] && use.def != nil {
+			members["@"+name.Local] = jsonValue(*use.def, use.typ)
+		}
+	}
+}
+
+// declarations returns the element declarations of a content model by their names, including the members of their
+// substitution groups, and which of them the content model allows more than once.
+func declarations(content *particle) (map[xml.Name]*element, map[xml.Name]bool) {
+	decls, repeated := map[xml.Name]*element{}, map[xml.Name]bool{}
+
+	var declare func(decl *element, repeats bool)
+	declare = func(decl *element, repeats bool) {
+		if _, duplicate := decls[decl.name]; duplicate || repeats {
+			repeated[decl.name] = true
+		}
+		decls[decl.name] = decl
+		for _, substitute := range decl.substitutes {
+			declare(substitute, repeats)
+		}
+	}
+
+	var walk func(p *particle, repeats bool)
+	walk = func(p *particle, repeats bool) {
+		if p == nil {
+			return
+		}
+		repeats = repeats || p.max != 1
+		if p.kind == elementParticle {
+			declare(p.element, repeats)
+		}
+		for _, child := range p.children {
+			walk(child, repeats)
+		}
+	}
+	walk(content, false)
+	return decls, repeated
+}
+
+// untypedElement returns the JSON value of an element without a declaration. Elements without attributes and child
+// elements become their text, and elements occurring more than once in their parent become arrays.
+func untypedElement(n *node) interface{} {
+	members := map[string]interface{}{}
+	for _, attr := range n.attrs {
+		if attr.Name.Space != instanceNamespace {
+			members["@"+attr.Name.Local] = attr.Value
+		}
+	}
+	if len(members) == 0 && len(n.children) == 0 {
+		return n.text
+	}
+
+	arrays := map[string]bool{}
+	for _, child := range n.children {
+		addMember(members, arrays, child.name.Local, untypedElement(child), false)
+	}
+	if n.mixed {
+		members["#text"] = strings.TrimSpace(n.text)
+	}
+	return members
+}
+
+// addMember adds the value of an element to the members of the object of its parent. Repeated elements, and
+// elements whose name is already a member, are collected in an array. Arrays holds the names of the members which
+// are arrays of elements.
+func addMember(members map[string]interface{}, arrays map[string]bool, name string, value interface{},
+	repeated bool) {
+	if existing, ok := members[name]; ok && !arrays[name] {
+		members[name], arrays[name] = []interface{}{existing}, true
+	}
+	switch {
+	case arrays[name]:
+		members[name] = append(members[name].([]interface{}), value)
+	case repeated:
+		members[name], arrays[name] = []interface{}{value}, true
+	default:
+		members[name] = value
+	}
+}
+
+// jsonValue returns the JSON value of a value of a simple type. The values of lists become arrays, decimal and
+// floating-point values numbers (except INF and NaN, which JSON can't represent), and booleans booleans. The other
+// values are strings.
+func jsonValue(lexical string, t *simpleType) interface{} {
+	switch t.variety {
+	case list:
+		items := []interface{}{}
+		for _, item := range strings.Fields(lexical) {
+			items = append(items, jsonValue(item, t.item))
+		}
+		return items
+	case union:
+		return lexical
+	}
+
+	value := normalize(lexical, t.facets.whiteSpace)
+	switch t.primitive {
+	case "boolean":
+		switch value {
+		case "true", "1":
+			return true
+		case "false", "0":
+			return false
+		}
+	case "decimal", "float", "double":
+		if floatPattern.MatchString(value) {
+			return jsonNumber(value)
+		}
+	}
+	return value
+}
+
+// jsonNumber returns a decimal or floating-point number in the syntax of JSON numbers, which has no plus sign,
+// leading zeros or empty integer and fraction parts, e.g. "+.5" becomes 0.5.
+func jsonNumber(lexical string) json.Number {
+	number, sign := strings.TrimPrefix(lexical, "+"), ""
+	if strings.HasPrefix(number, "-") {
+		number, sign = number[1:], "-"
+	}
+
+	exponent := ""
+	if i := strings.IndexAny(number, "eE"); i >= 0 {
+		number, exponent = number[:i], number[i:]
+	}
+	fraction := ""
+	if i := strings.IndexByte(number, '.'); i >= 0 {
+		number, fraction = number[:i], number[i+1:]
+	}
+
+	number = strings.TrimLeft(number, "0")
+	if number == "" {
+		number = "0"
+	}
+	if fraction != "" {
+		number += "." + fraction
+	}
+	return json.Number(sign + number + exponent)
+}
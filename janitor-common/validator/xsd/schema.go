@@ -0,0 +1,928 @@
+// Copyright 2020 Syntio Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package xsd validates XML documents with XML Schema 1.0 in-process. It covers the constructs the registered
+// schemas use: global and local elements and attributes, named and anonymous complex and simple types, sequences,
+// choices, all groups, model and attribute groups, wildcards, occurrence bounds, simple and complex content
+// derivations, substitution groups, the builtin simple types and the restrictions, lists and unions of simple types
+// with their facets. A schema is a single document: includes, redefinitions and the components of imported schemas
+// aren't supported, and identity constraints aren't checked.
+package xsd
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Schema is a compiled XML Schema document.
+type Schema struct {
+	targetNamespace        string
+	elementFormQualified   bool
+	attributeFormQualified bool
+
+	// The global definitions of the schema document by their names, which are compiled on first use.
+	elementDefs        map[xml.Name]*node
+	typeDefs           map[xml.Name]*node
+	groupDefs          map[xml.Name]*node
+	attributeGroupDefs map[xml.Name]*node
+	attributeDefs      map[xml.Name]*node
+
+	elements     map[xml.Name]*element
+	simpleTypes  map[xml.Name]*simpleType
+	complexTypes map[xml.Name]*complexType
+	attributes   map[xml.Name]*attributeUse
+}
+
+// element is an element declaration.
+type element struct {
+	name xml.Name
+	// The type of the element is either simple or complex.
+	simple   *simpleType
+	complex  *complexType
+	nillable bool
+	abstract bool
+	fixed    *string
+	def      *string
+	// substitutes are the elements of the element's substitution group.
+	substitutes []*element
+}
+
+// complexType is a complex type definition. A type has either simple content or a content model, which is empty
+// if it's nil.
+type complexType struct {
+	name         xml.Name
+	simple       *simpleType
+	content      *particle
+	mixed        bool
+	abstract     bool
+	attributes   map[xml.Name]*attributeUse
+	anyAttribute *wildcard
+	// anyType is set for xs:anyType, which accepts any attributes and content.
+	anyType bool
+}
+
+// attributeUse is an attribute declaration, with its use in a complex type.
+type attributeUse struct {
+	name     xml.Name
+	typ      *simpleType
+	required bool
+	fixed    *string
+	def      *string
+}
+
+type particleKind int
+
+const (
+	elementParticle particleKind = iota
+	sequenceParticle
+	choiceParticle
+	allParticle
+	anyParticle
+)
+
+// particle is a term of a content model with its occurrence bounds. A max of -1 is unbounded.
+type particle struct {
+	kind     particleKind
+	min      int
+	max      int
+	element  *element
+	children []*particle
+	wildcard *wildcard
+}
+
+// wildcard is an element or attribute wildcard.
+type wildcard struct {
+	any        bool
+	other      string
+	namespaces map[string]bool
+	// process is the processContents of the wildcard: strict, lax or skip.
+	process string
+}
+
+// allows reports whether the wildcard allows a name of the namespace.
+func (w *wildcard) allows(space string) bool {
+	switch {
+	case w.any:
+		return true
+	case w.namespaces != nil:
+		return w.namespaces[space]
+	default:
+		return space != "" && space != w.other
+	}
+}
+
+// anyType is xs:anyType.
+var anyType = &complexType{name: xml.Name{Space: Namespace, Local: "anyType"}, anyType: true, mixed: true}
+
+// Parse parses and compiles an XML Schema document. An error is returned if the document isn't a schema or uses
+// unsupported constructs.
+func Parse(document []byte) (*Schema, error) {
+	root, err := parseDocument(document)
+	if err != nil {
+		return nil, err
+	}
+	if root.name.Space != Namespace || root.name.Local != "schema" {
+		return nil, fmt.Errorf("the document isn't an XML Schema")
+	}
+
+	s := &Schema{
+		elementDefs:        map[xml.Name]*node{},
+		typeDefs:           map[xml.Name]*node{},
+		groupDefs:          map[xml.Name]*node{},
+		attributeGroupDefs: map[xml.Name]*node{},
+		attributeDefs:      map[xml.Name]*node{},
+		elements:           map[xml.Name]*element{},
+		simpleTypes:        map[xml.Name]*simpleType{},
+		complexTypes:       map[xml.Name]*complexType{},
+		attributes:         map[xml.Name]*attributeUse{},
+	}
+	s.targetNamespace, _ = root.attr("targetNamespace")
+	elementForm, _ := root.attr("elementFormDefault")
+	s.elementFormQualified = elementForm == "qualified"
+	attributeForm, _ := root.attr("attributeFormDefault")
+	s.attributeFormQualified = attributeForm == "qualified"
+
+	defs := map[string]map[xml.Name]*node{
+		"element":        s.elementDefs,
+		"complexType":    s.typeDefs,
+		"simpleType":     s.typeDefs,
+		"group":          s.groupDefs,
+		"attributeGroup": s.attributeGroupDefs,
+		"attribute":      s.attributeDefs,
+	}
+	for _, child := range root.children {
+		if child.name.Space != Namespace {
+			continue
+		}
+		switch child.name.Local {
+		case "include", "redefine":
+			return nil, fmt.Errorf("xs:%s isn't supported, the schema has to be a single document", child.name.Local)
+		case "import", "annotation", "notation":
+			continue
+		}
+		byName, ok := defs[child.name.Local]
+		if !ok {
+			return nil, fmt.Errorf("unexpected xs:%s in the schema", child.name.Local)
+		}
+		local, _ := child.attr("name")
+		name := xml.Name{Space: s.targetNamespace, Local: local}
+		if _, duplicate := byName[name]; duplicate {
+			return nil, fmt.Errorf("xs:%s %s is defined more than once", child.name.Local, local)
+		}
+		byName[name] = child
+	}
+
+	// Compile every global component, so that errors in the schema are found before any document is validated
+	for name := range s.elementDefs {
+		if _, err := s.globalElement(name); err != nil {
+			return nil, err
+		}
+	}
+	for name := range s.typeDefs {
+		if _, _, err := s.typeByName(name); err != nil {
+			return nil, err
+		}
+	}
+	for name := range s.attributeDefs {
+		if _, err := s.globalAttribute(name); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// resolve resolves a QName referring to a component. Schemas without a target namespace often declare the XML Schema
+// namespace as the default namespace and still refer to their own components unprefixed, so such names are
+// resolved to the components unless they name a builtin type.
+func (s *Schema) resolve(def *node, qname string) (xml.Name, error) {
+	name, err := def.resolve(qname)
+	if err != nil || name.Space != Namespace || s.targetNamespace != "" {
+		return name, err
+	}
+	if _, builtin := builtins[name.Local]; !builtin && name.Local != "anyType" {
+		name.Space = ""
+	}
+	return name, nil
+}
+
+// globalElement returns the global element declaration of a name.
+func (s *Schema) globalElement(name xml.Name) (*element, error) {
+	if e, ok := s.elements[name]; ok {
+		return e, nil
+	}
+	def, ok := s.elementDefs[name]
+	if !ok {
+		return nil, fmt.Errorf("element %s isn't declared", name.Local)
+	}
+
+	// The declaration is registered before its type is compiled, so that recursive types can refer to it
+	e := &element{name: name}
+	s.elements[name] = e
+	if err := s.compileElement(def, e); err != nil {
+		return nil, fmt.Errorf("element %s: %v", name.Local, err)
+	}
+
+	if head, ok := def.attr("substitutionGroup"); ok {
+		headName, err := s.resolve(def, head)
+		if err != nil {
+			return nil, err
+		}
+		headElement, err := s.globalElement(headName)
+		if err != nil {
+			return nil, fmt.Errorf("element %s: %v", name.Local, err)
+		}
+		headElement.substitutes = append(headElement.substitutes, e)
+		if e.simple == nil && e.complex == nil {
+			e.simple, e.complex = headElement.simple, headElement.complex
+		}
+	}
+	return e, nil
+}
+
+// localElement compiles a local element declaration, or returns the global one it refers to.
+func (s *Schema) localElement(def *node) (*element, error) {
+	if ref, ok := def.attr("ref"); ok {
+		name, err := s.resolve(def, ref)
+		if err != nil {
+			return nil, err
+		}
+		return s.globalElement(name)
+	}
+
+	local, _ := def.attr("name")
+	e := &element{name: xml.Name{Local: local}}
+	if s.qualified(def, s.elementFormQualified) {
+		e.name.Space = s.targetNamespace
+	}
+	if err := s.compileElement(def, e); err != nil {
+		return nil, fmt.Errorf("element %s: %v", local, err)
+	}
+	return e, nil
+}
+
+// qualified reports whether a local declaration is qualified by the form attribute or the schema default.
+func (s *Schema) qualified(def *node, formDefault bool) bool {
+	if form, ok := def.attr("form"); ok {
+		return form == "qualified"
+	}
+	return formDefault
+}
+
+// compileElement compiles the type and properties of an element declaration.
+func (s *Schema) compileElement(def *node, e *element) error {
+	nillable, _ := def.attr("nillable")
+	e.nillable = nillable == "true"
+	abstract, _ := def.attr("abstract")
+	e.abstract = abstract == "true"
+	if fixed, ok := def.attr("fixed"); ok {
+		e.fixed = &fixed
+	}
+	if def, ok := def.attr("default"); ok {
+		e.def = &def
+	}
+
+	var err error
+	if typeName, ok := def.attr("type"); ok {
+		name, err := s.resolve(def, typeName)
+		if err != nil {
+			return err
+		}
+		e.simple, e.complex, err = s.typeByName(name)
+		return err
+	}
+	for _, child := range schemaChildren(def) {
+		switch child.name.Local {
+		case "complexType":
+			e.complex, err = s.compileComplexType(child, xml.Name{})
+			return err
+		case "simpleType":
+			e.simple, err = s.compileSimpleType(child, xml.Name{})
+			return err
+		}
+	}
+
+	if _, ok := def.attr("substitutionGroup"); !ok {
+		e.complex = anyType
+	}
+	return nil
+}
+
+// typeByName returns the simple or complex type of a name.
+func (s *Schema) typeByName(name xml.Name) (*simpleType, *complexType, error) {
+	if name.Space == Namespace {
+		if name.Local == "anyType" {
+			return nil, anyType, nil
+		}
+		if t, ok := builtins[name.Local]; ok {
+			return t, nil, nil
+		}
+		return nil, nil, fmt.Errorf("unknown builtin type xs:%s", name.Local)
+	}
+	if t, ok := s.simpleTypes[name]; ok {
+		return t, nil, nil
+	}
+	if t, ok := s.complexTypes[name]; ok {
+		return nil, t, nil
+	}
+
+	def, ok := s.typeDefs[name]
+	if !ok {
+		return nil, nil, fmt.Errorf("type %s isn't defined", name.Local)
+	}
+	if def.name.Local == "simpleType" {
+		t, err := s.compileSimpleType(def, name)
+		return t, nil, err
+	}
+	t, err := s.compileComplexType(def, name)
+	return nil, t, err
+}
+
+// simpleTypeByName returns the simple type of a name, or an error if it's a complex type.
+func (s *Schema) simpleTypeByName(def *node, qname string) (*simpleType, error) {
+	name, err := s.resolve(def, qname)
+	if err != nil {
+		return nil, err
+	}
+	simple, _, err := s.typeByName(name)
+	if err != nil {
+		return nil, err
+	}
+	if simple == nil {
+		return nil, fmt.Errorf("%s isn't a simple type", qname)
+	}
+	return simple, nil
+}
+
+// compileSimpleType compiles a named or anonymous simple type definition.
+func (s *Schema) compileSimpleType(def *node, name xml.Name) (*simpleType, error) {
+	for _, child := range schemaChildren(def) {
+		var t *simpleType
+		var err error
+
+		switch child.name.Local {
+		case "restriction":
+			t, err = s.compileRestriction(child)
+		case "list":
+			t, err = s.compileList(child)
+		case "union":
+			t, err = s.compileUnion(child)
+		default:
+			continue
+		}
+		if err != nil {
+			if name.Local != "" {
+				return nil, fmt.Errorf("simple type %s: %v", name.Local, err)
+			}
+			return nil, err
+		}
+
+		t.name = name
+		if name.Local != "" {
+			s.simpleTypes[name] = t
+		}
+		return t, nil
+	}
+	return nil, fmt.Errorf("simple type %s has no restriction, list or union", name.Local)
+}
+
+// baseSimpleType returns the base type of a restriction, given by the base attribute or an anonymous simple type.
+func (s *Schema) baseSimpleType(def *node) (*simpleType, error) {
+	if base, ok := def.attr("base"); ok {
+		return s.simpleTypeByName(def, base)
+	}
+	for _, child := range schemaChildren(def) {
+		if child.name.Local == "simpleType" {
+			return s.compileSimpleType(child, xml.Name{})
+		}
+	}
+	return nil, fmt.Errorf("the restriction has no base type")
+}
+
+// compileRestriction restricts the base type of a restriction by its facets.
+func (s *Schema) compileRestriction(def *node) (*simpleType, error) {
+	base, err := s.baseSimpleType(def)
+	if err != nil {
+		return nil, err
+	}
+	t := *base
+	if err := s.restrict(&t, def); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// restrict applies the facets of a restriction to a copy of the base type.
+func (s *Schema) restrict(t *simpleType, def *node) error {
+	f := &t.facets
+	var patterns []pattern
+	var enumeration []enumValue
+
+	for _, child := range schemaChildren(def) {
+		value, _ := child.attr("value")
+		switch child.name.Local {
+		case "length", "minLength", "maxLength", "totalDigits", "fractionDigits":
+			n, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil || n < 0 {
+				return fmt.Errorf("the %s facet %q isn't a non-negative integer", child.name.Local, value)
+			}
+			*map[string]**int{
+				"length":         &f.length,
+				"minLength":      &f.minLength,
+				"maxLength":      &f.maxLength,
+				"totalDigits":    &f.totalDigits,
+				"fractionDigits": &f.fractionDigits,
+			}[child.name.Local] = &n
+
+		case "minInclusive", "maxInclusive", "minExclusive", "maxExclusive":
+			if t.variety != atomic {
+				return fmt.Errorf("the %s facet only applies to atomic types", child.name.Local)
+			}
+			parsed, err := parsePrimitive(t.primitive, normalize(value, collapse), child)
+			if err != nil {
+				return fmt.Errorf("the %s facet %q is invalid: %v", child.name.Local, value, err)
+			}
+			*map[string]**bound{
+				"minInclusive": &f.minInclusive,
+				"maxInclusive": &f.maxInclusive,
+				"minExclusive": &f.minExclusive,
+				"maxExclusive": &f.maxExclusive,
+			}[child.name.Local] = &bound{lexical: value, value: parsed}
+
+		case "pattern":
+			re, err := translatePattern(value)
+			if err != nil {
+				return fmt.Errorf("the pattern %q is invalid: %v", value, err)
+			}
+			patterns = append(patterns, pattern{source: value, re: re})
+
+		case "enumeration":
+			var parsed interface{} = normalize(value, collapse)
+			if t.variety == atomic {
+				var err error
+				lexical := normalize(value, t.facets.whiteSpace)
+				if parsed, err = parsePrimitive(t.primitive, lexical, child); err != nil {
+					return fmt.Errorf("the enumeration value %q is invalid: %v", value, err)
+				}
+			}
+			enumeration = append(enumeration, enumValue{lexical: value, value: parsed})
+
+		case "whiteSpace":
+			if value != preserve && value != replace && value != collapse {
+				return fmt.Errorf("the whiteSpace facet %q is invalid", value)
+			}
+			f.whiteSpace = value
+		}
+	}
+
+	if patterns != nil {
+		f.patterns = append(append([][]pattern{}, f.patterns...), patterns)
+	}
+	if enumeration != nil {
+		f.enumeration = enumeration
+	}
+	return nil
+}
+
+// compileList compiles a list of an item type.
+func (s *Schema) compileList(def *node) (*simpleType, error) {
+	t := &simpleType{variety: list, facets: facets{whiteSpace: collapse}}
+
+	var err error
+	if itemType, ok := def.attr("itemType"); ok {
+		t.item, err = s.simpleTypeByName(def, itemType)
+	} else {
+		err = fmt.Errorf("the list has no item type")
+		for _, child := range schemaChildren(def) {
+			if child.name.Local == "simpleType" {
+				t.item, err = s.compileSimpleType(child, xml.Name{})
+			}
+		}
+	}
+	return t, err
+}
+
+// compileUnion compiles a union of member types.
+func (s *Schema) compileUnion(def *node) (*simpleType, error) {
+	t := &simpleType{variety: union, facets: facets{whiteSpace: collapse}}
+
+	memberTypes, _ := def.attr("memberTypes")
+	for _, memberType := range strings.Fields(memberTypes) {
+		member, err := s.simpleTypeByName(def, memberType)
+		if err != nil {
+			return nil, err
+		}
+		t.members = append(t.members, member)
+	}
+	for _, child := range schemaChildren(def) {
+		if child.name.Local == "simpleType" {
+			member, err := s.compileSimpleType(child, xml.Name{})
+			if err != nil {
+				return nil, err
+			}
+			t.members = append(t.members, member)
+		}
+	}
+
+	if len(t.members) == 0 {
+		return nil, fmt.Errorf("the union has no member types")
+	}
+	return t, nil
+}
+
+// compileComplexType compiles a named or anonymous complex type definition.
+func (s *Schema) compileComplexType(def *node, name xml.Name) (*complexType, error) {
+	t := &complexType{name: name, attributes: map[xml.Name]*attributeUse{}}
+	if name.Local != "" {
+		// Registered before its content is compiled, so that the content can refer to the type
+		s.complexTypes[name] = t
+	}
+	mixed, _ := def.attr("mixed")
+	t.mixed = mixed == "true"
+	abstract, _ := def.attr("abstract")
+	t.abstract = abstract == "true"
+
+	var err error
+	for _, child := range schemaChildren(def) {
+		switch child.name.Local {
+		case "simpleContent":
+			err = s.compileSimpleContent(child, t)
+		case "complexContent":
+			err = s.compileComplexContent(child, t)
+		case "sequence", "choice", "all", "group":
+			t.content, err = s.compileParticle(child)
+		case "attribute", "attributeGroup", "anyAttribute":
+			err = s.compileAttributes(child, t)
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	if err != nil {
+		if name.Local != "" {
+			return nil, fmt.Errorf("complex type %s: %v", name.Local, err)
+		}
+		return nil, err
+	}
+	return t, nil
+}
+
+// derivation returns the extension or restriction of a simple or complex content, and its base type.
+func (s *Schema) derivation(def *node) (*node, *simpleType, *complexType, error) {
+	for _, child := range schemaChildren(def) {
+		if child.name.Local != "extension" && child.name.Local != "restriction" {
+			continue
+		}
+		base, ok := child.attr("base")
+		if !ok {
+			return nil, nil, nil, fmt.Errorf("the %s has no base type", child.name.Local)
+		}
+		name, err := s.resolve(child, base)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		simple, complex, err := s.typeByName(name)
+		return child, simple, complex, err
+	}
+	return nil, nil, nil, fmt.Errorf("%s has no extension or restriction", def.name.Local)
+}
+
+// compileSimpleContent compiles a simple content, which extends or restricts a simple type or a complex type with
+// simple content.
+func (s *Schema) compileSimpleContent(def *node, t *complexType) error {
+	derivation, baseSimple, baseComplex, err := s.derivation(def)
+	if err != nil {
+		return err
+	}
+
+	if baseComplex != nil {
+		if baseComplex.simple == nil && !baseComplex.anyType {
+			return fmt.Errorf("the base type %s doesn't have simple content", baseComplex.name.Local)
+		}
+		baseSimple = baseComplex.simple
+		if baseSimple == nil {
+			baseSimple = builtins["anySimpleType"]
+		}
+		inheritAttributes(t, baseComplex)
+	}
+
+	if derivation.name.Local == "restriction" {
+		restricted := *baseSimple
+		if err := s.restrict(&restricted, derivation); err != nil {
+			return err
+		}
+		restricted.name = xml.Name{}
+		baseSimple = &restricted
+	}
+	t.simple = baseSimple
+
+	return s.compileAttributeChildren(derivation, t)
+}
+
+// compileComplexContent compiles a complex content, which extends the content model of its base type with a
+// sequence, or restricts it by declaring it anew.
+func (s *Schema) compileComplexContent(def *node, t *complexType) error {
+	derivation, _, base, err := s.derivation(def)
+	if err != nil {
+		return err
+	}
+	if base == nil {
+		return fmt.Errorf("the base type of a complex content has to be a complex type")
+	}
+	if mixed, ok := def.attr("mixed"); ok {
+		t.mixed = mixed == "true"
+	}
+
+	var content *particle
+	for _, child := range schemaChildren(derivation) {
+		switch child.name.Local {
+		case "sequence", "choice", "all", "group":
+			if content, err = s.compileParticle(child); err != nil {
+				return err
+			}
+		}
+	}
+
+	switch {
+	case derivation.name.Local == "restriction":
+		t.content = content
+	case base.anyType:
+		t.content = content
+	case base.content == nil:
+		t.content = content
+	case content == nil:
+		t.content = base.content
+	default:
+		t.content = &particle{kind: sequenceParticle, min: 1, max: 1, children: []*particle{base.content, content}}
+	}
+
+	if !base.anyType {
+		inheritAttributes(t, base)
+	}
+	return s.compileAttributeChildren(derivation, t)
+}
+
+// inheritAttributes copies the attributes of a base type.
+func inheritAttributes(t, base *complexType) {
+	for name, use := range base.attributes {
+		t.attributes[name] = use
+	}
+	t.anyAttribute = base.anyAttribute
+}
+
+// compileAttributeChildren compiles the attributes, attribute groups and attribute wildcard among the children of a
+// definition.
+func (s *Schema) compileAttributeChildren(def *node, t *complexType) error {
+	for _, child := range schemaChildren(def) {
+		switch child.name.Local {
+		case "attribute", "attributeGroup", "anyAttribute":
+			if err := s.compileAttributes(child, t); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// compileAttributes adds an attribute, the attributes of an attribute group or an attribute wildcard to a type.
+func (s *Schema) compileAttributes(def *node, t *complexType) error {
+	switch def.name.Local {
+	case "anyAttribute":
+		t.anyAttribute = s.compileWildcard(def)
+		return nil
+
+	case "attributeGroup":
+		ref, ok := def.attr("ref")
+		if !ok {
+			return fmt.Errorf("the attribute group has no ref")
+		}
+		name, err := s.resolve(def, ref)
+		if err != nil {
+			return err
+		}
+		group, ok := s.attributeGroupDefs[name]
+		if !ok {
+			return fmt.Errorf("attribute group %s isn't defined", name.Local)
+		}
+		return s.compileAttributeChildren(group, t)
+	}
+
+	use, prohibited, err := s.localAttribute(def)
+	if err != nil {
+		return err
+	}
+	if prohibited {
+		delete(t.attributes, use.name)
+	} else {
+		t.attributes[use.name] = use
+	}
+	return nil
+}
+
+// globalAttribute returns the global attribute declaration of a name.
+func (s *Schema) globalAttribute(name xml.Name) (*attributeUse, error) {
+	if a, ok := s.attributes[name]; ok {
+		return a, nil
+	}
+	def, ok := s.attributeDefs[name]
+	if !ok {
+		return nil, fmt.Errorf("attribute %s isn't declared", name.Local)
+	}
+
+	a := &attributeUse{name: name}
+	if err := s.compileAttributeType(def, a); err != nil {
+		return nil, fmt.Errorf("attribute %s: %v", name.Local, err)
+	}
+	s.attributes[name] = a
+	return a, nil
+}
+
+// localAttribute compiles the use of an attribute in a complex type, and reports whether its use is prohibited.
+func (s *Schema) localAttribute(def *node) (*attributeUse, bool, error) {
+	use, _ := def.attr("use")
+	a := &attributeUse{required: use == "required"}
+
+	if ref, ok := def.attr("ref"); ok {
+		name, err := s.resolve(def, ref)
+		if err != nil {
+			return nil, false, err
+		}
+		global, err := s.globalAttribute(name)
+		if err != nil {
+			return nil, false, err
+		}
+		a.name, a.typ, a.fixed, a.def = global.name, global.typ, global.fixed, global.def
+	} else {
+		local, _ := def.attr("name")
+		a.name = xml.Name{Local: local}
+		if s.qualified(def, s.attributeFormQualified) {
+			a.name.Space = s.targetNamespace
+		}
+	}
+
+	if err := s.compileAttributeType(def, a); err != nil {
+		return nil, false, fmt.Errorf("attribute %s: %v", a.name.Local, err)
+	}
+	return a, use == "prohibited", nil
+}
+
+// compileAttributeType compiles the type and the value constraints of an attribute declaration.
+func (s *Schema) compileAttributeType(def *node, a *attributeUse) error {
+	if fixed, ok := def.attr("fixed"); ok {
+		a.fixed = &fixed
+	}
+	if def, ok := def.attr("default"); ok {
+		a.def = &def
+	}
+
+	var err error
+	if typeName, ok := def.attr("type"); ok {
+		a.typ, err = s.simpleTypeByName(def, typeName)
+		return err
+	}
+	for _, child := range schemaChildren(def) {
+		if child.name.Local == "simpleType" {
+			a.typ, err = s.compileSimpleType(child, xml.Name{})
+			return err
+		}
+	}
+	if a.typ == nil {
+		a.typ = builtins["anySimpleType"]
+	}
+	return nil
+}
+
+// compileParticle compiles an element, a model group, a model group reference or a wildcard with its occurrence
+// bounds.
+func (s *Schema) compileParticle(def *node) (*particle, error) {
+	p := &particle{min: 1, max: 1}
+	if min, ok := def.attr("minOccurs"); ok {
+		n, err := strconv.Atoi(strings.TrimSpace(min))
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("minOccurs %q isn't a non-negative integer", min)
+		}
+		p.min = n
+	}
+	if max, ok := def.attr("maxOccurs"); ok {
+		if strings.TrimSpace(max) == "unbounded" {
+			p.max = -1
+		} else {
+			n, err := strconv.Atoi(strings.TrimSpace(max))
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("maxOccurs %q isn't a non-negative integer or unbounded", max)
+			}
+			p.max = n
+		}
+	}
+	if p.max >= 0 && p.min > p.max {
+		return nil, fmt.Errorf("minOccurs %d is greater than maxOccurs %d", p.min, p.max)
+	}
+
+	var err error
+	switch def.name.Local {
+	case "element":
+		p.kind = elementParticle
+		p.element, err = s.localElement(def)
+
+	case "any":
+		p.kind = anyParticle
+		p.wildcard = s.compileWildcard(def)
+
+	case "sequence", "choice", "all":
+		p.kind = map[string]particleKind{"sequence": sequenceParticle, "choice": choiceParticle, "all": allParticle}[def.name.Local]
+		for _, child := range schemaChildren(def) {
+			switch child.name.Local {
+			case "element", "any", "sequence", "choice", "group":
+				term, err := s.compileParticle(child)
+				if err != nil {
+					return nil, err
+				}
+				p.children = append(p.children, term)
+			}
+		}
+
+	case "group":
+		ref, ok := def.attr("ref")
+		if !ok {
+			return nil, fmt.Errorf("the group has no ref")
+		}
+		name, err := s.resolve(def, ref)
+		if err != nil {
+			return nil, err
+		}
+		group, ok := s.groupDefs[name]
+		if !ok {
+			return nil, fmt.Errorf("group %s isn't defined", name.Local)
+		}
+		for _, child := range schemaChildren(group) {
+			switch child.name.Local {
+			case "sequence", "choice", "all":
+				term, err := s.compileParticle(child)
+				if err != nil {
+					return nil, fmt.Errorf("group %s: %v", name.Local, err)
+				}
+				term.min, term.max = p.min, p.max
+				return term, nil
+			}
+		}
+		return nil, fmt.Errorf("group %s has no model group", name.Local)
+	}
+	return p, err
+}
+
+// compileWildcard compiles the namespace constraint and the processContents of a wildcard.
+func (s *Schema) compileWildcard(def *node) *wildcard {
+	w := &wildcard{process: "strict"}
+	if process, ok := def.attr("processContents"); ok {
+		w.process = process
+	}
+
+	namespace, ok := def.attr("namespace")
+	switch strings.TrimSpace(namespace) {
+	case "##any":
+		w.any = true
+	case "##other":
+		w.other = s.targetNamespace
+	default:
+		if !ok {
+			w.any = true
+			break
+		}
+		w.namespaces = map[string]bool{}
+		for _, space := range strings.Fields(namespace) {
+			switch space {
+			case "##targetNamespace":
+				w.namespaces[s.targetNamespace] = true
+			case "##local":
+				w.namespaces[""] = true
+			default:
+				w.namespaces[space] = true
+			}
+		}
+	}
+	return w
+}
+
+// schemaChildren returns the XML Schema children of a definition, leaving out annotations.
+func schemaChildren(def *node) []*node {
+	children := make([]*node, 0, len(def.children))
+	for _, child := range def.children {
+		if child.name.Space == Namespace && child.name.Local != "annotation" {
+			children = append(children, child)
+		}
+	}
+	return children
+}
@@ -0,0 +1,704 @@
+// Copyright 2020 Syntio Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xsd
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Namespace is the namespace of XML Schema.
+const Namespace = "http://www.w3.org/2001/XMLSchema"
+
+// instanceNamespace is the namespace of the XML Schema attributes used in instance documents, e.g. xsi:nil.
+const instanceNamespace = "http://www.w3.org/2001/XMLSchema-instance"
+
+type variety int
+
+const (
+	atomic variety = iota
+	list
+	union
+)
+
+// whitespace handling of simple type values.
+const (
+	preserve = "preserve"
+	replace  = "replace"
+	collapse = "collapse"
+)
+
+// simpleType is a builtin simple type, or a restriction, list or union of simple types. The facets of a restriction
+// hold the facets of its base type, merged with its own.
+type simpleType struct {
+	name    xml.Name
+	variety variety
+	// primitive is the primitive builtin type an atomic type is derived from, e.g. "decimal" for xs:int.
+	primitive string
+	item      *simpleType
+	members   []*simpleType
+	facets    facets
+}
+
+// facets are the constraining facets of a simple type.
+type facets struct {
+	whiteSpace string
+	length     *int
+	minLength  *int
+	maxLength  *int
+	// patterns holds the patterns of every derivation step. A value has to match one of the patterns of each step.
+	patterns       [][]pattern
+	enumeration    []enumValue
+	minInclusive   *bound
+	maxInclusive   *bound
+	minExclusive   *bound
+	maxExclusive   *bound
+	totalDigits    *int
+	fractionDigits *int
+}
+
+type pattern struct {
+	source string
+	re     *regexp.Regexp
+	// builtin is the builtin type whose lexical space the pattern defines, if any.
+	builtin string
+}
+
+type enumValue struct {
+	lexical string
+	value   interface{}
+}
+
+type bound struct {
+	lexical string
+	value   interface{}
+}
+
+// describe returns the name of the type for messages.
+func (t *simpleType) describe() string {
+	switch {
+	case t.name.Local == "":
+		return "the anonymous simple type"
+	case t.name.Space == Namespace:
+		return "xs:" + t.name.Local
+	default:
+		return t.name.Local
+	}
+}
+
+// normalize normalizes the whitespace of a value by the whiteSpace facet.
+func normalize(value, whiteSpace string) string {
+	switch whiteSpace {
+	case replace:
+		return strings.Map(func(r rune) rune {
+			if r == '\t' || r == '\n' || r == '\r' {
+				return ' '
+			}
+			return r
+		}, value)
+	case collapse:
+		return strings.Join(strings.Fields(value), " ")
+	default:
+		return value
+	}
+}
+
+// validate validates a value of the simple type. The scope resolves the prefixes of QName values.
+func (t *simpleType) validate(value string, scope *node) error {
+	switch t.variety {
+	case list:
+		value = normalize(value, collapse)
+		items := strings.Fields(value)
+		if err := t.facets.checkLength(len(items), "items"); err != nil {
+			return err
+		}
+		for _, item := range items {
+			if err := t.item.validate(item, scope); err != nil {
+				return err
+			}
+		}
+		if err := t.facets.checkPatterns(value); err != nil {
+			return err
+		}
+		return t.facets.checkEnumeration(value, value, t)
+
+	case union:
+		matched := false
+		for _, member := range t.members {
+			if member.validate(value, scope) == nil {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return fmt.Errorf("value %q matches none of the member types of %s", value, t.describe())
+		}
+		value = normalize(value, collapse)
+		if err := t.facets.checkPatterns(value); err != nil {
+			return err
+		}
+		return t.facets.checkEnumeration(value, value, t)
+	}
+
+	value = normalize(value, t.facets.whiteSpace)
+	parsed, err := parsePrimitive(t.primitive, value, scope)
+	if err != nil {
+		return fmt.Errorf("value %q is not a valid %s: %v", value, t.describe(), err)
+	}
+
+	if length, ok := primitiveLength(t.primitive, value, parsed); ok {
+		if err := t.facets.checkLength(length, lengthUnit(t.primitive)); err != nil {
+			return fmt.Errorf("value %q: %v", value, err)
+		}
+	}
+	if err := t.facets.checkPatterns(value); err != nil {
+		return err
+	}
+	if err := t.facets.checkEnumeration(value, parsed, t); err != nil {
+		return err
+	}
+	if err := t.facets.checkBounds(value, parsed); err != nil {
+		return err
+	}
+	return t.facets.checkDigits(value, parsed)
+}
+
+func (f *facets) checkLength(length int, unit string) error {
+	switch {
+	case f.length != nil && length != *f.length:
+		return fmt.Errorf("the length is %d %s instead of %d", length, unit, *f.length)
+	case f.minLength != nil && length < *f.minLength:
+		return fmt.Errorf("the length is %d %s, less than the minimum of %d", length, unit, *f.minLength)
+	case f.maxLength != nil && length > *f.maxLength:
+		return fmt.Errorf("the length is %d %s, more than the maximum of %d", length, unit, *f.maxLength)
+	}
+	return nil
+}
+
+func (f *facets) checkPatterns(value string) error {
+	for _, step := range f.patterns {
+		matched := false
+		for _, p := range step {
+			if p.re.MatchString(value) {
+				matched = true
+				break
+			}
+		}
+		if !matched && step[0].builtin != "" {
+			return fmt.Errorf("value %q is not a valid xs:%s", value, step[0].builtin)
+		}
+		if !matched {
+			return fmt.Errorf("value %q doesn't match the pattern %q", value, step[0].source)
+		}
+	}
+	return nil
+}
+
+func (f *facets) checkEnumeration(lexical string, value interface{}, t *simpleType) error {
+	if f.enumeration == nil {
+		return nil
+	}
+	allowed := make([]string, 0, len(f.enumeration))
+	for _, enum := range f.enumeration {
+		if equal(enum.value, value) {
+			return nil
+		}
+		allowed = append(allowed, enum.lexical)
+	}
+	return fmt.Errorf("value %q is not one of the values of %s: %s", lexical, t.describe(), strings.Join(allowed, ", "))
+}
+
+func (f *facets) checkBounds(lexical string, value interface{}) error {
+	if f.minInclusive != nil {
+		if c, ok := compare(value, f.minInclusive.value); ok && c < 0 {
+			return fmt.Errorf("value %q is less than the minimum %s", lexical, f.minInclusive.lexical)
+		}
+	}
+	if f.minExclusive != nil {
+		if c, ok := compare(value, f.minExclusive.value); ok && c <= 0 {
+			return fmt.Errorf("value %q isn't greater than %s", lexical, f.minExclusive.lexical)
+		}
+	}
+	if f.maxInclusive != nil {
+		if c, ok := compare(value, f.maxInclusive.value); ok && c > 0 {
+			return fmt.Errorf("value %q is greater than the maximum %s", lexical, f.maxInclusive.lexical)
+		}
+	}
+	if f.maxExclusive != nil {
+		if c, ok := compare(value, f.maxExclusive.value); ok && c >= 0 {
+			return fmt.Errorf("value %q isn't less than %s", lexical, f.maxExclusive.lexical)
+		}
+	}
+	return nil
+}
+
+func (f *facets) checkDigits(lexical string, value interface{}) error {
+	if _, ok := value.(*big.Rat); !ok {
+		return nil
+	}
+	total, fraction := decimalDigits(lexical)
+	if f.totalDigits != nil && total > *f.totalDigits {
+		return fmt.Errorf("value %q has %d digits, more than %d", lexical, total, *f.totalDigits)
+	}
+	if f.fractionDigits != nil && fraction > *f.fractionDigits {
+		return fmt.Errorf("value %q has %d fraction digits, more than %d", lexical, fraction, *f.fractionDigits)
+	}
+	return nil
+}
+
+// decimalDigits returns the number of significant digits of a decimal and the number of its fraction digits.
+func decimalDigits(lexical string) (total, fraction int) {
+	lexical = strings.TrimLeft(lexical, "+-")
+	integer, fractional := lexical, ""
+	if i := strings.IndexByte(lexical, '.'); i >= 0 {
+		integer, fractional = lexical[:i], lexical[i+1:]
+	}
+	integer = strings.TrimLeft(integer, "0")
+	fractional = strings.TrimRight(fractional, "0")
+	return len(integer) + len(fractional), len(fractional)
+}
+
+// primitiveLength returns the length of a value in the units of the length facets, and false for types without a
+// length.
+func primitiveLength(primitive, lexical string, value interface{}) (int, bool) {
+	switch primitive {
+	case "string", "anyURI", "anySimpleType":
+		return utf8.RuneCountInString(lexical), true
+	case "hexBinary", "base64Binary":
+		return len(value.([]byte)), true
+	default:
+		return 0, false
+	}
+}
+
+func lengthUnit(primitive string) string {
+	if primitive == "hexBinary" || primitive == "base64Binary" {
+		return "bytes"
+	}
+	return "characters"
+}
+
+// equal reports whether two parsed values are the same value.
+func equal(a, b interface{}) bool {
+	if c, ok := compare(a, b); ok {
+		return c == 0
+	}
+	switch a := a.(type) {
+	case []byte:
+		b, ok := b.([]byte)
+		return ok && string(a) == string(b)
+	case xml.Name:
+		b, ok := b.(xml.Name)
+		return ok && a == b
+	}
+	return a == b
+}
+
+// compare compares two parsed values of an ordered type, and returns false if they can't be compared.
+func compare(a, b interface{}) (int, bool) {
+	switch a := a.(type) {
+	case *big.Rat:
+		if b, ok := b.(*big.Rat); ok {
+			return a.Cmp(b), true
+		}
+	case float64:
+		if b, ok := b.(float64); ok && !math.IsNaN(a) && !math.IsNaN(b) {
+			switch {
+			case a < b:
+				return -1, true
+			case a > b:
+				return 1, true
+			}
+			return 0, true
+		}
+	case time.Time:
+		if b, ok := b.(time.Time); ok {
+			switch {
+			case a.Before(b):
+				return -1, true
+			case a.After(b):
+				return 1, true
+			}
+			return 0, true
+		}
+	}
+	return 0, false
+}
+
+var (
+	decimalPattern  = regexp.MustCompile(`^[+-]?(\d+(\.\d*)?|\.\d+)$`)
+	floatPattern    = regexp.MustCompile(`^[+-]?(\d+(\.\d*)?|\.\d+)([eE][+-]?\d+)?$`)
+	durationPattern = regexp.MustCompile(`^-?P(\d+Y)?(\d+M)?(\d+D)?(T(\d+H)?(\d+M)?(\d+(\.\d+)?S)?)?$`)
+	hexPattern      = regexp.MustCompile(`^([0-9a-fA-F]{2})*$`)
+	qnamePattern    = regexp.MustCompile(`^(` + ncName + `:)?` + ncName + `$`)
+
+	timezone         = `(?P<tz>Z|[+-]\d{2}:\d{2})?`
+	dateTimePatterns = map[string]*regexp.Regexp{
+		"dateTime": regexp.MustCompile(`^(?P<year>-?\d{4,})-(?P<month>\d{2})-(?P<day>\d{2})` +
+			`T(?P<hour>\d{2}):(?P<minute>\d{2}):(?P<second>\d{2}(\.\d+)?)` + timezone + `$`),
+		"date":       regexp.MustCompile(`^(?P<year>-?\d{4,})-(?P<month>\d{2})-(?P<day>\d{2})` + timezone + `$`),
+		"time":       regexp.MustCompile(`^(?P<hour>\d{2}):(?P<minute>\d{2}):(?P<second>\d{2}(\.\d+)?)` + timezone + `$`),
+		"gYearMonth": regexp.MustCompile(`^(?P<year>-?\d{4,})-(?P<month>\d{2})` + timezone + `$`),
+		"gYear":      regexp.MustCompile(`^(?P<year>-?\d{4,})` + timezone + `$`),
+		"gMonthDay":  regexp.MustCompile(`^--(?P<month>\d{2})-(?P<day>\d{2})` + timezone + `$`),
+		"gDay":       regexp.MustCompile(`^---(?P<day>\d{2})` + timezone + `$`),
+		"gMonth":     regexp.MustCompile(`^--(?P<month>\d{2})` + timezone + `$`),
+	}
+)
+
+// Character classes of XML names.
+const (
+	nameStart = `\p{L}_`
+	nameChar  = `\p{L}\p{Nd}\p{Mn}\p{Mc}._\-\x{B7}`
+	ncName    = `[` + nameStart + `][` + nameChar + `]*`
+)
+
+// parsePrimitive parses the lexical form of a value of a primitive type. The parsed values of ordered types are
+// comparable by compare.
+func parsePrimitive(primitive, lexical string, scope *node) (interface{}, error) {
+	switch primitive {
+	case "boolean":
+		switch lexical {
+		case "true", "1":
+			return true, nil
+		case "false", "0":
+			return false, nil
+		}
+		return nil, fmt.Errorf("a boolean is true, false, 1 or 0")
+
+	case "decimal":
+		if !decimalPattern.MatchString(lexical) {
+			return nil, fmt.Errorf("not a decimal number")
+		}
+		number := strings.TrimPrefix(lexical, "+")
+		if strings.HasSuffix(number, ".") {
+			number += "0"
+		}
+		value, ok := new(big.Rat).SetString(number)
+		if !ok {
+			return nil, fmt.Errorf("not a decimal number")
+		}
+		return value, nil
+
+	case "float", "double":
+		switch lexical {
+		case "INF", "+INF":
+			return math.Inf(1), nil
+		case "-INF":
+			return math.Inf(-1), nil
+		case "NaN":
+			return math.NaN(), nil
+		}
+		if !floatPattern.MatchString(lexical) {
+			return nil, fmt.Errorf("not a floating-point number")
+		}
+		bitSize := 64
+		if primitive == "float" {
+			bitSize = 32
+		}
+		// Values out of range round to infinity or zero, which XML Schema allows.
+		value, err := strconv.ParseFloat(lexical, bitSize)
+		if err != nil && !isRangeError(err) {
+			return nil, fmt.Errorf("not a floating-point number")
+		}
+		return value, nil
+
+	case "duration":
+		if !durationPattern.MatchString(lexical) || strings.HasSuffix(lexical, "P") || strings.HasSuffix(lexical, "T") {
+			return nil, fmt.Errorf("a duration looks like P1Y2M3DT4H5M6S")
+		}
+		return lexical, nil
+
+	case "dateTime", "date", "time", "gYearMonth", "gYear", "gMonthDay", "gDay", "gMonth":
+		return parseDateTime(primitive, lexical)
+
+	case "hexBinary":
+		if !hexPattern.MatchString(lexical) {
+			return nil, fmt.Errorf("not an even number of hexadecimal digits")
+		}
+		value := make([]byte, len(lexical)/2)
+		for i := range value {
+			b, _ := strconv.ParseUint(lexical[2*i:2*i+2], 16, 8)
+			value[i] = byte(b)
+		}
+		return value, nil
+
+	case "base64Binary":
+		value, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(lexical), ""))
+		if err != nil {
+			return nil, fmt.Errorf("not base64 encoded")
+		}
+		return value, nil
+
+	case "QName", "NOTATION":
+		if !qnamePattern.MatchString(lexical) {
+			return nil, fmt.Errorf("not a qualified name")
+		}
+		if scope == nil {
+			return lexical, nil
+		}
+		return scope.resolve(lexical)
+	}
+
+	// string, anyURI and anySimpleType accept any characters.
+	return lexical, nil
+}
+
+func isRangeError(err error) bool {
+	numErr, ok := err.(*strconv.NumError)
+	return ok && numErr.Err == strconv.ErrRange
+}
+
+// parseDateTime parses the date and time types into the instant they start at. Values without a timezone are
+// taken as UTC.
+func parseDateTime(primitive, lexical string) (interface{}, error) {
+	re := dateTimePatterns[primitive]
+	match := re.FindStringSubmatch(lexical)
+	if match == nil {
+		return nil, fmt.Errorf("not in the lexical form of xs:%s", primitive)
+	}
+
+	year, month, day, hour, minute := 2000, 1, 1, 0, 0
+	second := 0.0
+	offset := 0
+	for i, group := range re.SubexpNames() {
+		if group == "" || match[i] == "" {
+			continue
+		}
+		switch group {
+		case "year":
+			year, _ = strconv.Atoi(match[i])
+		case "month":
+			month, _ = strconv.Atoi(match[i])
+		case "day":
+			day, _ = strconv.Atoi(match[i])
+		case "hour":
+			hour, _ = strconv.Atoi(match[i])
+		case "minute":
+			minute, _ = strconv.Atoi(match[i])
+		case "second":
+			second, _ = strconv.ParseFloat(match[i], 64)
+		case "tz":
+			if match[i] != "Z" {
+				hours, _ := strconv.Atoi(match[i][1:3])
+				minutes, _ := strconv.Atoi(match[i][4:6])
+				if hours > 14 || minutes > 59 {
+					return nil, fmt.Errorf("the timezone %s is out of range", match[i])
+				}
+				offset = hours*60 + minutes
+				if match[i][0] == '-' {
+					offset = -offset
+				}
+			}
+		}
+	}
+
+	switch {
+	case month < 1 || month > 12:
+		return nil, fmt.Errorf("the month %d is out of range", month)
+	case day < 1 || day > daysIn(year, month):
+		return nil, fmt.Errorf("the day %d is out of range", day)
+	case hour > 24 || minute > 59 || second >= 60:
+		return nil, fmt.Errorf("the time is out of range")
+	case hour == 24 && (minute != 0 || second != 0):
+		return nil, fmt.Errorf("the hour 24 is only allowed at 24:00:00")
+	}
+
+	whole, fraction := math.Modf(second)
+	instant := time.Date(year, time.Month(month), day, hour, minute, int(whole), int(fraction*1e9), time.UTC)
+	return instant.Add(-time.Duration(offset) * time.Minute), nil
+}
+
+// daysIn returns the number of days of a month.
+func daysIn(year, month int) int {
+	return time.Date(year, time.Month(month)+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// translatePattern translates an XML Schema regular expression into an anchored Go regular expression. Character
+// class subtraction and Unicode block escapes aren't supported.
+func translatePattern(source string) (*regexp.Regexp, error) {
+	var out strings.Builder
+	inClass := false
+
+	runes := []rune(source)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '\\' && i+1 < len(runes):
+			i++
+			escape, err := translateEscape(runes[i], inClass)
+			if err != nil {
+				return nil, err
+			}
+			if (runes[i] == 'p' || runes[i] == 'P') && i+1 < len(runes) && runes[i+1] == '{' {
+				end := i + 1
+				for end < len(runes) && runes[end] != '}' {
+					end++
+				}
+				property := string(runes[i+2 : end])
+				if strings.HasPrefix(property, "Is") {
+					return nil, fmt.Errorf("unsupported Unicode block escape \\%c{%s}", runes[i], property)
+				}
+				escape = `\` + string(runes[i]) + "{" + property + "}"
+				i = end
+			}
+			out.WriteString(escape)
+		case r == '[' && inClass:
+			return nil, fmt.Errorf("unsupported character class subtraction in pattern %q", source)
+		case r == '[':
+			inClass = true
+			out.WriteRune(r)
+			if i+1 < len(runes) && runes[i+1] == '^' {
+				out.WriteRune('^')
+				i++
+			}
+		case r == ']' && inClass:
+			inClass = false
+			out.WriteRune(r)
+		case r == '-' && inClass && i+1 < len(runes) && runes[i+1] == '[':
+			return nil, fmt.Errorf("unsupported character class subtraction in pattern %q", source)
+		case (r == '^' || r == '$') && !inClass:
+			out.WriteString(`\` + string(r))
+		case r == '.' && !inClass:
+			out.WriteString(`[^\n\r]`)
+		default:
+			out.WriteRune(r)
+		}
+	}
+
+	return regexp.Compile(`^(?:` + out.String() + `)$`)
+}
+
+// translateEscape translates the character or class escape following a backslash.
+func translateEscape(r rune, inClass bool) (string, error) {
+	classes := map[rune][2]string{
+		'd': {`\p{Nd}`, `\p{Nd}`},
+		'D': {`\P{Nd}`, `\P{Nd}`},
+		's': {`[ \t\n\r]`, ` \t\n\r`},
+		'i': {`[` + nameStart + `:]`, nameStart + `:`},
+		'c': {`[` + nameChar + `:]`, nameChar + `:`},
+		'w': {`[^\p{P}\p{Z}\p{C}]`, ""},
+		'W': {`[\p{P}\p{Z}\p{C}]`, `\p{P}\p{Z}\p{C}`},
+		'S': {`[^ \t\n\r]`, ""},
+		'I': {`[^` + nameStart + `:]`, ""},
+		'C': {`[^` + nameChar + `:]`, ""},
+	}
+	if class, ok := classes[r]; ok {
+		if !inClass {
+			return class[0], nil
+		}
+		if class[1] == "" {
+			return "", fmt.Errorf("unsupported class escape \\%c in a character class", r)
+		}
+		return class[1], nil
+	}
+
+	switch r {
+	case 'n':
+		return `\n`, nil
+	case 'r':
+		return `\r`, nil
+	case 't':
+		return `\t`, nil
+	case 'p', 'P':
+		return "", nil
+	}
+	return regexp.QuoteMeta(string(r)), nil
+}
+
+// builtins holds the builtin simple types by their local names.
+var builtins = map[string]*simpleType{}
+
+func init() {
+	builtins["anySimpleType"] = &simpleType{primitive: "anySimpleType", facets: facets{whiteSpace: preserve}}
+	for _, primitive := range []string{"string", "boolean", "decimal", "float", "double", "duration", "dateTime",
+		"time", "date", "gYearMonth", "gYear", "gMonthDay", "gDay", "gMonth", "hexBinary", "base64Binary", "anyURI",
+		"QName", "NOTATION"} {
+		whiteSpace := collapse
+		if primitive == "string" {
+			whiteSpace = preserve
+		}
+		builtins[primitive] = &simpleType{primitive: primitive, facets: facets{whiteSpace: whiteSpace}}
+	}
+
+	derive := func(name, base string, restrict func(f *facets)) {
+		t := *builtins[base]
+		restrict(&t.facets)
+		builtins[name] = &t
+	}
+	withPattern := func(builtin, source string) func(f *facets) {
+		return func(f *facets) {
+			f.whiteSpace = collapse
+			p := pattern{source: source, re: regexp.MustCompile(`^(?:` + source + `)$`), builtin: builtin}
+			f.patterns = append(append([][]pattern{}, f.patterns...), []pattern{p})
+		}
+	}
+	withRange := func(min, max string) func(f *facets) {
+		return func(f *facets) {
+			if min != "" {
+				value, _ := new(big.Rat).SetString(min)
+				f.minInclusive = &bound{lexical: min, value: value}
+			}
+			if max != "" {
+				value, _ := new(big.Rat).SetString(max)
+				f.maxInclusive = &bound{lexical: max, value: value}
+			}
+		}
+	}
+
+	derive("normalizedString", "string", func(f *facets) { f.whiteSpace = replace })
+	derive("token", "normalizedString", func(f *facets) { f.whiteSpace = collapse })
+	derive("language", "token", withPattern("language", `[a-zA-Z]{1,8}(-[a-zA-Z0-9]{1,8})*`))
+	derive("NMTOKEN", "token", withPattern("NMTOKEN", `[`+nameChar+`:]+`))
+	derive("Name", "token", withPattern("Name", `[`+nameStart+`:][`+nameChar+`:]*`))
+	derive("NCName", "token", withPattern("NCName", ncName))
+	derive("ID", "NCName", func(f *facets) {})
+	derive("IDREF", "NCName", func(f *facets) {})
+	derive("ENTITY", "NCName", func(f *facets) {})
+	one := 1
+	for name, item := range map[string]string{"NMTOKENS": "NMTOKEN", "IDREFS": "IDREF", "ENTITIES": "ENTITY"} {
+		builtins[name] = &simpleType{variety: list, item: builtins[item], facets: facets{whiteSpace: collapse, minLength: &one}}
+	}
+
+	zero := 0
+	derive("integer", "decimal", func(f *facets) {
+		f.fractionDigits = &zero
+		withPattern("integer", `[\-+]?[0-9]+`)(f)
+	})
+	derive("nonPositiveInteger", "integer", withRange("", "0"))
+	derive("negativeInteger", "nonPositiveInteger", withRange("", "-1"))
+	derive("long", "integer", withRange("-9223372036854775808", "9223372036854775807"))
+	derive("int", "long", withRange("-2147483648", "2147483647"))
+	derive("short", "int", withRange("-32768", "32767"))
+	derive("byte", "short", withRange("-128", "127"))
+	derive("nonNegativeInteger", "integer", withRange("0", ""))
+	derive("unsignedLong", "nonNegativeInteger", withRange("0", "18446744073709551615"))
+	derive("unsignedInt", "unsignedLong", withRange("0", "4294967295"))
+	derive("unsignedShort", "unsignedInt", withRange("0", "65535"))
+	derive("unsignedByte", "unsignedShort", withRange("0", "255"))
+	derive("positiveInteger", "nonNegativeInteger", withRange("1", ""))
+
+	for name, t := range builtins {
+		t.name = xml.Name{Space: Namespace, Local: name}
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright 2020 Syntio Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xsd
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// node is an element of a parsed XML document with its namespaces resolved.
+type node struct {
+	name     xml.Name
+	attrs    []xml.Attr
+	children []*node
+	parent   *node
+	// text is the character data directly inside the element, and mixed is set if any of it isn't whitespace.
+	text  string
+	mixed bool
+	// namespaces maps the prefixes in scope to their namespaces, "" maps to the default namespace.
+	namespaces map[string]string
+}
+
+// attr returns the value of an unqualified attribute of the element.
+func (n *node) attr(local string) (string, bool) {
+	for _, attr := range n.attrs {
+		if attr.Name.Space == "" && attr.Name.Local == local {
+			return attr.Value, true
+		}
+	}
+	return "", false
+}
+
+// resolve resolves a QName, e.g. "xs:string", in the namespaces in scope of the element. Unprefixed names are in
+// the default namespace.
+func (n *node) resolve(qname string) (xml.Name, error) {
+	qname = strings.TrimSpace(qname)
+	prefix, local := "", qname
+	if i := strings.IndexByte(qname, ':'); i >= 0 {
+		prefix, local = qname[:i], qname[i+1:]
+	}
+	space, ok := n.namespaces[prefix]
+	if !ok && prefix != "" {
+		return xml.Name{}, fmt.Errorf("undeclared namespace prefix %q in %q", prefix, qname)
+	}
+	return xml.Name{Space: space, Local: local}, nil
+}
+
+// parseDocument parses an XML document into a tree of nodes and returns its root element.
+func parseDocument(document []byte) (*node, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(document))
+	decoder.Strict = true
+
+	var root, current *node
+	var text strings.Builder
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch token := token.(type) {
+		case xml.StartElement:
+			element := &node{name: token.Name, parent: current, namespaces: map[string]string{}}
+			if current != nil {
+				current.text += text.String()
+				for prefix, space := range current.namespaces {
+					element.namespaces[prefix] = space
+				}
+				current.children = append(current.children, element)
+			} else if root != nil {
+				return nil, fmt.Errorf("the document has more than one root element")
+			} else {
+				root = element
+			}
+			for _, attr := range token.Attr {
+				switch {
+				case attr.Name.Space == "xmlns":
+					element.namespaces[attr.Name.Local] = attr.Value
+				case attr.Name.Space == "" && attr.Name.Local == "xmlns":
+					element.namespaces[""] = attr.Value
+				default:
+					element.attrs = append(element.attrs, attr)
+				}
+			}
+			current = element
+			text.Reset()
+
+		case xml.EndElement:
+			current.text += text.String()
+			text.Reset()
+			current = current.parent
+
+		case xml.CharData:
+			if current == nil {
+				continue
+			}
+			text.Write(token)
+			if len(bytes.TrimSpace(token)) > 0 {
+				current.mixed = true
+			}
+		}
+	}
+
+	if root == nil {
+		return nil, fmt.Errorf("the document has no root element")
+	}
+	return root, nil
+}
@@ -0,0 +1,456 @@
+// Copyright 2020 Syntio Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xsd
+
+import (
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// xmlNamespace is the namespace of the xml prefix, e.g. xml:lang.
+const xmlNamespace = "http://www.w3.org/XML/1998/namespace"
+
+// Violation is a part of a document which doesn't conform to the schema. Path locates the element or attribute,
+// e.g. "/order/item[2]/@sku". Rule names the violated constraint, e.g. "fixed" or "content", and Expected and Actual
+// are the values compared, if any.
+type Violation struct {
+	Path        string
+	Rule        string
+	Expected    string
+	Actual      string
+	Description string
+}
+
+// Validate validates an XML document with the schema and returns every violation found. An error is returned if
+// the document isn't well-formed.
+func (s *Schema) Validate(document []byte) ([]Violation, error) {
+	root, err := parseDocument(document)
+	if err != nil {
+		return nil, err
+	}
+
+	v := &validation{schema: s}
+	path := "/" + root.name.Local
+	if decl, ok := s.elements[root.name]; ok {
+		v.element(root, decl, path)
+	} else {
+		v.report(path, "declaration", "element %s isn't declared as a global element of the schema",
+			root.name.Local)
+	}
+	return v.violations, nil
+}
+
+// validation collects the violations of a document.
+type validation struct {
+	schema     *Schema
+	violations []Violation
+}
+
+// report reports a violation of a schema rule, e.g. "fixed" or "content".
+func (v *validation) report(path, rule, format string, args ...interface{}) {
+	v.mismatch(path, rule, "", "", format, args...)
+}
+
+// mismatch reports a violation of a schema rule with the expected and the actual value.
+func (v *validation) mismatch(path, rule, expected, actual, format string, args ...interface{}) {
+	v.violations = append(v.violations, Violation{Path: path, Rule: rule, Expected: expected, Actual: actual,
+		Description: fmt.Sprintf(format, args...)})
+}
+
+// element validates an element with its declaration.
+func (v *validation) element(n *node, decl *element, path string) {
+	if decl.abstract {
+		v.report(path, "abstract", "element %s is abstract, one of its substitutes has to be used", n.name.Local)
+	}
+
+	if nilled, ok := instanceAttr(n, "nil"); ok && (nilled == "true" || nilled == "1") {
+		if !decl.nillable {
+			v.report(path, "nillable", "element %s isn't nillable", n.name.Local)
+		} else if len(n.children) > 0 || n.mixed {
+			v.report(path, "nillable", "element %s is nil but has content", n.name.Local)
+		}
+		if decl.complex != nil {
+			v.attributes(n, decl.complex, path)
+		}
+		return
+	}
+
+	if decl.complex == nil {
+		v.noAttributes(n, path)
+		v.simpleContent(n, decl.simple, decl, path)
+		return
+	}
+
+	t := decl.complex
+	if t.abstract {
+		v.report(path, "abstract", "the type of element %s is abstract", n.name.Local)
+	}
+	if t.anyType {
+		return
+	}
+	v.attributes(n, t, path)
+
+	if t.simple != nil {
+		v.simpleContent(n, t.simple, decl, path)
+		return
+	}
+	if n.mixed && !t.mixed {
+		v.report(path, "mixed", "element %s can't contain text, only elements", n.name.Local)
+	}
+	if decl.fixed != nil && t.mixed && len(n.children) == 0 && n.text != *decl.fixed {
+		v.mismatch(path, "fixed", *decl.fixed, n.text, "element %s has the fixed value %q", n.name.Local,
+			*decl.fixed)
+	}
+	v.content(n, t.content, path)
+}
+
+// simpleContent validates the text of an element with a simple type, applying the element's default and fixed
+// values.
+func (v *validation) simpleContent(n *node, t *simpleType, decl *element, path string) {
+	if len(n.children) > 0 {
+		v.report(childPath(n, 0, path), "simple-content", "element %s has a simple type and can't contain elements",
+			n.name.Local)
+		return
+	}
+
+	value := n.text
+	if value == "" && decl.def != nil {
+		value = *decl.def
+	}
+	if err := t.validate(value, n); err != nil {
+		v.mismatch(path, "type", "", value, "%v", err)
+		return
+	}
+	if decl.fixed != nil && normalize(value, t.facets.whiteSpace) != normalize(*decl.fixed, t.facets.whiteSpace) {
+		v.mismatch(path, "fixed", *decl.fixed, value, "element %s has the fixed value %q", n.name.Local,
+			*decl.fixed)
+	}
+}
+
+// noAttributes reports the attributes of an element with a simple type.
+func (v *validation) noAttributes(n *node, path string) {
+	for _, attr := range n.attrs {
+		if attr.Name.Space != instanceNamespace && attr.Name.Space != xmlNamespace {
+			v.report(path+"/@"+attr.Name.Local, "simple-content", "element %s has a simple type and can't have attributes",
+				n.name.Local)
+		}
+	}
+}
+
+// attributes validates the attributes of an element with the attribute uses of its complex type.
+func (v *validation) attributes(n *node, t *complexType, path string) {
+	seen := map[xml.Name]bool{}
+
+	for _, attr := range n.attrs {
+		attrPath := path + "/@" + attr.Name.Local
+		if attr.Name.Space == instanceNamespace {
+			continue
+		}
+		seen[attr.Name] = true
+
+		use, ok := t.attributes[attr.Name]
+		if !ok {
+			switch {
+			case t.anyAttribute != nil && t.anyAttribute.allows(attr.Name.Space):
+				if t.anyAttribute.process == "skip" {
+					continue
+				}
+				if use, ok = v.schema.attributes[attr.Name]; !ok {
+					if t.anyAttribute.process == "strict" {
+						v.report(attrPath, "declaration", "attribute %s isn't declared", attr.Name.Local)
+					}
+					continue
+				}
+			case attr.Name.Space == xmlNamespace:
+				continue
+			default:
+				v.report(attrPath, "attribute", "attribute %s isn't allowed on element %s", attr.Name.Local,
+					n.name.Local)
+				continue
+			}
+		}
+
+		if err := use.typ.validate(attr.Value, n); err != nil {
+			v.mismatch(attrPath, "type", "", attr.Value, "%v", err)
+			continue
+		}
+		if use.fixed != nil && normalize(attr.Value, use.typ.facets.whiteSpace) != normalize(*use.fixed, use.typ.facets.whiteSpace) {
+			v.mismatch(attrPath, "fixed", *use.fixed, attr.Value, "attribute %s has the fixed value %q",
+				attr.Name.Local, *use.fixed)
+		}
+	}
+
+	var missing []string
+	for name, use := range t.attributes {
+		if use.required && !seen[name] {
+			missing = append(missing, name.Local)
+		}
+	}
+	sort.Strings(missing)
+	for _, name := range missing {
+		v.mismatch(path, "required", name, "", "element %s is missing the required attribute %s", n.name.Local,
+			name)
+	}
+}
+
+// content validates the child elements of an element with its content model.
+func (v *validation) content(n *node, content *particle, path string) {
+	if content == nil {
+		if len(n.children) > 0 {
+			v.report(childPath(n, 0, path), "content", "element %s must be empty", n.name.Local)
+		}
+		return
+	}
+
+	m := &matcher{children: n.children, assigned: make([]interface{}, len(n.children))}
+	ends := m.particle(content, positions{0: true})
+	if !ends[len(n.children)] {
+		expected := strings.Join(m.expected, ", ")
+		switch {
+		case m.furthest < len(n.children) && expected == "":
+			v.mismatch(childPath(n, m.furthest, path), "content", "", n.children[m.furthest].name.Local,
+				"unexpected element %s", n.children[m.furthest].name.Local)
+		case m.furthest < len(n.children):
+			v.mismatch(childPath(n, m.furthest, path), "content", expected, n.children[m.furthest].name.Local,
+				"unexpected element %s, expected %s", n.children[m.furthest].name.Local, expected)
+		default:
+			v.mismatch(path, "content", expected, "", "element %s is incomplete, expected %s", n.name.Local,
+				expected)
+		}
+	}
+
+	for i, child := range n.children {
+		switch term := m.assigned[i].(type) {
+		case *element:
+			v.element(child, term, childPath(n, i, path))
+		case *wildcard:
+			if term.process == "skip" {
+				continue
+			}
+			if decl, ok := v.schema.elements[child.name]; ok {
+				v.element(child, decl, childPath(n, i, path))
+			} else if term.process == "strict" {
+				v.report(childPath(n, i, path), "declaration", "element %s isn't declared", child.name.Local)
+			}
+		}
+	}
+}
+
+// childPath returns the path of a child element, indexed if the element has siblings of the same name.
+func childPath(n *node, i int, path string) string {
+	child := n.children[i]
+	index, count := 0, 0
+	for j, sibling := range n.children {
+		if sibling.name == child.name {
+			count++
+			if j <= i {
+				index++
+			}
+		}
+	}
+	if count == 1 {
+		return path + "/" + child.name.Local
+	}
+	return path + "/" + child.name.Local + "[" + strconv.Itoa(index) + "]"
+}
+
+// instanceAttr returns the value of an XML Schema instance attribute, e.g. xsi:nil.
+func instanceAttr(n *node, local string) (string, bool) {
+	for _, attr := range n.attrs {
+		if attr.Name.Space == instanceNamespace && attr.Name.Local == local {
+			return strings.TrimSpace(attr.Value), true
+		}
+	}
+	return "", false
+}
+
+// positions is a set of positions in the children of an element.
+type positions map[int]bool
+
+// matcher matches the children of an element with a content model. It follows every way the content model can
+// match at once, so it needs no backtracking, and assigns each matched child the declaration or wildcard it
+// matched.
+type matcher struct {
+	children []*node
+	assigned []interface{}
+	// furthest is the furthest position a term matched up to or failed to match at, and expected the terms which
+	// failed there.
+	furthest int
+	expected []string
+}
+
+// particle returns the positions a particle can end at when it starts at any of the given positions.
+func (m *matcher) particle(p *particle, starts positions) positions {
+	ends := positions{}
+	reached := positions{}
+	if p.min == 0 {
+		for start := range starts {
+			ends[start] = true
+			reached[start] = true
+		}
+	}
+
+	current := starts
+	for i := 1; p.max < 0 || i <= p.max; i++ {
+		next := positions{}
+		for start := range current {
+			for end := range m.term(p, start) {
+				next[end] = true
+			}
+		}
+		if len(next) == 0 {
+			break
+		}
+
+		if i >= p.min {
+			// Once the particle stops reaching new positions, further occurrences can't either
+			grown := false
+			for end := range next {
+				ends[end] = true
+				if !reached[end] {
+					reached[end] = true
+					grown = true
+				}
+			}
+			if !grown {
+				break
+			}
+		}
+		current = next
+	}
+	return ends
+}
+
+// term returns the positions one occurrence of a particle's term can end at when it starts at a position.
+func (m *matcher) term(p *particle, start int) positions {
+	switch p.kind {
+	case elementParticle:
+		if start < len(m.children) {
+			if decl := matchElement(p.element, m.children[start]); decl != nil {
+				m.assigned[start] = decl
+				m.reach(start + 1)
+				return positions{start + 1: true}
+			}
+		}
+		m.expect(start, p.element.name.Local)
+
+	case anyParticle:
+		if start < len(m.children) && p.wildcard.allows(m.children[start].name.Space) {
+			m.assigned[start] = p.wildcard
+			m.reach(start + 1)
+			return positions{start + 1: true}
+		}
+		m.expect(start, "any element")
+
+	case sequenceParticle:
+		current := positions{start: true}
+		for _, child := range p.children {
+			if current = m.particle(child, current); len(current) == 0 {
+				break
+			}
+		}
+		return current
+
+	case choiceParticle:
+		ends := positions{}
+		for _, child := range p.children {
+			for end := range m.particle(child, positions{start: true}) {
+				ends[end] = true
+			}
+		}
+		return ends
+
+	case allParticle:
+		return m.all(p, start)
+	}
+	return nil
+}
+
+// all matches the elements of an all group, which may appear once each in any order.
+func (m *matcher) all(p *particle, start int) positions {
+	used := make([]bool, len(p.children))
+	position := start
+
+next:
+	for position < len(m.children) {
+		for i, child := range p.children {
+			if used[i] || child.kind != elementParticle {
+				continue
+			}
+			if decl := matchElement(child.element, m.children[position]); decl != nil {
+				m.assigned[position] = decl
+				used[i] = true
+				position++
+				m.reach(position)
+				continue next
+			}
+		}
+		break
+	}
+
+	missing := false
+	for i, child := range p.children {
+		if !used[i] && child.min > 0 {
+			m.expect(position, child.element.name.Local)
+			missing = true
+		}
+	}
+	if missing {
+		return nil
+	}
+	return positions{position: true}
+}
+
+// reach records the position after a matched term.
+func (m *matcher) reach(position int) {
+	if position > m.furthest {
+		m.furthest = position
+		m.expected = nil
+	}
+}
+
+// expect records a term that failed to match at a position.
+func (m *matcher) expect(position int, term string) {
+	if position > m.furthest {
+		m.furthest = position
+		m.expected = nil
+	}
+	if position < m.furthest {
+		return
+	}
+	for _, expected := range m.expected {
+		if expected == term {
+			return
+		}
+	}
+	m.expected = append(m.expected, term)
+}
+
+// matchElement returns the declaration of an element, or of a member of its substitution group, which matches a
+// child element.
+func matchElement(decl *element, child *node) *element {
+	if decl.name == child.name {
+		return decl
+	}
+	for _, substitute := range decl.substitutes {
+		if match := matchElement(substitute, child); match != nil {
+			return match
+		}
+	}
+	return nil
+}
@@ -0,0 +1,300 @@
+// Copyright 2020 Syntio Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xsd
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// schemaOf wraps declarations into a schema document.
+func schemaOf(declarations string) string {
+	return `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">` + declarations + `</xs:schema>`
+}
+
+// located returns the path and rule of each violation, e.g. "/order/item[2]/@sku/type".
+func located(violations []Violation) []string {
+	locations := []string{}
+	for _, v := range violations {
+		locations = append(locations, v.Path+"/"+v.Rule)
+	}
+	return locations
+}
+
+// validate parses a schema and validates a document with it.
+func validate(t *testing.T, schema, document string) []Violation {
+	t.Helper()
+	s, err := Parse([]byte(schema))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	violations, err := s.Validate([]byte(document))
+	if err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	return violations
+}
+
+func TestFacets(t *testing.T) {
+	tests := []struct {
+		name    string
+		base    string
+		facets  string
+		valid   string
+		invalid string
+	}{
+		{"length", "xs:string", `<xs:length value="3"/>`, "abc", "ab"},
+		{"minLength", "xs:string", `<xs:minLength value="2"/>`, "ab", "a"},
+		{"maxLength", "xs:string", `<xs:maxLength value="2"/>`, "ab", "abc"},
+		{"pattern", "xs:string", `<xs:pattern value="[A-Z]{2}\d+"/>`, "AB12", "ab12"},
+		{"enumeration", "xs:string", `<xs:enumeration value="red"/><xs:enumeration value="green"/>`, "green",
+			"blue"},
+		{"minInclusive", "xs:int", `<xs:minInclusive value="1"/>`, "1", "0"},
+		{"maxInclusive", "xs:int", `<xs:maxInclusive value="10"/>`, "10", "11"},
+		{"minExclusive", "xs:decimal", `<xs:minExclusive value="0"/>`, "0.1", "0"},
+		{"maxExclusive", "xs:decimal", `<xs:maxExclusive value="1"/>`, "0.9", "1"},
+		{"totalDigits", "xs:decimal", `<xs:totalDigits value="4"/>`, "12.34", "123.45"},
+		{"fractionDigits", "xs:decimal", `<xs:fractionDigits value="2"/>`, "1.5", "1.555"},
+		{"whiteSpace", "xs:string", `<xs:whiteSpace value="collapse"/><xs:length value="3"/>`, "  abc  ",
+			"  ab  "},
+		{"date bounds", "xs:date", `<xs:minInclusive value="2020-01-01"/>`, "2020-01-01", "2019-12-31"},
+		{"builtin type", "xs:positiveInteger", "", "7", "0"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			schema := schemaOf(fmt.Sprintf(`<xs:element name="v"><xs:simpleType><xs:restriction base="%s">%s`+
+				`</xs:restriction></xs:simpleType></xs:element>`, test.base, test.facets))
+
+			if violations := validate(t, schema, "<v>"+test.valid+"</v>"); len(violations) != 0 {
+				t.Errorf("valid value %q: violations = %v", test.valid, violations)
+			}
+			violations := validate(t, schema, "<v>"+test.invalid+"</v>")
+			if got, want := located(violations), []string{"/v/type"}; !reflect.DeepEqual(got, want) {
+				t.Errorf("invalid value %q: violations = %v, want %v", test.invalid, got, want)
+			}
+		})
+	}
+}
+
+func TestSimpleTypes(t *testing.T) {
+	tests := []struct {
+		name       string
+		simpleType string
+		value      string
+		want       []string
+	}{
+		{
+			name:       "list",
+			simpleType: `<xs:list itemType="xs:int"/>`,
+			value:      "1 2 3",
+			want:       []string{},
+		},
+		{
+			name:       "list item of another type",
+			simpleType: `<xs:list itemType="xs:int"/>`,
+			value:      "1 two 3",
+			want:       []string{"/v/type"},
+		},
+		{
+			name: "list length",
+			simpleType: `<xs:restriction><xs:simpleType><xs:list itemType="xs:int"/></xs:simpleType>` +
+				`<xs:maxLength value="2"/></xs:restriction>`,
+			value: "1 2 3",
+			want:  []string{"/v/type"},
+		},
+		{
+			name:       "union member",
+			simpleType: `<xs:union memberTypes="xs:int xs:boolean"/>`,
+			value:      "true",
+			want:       []string{},
+		},
+		{
+			name:       "union of no member",
+			simpleType: `<xs:union memberTypes="xs:int xs:boolean"/>`,
+			value:      "maybe",
+			want:       []string{"/v/type"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			schema := schemaOf(`<xs:element name="v"><xs:simpleType>` + test.simpleType +
+				`</xs:simpleType></xs:element>`)
+			violations := validate(t, schema, "<v>"+test.value+"</v>")
+			if got := located(violations); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("violations = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestCompositors(t *testing.T) {
+	sequence := schemaOf(`<xs:element name="r"><xs:complexType><xs:sequence>` +
+		`<xs:element name="a" type="xs:string"/><xs:element name="b" type="xs:string"/>` +
+		`</xs:sequence></xs:complexType></xs:element>`)
+	choice := schemaOf(`<xs:element name="r"><xs:complexType><xs:choice>` +
+		`<xs:element name="a" type="xs:string"/><xs:element name="b" type="xs:string"/>` +
+		`</xs:choice></xs:complexType></xs:element>`)
+	all := schemaOf(`<xs:element name="r"><xs:complexType><xs:all>` +
+		`<xs:element name="a" type="xs:string"/><xs:element name="b" type="xs:string" minOccurs="0"/>` +
+		`</xs:all></xs:complexType></xs:element>`)
+	occurrences := schemaOf(`<xs:element name="r"><xs:complexType><xs:sequence>` +
+		`<xs:element name="a" type="xs:string" minOccurs="2" maxOccurs="3"/>` +
+		`</xs:sequence></xs:complexType></xs:element>`)
+	group := schemaOf(`<xs:group name="pair"><xs:sequence><xs:element name="a" type="xs:int"/>` +
+		`<xs:element name="b" type="xs:int"/></xs:sequence></xs:group>` +
+		`<xs:element name="r"><xs:complexType><xs:sequence><xs:group ref="pair" maxOccurs="unbounded"/>` +
+		`</xs:sequence></xs:complexType></xs:element>`)
+	substitution := schemaOf(`<xs:element name="shape" type="xs:string" abstract="true"/>` +
+		`<xs:element name="circle" type="xs:string" substitutionGroup="shape"/>` +
+		`<xs:element name="r"><xs:complexType><xs:sequence><xs:element ref="shape"/>` +
+		`</xs:sequence></xs:complexType></xs:element>`)
+	wildcard := schemaOf(`<xs:element name="known" type="xs:int"/>` +
+		`<xs:element name="r"><xs:complexType><xs:sequence><xs:any processContents="lax"/>` +
+		`</xs:sequence></xs:complexType></xs:element>`)
+
+	tests := []struct {
+		name     string
+		schema   string
+		document string
+		want     []string
+	}{
+		{"sequence", sequence, "<r><a/><b/></r>", []string{}},
+		{"sequence out of order", sequence, "<r><b/><a/></r>", []string{"/r/b/content"}},
+		{"choice", choice, "<r><b/></r>", []string{}},
+		{"choice of both", choice, "<r><a/><b/></r>", []string{"/r/b/content"}},
+		{"all in any order", all, "<r><b/><a/></r>", []string{}},
+		{"all with an optional element left out", all, "<r><a/></r>", []string{}},
+		{"all with an element repeated", all, "<r><a/><a/></r>", []string{"/r/a[2]/content"}},
+		{"occurrences within bounds", occurrences, "<r><a/><a/><a/></r>", []string{}},
+		{"too few occurrences", occurrences, "<r><a/></r>", []string{"/r/content"}},
+		{"too many occurrences", occurrences, "<r><a/><a/><a/><a/></r>", []string{"/r/a[4]/content"}},
+		{"repeated group", group, "<r><a>1</a><b>2</b><a>3</a><b>4</b></r>", []string{}},
+		{"group element of another type", group, "<r><a>1</a><b>x</b></r>", []string{"/r/b/type"}},
+		{"substitute", substitution, "<r><circle/></r>", []string{}},
+		{"abstract head", substitution, "<r><shape/></r>", []string{"/r/shape/abstract"}},
+		{"lax wildcard of a declared element", wildcard, "<r><known>x</known></r>", []string{"/r/known/type"}},
+		{"lax wildcard of an undeclared element", wildcard, "<r><unknown>x</unknown></r>", []string{}},
+		{"element content in a simple type", sequence, "<r><a><b/></a><b/></r>", []string{"/r/a/b/simple-content"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			violations := validate(t, test.schema, test.document)
+			if got := located(violations); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("violations = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestAttributes(t *testing.T) {
+	schema := schemaOf(`<xs:element name="r"><xs:complexType>` +
+		`<xs:attribute name="id" type="xs:int" use="required"/>` +
+		`<xs:attribute name="kind" type="xs:string" fixed="order"/>` +
+		`</xs:complexType></xs:element>`)
+
+	tests := []struct {
+		name     string
+		document string
+		want     []string
+	}{
+		{"valid attributes", `<r id="1" kind="order"/>`, []string{}},
+		{"missing required attribute", `<r kind="order"/>`, []string{"/r/required"}},
+		{"attribute of another type", `<r id="x"/>`, []string{"/r/@id/type"}},
+		{"fixed attribute", `<r id="1" kind="invoice"/>`, []string{"/r/@kind/fixed"}},
+		{"undeclared attribute", `<r id="1" extra="x"/>`, []string{"/r/@extra/attribute"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			violations := validate(t, schema, test.document)
+			if got := located(violations); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("violations = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestValidateErrors(t *testing.T) {
+	schema := schemaOf(`<xs:element name="order"><xs:complexType><xs:sequence>` +
+		`<xs:element name="id" type="xs:int"/><xs:element name="customer" type="xs:string"/>` +
+		`</xs:sequence></xs:complexType></xs:element>`)
+
+	tests := []struct {
+		name     string
+		document string
+		want     []string
+	}{
+		{"undeclared root", "<invoice/>", []string{"/invoice/declaration"}},
+		{"missing required elements", "<order/>", []string{"/order/content"}},
+		{"missing last required element", "<order><id>1</id></order>", []string{"/order/content"}},
+		{"undeclared child", "<order><id>1</id><customer/><note/></order>", []string{"/order/note/content"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			violations := validate(t, schema, test.document)
+			if got := located(violations); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("violations = %v, want %v", got, test.want)
+			}
+		})
+	}
+
+	t.Run("expected elements", func(t *testing.T) {
+		violations := validate(t, schema, "<order><id>1</id></order>")
+		if len(violations) != 1 || violations[0].Expected != "customer" {
+			t.Errorf("violations = %v, want one expecting customer", violations)
+		}
+	})
+
+	t.Run("malformed XML", func(t *testing.T) {
+		s, err := Parse([]byte(schema))
+		if err != nil {
+			t.Fatalf("Parse() error = %v", err)
+		}
+		if _, err := s.Validate([]byte("<order><id>1</order>")); err == nil {
+			t.Error("Validate() error = nil, want an error")
+		}
+	})
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+	}{
+		{"malformed schema", `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">`},
+		{"not a schema", `<schema/>`},
+		{"include", schemaOf(`<xs:include schemaLocation="other.xsd"/>`)},
+		{"unknown type", schemaOf(`<xs:element name="v" type="xs:nothing"/>`)},
+		{"undefined type", schemaOf(`<xs:element name="v" type="missing"/>`)},
+		{"invalid facet", schemaOf(`<xs:simpleType name="t"><xs:restriction base="xs:string">` +
+			`<xs:maxLength value="-1"/></xs:restriction></xs:simpleType>`)},
+		{"invalid pattern", schemaOf(`<xs:simpleType name="t"><xs:restriction base="xs:string">` +
+			`<xs:pattern value="[a-"/></xs:restriction></xs:simpleType>`)},
+		{"duplicate element", schemaOf(`<xs:element name="v"/><xs:element name="v"/>`)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := Parse([]byte(test.schema)); err == nil {
+				t.Error("Parse() error = nil, want an error")
+			}
+		})
+	}
+}
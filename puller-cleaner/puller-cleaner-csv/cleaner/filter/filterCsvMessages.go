@@ -18,10 +18,13 @@ package filter
 
 import (
 	"context"
+	"fmt"
 	"log"
 
 	"cloud.google.com/go/pubsub"
 
+	"github.com/syntio/janitor-common/envelope"
+	"github.com/syntio/janitor-common/metrics"
 	"github.com/syntio/puller-cleaner-csv/cleaner/sender"
 )
 
@@ -68,6 +71,32 @@ func isDeadCSVLetter(msg pubsub.Message) (bool, bool, string) {
 	return false, foundSchemaID, format
 }
 
+// jsonCleanedFormats are the formats of the messages cleaned by the JSON puller-cleaner: 
+// JSON and the formats validated with a JSON Schema.
+var jsonCleanedFormats = map[string]bool{
+	"json":    true,
+	"yaml":    true,
+	"msgpack": true,
+	"cbor":    true,
+	"bson":    true,
+}
+
+// deadLetterCause returns why a message is an obvious CSV dead letter.
+//
+// Input parameter is a message that is a dead letter.
+//
+// Output parameters are the reason the message is a dead letter for, and 
+// the error which makes it one.
+func deadLetterCause(msg pubsub.Message) (string, error) {
+	_, foundSchemaID, format, foundFormat := GetAttributes(msg)
+
+	if !foundSchemaID || !foundFormat {
+		return envelope.ReasonMissingMetadata, fmt.Errorf("the metadata \"schemaId\" and \"format\" are required")
+	}
+
+	return envelope.ReasonUnsupportedFormat, fmt.Errorf("unsupported message format %q", format)
+}
+
 // RemoveFromSlice is a helper function for removing message 
 // (specified with its index) from a slice of messages.
 //
@@ -100,16 +129,25 @@ func RemoveInvalidFormats(ctx context.Context, msgs *[]pubsub.Message, projectID
 		}
 
 		var topic string
+		var stage string
 
-		if format == "json" && isFoundSchemaID {
+		if jsonCleanedFormats[format] && isFoundSchemaID {
 			topic = invalidTopicJSON
+			stage = metrics.StageRerouted
+			msg.Attributes = envelope.Stamp(msg.Attributes, envelope.ComponentPullerCleanerCSV, 
+				envelope.ReasonWrongFormat, envelope.StageFiltering, nil)
 		} else {
 			topic = deadLetterTopic
+			stage = metrics.StageDeadLettered
+			reason, err := deadLetterCause(msg)
+			msg.Attributes = envelope.Stamp(msg.Attributes, envelope.ComponentPullerCleanerCSV, 
+				reason, envelope.StageFiltering, err)
 		}
 
 		if forwarded, err := sender.ForwardMessage(ctx, projectID, topic, &msg); !forwarded {
 			log.Printf("Couldn't forward message to PubSub topic, but will delete it from slice: %v", err)
 		}
+		metrics.Count(ctx, stage)
 
 		RemoveFromSlice(msgs, &i, &n)
 	}
@@ -19,7 +19,9 @@ package registry
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -27,9 +29,18 @@ import (
 
 	"cloud.google.com/go/pubsub"
 
+	"github.com/syntio/janitor-common/tracing"
 	"github.com/syntio/puller-cleaner-csv/cleaner/filter"
 )
 
+// Errors of an evolution the Schema Registry didn't infer a new schema version for. A message whose evolution is 
+// pending or limited can be cleaned later, while a refused evolution won't succeed however often it is retried.
+var (
+	ErrEvolutionPending = errors.New("the evolved schema is waiting for an approval")
+	ErrEvolutionLimited = errors.New("the schema can't evolve again today")
+	ErrEvolutionRefused = errors.New("the Schema Registry refused to evolve the schema")
+)
+
 type postResponse struct {
 	Identification string `json:"identification"`
 	Version        int32  `json:"version"`
@@ -40,13 +51,16 @@ type postResponse struct {
 // It sends the message and its format to the Schema Registry. Schema Registry's REST server returns whether 
 // the schema is inferred from the message, and if so, the ID and version of derived schema.
 //
-// Input parameters are a message that a schema needs to be inferred from, an URL of Schema Registry's Evolution 
+// Input parameters are context whose trace context is propagated to Schema Registry, a message that a schema 
+// needs to be inferred from, an URL of Schema Registry's Evolution 
 // component for communication with Schema Registry about evolution, and a content type for communication with 
 // Schema Registry's REST server.
 //
 // Output parameters are a struct that contains details of the inferred schema, and a bool which indicates whether 
-// schema is successfully inferred or not, and a possible error occurred while communication with Schema Registry.
-func InferSchema(msg pubsub.Message, schemaRegistryEvolutionURL, contentType string) (*postResponse, bool, error) {
+// schema is successfully inferred or not, and a possible error occurred while communication with Schema Registry. 
+// The error wraps ErrEvolutionPending, ErrEvolutionLimited or ErrEvolutionRefused if the Schema Registry answered 
+// that the evolution is held for an approval (202), over the daily limit (429) or refused (403).
+func InferSchema(ctx context.Context, msg pubsub.Message, schemaRegistryEvolutionURL, contentType string) (*postResponse, bool, error) {
 	schemaIDstring, _, format, _ := filter.GetAttributes(msg)
 
 	// Create request to send to Schema Registry
@@ -56,9 +70,15 @@ func InferSchema(msg pubsub.Message, schemaRegistryEvolutionURL, contentType str
 		return nil, false, fmt.Errorf("ERROR: Couldn't marshal request to send to Schema Registry to infer schema: %v", err)
 	}
 
-	// Send request to Schema Registry
-	surl := fmt.Sprintf(schemaRegistryEvolutionURL, schemaIDstring)
-	response, err := http.Post(surl, contentType, bytes.NewBuffer(jsonRequest))
+	// Send request to Schema Registry, in the namespace of the message if it carries one
+	surl := namespacedURL(fmt.Sprintf(schemaRegistryEvolutionURL, schemaIDstring), msg.Attributes["namespace"])
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, surl, bytes.NewBuffer(jsonRequest))
+	if err != nil {
+		return nil, false, fmt.Errorf("ERROR: While creating schema registration request: %v", err)
+	}
+	request.Header.Set("Content-Type", contentType)
+
+	response, err := tracing.HTTPClient.Do(request)
 	if err != nil {
 		return nil, false, fmt.Errorf("ERROR: While sending schema registration request: %v", err)
 	}
@@ -75,18 +95,28 @@ func InferSchema(msg pubsub.Message, schemaRegistryEvolutionURL, contentType str
 	postResponseInfo := &postResponse{}
 	json.Unmarshal(responseBody, postResponseInfo)
 
+	switch response.StatusCode {
 	// If response from Schema Registry is okay and contains information, return response 
-	if response.StatusCode == http.StatusOK {
-		if (&postResponseInfo.Identification != nil && &postResponseInfo.Version != nil) &&
-			(postResponseInfo.Identification != "" && postResponseInfo.Version != 0) {
+	case http.StatusOK:
+		if postResponseInfo.Identification != "" && postResponseInfo.Version != 0 {
 			return postResponseInfo, true, nil
-		} else {
-			return nil, false, fmt.Errorf("ERROR: Schema's ID or version received from Schema Registry is not ok: ID = %s, version = %d", 
-			postResponseInfo.Identification, postResponseInfo.Version)
 		}
-
-	} else {
-		return nil, false, fmt.Errorf("ERROR: Unhandled error while inferring schema, status code: %d", response.StatusCode)
+		return nil, false, fmt.Errorf("ERROR: Schema's ID or version received from Schema Registry is not ok: " + 
+			"ID = %s, version = %d", postResponseInfo.Identification, postResponseInfo.Version)
+
+	// The evolved schema waits for an approval, or the schema can't evolve again today
+	case http.StatusAccepted:
+		return nil, false, fmt.Errorf("%w: %s", ErrEvolutionPending, postResponseInfo.Message)
+	case http.StatusTooManyRequests:
+		return nil, false, fmt.Errorf("%w: %s", ErrEvolutionLimited, postResponseInfo.Message)
+
+	// Evolution of the schema is disabled, or its namespace is over its quota
+	case http.StatusForbidden:
+		return nil, false, fmt.Errorf("%w: %s", ErrEvolutionRefused, postResponseInfo.Message)
+
+	default:
+		return nil, false, fmt.Errorf("ERROR: Unhandled error while inferring schema, status code: %d", 
+			response.StatusCode)
 	}
 }
 
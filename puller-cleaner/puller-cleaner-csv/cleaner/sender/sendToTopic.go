@@ -22,24 +22,45 @@ import (
 	"log"
 
 	"cloud.google.com/go/pubsub"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/syntio/janitor-common/envelope"
+	"github.com/syntio/janitor-common/metrics"
+	"github.com/syntio/janitor-common/publisher"
+	"github.com/syntio/janitor-common/tracing"
 )
 
 // ForwardMessage forwards the given message to the specified topic in the specified project.
 //
+// The message is published in a span in its own trace, whose trace context is set into the message's attributes.
+// If the context carries a publisher.Publishing, the message is published asynchronously and its result is checked 
+// by Publishing.Wait, otherwise ForwardMessage waits for the result.
+//
 // Input parameters are context for the connection with PubSub, project's and topic's IDs where message needs 
 // to be sent, and a message.
 //
 // Output parameters are a bool which indicates whether or not the message was successfully forwarded to the
 // topic, and a possible error occurred while connection and sending to PubSub.
 func ForwardMessage(ctx context.Context, projectID, topicName string, message *pubsub.Message) (bool, error) {
-	clientPubSub, err := pubsub.NewClient(ctx, projectID)
+	ctx, span := tracing.StartMessage(ctx, "pubsub.Publish", message.Attributes, 
+		trace.WithSpanKind(trace.SpanKindProducer), trace.WithAttributes(attribute.String("messaging.destination", topicName)))
+
+	if message.Attributes == nil {
+		message.Attributes = map[string]string{}
+	}
+	tracing.Inject(ctx, message.Attributes)
+
+	result, err := publisher.Publish(ctx, projectID, topicName, message)
 	if err != nil {
-		return false, fmt.Errorf("ERROR: Couldn't establish connection to PubSub to forward the message.")
+		span.End()
+		return false, fmt.Errorf("ERROR: Couldn't establish connection to PubSub to forward the message: %v", err)
 	}
-	defer clientPubSub.Close()
 
-	topic := clientPubSub.Topic(topicName)
-	result := topic.Publish(ctx, message)
+	if publisher.Track(ctx, *message, topicName, result, span) {
+		return true, nil
+	}
+	defer span.End()
 
 	_, err = result.Get(ctx)
 	if err != nil {
@@ -60,6 +81,51 @@ func ForwardAndDelete(ctx context.Context, projectID, deadLetterTopic string,
 	if forwarded, err := ForwardMessage(ctx, projectID, deadLetterTopic, &message); !forwarded {
 		log.Printf("Couldn't forward message to PubSub topic, but will delete it from slice: %v", err)
 	}
+	metrics.Count(ctx, metrics.StageDeadLettered)
+
+	deleteFirstMessage(msgs, length) // we know that message is the first element in msgs
+}
+
+// DeadLetterUnpublished forwards the messages that couldn't be published to their topic to the dead-letter topic 
+// instead, stamped with the publish error. The messages are acknowledged when they're pulled, so a message that 
+// can't be forwarded to the dead-letter topic either is lost, and it is logged as such.
+//
+// Input parameters are context for the connection with PubSub, project's ID, the dead-letter topic's ID, and 
+// the errors of the messages that couldn't be published.
+func DeadLetterUnpublished(ctx context.Context, projectID, deadLetterTopic string, errs []publisher.PublishError) {
+	for _, publishErr := range errs {
+		log.Print(publishErr)
+		if publishErr.Topic == deadLetterTopic {
+			log.Printf("ERROR: The message %s is lost, it couldn't be forwarded to the dead-letter topic.", 
+				publishErr.Message.ID)
+			continue
+		}
+
+		message := publishErr.Message
+		message.Attributes = envelope.Stamp(message.Attributes, envelope.ComponentPullerCleanerCSV, 
+			envelope.ReasonPublishFailed, envelope.StageTransmission, publishErr.Err)
+		if forwarded, err := ForwardMessage(ctx, projectID, deadLetterTopic, &message); !forwarded {
+			log.Printf("ERROR: The message %s is lost, it couldn't be forwarded to the dead-letter topic: %v", 
+				message.ID, err)
+			continue
+		}
+		metrics.Count(ctx, metrics.StageDeadLettered)
+	}
+}
+
+// RequeueAndDelete forwards message back to the topic the puller-cleaner pulls from, so a later run cleans it 
+// again, and deletes it from the slice msgs.
+//
+// Input parameters are context for the connection with PubSub, project's and topic's IDs where 
+// message needs to be sent, a message that needs to be sent, slice of messages that the message 
+// will be deleted from, and slice's current length.
+func RequeueAndDelete(ctx context.Context, projectID, retryTopic string, 
+	message pubsub.Message, msgs *[]pubsub.Message, length *int) {
+
+	if forwarded, err := ForwardMessage(ctx, projectID, retryTopic, &message); !forwarded {
+		log.Printf("Couldn't forward message to PubSub topic, but will delete it from slice: %v", err)
+	}
+	metrics.Count(ctx, metrics.StageRerouted)
 
 	deleteFirstMessage(msgs, length) // we know that message is the first element in msgs
 }
@@ -18,71 +18,63 @@ package validator
 
 import (
 	"context"
-	"bytes"
-	"encoding/json"
-	"io/ioutil"
+	"fmt"
 	"log"
-	"net/http"
 
 	"cloud.google.com/go/pubsub"
 
+	"github.com/syntio/janitor-common/envelope"
+	"github.com/syntio/janitor-common/metrics"
+	"github.com/syntio/janitor-common/validator/csvschema"
+	"github.com/syntio/janitor-common/validator/impl"
 	"github.com/syntio/puller-cleaner-csv/cleaner/filter"
 	"github.com/syntio/puller-cleaner-csv/cleaner/sender"
 )
 
-type csvValidationRequest struct {
-	Data   string `json:"data"`
-	Schema string `json:"schema"`
-}
-
-type csvValidationResponse struct {
-	Validation bool   `json:"validation"`
-	Info       string `json:"info"`
-}
-
 // ValidateMessageWithSchemaCSV validates the given CSV message with the given CSV schema.
-// It is done by using a separate cloud function for validation.
+// It is done in-process, by the CSV Schema validator of the csvschema package.
 //
-// Input parameters are message to be validated, schema to validate message with, cloud function 
-// that does the validation, and a content type for communication with that cloud function.
+// Input parameters are message to be validated and schema to validate message with.
 //
 // Output parameter is a bool that indicates whether message is successfully validated.
-func ValidateMessageWithSchemaCSV(message, schema []byte, csvValidatorURL, contentType string) bool {
-	// Create request
-	req := csvValidationRequest{
-		Data:   string(message),
-		Schema: string(schema),
-	}
-
-	jsonReq, _ := json.Marshal(req)
-
-	// Post request
-	response, err := http.Post(csvValidatorURL, contentType, bytes.NewBuffer(jsonReq))
+func ValidateMessageWithSchemaCSV(message []byte, schema *csvschema.Schema) bool {
+	violations, err := ExplainMessageWithSchemaCSV(message, schema)
 	if err != nil {
-		log.Println("ERROR: Can't connect to the CSV validator")
+		log.Printf("ERROR: Can't read the message as CSV: %v", err)
 		return false
 	}
 
-	defer response.Body.Close()
+	return len(violations) == 0
+}
 
-	// Parse response
-	responseByte, err := ioutil.ReadAll(response.Body)
+// ExplainMessageWithSchemaCSV validates the given CSV message with the given CSV schema 
+// and lists every violation found.
+//
+// Input parameters are message to be validated and schema to validate message with.
+//
+// Output parameters are the violations of the message, located by the row and the column, 
+// e.g. "/3/email" (none if the message follows the schema), and a possible error occurred 
+// while reading the message as CSV.
+func ExplainMessageWithSchemaCSV(message []byte, schema *csvschema.Schema) ([]impl.Violation, error) {
+	found, err := schema.Validate(message)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
-	// Save response to struct 'csvValidationResponse'
-	responseStruct := &csvValidationResponse{}
-	json.Unmarshal(responseByte, responseStruct)
-
-	// If response is okay and contains information, return response 
-	if response.StatusCode == http.StatusOK {
-		return responseStruct.Validation
-	} else {
-		log.Printf("ERROR: Can't connect to the CSV validator. Response code: %d. Message: %s", 
-			response.StatusCode, responseStruct.Info)
-		return false
+	violations := make([]impl.Violation, 0, len(found))
+	for _, violation := range found {
+		location := "/"
+		if violation.Row > 0 {
+			location = fmt.Sprintf("/%d", violation.Row)
+			if violation.Column != "" {
+				location += "/" + violation.Column
+			}
+		}
+		violations = append(violations, impl.Violation{Location: location, Rule: violation.Rule, 
+			Expected: violation.Expected, Actual: violation.Actual, Description: violation.Description})
 	}
+
+	return violations, nil
 }
 
 // cleanMessage cleans the message that has been validated with one schema - it attaches schema's ID
@@ -96,10 +88,12 @@ func cleanMessage(ctx context.Context, msgs *[]pubsub.Message, msg *pubsub.Messa
 	schemaIDstring, versionIDstring, projectID, validTopic string) {
 
 	filter.SetAttributes(msg, schemaIDstring, versionIDstring)
+	envelope.Clear(msg.Attributes)
 
 	if forwarded, err := sender.ForwardMessage(ctx, projectID, validTopic, msg); !forwarded {
 		log.Printf("Couldn't forward message to PubSub topic, but will delete it from slice: %v", err)
 	}
+	metrics.Count(ctx, metrics.StageCleaned)
 
 	filter.RemoveFromSlice(msgs, i, length)
 }
@@ -110,17 +104,34 @@ func cleanMessage(ctx context.Context, msgs *[]pubsub.Message, msg *pubsub.Messa
 //
 // Input parameters are context for communication with PubSub, messages to be checked, current length of slice of pulled messages, 
 // schema specification that messages should be checked against, ID of message from which schema specification was inferred from, 
-// schema's ID and version, project ID and topics to forward messages to corresponding places, cloud function that does the 
-// validation, and a content type for communication with that cloud function.
+// schema's ID and version, and project ID and topics to forward messages to corresponding places.
 func CheckAgainstNewSchema(ctx context.Context, msgs *[]pubsub.Message, length *int, schemaSpecification []byte, 
-	firstMsgID, schemaIDstring, versionIDstring, projectID, validTopic, deadLetterTopic, csvValidatorURL, contentType string) {
+	firstMsgID, schemaIDstring, versionIDstring, projectID, validTopic, deadLetterTopic string) {
+
+	schema, err := csvschema.Parse(schemaSpecification)
+	if err != nil {
+		log.Printf("ERROR: Can't parse the CSV schema: %v", err)
+	}
 
 	for i := 0; i < (*length); i++ {
 		msg := (*msgs)[i]
 
-		if isValid := ValidateMessageWithSchemaCSV(msg.Data, schemaSpecification, csvValidatorURL, contentType); !isValid {
+		var violations []impl.Violation
+		validationErr := err
+		if validationErr == nil {
+			violations, validationErr = ExplainMessageWithSchemaCSV(msg.Data, schema)
+		}
+
+		if validationErr != nil || len(violations) > 0 {
 			// Couldn't validate the very same message that Schema Registry inferred the schema from
 			if msg.ID == firstMsgID {
+				if len(violations) > 0 {
+					msg.Attributes = envelope.StampViolations(msg.Attributes, envelope.ComponentPullerCleanerCSV, 
+						envelope.StageValidation, violations)
+				} else {
+					msg.Attributes = envelope.Stamp(msg.Attributes, envelope.ComponentPullerCleanerCSV, 
+						envelope.ReasonValidationFailed, envelope.StageValidation, validationErr)
+				}
 				sender.ForwardAndDelete(ctx, projectID, deadLetterTopic, msg, msgs, length)
 				break
 			}
@@ -54,23 +54,44 @@ type Config struct {
 		PullerCleanerCsvURL        string `yaml:"pullerCleanerCsvURL"`
 	} `yaml:"functions"`
 
-	Protoparam struct {
-		TmpFilePath string `yaml:"tmpFilePath"`
-		TmpFileName string `yaml:"tmpFileName"`
-	} `yaml:"protoparam"`
+	Protobuf struct {
+		DescriptorCacheSize int  `yaml:"descriptorCacheSize"`
+		StrictUnknownFields bool `yaml:"strictUnknownFields"`
+	} `yaml:"protobuf"`
 
 	PullerCleanerJSON struct {
 		TimeDurationSeconds time.Duration `yaml:"timeDurationSeconds"`
 		MaxBatchSize        int           `yaml:"maxBatchSize"`
 		MaxThroughput       int           `yaml:"maxThroughput"`
+		MaxAttempts         int           `yaml:"maxAttempts"`
 	} `yaml:"pullercleanerjson"`
 
 	PullerCleanerCSV struct {
 		TimeDurationSeconds time.Duration `yaml:"timeDurationSeconds"`
 		MaxBatchSize        int           `yaml:"maxBatchSize"`
 		MaxThroughput       int           `yaml:"maxThroughput"`
+		MaxAttempts         int           `yaml:"maxAttempts"`
 	} `yaml:"pullercleanercsv"`
 
+	Metrics struct {
+		PushgatewayURL string `yaml:"pushgatewayURL"`
+	} `yaml:"metrics"`
+
+	Tracing struct {
+		Endpoint    string  `yaml:"endpoint"`
+		Insecure    bool    `yaml:"insecure"`
+		SampleRatio float64 `yaml:"sampleRatio"`
+	} `yaml:"tracing"`
+
+	Publisher struct {
+		DelayThresholdMilliseconds time.Duration `yaml:"delayThresholdMilliseconds"`
+		CountThreshold             int           `yaml:"countThreshold"`
+		ByteThreshold              int           `yaml:"byteThreshold"`
+		TimeoutSeconds             time.Duration `yaml:"timeoutSeconds"`
+		MaxOutstandingMessages     int64         `yaml:"maxOutstandingMessages"`
+		MaxOutstandingBytes        int64         `yaml:"maxOutstandingBytes"`
+	} `yaml:"publisher"`
+
 	PullerCleanerTaskQueue  string      `yaml:"pullerCleanerTaskQueue"`
 	ContentType             string      `yaml:"contentType"`
 	FileMode                os.FileMode `yaml:"fileMode"`
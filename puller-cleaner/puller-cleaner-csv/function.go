@@ -25,8 +25,13 @@ import (
 	"time"
 
 	"cloud.google.com/go/pubsub"
+	"go.opentelemetry.io/otel/trace"
 
+	"github.com/syntio/janitor-common/metrics"
+	"github.com/syntio/janitor-common/publisher"
+	"github.com/syntio/janitor-common/tracing"
 	"github.com/syntio/puller-cleaner-csv/cleaner"
+	"github.com/syntio/puller-cleaner-csv/cleaner/sender"
 	"github.com/syntio/puller-cleaner-csv/configuration"
 	"github.com/syntio/puller-cleaner-csv/puller"
 )
@@ -39,14 +44,15 @@ var subIdCSV string
 var validTopic string
 var invalidTopicJSON string
 var deadLetterTopic string
+var retryTopic string
 
 var timeDurationSeconds time.Duration
 var maxBatchSize int
 var maxThroughput int
+var maxAttempts int
 
 var contentType string
 
-var csvValidatorURL string = os.Getenv("CSV_VALIDATOR_URL")
 var schemaRegistryURL string = os.Getenv("SCHEMA_REGISTRY_URL")
 
 const resourcePath string = "/schema/%s/evolution"
@@ -70,16 +76,22 @@ func init() {
 	validTopic = Cfg.Topics.ValidTopic
 	invalidTopicJSON = Cfg.Topics.InvalidTopicJSON
 	deadLetterTopic = Cfg.Topics.DeadLetterTopic
+	retryTopic = Cfg.Topics.InvalidTopicCSV
 
 	timeDurationSeconds = Cfg.PullerCleanerCSV.TimeDurationSeconds
 	maxBatchSize = Cfg.PullerCleanerCSV.MaxBatchSize
 	maxThroughput = Cfg.PullerCleanerCSV.MaxThroughput
+	maxAttempts = Cfg.PullerCleanerCSV.MaxAttempts
 
 	schemaRegistryURL = Cfg.Functions.SchemaRegistryURL
 	schemaRegistryEvolutionURL = Cfg.Functions.SchemaRegistryEvolutionURL
 	contentType = Cfg.ContentType
 
-	csvValidatorURL = Cfg.Functions.CsvValidatorURL
+	metrics.Configure("puller-cleaner-csv", Cfg.Metrics.PushgatewayURL)
+	tracing.Configure("puller-cleaner-csv", Cfg.Tracing.Endpoint, Cfg.Tracing.Insecure, Cfg.Tracing.SampleRatio)
+	publisher.Configure(publisher.Settings(Cfg.Publisher.DelayThresholdMilliseconds*time.Millisecond,
+		Cfg.Publisher.CountThreshold, Cfg.Publisher.ByteThreshold, Cfg.Publisher.TimeoutSeconds*time.Second),
+		Cfg.Publisher.MaxOutstandingMessages, Cfg.Publisher.MaxOutstandingBytes)
 }
 
 // PullerCleaner is the main function.
@@ -89,7 +101,10 @@ func init() {
 // Input paramaters are http.ResponseWriter and http.Request
 // because of the HTTP trigger on Cloud Function.
 func PullerCleaner(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
+	ctx, span := tracing.Start(tracing.ExtractHeaders(r.Context(), r.Header), "PullerCleaner",
+		trace.WithSpanKind(trace.SpanKindServer))
+	defer tracing.Flush(r.Context())
+	defer span.End()
 
 	// Pull messages from PubSub subscription to a slice
 	var msgs []pubsub.Message
@@ -104,9 +119,15 @@ func PullerCleaner(w http.ResponseWriter, r *http.Request) {
 	}
 	log.Printf("Pulled %d messages!", length)
 
-	// Clean messages
-	cleaner.Clean(ctx, msgs, projectID, validTopic, invalidTopicJSON, deadLetterTopic,
-		schemaRegistryURL, schemaRegistryEvolutionURL, contentType, csvValidatorURL)
+	ctx, batch := metrics.NewBatch(ctx, length)
+	defer batch.Finish()
+
+	// Clean messages, wait for the messages forwarded meanwhile to be published, and forward those that couldn't 
+	// be published to the dead-letter topic
+	publishCtx, publishing := publisher.StartPublishing(ctx)
+	cleaner.Clean(publishCtx, msgs, projectID, validTopic, invalidTopicJSON, deadLetterTopic, retryTopic,
+		schemaRegistryURL, schemaRegistryEvolutionURL, contentType, maxAttempts)
+	sender.DeadLetterUnpublished(ctx, projectID, deadLetterTopic, publishing.Wait(publishCtx))
 
 	fmt.Fprint(w, "Finished execution")
 }
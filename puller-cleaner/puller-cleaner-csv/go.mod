@@ -1,11 +1,20 @@
 module github.com/syntio/puller-cleaner-csv
 
-go 1.15
+go 1.16
 
 require (
 	cloud.google.com/go/pubsub v1.8.2
 	cloud.google.com/go/storage v1.10.0
+	github.com/prometheus/client_golang v1.7.1
+	github.com/syntio/janitor-common v0.0.0
+	go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp v0.25.0
+	go.opentelemetry.io/otel v1.0.1
+	go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp v1.0.1
+	go.opentelemetry.io/otel/sdk v1.0.1
+	go.opentelemetry.io/otel/trace v1.0.1
 	google.golang.org/api v0.34.0 // indirect
-	google.golang.org/grpc v1.33.1
-	gopkg.in/yaml.v2 v2.2.2
+	google.golang.org/grpc v1.41.0
+	gopkg.in/yaml.v2 v2.2.8
 )
+
+replace github.com/syntio/janitor-common => ../../janitor-common
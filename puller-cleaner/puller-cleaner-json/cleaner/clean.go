@@ -19,10 +19,13 @@ package cleaner
 import (
 	"context"
 	"encoding/base64"
+	"errors"
 	"log"
 
 	"cloud.google.com/go/pubsub"
 
+	"github.com/syntio/janitor-common/envelope"
+	"github.com/syntio/janitor-common/tracing"
 	"github.com/syntio/puller-cleaner-json/cleaner/filter"
 	"github.com/syntio/puller-cleaner-json/cleaner/registry"
 	"github.com/syntio/puller-cleaner-json/cleaner/sender"	
@@ -62,11 +65,13 @@ func schemaBase64Decode(schemaBase64 string) ([]byte, bool, error) {
 //		4. validate the rest of the messages with the retrieved schema
 //
 // Input parameters are context for communication with PubSub, array of messages that need to be cleaned, 
-// project's and topics' names where resolved messages need to be sent to, URL of Schema Registry for 
+// project's and topics' names where resolved messages need to be sent to (the retry topic is the one the messages 
+// were pulled from, for the messages whose schema can evolve only later), URL of Schema Registry for 
 // communication with Schema Registry, URL of Schema Registry's Evolution component for communication with 
-// Schema Registry about schema evolution, and a content type for communication with Schema Registry's REST server.
+// Schema Registry about schema evolution, a content type for communication with Schema Registry's REST server, 
+// and the number of times a message may be rerouted before its schema evolution is given up on.
 func Clean(ctx context.Context, msgs []pubsub.Message, projectID, validTopic, invalidTopicCSV, deadLetterTopic, 
-	schemaRegistryURL, schemaRegistryEvolutionURL, contentType string) {
+	retryTopic, schemaRegistryURL, schemaRegistryEvolutionURL, contentType string, maxAttempts int) {
 	
 	// First remove all messages with invalid format (faulty metadata or non-corresponding formats)
 	filter.RemoveInvalidFormats(ctx, &msgs, projectID, invalidTopicCSV, deadLetterTopic)
@@ -77,19 +82,40 @@ func Clean(ctx context.Context, msgs []pubsub.Message, projectID, validTopic, in
 	for length != 0 {
 		msgFirst := msgs[0]
 
+		// Infer the schema in the trace of the message
+		msgCtx, span := tracing.StartMessage(ctx, "registry.InferSchema", msgFirst.Attributes)
+		schemaInfo, isCleaned, err := registry.InferSchema(msgCtx, msgFirst, schemaRegistryEvolutionURL, contentType)
+		span.End()
+
 		// If schema is not inferred
-		if schemaInfo, isCleaned, err := registry.InferSchema(msgFirst, schemaRegistryEvolutionURL, contentType); !isCleaned {
-			log.Printf("Couldn't infer the schema from a message, it is a dead letter: %v", err)
-			sender.ForwardAndDelete(ctx, projectID, deadLetterTopic, msgFirst, &msgs, &length)
-			
+		if !isCleaned {
+			reason := inferenceReason(err)
+			msgFirst.Attributes = envelope.Stamp(msgFirst.Attributes, envelope.ComponentPullerCleanerJSON, reason, 
+				envelope.StageSchemaInference, err)
+
+			// An evolution held for an approval or over the daily limit may succeed later, so the message 
+			// goes back to the topic it was pulled from, to be cleaned by a later run, until it has been 
+			// rerouted maxAttempts times
+			retriable := reason == envelope.ReasonEvolutionPending || reason == envelope.ReasonEvolutionLimited
+			if retriable && envelope.Attempt(msgFirst.Attributes) < maxAttempts {
+				log.Printf("Couldn't infer the schema from a message yet, it will be retried: %v", err)
+				sender.RequeueAndDelete(ctx, projectID, retryTopic, msgFirst, &msgs, &length)
+			} else {
+				log.Printf("Couldn't infer the schema from a message, it is a dead letter: %v", err)
+				sender.ForwardAndDelete(ctx, projectID, deadLetterTopic, msgFirst, &msgs, &length)
+			}
+
 		// If schema is inferred
 		} else {
 			schemaIDstring, versionIDstring := registry.ExtractIDAndVersion(schemaInfo)
 			
 			// 2. Retrieve the schema
-			schema, found, err := registry.GetSchema(schemaIDstring, versionIDstring, schemaRegistryURL)
+			schema, found, err := registry.GetSchema(ctx, msgFirst.Attributes["namespace"], schemaIDstring, 
+				versionIDstring, schemaRegistryURL)
 			if !found {
 				log.Printf("Couldn't retrieve the schema, it is a dead letter: %v", err)
+				msgFirst.Attributes = envelope.Stamp(msgFirst.Attributes, envelope.ComponentPullerCleanerJSON, 
+					envelope.ReasonSchemaNotFound, envelope.StageSchemaRetrieval, err)
 				sender.ForwardAndDelete(ctx, projectID, deadLetterTopic, msgFirst, &msgs, &length)
 
 				continue
@@ -99,6 +125,8 @@ func Clean(ctx context.Context, msgs []pubsub.Message, projectID, validTopic, in
 			schemaSpecBytes, success, err := schemaBase64Decode(schema.SchemaDetails[0].Specification)
 			if !success {
 				log.Printf("Couldn't parse the schema using base64 decoding, it is a dead letter: %v", err)
+				msgFirst.Attributes = envelope.Stamp(msgFirst.Attributes, envelope.ComponentPullerCleanerJSON, 
+					envelope.ReasonSchemaDecoding, envelope.StageSchemaDecoding, err)
 				sender.ForwardAndDelete(ctx, projectID, deadLetterTopic, msgFirst, &msgs, &length)
 
 				continue
@@ -110,4 +138,22 @@ func Clean(ctx context.Context, msgs []pubsub.Message, projectID, validTopic, in
 			log.Printf("Just cleaned input down to %d messages", length)
 		}
 	}
-}
\ No newline at end of file
+}
+
+// inferenceReason returns the reason a message whose schema couldn't be inferred is rerouted for.
+//
+// Input parameter is the error returned while inferring the schema.
+//
+// Output is the reason of the envelope the message is stamped with.
+func inferenceReason(err error) string {
+	switch {
+	case errors.Is(err, registry.ErrEvolutionPending):
+		return envelope.ReasonEvolutionPending
+	case errors.Is(err, registry.ErrEvolutionLimited):
+		return envelope.ReasonEvolutionLimited
+	case errors.Is(err, registry.ErrEvolutionRefused):
+		return envelope.ReasonEvolutionRefused
+	default:
+		return envelope.ReasonInferenceFailed
+	}
+}
@@ -14,15 +14,20 @@
 
 
 // Package filter handles removing messages with non-JSON format (and/or invalid metadata). It also implements several helper function.
+// Messages of the formats validated with a JSON Schema (YAML, MessagePack, CBOR and BSON) are cleaned like JSON messages.
 package filter
 
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"log"
 
 	"cloud.google.com/go/pubsub"
 
+	"github.com/syntio/janitor-common/envelope"
+	"github.com/syntio/janitor-common/metrics"
+	"github.com/syntio/janitor-common/validator/convert"
 	"github.com/syntio/puller-cleaner-json/cleaner/sender"
 )
 
@@ -41,6 +46,22 @@ func GetAttributes(msg pubsub.Message) (string, bool, string, bool) {
 	return schemaID, foundSchemaID, format, foundFormat
 }
 
+// JSONData returns the data of a message as a JSON document. Messages of the formats validated 
+// with a JSON Schema are converted into JSON.
+//
+// Input parameter is a message whose data is needed as JSON.
+//
+// Output parameters are the JSON document, and a possible error occurred while converting.
+func JSONData(msg pubsub.Message) ([]byte, error) {
+	_, _, format, _ := GetAttributes(msg)
+
+	if convert.IsConvertible(format) {
+		return convert.ToJSON(format, msg.Data)
+	}
+
+	return msg.Data, nil
+}
+
 // SetAttributes sets schema ID and version ID attributes in a message.
 //
 // Input parameters are a PubSub message which attributes need to be set, and
@@ -52,8 +73,9 @@ func SetAttributes(msg *pubsub.Message, schemaIDstring, versionIDstring string)
 
 // isDeadJSONLetter checks whether the message is an obvious JSON 
 // dead letter. It is, if it does not contain the metadata "schemaId" 
-// and "format", or if the format is different of the "json" value. 
-// It also validates that the message is a valid JSON object.
+// and "format", or if the format is neither "json" nor a format 
+// validated with a JSON Schema. It also validates that the message 
+// is a valid JSON object, or can be converted into one.
 //
 // Input parameter is a message that needs to be checked if it is 
 // a dead letter or a valid JSON message.
@@ -63,15 +85,42 @@ func SetAttributes(msg *pubsub.Message, schemaIDstring, versionIDstring string)
 func isDeadJSONLetter(msg pubsub.Message) (bool, bool, string) {
 	_, foundSchemaID, format, foundFormat := GetAttributes(msg)
 
-	if !foundSchemaID || !foundFormat || format != "json" {
+	if !foundSchemaID || !foundFormat || (format != "json" && !convert.IsConvertible(format)) {
 		return true, foundSchemaID, format
 	}
 
+	if format != "json" {
+		_, err := convert.ToJSON(format, msg.Data)
+		return err != nil, foundSchemaID, format
+	}
+
 	isValidJSON := json.Valid(msg.Data)
 
 	return !isValidJSON, foundSchemaID, format
 }
 
+// deadLetterCause returns why a message is an obvious JSON dead letter.
+//
+// Input parameter is a message that is a dead letter.
+//
+// Output parameters are the reason the message is a dead letter for, and 
+// the error which makes it one, if any.
+func deadLetterCause(msg pubsub.Message) (string, error) {
+	_, foundSchemaID, format, foundFormat := GetAttributes(msg)
+
+	switch {
+	case !foundSchemaID || !foundFormat:
+		return envelope.ReasonMissingMetadata, fmt.Errorf("the metadata \"schemaId\" and \"format\" are required")
+	case format != "json" && !convert.IsConvertible(format):
+		return envelope.ReasonUnsupportedFormat, fmt.Errorf("unsupported message format %q", format)
+	case format != "json":
+		_, err := convert.ToJSON(format, msg.Data)
+		return envelope.ReasonInvalidMessage, err
+	default:
+		return envelope.ReasonInvalidMessage, fmt.Errorf("the message isn't a valid JSON document")
+	}
+}
+
 // RemoveFromSlice is a helper function for removing message 
 // (specified with its index) from a slice of messages.
 //
@@ -104,16 +153,25 @@ func RemoveInvalidFormats(ctx context.Context, msgs *[]pubsub.Message, projectID
 		}
 
 		var topic string
+		var stage string
 
 		if format == "csv" && isFoundSchemaID {
 			topic = invalidTopicCSV
+			stage = metrics.StageRerouted
+			msg.Attributes = envelope.Stamp(msg.Attributes, envelope.ComponentPullerCleanerJSON, 
+				envelope.ReasonWrongFormat, envelope.StageFiltering, nil)
 		} else {
 			topic = deadLetterTopic
+			stage = metrics.StageDeadLettered
+			reason, err := deadLetterCause(msg)
+			msg.Attributes = envelope.Stamp(msg.Attributes, envelope.ComponentPullerCleanerJSON, 
+				reason, envelope.StageFiltering, err)
 		}
 
 		if forwarded, err := sender.ForwardMessage(ctx, projectID, topic, &msg); !forwarded {
 			log.Printf("Couldn't forward message to PubSub topic, but will delete it from slice: %v", err)
 		}
+		metrics.Count(ctx, stage)
 
 		RemoveFromSlice(msgs, &i, &n)
 	}
@@ -18,12 +18,17 @@
 package registry
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
+
+	"github.com/syntio/janitor-common/tracing"
 )
 
 // Schema represents a message schema from Schema Registry.
@@ -88,15 +93,21 @@ func deserializeIntoString(jsonMessage []byte) (string, bool) {
 
 // GetSchema communicates with the Schema registry to retrieve the specified schema.
 //
-// Input parameters are schema's ID and version, and URL of Schema Registry for communication with it.
+// Input parameters are context whose trace context is propagated to Schema Registry, schema's namespace (empty 
+// if the message carries none), schema's ID and version, and URL of Schema Registry for communication with it.
 //
 // Output parameteres are a struct containing details about schema, a bool which indicates whether 
 // schema is obtained or not, and a possible error occurred while communication with Schema Registry.
-func GetSchema(schemaID string, versionID string, schemaRegistryURL string) (*Schema, bool, error) {
-	finalURL := fmt.Sprintf("%s/schema/%s/version/%s", schemaRegistryURL, schemaID, versionID)
+func GetSchema(ctx context.Context, namespace, schemaID, versionID, schemaRegistryURL string) (*Schema, bool, error) {
+	finalURL := fmt.Sprintf("%s%s/schema/%s/version/%s", schemaRegistryURL, namespacePath(namespace), schemaID, 
+		versionID)
 
 	// Send request to Schema Registry to get schema
-	response, err := http.Get(finalURL)
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, finalURL, nil)
+	if err != nil {
+		return nil, false, fmt.Errorf("ERROR: Couldn't create request for Schema Registry URL: %s", finalURL)
+	}
+	response, err := tracing.HTTPClient.Do(request)
 	if err != nil {
 		return nil, false, fmt.Errorf("ERROR: Couldn't communicate with Schema Registry URL: %s", finalURL)
 	}
@@ -118,3 +129,31 @@ func GetSchema(schemaID string, versionID string, schemaRegistryURL string) (*Sc
 		return nil, false, fmt.Errorf("ERROR: Reponse status from Schema Registry is not ok: %s", infoMessage)
 	}
 }
+
+// namespacePath returns the Schema Registry URL path prefix of a namespace.
+//
+// Input parameter is a namespace, empty for none.
+//
+// Output is the path prefix, e.g. "/ns/orders", or an empty string for no namespace.
+func namespacePath(namespace string) string {
+	if namespace == "" {
+		return ""
+	}
+
+	return "/ns/" + url.PathEscape(namespace)
+}
+
+// namespacedURL scopes a Schema Registry URL to a namespace, by inserting the path prefix of the 
+// namespace before the "/schema/" resource path of the URL.
+//
+// Input parameters are a Schema Registry URL and a namespace, empty for none.
+//
+// Output is the URL scoped to the namespace.
+func namespacedURL(registryURL, namespace string) string {
+	i := strings.LastIndex(registryURL, "/schema/")
+	if namespace == "" || i < 0 {
+		return registryURL
+	}
+
+	return registryURL[:i] + namespacePath(namespace) + registryURL[i:]
+}
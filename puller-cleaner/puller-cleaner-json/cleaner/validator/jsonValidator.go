@@ -18,33 +18,88 @@ package validator
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"log"
+	"strings"
 
 	"cloud.google.com/go/pubsub"
 	"github.com/xeipuuv/gojsonschema"
 
+	"github.com/syntio/janitor-common/envelope"
+	"github.com/syntio/janitor-common/metrics"
+	"github.com/syntio/janitor-common/validator/impl"
 	"github.com/syntio/puller-cleaner-json/cleaner/filter"
 	"github.com/syntio/puller-cleaner-json/cleaner/sender"
 )
 
-// ValidateMessageWithSchemaJSON validates the given JSON message with the 
-// given JSON schema.
+// explainMessageWithSchemaJSON validates the given JSON message with the 
+// given JSON schema and lists every violation found.
 //
 // Input parameters are the message to be validated and the schema by which 
 // the message is to be validated.
 //
-// Output parameters are bool which indicates whether message can be validated 
-// with a given schema or not, and a possible error occurred while validation.
-func validateMessageWithSchemaJSON(message, schema []byte) (bool, error) {
+// Output parameters are the violations of the message, located by a JSON 
+// pointer and named by the JSON Schema keyword they violate (none if the 
+// message follows the schema), and a possible error occurred while validation.
+func explainMessageWithSchemaJSON(message, schema []byte) ([]impl.Violation, error) {
 	documentLoader := gojsonschema.NewBytesLoader(message)
 	schemaLoader := gojsonschema.NewBytesLoader(schema)
 	
-	messageFollowsSchema, err := gojsonschema.Validate(schemaLoader, documentLoader)
+	result, err := gojsonschema.Validate(schemaLoader, documentLoader)
 	if err != nil {
-		return false, err
+		return nil, err
 	}
 
-	return messageFollowsSchema.Valid(), nil
+	violations := make([]impl.Violation, 0, len(result.Errors()))
+	for _, resultError := range result.Errors() {
+		expected, actual := jsonComparison(resultError)
+		violations = append(violations, impl.Violation{
+			Location:    strings.TrimPrefix(resultError.Context().String("/"), gojsonschema.STRING_CONTEXT_ROOT),
+			Rule:        resultError.Type(),
+			Expected:    expected,
+			Actual:      actual,
+			Description: resultError.Description(),
+		})
+	}
+
+	return violations, nil
+}
+
+// expectedDetails are the details of JSON Schema errors which hold the expected value, in the order of preference.
+var expectedDetails = []string{"expected", "min", "max", "pattern", "allowed", "format", "property"}
+
+// jsonComparison returns the expected and the actual value of a JSON Schema error. The actual 
+// value is only given for scalar values, so it doesn't repeat whole objects and arrays.
+//
+// Input parameter is the JSON Schema error.
+//
+// Output parameters are the expected and the actual value, empty if unknown.
+func jsonComparison(resultError gojsonschema.ResultError) (string, string) {
+	var expected, actual string
+
+	details := resultError.Details()
+	for _, key := range expectedDetails {
+		if value, ok := details[key]; ok {
+			expected = fmt.Sprint(value)
+			break
+		}
+	}
+
+	switch value := resultError.Value().(type) {
+	case map[string]interface{}, []interface{}:
+		if given, ok := details["given"]; ok {
+			actual = fmt.Sprint(given)
+		}
+	case nil:
+		actual = "null"
+	default:
+		if encoded, err := json.Marshal(value); err == nil {
+			actual = string(encoded)
+		}
+	}
+
+	return expected, actual
 }
 
 // cleanMessage cleans the message that has been validated with one schema - it attaches schema's ID
@@ -58,10 +113,12 @@ func cleanMessage(ctx context.Context, msgs *[]pubsub.Message, msg *pubsub.Messa
 	schemaIDstring, versionIDstring, projectID, validTopic string) {
 
 	filter.SetAttributes(msg, schemaIDstring, versionIDstring)
+	envelope.Clear(msg.Attributes)
 
 	if forwarded, err := sender.ForwardMessage(ctx, projectID, validTopic, msg); !forwarded {
 		log.Printf("Couldn't forward message to PubSub topic, but will delete it from slice: %v", err)
 	}
+	metrics.Count(ctx, metrics.StageCleaned)
 
 	filter.RemoveFromSlice(msgs, i, length)
 }
@@ -80,9 +137,25 @@ func CheckAgainstNewSchema(ctx context.Context, msgs *[]pubsub.Message, length *
 	for i := 0; i < (*length); i++ {
 		msg := (*msgs)[i]
 
-		if isValid, _ := validateMessageWithSchemaJSON(msg.Data, schemaSpecification); !isValid {
+		data, err := filter.JSONData(msg)
+		if err != nil {
+			log.Printf("Couldn't convert the message into JSON: %v", err)
+		}
+
+		violations, validationErr := explainMessageWithSchemaJSON(data, schemaSpecification)
+		if err != nil || validationErr != nil || len(violations) > 0 {
 			// Couldn't validate the very same message that Schema Registry inferred the schema from
 			if msg.ID == firstMsgID {
+				if err == nil {
+					err = validationErr
+				}
+				if len(violations) > 0 {
+					msg.Attributes = envelope.StampViolations(msg.Attributes, envelope.ComponentPullerCleanerJSON, 
+						envelope.StageValidation, violations)
+				} else {
+					msg.Attributes = envelope.Stamp(msg.Attributes, envelope.ComponentPullerCleanerJSON, 
+						envelope.ReasonValidationFailed, envelope.StageValidation, err)
+				}
 				sender.ForwardAndDelete(ctx, projectID, deadLetterTopic, msg, msgs, length)
 				break
 			}
@@ -25,8 +25,13 @@ import (
 	"time"
 
 	"cloud.google.com/go/pubsub"
+	"go.opentelemetry.io/otel/trace"
 
+	"github.com/syntio/janitor-common/metrics"
+	"github.com/syntio/janitor-common/publisher"
+	"github.com/syntio/janitor-common/tracing"
 	"github.com/syntio/puller-cleaner-json/cleaner"
+	"github.com/syntio/puller-cleaner-json/cleaner/sender"
 	"github.com/syntio/puller-cleaner-json/configuration"
 	"github.com/syntio/puller-cleaner-json/puller"
 )
@@ -39,10 +44,12 @@ var subIdJSON string
 var validTopic string
 var invalidTopicCSV string
 var deadLetterTopic string
+var retryTopic string
 
 var timeDurationSeconds time.Duration
 var maxBatchSize int
 var maxThroughput int
+var maxAttempts int
 
 var contentType string
 
@@ -67,14 +74,22 @@ func init() {
 	validTopic = Cfg.Topics.ValidTopic
 	invalidTopicCSV = Cfg.Topics.InvalidTopicCSV
 	deadLetterTopic = Cfg.Topics.DeadLetterTopic
+	retryTopic = Cfg.Topics.InvalidTopicJSON
 
 	timeDurationSeconds = Cfg.PullerCleanerJSON.TimeDurationSeconds
 	maxBatchSize = Cfg.PullerCleanerJSON.MaxBatchSize
 	maxThroughput = Cfg.PullerCleanerJSON.MaxThroughput
+	maxAttempts = Cfg.PullerCleanerJSON.MaxAttempts
 
 	schemaRegistryURL = Cfg.Functions.SchemaRegistryURL
 	schemaRegistryEvolutionURL = Cfg.Functions.SchemaRegistryEvolutionURL
 	contentType = Cfg.ContentType
+
+	metrics.Configure("puller-cleaner-json", Cfg.Metrics.PushgatewayURL)
+	tracing.Configure("puller-cleaner-json", Cfg.Tracing.Endpoint, Cfg.Tracing.Insecure, Cfg.Tracing.SampleRatio)
+	publisher.Configure(publisher.Settings(Cfg.Publisher.DelayThresholdMilliseconds*time.Millisecond,
+		Cfg.Publisher.CountThreshold, Cfg.Publisher.ByteThreshold, Cfg.Publisher.TimeoutSeconds*time.Second),
+		Cfg.Publisher.MaxOutstandingMessages, Cfg.Publisher.MaxOutstandingBytes)
 }
 
 // PullerCleaner is the main function.
@@ -84,7 +99,10 @@ func init() {
 // Input paramaters are http.ResponseWriter and http.Request
 // because of the HTTP trigger on Cloud Function.
 func PullerCleaner(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
+	ctx, span := tracing.Start(tracing.ExtractHeaders(r.Context(), r.Header), "PullerCleaner",
+		trace.WithSpanKind(trace.SpanKindServer))
+	defer tracing.Flush(r.Context())
+	defer span.End()
 
 	// Pull messages from PubSub subscription to a slice
 	var msgs []pubsub.Message
@@ -99,9 +117,15 @@ func PullerCleaner(w http.ResponseWriter, r *http.Request) {
 	}
 	log.Printf("Pulled %d messages!", length)
 
-	// Clean messages
-	cleaner.Clean(ctx, msgs, projectID, validTopic, invalidTopicCSV, deadLetterTopic,
-		schemaRegistryURL, schemaRegistryEvolutionURL, contentType)
+	ctx, batch := metrics.NewBatch(ctx, length)
+	defer batch.Finish()
+
+	// Clean messages, wait for the messages forwarded meanwhile to be published, and forward those that couldn't 
+	// be published to the dead-letter topic
+	publishCtx, publishing := publisher.StartPublishing(ctx)
+	cleaner.Clean(publishCtx, msgs, projectID, validTopic, invalidTopicCSV, deadLetterTopic, retryTopic,
+		schemaRegistryURL, schemaRegistryEvolutionURL, contentType, maxAttempts)
+	sender.DeadLetterUnpublished(ctx, projectID, deadLetterTopic, publishing.Wait(publishCtx))
 
 	fmt.Fprint(w, "Finished execution")
 }
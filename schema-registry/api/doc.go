@@ -0,0 +1,25 @@
+// Copyright 2020 Syntio Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package api holds the gRPC API of the Schema Registry, generated from registry.proto, including the Go client:
+//
+//  conn, err := grpc.Dial("schema-registry:8080", grpc.WithInsecure())
+//  ...
+//  client := api.NewRegistryClient(conn)
+//  schema, err := client.GetLatest(ctx, &api.GetLatestRequest{Id: "1234"})
+//
+// The API is served on the same port as the REST API, over HTTP/2 without TLS (h2c) or TLS.
+package api
+
+//go:generate protoc --go_out=plugins=grpc,paths=source_relative:. registry.proto
@@ -0,0 +1,105 @@
+// Copyright 2020 Syntio Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package business_logic
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/syntio/schema-registry/model/dto"
+)
+
+// Kinds of schema changes.
+const (
+	ChangeCreated        = "created"
+	ChangeVersionAdded   = "version-added"
+	ChangeVersionRemoved = "version-removed"
+)
+
+// changeBufferSize is the number of changes buffered for a subscriber. Changes are dropped for subscribers which
+// fall further behind, so a slow watcher never blocks a registration.
+const changeBufferSize = 64
+
+// changeSubscriber receives the changes of a namespace, or of a single schema if schemaId is set.
+type changeSubscriber struct {
+	namespace string
+	schemaId  string
+	changes   chan dto.SchemaChangeDTO
+}
+
+var (
+	subscribersLock sync.Mutex
+	subscribers     = make(map[*changeSubscriber]bool)
+)
+
+// SubscribeChanges subscribes to the changes of the schemas registered through this instance of the registry. An
+// empty namespace matches every namespace, an empty schemaId every schema of the namespace.
+//
+// The feed is per instance: changes are published in memory by the instance which makes them, so a subscriber
+// misses the changes made through any other instance of the registry, e.g. when Cloud Run scales it out, and those
+// made while it isn't subscribed. Subscribers which must see every change have to read the storage as well.
+//
+// The output of this function is the channel of changes and a function ending the subscription, which closes the
+// channel.
+func SubscribeChanges(namespace, schemaId string) (<-chan dto.SchemaChangeDTO, func()) {
+	subscriber := &changeSubscriber{
+		namespace: namespace,
+		schemaId:  schemaId,
+		changes:   make(chan dto.SchemaChangeDTO, changeBufferSize),
+	}
+
+	subscribersLock.Lock()
+	subscribers[subscriber] = true
+	subscribersLock.Unlock()
+
+	var once sync.Once
+	return subscriber.changes, func() {
+		once.Do(func() {
+			subscribersLock.Lock()
+			delete(subscribers, subscriber)
+			subscribersLock.Unlock()
+			close(subscriber.changes)
+		})
+	}
+}
+
+// publishChange delivers a schema change to every matching subscriber.
+func publishChange(kind, namespace, schemaId string, version int32, schemaType string) {
+	change := dto.SchemaChangeDTO{
+		Kind:       kind,
+		Id:         schemaId,
+		Namespace:  namespace,
+		Version:    version,
+		SchemaType: schemaType,
+		Time:       time.Now(),
+	}
+
+	subscribersLock.Lock()
+	defer subscribersLock.Unlock()
+	for subscriber := range subscribers {
+		if subscriber.namespace != "" && subscriber.namespace != namespace {
+			continue
+		}
+		if subscriber.schemaId != "" && subscriber.schemaId != schemaId {
+			continue
+		}
+		select {
+		case subscriber.changes <- change:
+		default:
+			log.Printf("Change subscriber is too slow, dropped %s change of schema %s\n", kind, schemaId)
+		}
+	}
+}
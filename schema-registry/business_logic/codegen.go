@@ -0,0 +1,43 @@
+// Copyright 2020 Syntio Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package business_logic
+
+import (
+	"context"
+
+	"github.com/syntio/schema-registry/codegen"
+	"github.com/syntio/schema-registry/util"
+)
+
+// GenerateCode generates source code of types matching a schema version. The root type is named after the schema
+// unless the options name it.
+//
+// The output of this function is the generated source code and an error.
+func GenerateCode(ctx context.Context, namespace, schemaId string, version int32, options codegen.Options) ([]byte,
+	error) {
+	schema, err := databaseExecutor.GetSchemaByIdAndVersion(ctx, namespace, schemaId, version)
+	if err != nil {
+		return nil, err
+	}
+	specification, err := util.SchemaBase64Decode(schema.SchemaDetails[0].Specification)
+	if err != nil {
+		return nil, err
+	}
+
+	if options.TypeName == "" {
+		options.TypeName = schema.Name
+	}
+	return codegen.Generate(schema.SchemaType, specification, options)
+}
@@ -0,0 +1,114 @@
+// Copyright 2020 Syntio Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package business_logic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/syntio/schema-registry/conversion"
+	"github.com/syntio/schema-registry/model"
+	"github.com/syntio/schema-registry/model/dto"
+	"github.com/syntio/schema-registry/util"
+)
+
+// convertSchema retrieves a schema version and converts its specification to another schema type.
+//
+// The output of this function is the retrieved schema, the converted specification and an error.
+func convertSchema(ctx context.Context, namespace, schemaId string, version int32, schemaType string) (*model.Schema,
+	[]byte, error) {
+	schema, err := databaseExecutor.GetSchemaByIdAndVersion(ctx, namespace, schemaId, version)
+	if err != nil {
+		return nil, nil, err
+	}
+	specification, err := util.SchemaBase64Decode(schema.SchemaDetails[0].Specification)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	converted, err := conversion.Convert(schema.SchemaType, schemaType, specification, schema.Name)
+	if err != nil {
+		return nil, nil, err
+	}
+	return schema, converted, nil
+}
+
+// GetConvertedSchema retrieves a schema version converted to another schema type, e.g. a JSON schema as an Avro
+// schema. The converted schema isn't registered.
+//
+// The output of this function is a marshaled schema with the converted specification and an error.
+func GetConvertedSchema(ctx context.Context, namespace, schemaId string, version int32, schemaType string) ([]byte,
+	error) {
+	schema, converted, err := convertSchema(ctx, namespace, schemaId, version, schemaType)
+	if err != nil {
+		return nil, err
+	}
+
+	schema.SchemaType = schemaType
+	schema.SchemaDetails[0].Specification = util.SchemaBase64Encode(converted)
+	schema.SchemaDetails[0].SchemaHash = util.CalculateSchemaHash(converted)
+	return json.Marshal(schema)
+}
+
+// RegisterConversion registers a schema version converted to another schema type as a sibling schema in the same
+// namespace. The relationship is recorded on both schemas.
+//
+// The output of this function is a marshaled insert info of the sibling schema and an error.
+func RegisterConversion(ctx context.Context, namespace, schemaId string, version int32, schemaType string) ([]byte,
+	error) {
+	schema, converted, err := convertSchema(ctx, namespace, schemaId, version, schemaType)
+	if err != nil {
+		return nil, err
+	}
+
+	insertInfo, added, violations, err := createSchema(ctx, schemaNamespace(schema), dto.SchemaDTO{
+		Description:   fmt.Sprintf("Converted from schema %s version %d. %s", schema.Id, version, schema.Description),
+		Specification: string(converted),
+		Name:          schema.Name,
+		SchemaType:    schemaType,
+		Compatibility: schema.Compatibility,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = databaseExecutor.AddSchemaLink(ctx, namespace, schema.Id, model.SchemaLink{
+		Relation:   model.ConvertedToRelation,
+		SchemaId:   insertInfo.Id,
+		Version:    insertInfo.Version,
+		SchemaType: schemaType,
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = databaseExecutor.AddSchemaLink(ctx, namespace, insertInfo.Id, model.SchemaLink{
+		Relation:   model.ConvertedFromRelation,
+		SchemaId:   schema.Id,
+		Version:    version,
+		SchemaType: schema.SchemaType,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	message := "Converted schema added"
+	if !added {
+		message = "Converted schema already exists in the registry"
+	}
+	response := util.MapToResponse(insertInfo, message)
+	response.Lint = violations
+	return json.Marshal(response)
+}
@@ -0,0 +1,55 @@
+// Copyright 2020 Syntio Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package business_logic
+
+import (
+	"context"
+
+	"github.com/syntio/schema-registry/database"
+	"github.com/syntio/schema-registry/docs"
+)
+
+// RenderVersionDocs renders the documentation of a schema version in the given format (HTML or Markdown).
+func RenderVersionDocs(ctx context.Context, namespace, schemaId string, version int32, format string,
+	links docs.Links) ([]byte, error) {
+	schema, err := databaseExecutor.GetSchemaByIdAndVersion(ctx, namespace, schemaId, version)
+	if err != nil {
+		return nil, err
+	}
+	document, err := docs.Describe(schema, schema.SchemaDetails[0])
+	if err != nil {
+		return nil, err
+	}
+	return docs.RenderVersion(document, format, links)
+}
+
+// RenderHistoryDocs renders the version history of a schema in the given format (HTML or Markdown).
+func RenderHistoryDocs(ctx context.Context, namespace, schemaId, format string, links docs.Links) ([]byte, error) {
+	schema, found := databaseExecutor.GetSchemaById(ctx, namespace, schemaId)
+	if !found {
+		return nil, database.ErrNotFound
+	}
+	return docs.RenderHistory(schema, format, links)
+}
+
+// RenderCatalogDocs renders the list of schemas of a namespace, or of every namespace if the namespace is empty, in
+// the given format (HTML or Markdown).
+func RenderCatalogDocs(ctx context.Context, namespace, format string, links docs.Links) ([]byte, error) {
+	schemas, err := databaseExecutor.GetSchemas(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	return docs.RenderCatalog(schemas, format, links)
+}
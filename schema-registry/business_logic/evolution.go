@@ -0,0 +1,371 @@
+// Copyright 2020 Syntio Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package business_logic
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/syntio/schema-registry/database"
+	"github.com/syntio/schema-registry/metrics"
+	"github.com/syntio/schema-registry/model"
+	"github.com/syntio/schema-registry/model/dto"
+	"github.com/syntio/schema-registry/util"
+)
+
+// Outcomes of a schema evolution request.
+const (
+	OutcomeCreated       = "created"
+	OutcomeExists        = "exists"
+	OutcomePending       = "pending"
+	OutcomeDisabled      = "disabled"
+	OutcomeLimitExceeded = "limit-exceeded"
+	OutcomeRejected      = "rejected"
+)
+
+// ErrNotInferred is returned when no schema could be inferred from the evolution data.
+var ErrNotInferred = errors.New("schema couldn't be generated")
+
+// ErrEvolutionDisabled is returned when the evolution policy of a schema disables evolution.
+var ErrEvolutionDisabled = errors.New("schema evolution is disabled")
+
+// ErrEvolutionLimit is returned when the daily limit of autogenerated versions of a schema is reached.
+var ErrEvolutionLimit = errors.New("daily limit of autogenerated versions reached")
+
+// ErrEvolutionRejected is returned when a reviewer already rejected the evolved schema.
+var ErrEvolutionRejected = errors.New("evolved schema was rejected")
+
+// ErrNotPending is returned when an already approved or rejected evolution is approved or rejected again.
+var ErrNotPending = errors.New("evolution isn't pending")
+
+// ErrInvalidPolicy is returned when an evolution policy has an unknown mode or a negative limit.
+var ErrInvalidPolicy = errors.New("invalid evolution policy")
+
+// EvolveSchema infers a new version of a schema from the evolution data and applies the evolution policy of the
+// schema to it:
+//  - disabled: the evolution is refused (ErrEvolutionDisabled)
+//  - auto: the inferred schema is registered as a new, autogenerated version
+//  - approval: the inferred schema is put into the approval queue of the schema
+//
+// The number of autogenerated versions per day is limited by the policy (ErrEvolutionLimit). If dryRun is set,
+// nothing is persisted and the response describes the would-be schema, its diff to the latest version and the
+// outcome the evolution would have.
+//
+// The output of this function is a marshaled evolution result, the outcome of the evolution and an error.
+func EvolveSchema(ctx context.Context, namespace, schemaId string, evolution dto.EvolutionDTO,
+	dryRun bool) ([]byte, string, error) {
+	schema, found := databaseExecutor.GetSchemaById(ctx, namespace, schemaId)
+	if !found {
+		return nil, "", database.ErrNotFound
+	}
+
+	generatedSchema, isGenerated, err := Evolve(evolution)
+	if err != nil {
+		return nil, "", err
+	}
+	if !isGenerated {
+		return nil, "", ErrNotInferred
+	}
+
+	result := &dto.EvolutionResultDTO{
+		Id:            schema.Id,
+		DryRun:        dryRun,
+		Specification: string(generatedSchema),
+	}
+	if len(schema.SchemaDetails) > 0 {
+		latest, err := util.SchemaBase64Decode(schema.SchemaDetails[len(schema.SchemaDetails)-1].Specification)
+		if err != nil {
+			return nil, "", err
+		}
+		result.Diff = util.Diff(latest, generatedSchema)
+	}
+
+	err = applyEvolutionPolicy(ctx, schema, evolution.Format, generatedSchema, result)
+	if !dryRun && result.Outcome != "" {
+		metrics.Evolutions.WithLabelValues(result.Outcome).Inc()
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	response, err := json.Marshal(result)
+	return response, result.Outcome, err
+}
+
+// applyEvolutionPolicy decides the outcome of an evolution and, unless the evolution is a dry run, persists it.
+func applyEvolutionPolicy(ctx context.Context, schema *model.Schema, format string, generatedSchema []byte,
+	result *dto.EvolutionResultDTO) error {
+	namespace := schemaNamespace(schema)
+	// An existing specification is answered before any limit or quota is checked, since it adds nothing
+	hash := util.CalculateSchemaHash(generatedSchema)
+	if version, exists := existingVersion(schema, hash); exists {
+		result.Outcome = OutcomeExists
+		result.Version = version
+		result.Message = fmt.Sprintf("Schema already exists on id: %s ", schema.Id)
+		return nil
+	}
+
+	policy := evolutionPolicy(schema)
+
+	if policy.Mode == model.EvolutionDisabled {
+		result.Outcome = OutcomeDisabled
+		result.Message = "Evolution is disabled for the schema"
+		if !result.DryRun {
+			return ErrEvolutionDisabled
+		}
+		return nil
+	}
+
+	// Evolutions waiting for an approval count toward the daily limit, an evolution which is already waiting isn't
+	// queued again and a rejected one stays rejected
+	var pending []*model.PendingEvolution
+	if policy.Mode == model.EvolutionApproval {
+		var err error
+		if pending, err = databaseExecutor.GetPendingEvolutions(ctx, namespace, schema.Id); err != nil {
+			return err
+		}
+		if rejected := evolutionWithHash(pending, model.RejectedStatus, hash); rejected != nil {
+			result.Outcome = OutcomeRejected
+			result.PendingId = rejected.Id
+			result.Message = "Evolved schema was rejected by a reviewer"
+			if !result.DryRun {
+				return fmt.Errorf("%w: evolution %s", ErrEvolutionRejected, rejected.Id)
+			}
+			return nil
+		}
+		if queued := evolutionWithHash(pending, model.PendingStatus, hash); queued != nil {
+			result.Outcome = OutcomePending
+			result.PendingId = queued.Id
+			result.Message = "Evolved schema is already waiting for an approval"
+			return nil
+		}
+	}
+
+	limit := policy.MaxAutogeneratedPerDay
+	if limit > 0 && autogeneratedToday(schema)+pendingToday(pending) >= limit {
+		result.Outcome = OutcomeLimitExceeded
+		result.Message = fmt.Sprintf("At most %d versions can be autogenerated per day", limit)
+		if !result.DryRun {
+			return fmt.Errorf("%w: %d versions per day", ErrEvolutionLimit, limit)
+		}
+		return nil
+	}
+
+	if err := checkNewVersion(schema, generatedSchema); err != nil {
+		if result.DryRun {
+			result.Outcome = OutcomeRejected
+			result.Message = err.Error()
+			return nil
+		}
+		return err
+	}
+
+	if policy.Mode == model.EvolutionApproval {
+		result.Outcome = OutcomePending
+		result.Message = "Evolved schema is waiting for an approval"
+		if result.DryRun {
+			return nil
+		}
+		pendingId, err := databaseExecutor.CreatePendingEvolution(ctx, namespace, &model.PendingEvolution{
+			SchemaId:      schema.Id,
+			Format:        format,
+			Specification: util.SchemaBase64Encode(generatedSchema),
+			Diff:          result.Diff,
+			Status:        model.PendingStatus,
+			CreationDate:  time.Now(),
+		})
+		result.PendingId = pendingId
+		return err
+	}
+
+	result.Outcome = OutcomeCreated
+	result.Version = latestVersion(schema) + 1
+	result.Message = "Successfully updated schema"
+	if result.DryRun {
+		return nil
+	}
+	insertInfo, updated, err := databaseExecutor.UpdateSchemaById(ctx, namespace, schema.Id, generatedSchema, true)
+	if err != nil {
+		return err
+	}
+	result.Version = insertInfo.Version
+	if !updated {
+		result.Outcome = OutcomeExists
+		result.Message = fmt.Sprintf("Schema already exists on id: %s ", insertInfo.Id)
+		return nil
+	}
+	publishChange(ChangeVersionAdded, namespace, schema.Id, insertInfo.Version, schema.SchemaType)
+	return nil
+}
+
+// evolutionPolicy returns the evolution policy of a schema. Schemas without a policy inherit the evolution default
+// of their namespace, and evolution is automatic if neither defines a mode.
+func evolutionPolicy(schema *model.Schema) model.EvolutionPolicy {
+	policy := cfg.Namespace(schemaNamespace(schema)).Evolution
+	if schema.EvolutionPolicy != nil {
+		policy = *schema.EvolutionPolicy
+	}
+	if policy.Mode == "" {
+		policy.Mode = model.EvolutionAuto
+	}
+	return policy
+}
+
+// autogeneratedToday counts the versions of a schema autogenerated during the current UTC day.
+func autogeneratedToday(schema *model.Schema) int {
+	now := time.Now().UTC()
+	startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	count := 0
+	for _, details := range schema.SchemaDetails {
+		if details.Autogenerated && !details.CreationDate.Before(startOfDay) {
+			count++
+		}
+	}
+	return count
+}
+
+// pendingToday counts the evolutions created since midnight UTC which are still waiting for an approval.
+func pendingToday(pending []*model.PendingEvolution) int {
+	now := time.Now().UTC()
+	startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	count := 0
+	for _, evolution := range pending {
+		if evolution.Status == model.PendingStatus && !evolution.CreationDate.Before(startOfDay) {
+			count++
+		}
+	}
+	return count
+}
+
+// evolutionWithHash returns the evolution of the approval queue with the given status, e.g. waiting for an approval
+// or rejected, whose specification has the given hash, or nil if there is none.
+func evolutionWithHash(pending []*model.PendingEvolution, status, hash string) *model.PendingEvolution {
+	for _, evolution := range pending {
+		if evolution.Status != status {
+			continue
+		}
+		specification, err := util.SchemaBase64Decode(evolution.Specification)
+		if err == nil && util.CalculateSchemaHash(specification) == hash {
+			return evolution
+		}
+	}
+	return nil
+}
+
+// GetEvolutionPolicy returns the marshaled evolution policy in effect for a schema.
+func GetEvolutionPolicy(ctx context.Context, namespace, schemaId string) ([]byte, error) {
+	schema, found := databaseExecutor.GetSchemaById(ctx, namespace, schemaId)
+	if !found {
+		return nil, database.ErrNotFound
+	}
+	policy := evolutionPolicy(schema)
+	return json.Marshal(dto.EvolutionPolicyDTO{
+		Mode:                   policy.Mode,
+		MaxAutogeneratedPerDay: policy.MaxAutogeneratedPerDay,
+	})
+}
+
+// SetEvolutionPolicy validates and stores the evolution policy of a schema.
+//
+// The output of this function is the marshaled stored policy and an error.
+func SetEvolutionPolicy(ctx context.Context, namespace, schemaId string, policyDTO dto.EvolutionPolicyDTO) ([]byte,
+	error) {
+	mode := strings.ToLower(policyDTO.Mode)
+	if mode != model.EvolutionDisabled && mode != model.EvolutionAuto && mode != model.EvolutionApproval {
+		return nil, fmt.Errorf("%w: unknown mode %q", ErrInvalidPolicy, policyDTO.Mode)
+	}
+	if policyDTO.MaxAutogeneratedPerDay < 0 {
+		return nil, fmt.Errorf("%w: negative limit of autogenerated versions", ErrInvalidPolicy)
+	}
+
+	policy := &model.EvolutionPolicy{
+		Mode:                   mode,
+		MaxAutogeneratedPerDay: policyDTO.MaxAutogeneratedPerDay,
+	}
+	if err := databaseExecutor.SetEvolutionPolicy(ctx, namespace, schemaId, policy); err != nil {
+		return nil, err
+	}
+	policyDTO.Mode = mode
+	return json.Marshal(policyDTO)
+}
+
+// ListPendingEvolutions returns the marshaled approval queue of a schema, including already reviewed evolutions.
+func ListPendingEvolutions(ctx context.Context, namespace, schemaId string) ([]byte, error) {
+	pending, err := databaseExecutor.GetPendingEvolutions(ctx, namespace, schemaId)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(pending)
+}
+
+// ApprovePendingEvolution registers a queued evolution as a new autogenerated version of its schema.
+//
+// The output of this function is a marshaled insert info object and an error.
+func ApprovePendingEvolution(ctx context.Context, namespace, schemaId, pendingId string) ([]byte, error) {
+	pending, err := reviewablePendingEvolution(ctx, namespace, schemaId, pendingId)
+	if err != nil {
+		return nil, err
+	}
+	specification, err := util.SchemaBase64Decode(pending.Specification)
+	if err != nil {
+		return nil, err
+	}
+
+	insertInfo, _, _, err := updateSchema(ctx, namespace, schemaId, specification, true)
+	if err != nil {
+		return nil, err
+	}
+
+	pending.Status = model.ApprovedStatus
+	pending.Version = insertInfo.Version
+	if err = databaseExecutor.UpdatePendingEvolution(ctx, namespace, pending); err != nil {
+		return nil, err
+	}
+	return json.Marshal(util.MapToResponse(insertInfo, "Evolution approved"))
+}
+
+// RejectPendingEvolution marks a queued evolution as rejected, the evolved schema is never registered.
+//
+// The output of this function is the marshaled rejected evolution and an error.
+func RejectPendingEvolution(ctx context.Context, namespace, schemaId, pendingId string) ([]byte, error) {
+	pending, err := reviewablePendingEvolution(ctx, namespace, schemaId, pendingId)
+	if err != nil {
+		return nil, err
+	}
+	pending.Status = model.RejectedStatus
+	if err = databaseExecutor.UpdatePendingEvolution(ctx, namespace, pending); err != nil {
+		return nil, err
+	}
+	return json.Marshal(pending)
+}
+
+// reviewablePendingEvolution retrieves a queued evolution which wasn't approved or rejected yet.
+func reviewablePendingEvolution(ctx context.Context, namespace, schemaId,
+	pendingId string) (*model.PendingEvolution, error) {
+	pending, found := databaseExecutor.GetPendingEvolution(ctx, namespace, schemaId, pendingId)
+	if !found {
+		return nil, database.ErrNotFound
+	}
+	if pending.Status != model.PendingStatus {
+		return nil, fmt.Errorf("%w: evolution %s is %s", ErrNotPending, pendingId, pending.Status)
+	}
+	return pending, nil
+}
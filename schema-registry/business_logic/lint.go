@@ -0,0 +1,61 @@
+// Copyright 2020 Syntio Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package business_logic
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/syntio/schema-registry/lint"
+	"github.com/syntio/schema-registry/model/dto"
+)
+
+// ErrLintFailed is returned when a specification violates a linting rule with the "error" severity.
+var ErrLintFailed = errors.New("schema violates linting rules")
+
+// LintError carries every violation found in a specification refused by the linting rules of its namespace.
+// It matches ErrLintFailed with errors.Is.
+type LintError struct {
+	Violations []dto.LintViolationDTO
+}
+
+func (e *LintError) Error() string {
+	return fmt.Sprintf("%v: %d violation(s)", ErrLintFailed, len(e.Violations))
+}
+
+func (e *LintError) Unwrap() error {
+	return ErrLintFailed
+}
+
+// lintSpecification checks a specification against the linting rules of a namespace.
+//
+// The output of this function is the found violations and a LintError if any of them has the "error" severity.
+func lintSpecification(namespace, schemaType, name string, specification []byte) ([]dto.LintViolationDTO, error) {
+	violations := lint.Check(cfg.Namespace(namespace).Lint, schemaType, name, specification)
+
+	violationDTOs := make([]dto.LintViolationDTO, 0, len(violations))
+	for _, violation := range violations {
+		violationDTOs = append(violationDTOs, dto.LintViolationDTO{
+			Rule:     violation.Rule,
+			Severity: violation.Severity,
+			Location: violation.Location,
+			Message:  violation.Message,
+		})
+	}
+	if lint.HasErrors(violations) {
+		return nil, &LintError{Violations: violationDTOs}
+	}
+	return violationDTOs, nil
+}
@@ -17,56 +17,136 @@ package business_logic
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"strings"
+	"time"
 
+	"github.com/syntio/janitor-common/validator"
+	"github.com/syntio/janitor-common/validator/convert"
+	"github.com/syntio/janitor-common/validator/impl"
+	"github.com/syntio/schema-registry/compatibility"
 	"github.com/syntio/schema-registry/configuration"
 	"github.com/syntio/schema-registry/database"
 	"github.com/syntio/schema-registry/database/firestore"
+	"github.com/syntio/schema-registry/model"
 	"github.com/syntio/schema-registry/model/dto"
 	"github.com/syntio/schema-registry/util"
 )
 
 var databaseExecutor database.DBExecutor
 
+// defaultAccessTrackingMinutes is used when the config file doesn't define how often version accesses are tracked.
+const defaultAccessTrackingMinutes = 60
+
+var cfg configuration.Config
+
+// ErrQuotaExceeded is returned when a namespace quota doesn't allow registering a new schema or version.
+var ErrQuotaExceeded = errors.New("namespace quota exceeded")
+
+// ErrIncompatible is returned when a new schema version violates the compatibility mode of the schema.
+var ErrIncompatible = errors.New("schema version is incompatible")
+
 func init() {
-	cfg := configuration.RetrieveConfig()
+	cfg = configuration.RetrieveConfig()
+	validator.Configure(cfg.Validators)
+	impl.ConfigureProtobuf(cfg.Protobuf.DescriptorCacheSize, cfg.Protobuf.StrictUnknownFields)
 
+	accessTracking := cfg.Retention.AccessTrackingMinutes
+	if accessTracking <= 0 {
+		accessTracking = defaultAccessTrackingMinutes
+	}
 	databaseExecutor = &firestore.FirestoreDB{
-		Collection: cfg.FirestoreCollectionName,
+		Collection:             cfg.FirestoreCollectionName,
+		AccessTrackingInterval: accessTracking * time.Minute,
 	}
 }
 
+// ServerConfig returns the server configuration from the config file.
+func ServerConfig() configuration.ServerConfig {
+	return cfg.Server
+}
+
+// TracingConfig returns the tracing configuration from the config file.
+func TracingConfig() configuration.TracingConfig {
+	return cfg.Tracing
+}
+
+// CheckStorage checks if the underlying database can be reached. database.ErrUnavailable is returned if it can't.
+func CheckStorage(ctx context.Context) error {
+	return databaseExecutor.Ping(ctx)
+}
+
 // GetSchema invokes the database executor retrieving the latest schema.
 //
-// The input arguments are the request context, a schemaId and a version of a schema.
+// The input arguments are the request context, a namespace, a schemaId and a version of a schema.
 //
-// The output of this function is a marhsaled schema and a boolean which indicates if schema was found.
-func GetSchema(ctx context.Context, schemaId string, version int32) ([]byte, bool) {
-	schemaInfo, found := databaseExecutor.GetSchemaByIdAndVersion(ctx, schemaId, version)
-	jsonResponse, err := json.Marshal(schemaInfo)
+// The output of this function is a marhsaled schema and an error. database.ErrNotFound is returned if the schema
+// version isn't registered, and database.ErrUnavailable if the storage can't be reached, so an outage isn't
+// reported as a missing version.
+func GetSchema(ctx context.Context, namespace, schemaId string, version int32) ([]byte, error) {
+	schemaInfo, err := databaseExecutor.GetSchemaByIdAndVersion(ctx, namespace, schemaId, version)
 	if err != nil {
-		return nil, found
+		return nil, err
 	}
-
-	return jsonResponse, found
+	return json.Marshal(schemaInfo)
 }
 
-// CreateSchema invokes the database executor to create a new schema document.
+// GetLatestSchema retrieves a schema with its latest version only.
 //
-// The input arguments are the request context, and a data transfer object,
-// in which are all required assets  for a new schema
+// The output of this function is a marshaled schema and an error.
+func GetLatestSchema(ctx context.Context, namespace, schemaId string) ([]byte, error) {
+	schema, found := databaseExecutor.GetSchemaById(ctx, namespace, schemaId)
+	if !found || len(schema.SchemaDetails) == 0 {
+		return nil, database.ErrNotFound
+	}
+	schema.SchemaDetails = schema.SchemaDetails[len(schema.SchemaDetails)-1:]
+	return json.Marshal(schema)
+}
+
+// GetSchemaWithVersions retrieves a schema with all of its versions.
 //
 // The output of this function is a marshaled schema and an error.
-func CreateSchema(ctx context.Context, schemaInfoDTO dto.SchemaDTO) ([]byte, error) {
-	insertInfo, added, err := databaseExecutor.CreateSchema(ctx, &schemaInfoDTO)
-	var message string
+func GetSchemaWithVersions(ctx context.Context, namespace, schemaId string) ([]byte, error) {
+	schema, found := databaseExecutor.GetSchemaById(ctx, namespace, schemaId)
+	if !found {
+		return nil, database.ErrNotFound
+	}
+	return json.Marshal(schema)
+}
+
+// GetSchemas retrieves the schemas of a namespace, or of every namespace if the namespace is empty. Versions of the
+// schemas aren't included.
+//
+// The output of this function is a marshaled list of schemas and an error.
+func GetSchemas(ctx context.Context, namespace string) ([]byte, error) {
+	schemas, err := databaseExecutor.GetSchemas(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	if schemas == nil {
+		schemas = make([]*model.Schema, 0)
+	}
+	return json.Marshal(schemas)
+}
 
+// CreateSchema invokes the database executor to create a new schema document.
+//
+// The input arguments are the request context, a namespace, and a data transfer object,
+// in which are all required assets  for a new schema. An empty namespace stands for the default namespace.
+// Schemas without a compatibility mode inherit the compatibility default of the namespace.
+//
+// The output of this function is a marshaled schema and an error. ErrQuotaExceeded is returned if the namespace
+// already holds the maximum number of schemas, a LintError if the specification violates a linting rule of the
+// namespace with the "error" severity. Violations of "warn" rules are listed in the response.
+func CreateSchema(ctx context.Context, namespace string, schemaInfoDTO dto.SchemaDTO) ([]byte, error) {
+	insertInfo, added, violations, err := createSchema(ctx, namespace, schemaInfoDTO)
 	if err != nil {
 		return nil, err
 	}
 
+	var message string
 	if added {
 		message = "New Schema added"
 	} else {
@@ -74,6 +154,7 @@ func CreateSchema(ctx context.Context, schemaInfoDTO dto.SchemaDTO) ([]byte, err
 	}
 
 	response := util.MapToResponse(insertInfo, message)
+	response.Lint = violations
 
 	if jsonResponse, err := json.Marshal(response); err != nil {
 		return nil, err
@@ -83,7 +164,46 @@ func CreateSchema(ctx context.Context, schemaInfoDTO dto.SchemaDTO) ([]byte, err
 
 }
 
-// Evolve tries to construct a schema from a given message. JSON and CSV are supported for now
+// createSchema enforces the namespace linting rules, quota and defaults before creating a new schema document.
+//
+// The output of this function is the insert info, a flag indicating if a new schema was added, the violations of
+// "warn" linting rules and an error.
+func createSchema(ctx context.Context, namespace string, schemaInfoDTO dto.SchemaDTO) (*model.InsertInfo, bool,
+	[]dto.LintViolationDTO, error) {
+	if namespace == "" {
+		namespace = model.DefaultNamespace
+	}
+	nsCfg := cfg.Namespace(namespace)
+
+	violations, err := lintSpecification(namespace, schemaInfoDTO.SchemaType, schemaInfoDTO.Name,
+		[]byte(schemaInfoDTO.Specification))
+	if err != nil {
+		return nil, false, nil, err
+	}
+
+	if nsCfg.Quotas.MaxSchemas > 0 {
+		count, err := databaseExecutor.CountSchemas(ctx, namespace)
+		if err != nil {
+			return nil, false, nil, err
+		}
+		if count >= nsCfg.Quotas.MaxSchemas {
+			return nil, false, nil, fmt.Errorf("%w: namespace %s allows at most %d schemas", ErrQuotaExceeded, namespace,
+				nsCfg.Quotas.MaxSchemas)
+		}
+	}
+	if schemaInfoDTO.Compatibility == "" {
+		schemaInfoDTO.Compatibility = nsCfg.Compatibility
+	}
+
+	insertInfo, added, err := databaseExecutor.CreateSchema(ctx, namespace, &schemaInfoDTO)
+	if err == nil && added {
+		publishChange(ChangeCreated, namespace, insertInfo.Id, insertInfo.Version, schemaInfoDTO.SchemaType)
+	}
+	return insertInfo, added, violations, err
+}
+
+// Evolve tries to construct a schema from a given message. JSON and CSV are supported for now, and the formats
+// validated with a JSON Schema (YAML, MessagePack, CBOR and BSON) are converted into JSON to infer a JSON Schema.
 //
 // The input argument is the evolution data transfer object, which hold the format and data that
 // needs to be evolved.
@@ -91,9 +211,18 @@ func CreateSchema(ctx context.Context, schemaInfoDTO dto.SchemaDTO) ([]byte, err
 // The output of this function is a new generated schema []byte, a boolean that indicates succesful
 // creation and an error.
 func Evolve(evolution dto.EvolutionDTO) ([]byte, bool, error) {
+	format, data := evolution.Format, []byte(evolution.Data)
+	if convert.IsConvertible(format) {
+		var err error
+		if data, err = convert.ToJSON(format, data); err != nil {
+			return nil, false, err
+		}
+		format = "json"
+	}
+
 	results, err := util.CallFunction(fmt.Sprintf("schema_creation.%sSchemaDynamicCreation",
-		strings.ToUpper(evolution.Format)),
-		[]byte(evolution.Data))
+		strings.ToUpper(format)),
+		data)
 	if err != nil {
 		log.Println("Could not invoke Creation Method")
 		return nil, false, err
@@ -113,15 +242,17 @@ func Evolve(evolution dto.EvolutionDTO) ([]byte, bool, error) {
 
 // UpdateSchema invokes the databaseExecutor to update an existing schema document in the collection.
 //
-// The input argument is the request context, a schemaId and a specification data transfer object
+// The input argument is the request context, a namespace, a schemaId and a specification data transfer object
 // which is actually a wrapper structure, wrapping a new schema definition.
 //
-// The output of this function is a marshaled insert info object and an error.
+// The output of this function is a marshaled insert info object and an error. The new version has to respect the
+// version quota of the namespace (ErrQuotaExceeded), the compatibility mode of the schema (ErrIncompatible) and the
+// linting rules of the namespace (LintError). Violations of "warn" rules are listed in the response.
 func UpdateSchema(ctx context.Context,
-	schemaId string,
+	namespace, schemaId string,
 	specification *dto.SpectificationDTO, autogenerated bool) ([]byte, error) {
-	insertInfo, updated, err := databaseExecutor.
-		UpdateSchemaById(ctx, schemaId, []byte(specification.Specification), false)
+	insertInfo, updated, violations, err := updateSchema(ctx, namespace, schemaId,
+		[]byte(specification.Specification), autogenerated)
 	var message string
 
 	if err != nil {
@@ -134,6 +265,7 @@ func UpdateSchema(ctx context.Context,
 	}
 
 	response := util.MapToResponse(insertInfo, message)
+	response.Lint = violations
 
 	if jsonResponse, err := json.Marshal(response); err != nil {
 		return nil, err
@@ -143,16 +275,118 @@ func UpdateSchema(ctx context.Context,
 
 }
 
+// updateSchema checks the new specification against the namespace quotas, the schema compatibility mode and, unless
+// the version is autogenerated, the namespace linting rules, and invokes the databaseExecutor to add it as a new
+// version of the schema. Inferred schemas carry no descriptions or naming conventions, so they aren't linted.
+//
+// The output of this function is the insert info, a flag indicating if a new version was added, the violations of
+// "warn" linting rules and an error.
+func updateSchema(ctx context.Context, namespace, schemaId string, specification []byte,
+	autogenerated bool) (*model.InsertInfo, bool, []dto.LintViolationDTO, error) {
+	schema, found := databaseExecutor.GetSchemaById(ctx, namespace, schemaId)
+	if !found {
+		return nil, false, nil, database.ErrNotFound
+	}
+
+	var violations []dto.LintViolationDTO
+	if !autogenerated {
+		var err error
+		violations, err = lintSpecification(schemaNamespace(schema), schema.SchemaType, schema.Name, specification)
+		if err != nil {
+			return nil, false, nil, err
+		}
+	}
+	if err := checkNewVersion(schema, specification); err != nil {
+		return nil, false, nil, err
+	}
+
+	insertInfo, updated, err := databaseExecutor.UpdateSchemaById(ctx, namespace, schemaId, specification,
+		autogenerated)
+	if err == nil && updated {
+		publishChange(ChangeVersionAdded, schemaNamespace(schema), schema.Id, insertInfo.Version, schema.SchemaType)
+	}
+	return insertInfo, updated, violations, err
+}
+
 // ListSchemas invokes the databaseExecutor to retireve all schema versions of a specific schema document.
 //
-// The input arguments are the request context and schemaId.
+// The input arguments are the request context, a namespace and schemaId.
 //
 // The output of this function is a marshaled insert info object and an error.
-func ListSchemas(ctx context.Context, schemaId string) ([]byte, error) {
-	res, err := databaseExecutor.GetSchemaVersions(ctx, schemaId)
+func ListSchemas(ctx context.Context, namespace, schemaId string) ([]byte, error) {
+	res, err := databaseExecutor.GetSchemaVersions(ctx, namespace, schemaId)
 	if err != nil {
 		return nil, err
 	}
 	return json.Marshal(res)
 
 }
+
+// checkNewVersion checks if a new specification can be added as a new version of the schema. The version quota
+// of the schema's namespace and the compatibility with the latest version of the schema are checked.
+//
+// The compatibility mode of the schema is used, or the namespace default if the schema doesn't define one.
+func checkNewVersion(schema *model.Schema, specification []byte) error {
+	if _, exists := existingVersion(schema, util.CalculateSchemaHash(specification)); exists {
+		// Nothing will be added, the existing version is returned
+		return nil
+	}
+
+	namespace := schemaNamespace(schema)
+	nsCfg := cfg.Namespace(namespace)
+
+	if limit := nsCfg.Quotas.MaxVersionsPerSchema; limit > 0 && len(schema.SchemaDetails) >= limit {
+		return fmt.Errorf("%w: namespace %s allows at most %d versions per schema", ErrQuotaExceeded, namespace,
+			limit)
+	}
+
+	if len(schema.SchemaDetails) == 0 {
+		return nil
+	}
+	mode := schema.Compatibility
+	if mode == "" {
+		mode = nsCfg.Compatibility
+	}
+	latest := schema.SchemaDetails[len(schema.SchemaDetails)-1]
+	previous, err := util.SchemaBase64Decode(latest.Specification)
+	if err != nil {
+		return err
+	}
+	if err := compatibility.Check(mode, schema.SchemaType, previous, specification); err != nil {
+		return fmt.Errorf("%w: %v", ErrIncompatible, err)
+	}
+	return nil
+}
+
+// existingVersion returns the version of a schema whose specification has the given hash, if there is one. The hashes
+// stored with the schema are looked up too, so the check doesn't depend on which versions are loaded.
+func existingVersion(schema *model.Schema, hash string) (int32, bool) {
+	if version, ok := schema.Hashes[hash]; ok {
+		return version, true
+	}
+	for _, details := range schema.SchemaDetails {
+		if details.SchemaHash == hash {
+			return details.Version, true
+		}
+	}
+	return 0, false
+}
+
+// schemaNamespace returns the namespace of a stored schema.
+func schemaNamespace(schema *model.Schema) string {
+	if schema.Namespace == "" {
+		return model.DefaultNamespace
+	}
+	return schema.Namespace
+}
+
+// latestVersion returns the highest version number a schema ever had, including removed versions.
+func latestVersion(schema *model.Schema) int32 {
+	latest := schema.LatestVersion
+	for _, details := range schema.SchemaDetails {
+		if details.Version > latest {
+			latest = details.Version
+		}
+	}
+	return latest
+}
@@ -0,0 +1,159 @@
+// Copyright 2020 Syntio Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package business_logic
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/syntio/schema-registry/database"
+	"github.com/syntio/schema-registry/model"
+	"github.com/syntio/schema-registry/model/dto"
+)
+
+// ErrInvalidRetention is returned when a retention policy has negative limits.
+var ErrInvalidRetention = errors.New("invalid retention policy")
+
+// retentionPolicy returns the retention policy of a schema. Schemas without a policy inherit the retention default
+// of their namespace.
+func retentionPolicy(schema *model.Schema) model.RetentionPolicy {
+	if schema.RetentionPolicy != nil {
+		return *schema.RetentionPolicy
+	}
+	return cfg.Namespace(schemaNamespace(schema)).Retention
+}
+
+// expiredVersions returns the versions of a schema which the retention policy removes at the given time.
+// The versions of the schema have to be ordered by version number.
+func expiredVersions(schema *model.Schema, policy model.RetentionPolicy, now time.Time) []int32 {
+	expired := make([]int32, 0)
+	count := len(schema.SchemaDetails)
+
+	for i, details := range schema.SchemaDetails {
+		if i == count-1 {
+			// The latest version is never removed
+			break
+		}
+		if policy.KeepManual && !details.Autogenerated {
+			continue
+		}
+
+		beyondKeepLast := policy.KeepLast > 0 && i < count-policy.KeepLast
+		inactive := policy.ExpireAutogeneratedAfterDays > 0 && details.Autogenerated &&
+			now.Sub(details.LastActivity()) > time.Duration(policy.ExpireAutogeneratedAfterDays)*24*time.Hour
+
+		if beyondKeepLast || inactive {
+			expired = append(expired, details.Version)
+		}
+	}
+	return expired
+}
+
+// compactSchema removes the versions of a schema expired by its retention policy.
+func compactSchema(ctx context.Context, namespace, schemaId string) (*dto.CompactionDTO, error) {
+	schema, found := databaseExecutor.GetSchemaById(ctx, namespace, schemaId)
+	if !found {
+		return nil, database.ErrNotFound
+	}
+
+	result := &dto.CompactionDTO{
+		Id:              schema.Id,
+		Namespace:       schemaNamespace(schema),
+		RemovedVersions: expiredVersions(schema, retentionPolicy(schema), time.Now()),
+	}
+	if len(result.RemovedVersions) == 0 {
+		return result, nil
+	}
+	if err := databaseExecutor.DeleteVersions(ctx, namespace, schemaId, result.RemovedVersions); err != nil {
+		return nil, err
+	}
+	for _, version := range result.RemovedVersions {
+		publishChange(ChangeVersionRemoved, result.Namespace, schema.Id, version, schema.SchemaType)
+	}
+	log.Printf("Compacted schema %s, removed versions %v\n", schemaId, result.RemovedVersions)
+	return result, nil
+}
+
+// CompactSchema removes the versions of a schema expired by its retention policy.
+//
+// The output of this function is a marshaled compaction result and an error.
+func CompactSchema(ctx context.Context, namespace, schemaId string) ([]byte, error) {
+	result, err := compactSchema(ctx, namespace, schemaId)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(result)
+}
+
+// CompactAll compacts every schema of a namespace, or of every namespace if the namespace is empty.
+// Schemas which couldn't be compacted are logged and skipped. Periodic compaction is triggered by a Cloud Scheduler
+// job, since the registry's instances get no CPU between requests.
+//
+// The output of this function is a marshaled list of compaction results and an error.
+func CompactAll(ctx context.Context, namespace string) ([]byte, error) {
+	schemas, err := databaseExecutor.GetSchemas(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*dto.CompactionDTO, 0, len(schemas))
+	for _, schema := range schemas {
+		result, err := compactSchema(ctx, namespace, schema.Id)
+		if err != nil {
+			log.Printf("Could not compact schema %s: %v\n", schema.Id, err)
+			continue
+		}
+		results = append(results, result)
+	}
+	return json.Marshal(results)
+}
+
+// GetRetentionPolicy returns the marshaled retention policy in effect for a schema.
+func GetRetentionPolicy(ctx context.Context, namespace, schemaId string) ([]byte, error) {
+	schema, found := databaseExecutor.GetSchemaById(ctx, namespace, schemaId)
+	if !found {
+		return nil, database.ErrNotFound
+	}
+	policy := retentionPolicy(schema)
+	return json.Marshal(dto.RetentionPolicyDTO{
+		KeepLast:                     policy.KeepLast,
+		KeepManual:                   policy.KeepManual,
+		ExpireAutogeneratedAfterDays: policy.ExpireAutogeneratedAfterDays,
+	})
+}
+
+// SetRetentionPolicy validates and stores the retention policy of a schema. The policy is applied on the next
+// compaction.
+//
+// The output of this function is the marshaled stored policy and an error.
+func SetRetentionPolicy(ctx context.Context, namespace, schemaId string, policyDTO dto.RetentionPolicyDTO) ([]byte,
+	error) {
+	if policyDTO.KeepLast < 0 || policyDTO.ExpireAutogeneratedAfterDays < 0 {
+		return nil, fmt.Errorf("%w: limits can't be negative", ErrInvalidRetention)
+	}
+	policy := &model.RetentionPolicy{
+		KeepLast:                     policyDTO.KeepLast,
+		KeepManual:                   policyDTO.KeepManual,
+		ExpireAutogeneratedAfterDays: policyDTO.ExpireAutogeneratedAfterDays,
+	}
+	if err := databaseExecutor.SetRetentionPolicy(ctx, namespace, schemaId, policy); err != nil {
+		return nil, err
+	}
+	return json.Marshal(policyDTO)
+}
@@ -0,0 +1,93 @@
+// Copyright 2020 Syntio Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package business_logic
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/syntio/janitor-common/validator"
+	"github.com/syntio/schema-registry/model/dto"
+	"github.com/syntio/schema-registry/util"
+)
+
+// ErrUnsupportedFormat is returned when a payload is validated in a format without a validator.
+var ErrUnsupportedFormat = errors.New("unsupported payload format")
+
+// ValidatePayload validates a payload against a schema version, using the same validators as the Central Consumer.
+// The payload format defaults to the schema type if it isn't given. The attributes are the message attributes the
+// validators depend on, e.g. messageType for Protobuf payloads or encoding for Avro payloads.
+//
+// The output of this function is a marshaled validation result and an error.
+func ValidatePayload(ctx context.Context, namespace, schemaId string, version int32, format string,
+	attributes map[string]string, payload []byte) ([]byte, error) {
+	schema, err := databaseExecutor.GetSchemaByIdAndVersion(ctx, namespace, schemaId, version)
+	if err != nil {
+		return nil, err
+	}
+	if format == "" {
+		format = schema.SchemaType
+	}
+
+	payloadValidator, err := validator.ForFormat(format)
+	if errors.Is(err, validator.ErrUnknownFormat) {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedFormat, err)
+	} else if err != nil {
+		return nil, err
+	}
+	defer validator.Close(payloadValidator)
+	validator.UseAttributes(payloadValidator, attributes)
+	specification, err := util.SchemaBase64Decode(schema.SchemaDetails[0].Specification)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &dto.ValidationDTO{
+		Id:      schema.Id,
+		Version: version,
+		Format:  format,
+	}
+	result.Valid, result.Violations = validatePayload(payloadValidator, payload, specification)
+	return json.Marshal(result)
+}
+
+// validatePayload validates a payload with a validator and lists the violations of an invalid payload. Errors of the
+// validator make the payload invalid, as the Central Consumer doesn't transmit such messages to the valid topic.
+func validatePayload(payloadValidator validator.Validator, payload, specification []byte) (bool, []dto.ViolationDTO) {
+	result, err := payloadValidator.Validate(payload, specification)
+	if err != nil {
+		return false, []dto.ViolationDTO{{Description: err.Error()}}
+	}
+	if result.Valid {
+		return true, nil
+	}
+	if len(result.Violations) == 0 {
+		return false, []dto.ViolationDTO{{Description: "payload doesn't conform to the schema"}}
+	}
+
+	violations := make([]dto.ViolationDTO, 0, len(result.Violations))
+	for _, violation := range result.Violations {
+		violations = append(violations, dto.ViolationDTO{
+			Location:    violation.Location,
+			Rule:        violation.Rule,
+			Expected:    violation.Expected,
+			Actual:      violation.Actual,
+			Description: violation.Description,
+		})
+	}
+	return false, violations
+}
@@ -0,0 +1,130 @@
+// Copyright 2020 Syntio Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command codegen writes Go types generated from a registered schema into a package directory. It is meant for
+// go generate workflows, e.g.:
+//
+//  //go:generate go run github.com/syntio/schema-registry/cli/codegen -id 1234 -version 2 -out ./events
+//
+// The registry URL is taken from the -registry flag or the SCHEMA_REGISTRY_URL environment variable.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	registryURL := flag.String("registry", os.Getenv("SCHEMA_REGISTRY_URL"), "URL of the schema registry")
+	namespace := flag.String("namespace", "", "namespace of the schema")
+	id := flag.String("id", "", "ID of the schema")
+	version := flag.String("version", "1", "version of the schema")
+	language := flag.String("lang", "go", "language of the generated code")
+	out := flag.String("out", ".", "package directory the generated file is written to")
+	pkg := flag.String("package", "", "package name of the generated code, defaults to the directory name")
+	typeName := flag.String("type", "", "name of the root type, defaults to the schema name")
+	fileName := flag.String("file", "", "name of the generated file, defaults to the type name or the schema ID")
+	flag.Parse()
+
+	if *registryURL == "" || *id == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	directory, err := filepath.Abs(*out)
+	if err != nil {
+		log.Fatalf("Output directory is invalid: %v", err)
+	}
+	if *pkg == "" {
+		*pkg = packageName(filepath.Base(directory))
+	}
+	if *fileName == "" {
+		*fileName = defaultFileName(*typeName, *id)
+	}
+
+	source, err := fetchCode(*registryURL, *namespace, *id, *version, *language, *pkg, *typeName)
+	if err != nil {
+		log.Fatalf("Code couldn't be generated: %v", err)
+	}
+
+	if err = os.MkdirAll(directory, 0755); err != nil {
+		log.Fatalf("Output directory couldn't be created: %v", err)
+	}
+	path := filepath.Join(directory, *fileName)
+	if err = ioutil.WriteFile(path, source, 0644); err != nil {
+		log.Fatalf("Generated code couldn't be written: %v", err)
+	}
+	fmt.Println("Generated", path)
+}
+
+// fetchCode requests the generated code of a schema version from the schema registry.
+func fetchCode(registryURL, namespace, id, version, language, pkg, typeName string) ([]byte, error) {
+	path := fmt.Sprintf("/schema/%s/version/%s/codegen", url.PathEscape(id), url.PathEscape(version))
+	if namespace != "" {
+		path = "/ns/" + url.PathEscape(namespace) + path
+	}
+	query := url.Values{}
+	query.Set("lang", language)
+	query.Set("package", pkg)
+	if typeName != "" {
+		query.Set("type", typeName)
+	}
+
+	response, err := http.Get(strings.TrimSuffix(registryURL, "/") + path + "?" + query.Encode())
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status code [%v]: %s", response.StatusCode, body)
+	}
+	return body, nil
+}
+
+// packageName converts a directory name into a valid package name.
+func packageName(directory string) string {
+	name := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '_' {
+			return r
+		}
+		if r >= 'A' && r <= 'Z' {
+			return r + 'a' - 'A'
+		}
+		return -1
+	}, directory)
+	if name == "" || name[0] >= '0' && name[0] <= '9' {
+		return "schemas"
+	}
+	return name
+}
+
+// defaultFileName returns the name of the generated file: the type name if it is given, otherwise the schema ID.
+func defaultFileName(typeName, id string) string {
+	if typeName != "" {
+		return strings.ToLower(typeName) + ".go"
+	}
+	return "schema_" + strings.ToLower(id) + ".go"
+}
@@ -0,0 +1,146 @@
+// Copyright 2020 Syntio Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command docs exports the documentation of the schemas registered in a namespace as a static site, e.g.:
+//
+//  go run github.com/syntio/schema-registry/cli/docs -namespace payments -format markdown -out ./site
+//
+// The export holds a catalog page (index), a history page per schema and a page per schema version.
+// The registry URL is taken from the -registry flag or the SCHEMA_REGISTRY_URL environment variable.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/syntio/schema-registry/docs"
+	"github.com/syntio/schema-registry/model"
+)
+
+func main() {
+	registryURL := flag.String("registry", os.Getenv("SCHEMA_REGISTRY_URL"), "URL of the schema registry")
+	namespace := flag.String("namespace", "", "namespace of the exported schemas, all namespaces if empty")
+	format := flag.String("format", docs.FormatHTML, "format of the pages, html or markdown")
+	out := flag.String("out", "docs", "directory the pages are written to")
+	flag.Parse()
+
+	if *registryURL == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	client := registryClient{url: strings.TrimSuffix(*registryURL, "/"), namespace: *namespace}
+	var schemas []*model.Schema
+	if err := client.get("/schema/", &schemas); err != nil {
+		log.Fatalf("Schemas couldn't be listed: %v", err)
+	}
+
+	if err := os.MkdirAll(*out, 0755); err != nil {
+		log.Fatalf("Output directory couldn't be created: %v", err)
+	}
+	ext := docs.Extension(*format)
+	links := docs.Links{
+		Catalog: "index" + ext,
+		History: func(id string) string {
+			return fileName(id) + ext
+		},
+		Version: func(id string, version int32) string {
+			return fmt.Sprintf("%s-v%d%s", fileName(id), version, ext)
+		},
+	}
+
+	page, err := docs.RenderCatalog(schemas, *format, links)
+	if err != nil {
+		log.Fatalf("Catalog couldn't be rendered: %v", err)
+	}
+	write(*out, links.Catalog, page)
+
+	for _, listed := range schemas {
+		var schema model.Schema
+		if err = client.get("/schema/"+url.PathEscape(listed.Id), &schema); err != nil {
+			log.Printf("Schema %s couldn't be retrieved: %v", listed.Id, err)
+			continue
+		}
+		if page, err = docs.RenderHistory(&schema, *format, links); err != nil {
+			log.Printf("History of schema %s couldn't be rendered: %v", schema.Id, err)
+			continue
+		}
+		write(*out, links.History(schema.Id), page)
+
+		for _, version := range schema.SchemaDetails {
+			document, err := docs.Describe(&schema, version)
+			if err == nil {
+				page, err = docs.RenderVersion(document, *format, links)
+			}
+			if err != nil {
+				log.Printf("Version %d of schema %s couldn't be rendered: %v", version.Version, schema.Id, err)
+				continue
+			}
+			write(*out, links.Version(schema.Id, version.Version), page)
+		}
+	}
+	fmt.Println("Exported", len(schemas), "schemas to", *out)
+}
+
+// registryClient reads schemas from the REST API of the schema registry.
+type registryClient struct {
+	url       string
+	namespace string
+}
+
+// get requests a path of the schema registry and decodes the JSON response into the target.
+func (c registryClient) get(path string, target interface{}) error {
+	if c.namespace != "" {
+		path = "/ns/" + url.PathEscape(c.namespace) + path
+	}
+	response, err := http.Get(c.url + path)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("status code [%v]: %s", response.StatusCode, body)
+	}
+	return json.Unmarshal(body, target)
+}
+
+// fileName converts a schema ID into a safe file name.
+func fileName(id string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == ':' {
+			return '_'
+		}
+		return r
+	}, id)
+}
+
+// write writes a page into the output directory, stopping the export if it fails.
+func write(directory, name string, page []byte) {
+	if err := ioutil.WriteFile(filepath.Join(directory, name), page, 0644); err != nil {
+		log.Fatalf("Page %s couldn't be written: %v", name, err)
+	}
+}
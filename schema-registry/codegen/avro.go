@@ -0,0 +1,130 @@
+// Copyright 2020 Syntio Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package codegen
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/hamba/avro"
+)
+
+// avroGenerator generates Go types from an Avro schema.
+type avroGenerator struct {
+	file     *goFile
+	rootName string
+	named    map[string]string
+}
+
+// generateAvro declares a struct for every record and a named type for every enum of an Avro schema.
+func generateAvro(file *goFile, specification []byte, typeName string) error {
+	schema, err := avro.Parse(string(specification))
+	if err != nil {
+		return fmt.Errorf("schema isn't a valid Avro schema: %v", err)
+	}
+
+	generator := &avroGenerator{file: file, rootName: typeName, named: make(map[string]string)}
+	if _, ok := schema.(*avro.RecordSchema); !ok {
+		root := file.declare(typeName, "is generated from the Avro schema.")
+		root.underlying = generator.goType(schema)
+		return nil
+	}
+	generator.goType(schema)
+	return nil
+}
+
+// goType returns the Go type of an Avro schema, declaring named types on their first use.
+func (g *avroGenerator) goType(schema avro.Schema) string {
+	switch s := schema.(type) {
+	case *avro.RecordSchema:
+		return g.declareRecord(s)
+	case *avro.EnumSchema:
+		return g.declareEnum(s)
+	case *avro.RefSchema:
+		return g.goType(s.Schema())
+	case *avro.ArraySchema:
+		return "[]" + g.goType(s.Items())
+	case *avro.MapSchema:
+		return "map[string]" + g.goType(s.Values())
+	case *avro.FixedSchema:
+		return "[" + strconv.Itoa(s.Size()) + "]byte"
+	case *avro.UnionSchema:
+		if s.Nullable() && len(s.Types()) == 2 {
+			for _, typ := range s.Types() {
+				if typ.Type() != avro.Null {
+					return "*" + g.goType(typ)
+				}
+			}
+		}
+		return "interface{}"
+	}
+
+	switch schema.Type() {
+	case avro.String:
+		return "string"
+	case avro.Bytes:
+		return "[]byte"
+	case avro.Int:
+		return "int32"
+	case avro.Long:
+		return "int64"
+	case avro.Float:
+		return "float32"
+	case avro.Double:
+		return "float64"
+	case avro.Boolean:
+		return "bool"
+	}
+	return "interface{}"
+}
+
+// declareRecord declares a struct for a record. The first declared record is the root type.
+func (g *avroGenerator) declareRecord(record *avro.RecordSchema) string {
+	if name, ok := g.named[record.FullName()]; ok {
+		return name
+	}
+
+	name := GoName(record.Name())
+	if len(g.named) == 0 {
+		name = g.rootName
+	}
+	declared := g.file.declare(name, fmt.Sprintf("is generated from the Avro record %s.", record.FullName()))
+	g.named[record.FullName()] = declared.name
+
+	for _, field := range record.Fields() {
+		tag := fmt.Sprintf(`avro:"%s" json:"%s"`, field.Name(), field.Name())
+		declared.addField(GoName(field.Name()), g.goType(field.Type()), tag)
+	}
+	return declared.name
+}
+
+// declareEnum declares a string type for an enum, with a constant for every symbol.
+func (g *avroGenerator) declareEnum(enum *avro.EnumSchema) string {
+	if name, ok := g.named[enum.FullName()]; ok {
+		return name
+	}
+
+	declared := g.file.declare(GoName(enum.Name()), fmt.Sprintf("is generated from the Avro enum %s.", enum.FullName()))
+	declared.underlying = "string"
+	g.named[enum.FullName()] = declared.name
+
+	for _, symbol := range enum.Symbols() {
+		declared.constants = append(declared.constants, goConstant{
+			name:  declared.name + GoName(symbol),
+			value: strconv.Quote(symbol),
+		})
+	}
+	return declared.name
+}
@@ -0,0 +1,225 @@
+// Copyright 2020 Syntio Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package codegen generates source code of types matching a registered schema specification.
+package codegen
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/format"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// LanguageGo is the language of Go source code.
+const LanguageGo = "go"
+
+// ErrUnsupportedLanguage is returned when code is requested in a language without a generator.
+var ErrUnsupportedLanguage = errors.New("unsupported code generation language")
+
+// ErrUnsupportedSchemaType is returned when code is requested for a schema type without a generator.
+var ErrUnsupportedSchemaType = errors.New("unsupported schema type for code generation")
+
+// Options configure the generated code.
+//
+// Package is the name of the generated package. TypeName is the name of the root type of a JSON schema or an Avro
+// record schema; Protobuf messages keep their own names.
+type Options struct {
+	Language string
+	Package  string
+	TypeName string
+}
+
+// Generate generates Go types matching a JSON schema, Avro schema or Protobuf specification. Generated fields carry
+// json tags, and avro tags for Avro schemas.
+//
+// The output of this function is formatted source code of a single file and an error.
+func Generate(schemaType string, specification []byte, options Options) ([]byte, error) {
+	language := strings.ToLower(options.Language)
+	if language == "" {
+		language = LanguageGo
+	}
+	if language != LanguageGo {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedLanguage, options.Language)
+	}
+	if options.Package == "" {
+		options.Package = "schemas"
+	}
+	if options.TypeName == "" {
+		options.TypeName = "Schema"
+	}
+
+	file := newFile(options.Package)
+	var err error
+	switch strings.ToLower(schemaType) {
+	case "json":
+		err = generateJSONSchema(file, specification, GoName(options.TypeName))
+	case "avro":
+		err = generateAvro(file, specification, GoName(options.TypeName))
+	case "protobuf":
+		err = generateProtobuf(file, specification)
+	default:
+		err = fmt.Errorf("%w: %s", ErrUnsupportedSchemaType, schemaType)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return file.render()
+}
+
+// goFile collects the type declarations of a generated Go file.
+type goFile struct {
+	pkg   string
+	types []*goType
+	names map[string]bool
+}
+
+// goType is a generated type declaration: a struct if it has fields, otherwise a named type of the underlying type.
+// Constants are declared together with the type.
+type goType struct {
+	name       string
+	comment    string
+	underlying string
+	fields     []goField
+	constants  []goConstant
+}
+
+// goField is a field of a generated struct.
+type goField struct {
+	name string
+	typ  string
+	tag  string
+}
+
+// goConstant is a constant value of a generated type.
+type goConstant struct {
+	name  string
+	value string
+}
+
+func newFile(pkg string) *goFile {
+	return &goFile{pkg: pkg, names: make(map[string]bool)}
+}
+
+// declare adds a type declaration to the file and returns it. The name of the type is made unique in the file.
+func (f *goFile) declare(name, comment string) *goType {
+	name = f.uniqueName(name)
+	declared := &goType{name: name, comment: comment}
+	f.types = append(f.types, declared)
+	return declared
+}
+
+// uniqueName reserves a unique type name in the file, suffixing a number to an already reserved name.
+func (f *goFile) uniqueName(name string) string {
+	unique := name
+	for i := 2; f.names[unique]; i++ {
+		unique = fmt.Sprintf("%s%d", name, i)
+	}
+	f.names[unique] = true
+	return unique
+}
+
+// addField adds a field to a struct, the name of the field is made unique in the struct.
+func (t *goType) addField(name, typ, tag string) {
+	unique := name
+	for i := 2; t.hasField(unique); i++ {
+		unique = fmt.Sprintf("%s%d", name, i)
+	}
+	t.fields = append(t.fields, goField{name: unique, typ: typ, tag: tag})
+}
+
+func (t *goType) hasField(name string) bool {
+	for _, field := range t.fields {
+		if field.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// render writes the declarations of the file as formatted Go source code.
+func (f *goFile) render() ([]byte, error) {
+	var buffer bytes.Buffer
+	buffer.WriteString("// Code generated by the schema registry. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&buffer, "package %s\n", f.pkg)
+
+	for _, declared := range f.types {
+		buffer.WriteString("\n")
+		if declared.comment != "" {
+			fmt.Fprintf(&buffer, "// %s %s\n", declared.name, declared.comment)
+		}
+		if declared.underlying != "" {
+			fmt.Fprintf(&buffer, "type %s %s\n", declared.name, declared.underlying)
+		} else {
+			fmt.Fprintf(&buffer, "type %s struct {\n", declared.name)
+			for _, field := range declared.fields {
+				fmt.Fprintf(&buffer, "\t%s %s `%s`\n", field.name, field.typ, field.tag)
+			}
+			buffer.WriteString("}\n")
+		}
+
+		if len(declared.constants) > 0 {
+			buffer.WriteString("\nconst (\n")
+			for _, constant := range declared.constants {
+				fmt.Fprintf(&buffer, "\t%s %s = %s\n", constant.name, declared.name, constant.value)
+			}
+			buffer.WriteString(")\n")
+		}
+	}
+
+	source, err := format.Source(buffer.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("generated code isn't valid Go: %v", err)
+	}
+	return source, nil
+}
+
+// GoName converts a schema name, e.g. "user_name" or "user-name", into an exported Go identifier ("UserName").
+func GoName(name string) string {
+	var builder strings.Builder
+	upper := true
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		builder.WriteRune(r)
+	}
+
+	goName := builder.String()
+	if goName == "" {
+		return "Field"
+	}
+	if unicode.IsDigit(rune(goName[0])) {
+		return "X" + goName
+	}
+	return goName
+}
+
+// sortedKeys returns the keys of a JSON object in a deterministic order.
+func sortedKeys(object map[string]interface{}) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,179 @@
+// Copyright 2020 Syntio Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package codegen
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// jsonSchemaGenerator generates Go types from a JSON schema.
+type jsonSchemaGenerator struct {
+	file        *goFile
+	definitions map[string]string
+}
+
+// generateJSONSchema declares the root type of a JSON schema and a type for every definition and nested object.
+func generateJSONSchema(file *goFile, specification []byte, typeName string) error {
+	var schema map[string]interface{}
+	if err := json.Unmarshal(specification, &schema); err != nil {
+		return fmt.Errorf("schema isn't valid JSON: %v", err)
+	}
+
+	generator := &jsonSchemaGenerator{file: file, definitions: make(map[string]string)}
+	definitions := make(map[string]interface{})
+	for _, keyword := range []string{"definitions", "$defs"} {
+		if object, ok := schema[keyword].(map[string]interface{}); ok {
+			for name, definition := range object {
+				definitions["#/"+keyword+"/"+name] = definition
+			}
+		}
+	}
+	// Definition names are reserved first, so references resolve regardless of the declaration order
+	for _, ref := range sortedKeys(definitions) {
+		generator.definitions[ref] = file.uniqueName(GoName(ref[strings.LastIndex(ref, "/")+1:]))
+	}
+
+	generator.declareRoot(schema, file.uniqueName(typeName))
+	for _, ref := range sortedKeys(definitions) {
+		definition, _ := definitions[ref].(map[string]interface{})
+		generator.declareRoot(definition, generator.definitions[ref])
+	}
+	return nil
+}
+
+// declareRoot declares a type with an already reserved name: a struct for object schemas, a named type otherwise.
+func (g *jsonSchemaGenerator) declareRoot(schema map[string]interface{}, name string) {
+	declared := &goType{name: name, comment: description(schema, "is generated from the JSON schema.")}
+	g.file.types = append(g.file.types, declared)
+	if properties, ok := schema["properties"].(map[string]interface{}); ok || schemaType(schema) == "object" {
+		g.addProperties(declared, properties, stringList(schema["required"]))
+		return
+	}
+	declared.underlying = g.goType(schema, name)
+}
+
+// addProperties adds a field to the struct for every property of an object schema.
+func (g *jsonSchemaGenerator) addProperties(declared *goType, properties map[string]interface{}, required []string) {
+	requiredNames := make(map[string]bool, len(required))
+	for _, name := range required {
+		requiredNames[name] = true
+	}
+
+	for _, name := range sortedKeys(properties) {
+		property, _ := properties[name].(map[string]interface{})
+		fieldName := GoName(name)
+		tag := fmt.Sprintf(`json:"%s"`, name)
+		if !requiredNames[name] {
+			tag = fmt.Sprintf(`json:"%s,omitempty"`, name)
+		}
+		declared.addField(fieldName, g.goType(property, declared.name+fieldName), tag)
+	}
+}
+
+// goType returns the Go type of a schema. Nested object schemas are declared as structs named by their location.
+func (g *jsonSchemaGenerator) goType(schema map[string]interface{}, name string) string {
+	if schema == nil {
+		return "interface{}"
+	}
+	if ref, ok := schema["$ref"].(string); ok {
+		if definition, ok := g.definitions[ref]; ok {
+			return definition
+		}
+		return "interface{}"
+	}
+
+	typ, nullable := schemaType(schema), false
+	if types, ok := schema["type"].([]interface{}); ok {
+		typ, nullable = nullableType(types)
+	}
+
+	var goType string
+	switch typ {
+	case "string":
+		goType = "string"
+	case "integer":
+		goType = "int64"
+	case "number":
+		goType = "float64"
+	case "boolean":
+		goType = "bool"
+	case "array":
+		items, _ := schema["items"].(map[string]interface{})
+		goType = "[]" + g.goType(items, name+"Item")
+	case "object":
+		properties, ok := schema["properties"].(map[string]interface{})
+		if !ok {
+			values, _ := schema["additionalProperties"].(map[string]interface{})
+			goType = "map[string]" + g.goType(values, name+"Value")
+			break
+		}
+		declared := g.file.declare(name, description(schema, "is generated from a nested JSON schema object."))
+		g.addProperties(declared, properties, stringList(schema["required"]))
+		goType = declared.name
+	default:
+		return "interface{}"
+	}
+
+	if nullable {
+		return "*" + goType
+	}
+	return goType
+}
+
+// schemaType returns the type keyword of a schema with a single type.
+func schemaType(schema map[string]interface{}) string {
+	typ, _ := schema["type"].(string)
+	return typ
+}
+
+// nullableType returns the type of a schema with a list of types. Only a single type, optionally combined with
+// "null", has a matching Go type; an empty type is returned otherwise.
+func nullableType(types []interface{}) (string, bool) {
+	nullable := false
+	nonNull := make([]string, 0, len(types))
+	for _, typ := range types {
+		if typ == "null" {
+			nullable = true
+		} else if s, ok := typ.(string); ok {
+			nonNull = append(nonNull, s)
+		}
+	}
+	if len(nonNull) != 1 {
+		return "", false
+	}
+	return nonNull[0], nullable
+}
+
+// description returns the doc comment of a generated type, the description of the schema if it has one.
+func description(schema map[string]interface{}, fallback string) string {
+	if text, ok := schema["description"].(string); ok && text != "" {
+		return fallback + " " + strings.Join(strings.Fields(text), " ")
+	}
+	return fallback
+}
+
+// stringList converts a JSON array of strings into a string slice.
+func stringList(value interface{}) []string {
+	values, _ := value.([]interface{})
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		if s, ok := v.(string); ok {
+			result = append(result, s)
+		}
+	}
+	return result
+}
@@ -0,0 +1,178 @@
+// Copyright 2020 Syntio Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package codegen
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
+)
+
+// protoFileName is the name under which the in-memory specification is compiled.
+const protoFileName = "schema.proto"
+
+// protobufGenerator generates Go types from a Protobuf specification.
+type protobufGenerator struct {
+	file  *goFile
+	named map[string]string
+}
+
+// generateProtobuf declares a struct for every message and a named type for every enum of a Protobuf specification.
+func generateProtobuf(file *goFile, specification []byte) error {
+	parser := protoparse.Parser{
+		Accessor: protoparse.FileContentsFromMap(map[string]string{protoFileName: string(specification)}),
+	}
+	descriptors, err := parser.ParseFiles(protoFileName)
+	if err != nil {
+		return fmt.Errorf("schema isn't a valid Protobuf specification: %v", err)
+	}
+
+	generator := &protobufGenerator{file: file, named: make(map[string]string)}
+	// Names are reserved first, so fields can reference types declared later
+	for _, enum := range descriptors[0].GetEnumTypes() {
+		generator.reserveEnum(enum)
+	}
+	for _, message := range descriptors[0].GetMessageTypes() {
+		generator.reserveMessage(message)
+	}
+
+	for _, enum := range descriptors[0].GetEnumTypes() {
+		generator.declareEnum(enum)
+	}
+	for _, message := range descriptors[0].GetMessageTypes() {
+		generator.declareMessage(message)
+	}
+	return nil
+}
+
+// typeName returns the Go name of a message or enum, nested types are prefixed with their parent names.
+func typeName(fullyQualifiedName, pkg string) string {
+	relative := strings.TrimPrefix(fullyQualifiedName, pkg+".")
+	parts := strings.Split(relative, ".")
+	for i, part := range parts {
+		parts[i] = GoName(part)
+	}
+	return strings.Join(parts, "_")
+}
+
+func (g *protobufGenerator) reserveMessage(message *desc.MessageDescriptor) {
+	if message.IsMapEntry() {
+		return
+	}
+	g.named[message.GetFullyQualifiedName()] = g.file.uniqueName(
+		typeName(message.GetFullyQualifiedName(), message.GetFile().GetPackage()))
+	for _, enum := range message.GetNestedEnumTypes() {
+		g.reserveEnum(enum)
+	}
+	for _, nested := range message.GetNestedMessageTypes() {
+		g.reserveMessage(nested)
+	}
+}
+
+func (g *protobufGenerator) reserveEnum(enum *desc.EnumDescriptor) {
+	g.named[enum.GetFullyQualifiedName()] = g.file.uniqueName(
+		typeName(enum.GetFullyQualifiedName(), enum.GetFile().GetPackage()))
+}
+
+// declareMessage declares a struct for a message, followed by its nested messages and enums.
+func (g *protobufGenerator) declareMessage(message *desc.MessageDescriptor) {
+	if message.IsMapEntry() {
+		return
+	}
+
+	declared := &goType{
+		name:    g.named[message.GetFullyQualifiedName()],
+		comment: fmt.Sprintf("is generated from the Protobuf message %s.", message.GetFullyQualifiedName()),
+	}
+	g.file.types = append(g.file.types, declared)
+	for _, field := range message.GetFields() {
+		tag := fmt.Sprintf(`json:"%s,omitempty"`, field.GetJSONName())
+		declared.addField(GoName(field.GetName()), g.fieldType(field), tag)
+	}
+
+	for _, enum := range message.GetNestedEnumTypes() {
+		g.declareEnum(enum)
+	}
+	for _, nested := range message.GetNestedMessageTypes() {
+		g.declareMessage(nested)
+	}
+}
+
+// declareEnum declares an int32 type for an enum, with a constant for every value.
+func (g *protobufGenerator) declareEnum(enum *desc.EnumDescriptor) {
+	declared := &goType{
+		name:       g.named[enum.GetFullyQualifiedName()],
+		comment:    fmt.Sprintf("is generated from the Protobuf enum %s.", enum.GetFullyQualifiedName()),
+		underlying: "int32",
+	}
+	g.file.types = append(g.file.types, declared)
+	for _, value := range enum.GetValues() {
+		declared.constants = append(declared.constants, goConstant{
+			name:  declared.name + "_" + GoName(value.GetName()),
+			value: strconv.Itoa(int(value.GetNumber())),
+		})
+	}
+}
+
+// fieldType returns the Go type of a message field.
+func (g *protobufGenerator) fieldType(field *desc.FieldDescriptor) string {
+	if field.IsMap() {
+		return "map[" + g.scalarType(field.GetMapKeyType()) + "]" + g.scalarType(field.GetMapValueType())
+	}
+	if field.IsRepeated() {
+		return "[]" + g.scalarType(field)
+	}
+	return g.scalarType(field)
+}
+
+// scalarType returns the Go type of a single value of a field, messages are referenced by pointers.
+func (g *protobufGenerator) scalarType(field *desc.FieldDescriptor) string {
+	switch field.GetType() {
+	case descriptor.FieldDescriptorProto_TYPE_DOUBLE:
+		return "float64"
+	case descriptor.FieldDescriptorProto_TYPE_FLOAT:
+		return "float32"
+	case descriptor.FieldDescriptorProto_TYPE_INT32, descriptor.FieldDescriptorProto_TYPE_SINT32,
+		descriptor.FieldDescriptorProto_TYPE_SFIXED32:
+		return "int32"
+	case descriptor.FieldDescriptorProto_TYPE_INT64, descriptor.FieldDescriptorProto_TYPE_SINT64,
+		descriptor.FieldDescriptorProto_TYPE_SFIXED64:
+		return "int64"
+	case descriptor.FieldDescriptorProto_TYPE_UINT32, descriptor.FieldDescriptorProto_TYPE_FIXED32:
+		return "uint32"
+	case descriptor.FieldDescriptorProto_TYPE_UINT64, descriptor.FieldDescriptorProto_TYPE_FIXED64:
+		return "uint64"
+	case descriptor.FieldDescriptorProto_TYPE_BOOL:
+		return "bool"
+	case descriptor.FieldDescriptorProto_TYPE_STRING:
+		return "string"
+	case descriptor.FieldDescriptorProto_TYPE_BYTES:
+		return "[]byte"
+	case descriptor.FieldDescriptorProto_TYPE_ENUM:
+		if name, ok := g.named[field.GetEnumType().GetFullyQualifiedName()]; ok {
+			return name
+		}
+		return "int32"
+	case descriptor.FieldDescriptorProto_TYPE_MESSAGE, descriptor.FieldDescriptorProto_TYPE_GROUP:
+		if name, ok := g.named[field.GetMessageType().GetFullyQualifiedName()]; ok {
+			return "*" + name
+		}
+	}
+	return "interface{}"
+}
@@ -0,0 +1,144 @@
+// Copyright 2020 Syntio Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package compatibility checks if a new version of a schema is compatible with the previous version.
+package compatibility
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/syntio/schema-registry/model"
+)
+
+// Check checks if the current specification of a schema is compatible with the previous one, using the given
+// compatibility mode:
+//  - BACKWARD: data written with the previous version can be read with the current version
+//  - FORWARD: data written with the current version can be read with the previous version
+//  - FULL: both BACKWARD and FORWARD
+//  - NONE (or empty): no check is done
+//
+// Only JSON schemas are checked structurally, other schema types are considered compatible.
+//
+// An error describing every found incompatibility is returned if the specifications aren't compatible.
+func Check(mode, schemaType string, previous, current []byte) error {
+	mode = strings.ToUpper(mode)
+	if mode == "" || mode == model.CompatibilityNone {
+		return nil
+	}
+	if strings.ToLower(schemaType) != "json" {
+		log.Printf("Compatibility check isn't supported for schema type %s, skipping it.\n", schemaType)
+		return nil
+	}
+
+	var previousSchema, currentSchema map[string]interface{}
+	if err := json.Unmarshal(previous, &previousSchema); err != nil {
+		return fmt.Errorf("previous schema version isn't valid JSON: %v", err)
+	}
+	if err := json.Unmarshal(current, &currentSchema); err != nil {
+		return fmt.Errorf("new schema version isn't valid JSON: %v", err)
+	}
+
+	var problems []string
+	switch mode {
+	case model.CompatibilityBackward:
+		problems = readable(currentSchema, previousSchema, "")
+	case model.CompatibilityForward:
+		problems = readable(previousSchema, currentSchema, "")
+	case model.CompatibilityFull:
+		problems = append(readable(currentSchema, previousSchema, ""), readable(previousSchema, currentSchema, "")...)
+	default:
+		return fmt.Errorf("unknown compatibility mode %s", mode)
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%s compatibility violated: %s", mode, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// readable checks if data written with the writer JSON schema can be read with the reader JSON schema.
+// Nested object schemas are checked recursively. The output is a list of found problems.
+func readable(reader, writer map[string]interface{}, path string) []string {
+	var problems []string
+
+	if !reflect.DeepEqual(reader["type"], writer["type"]) {
+		problems = append(problems, fmt.Sprintf("type of %s changed from %v to %v", location(path),
+			writer["type"], reader["type"]))
+		return problems
+	}
+
+	readerProperties, _ := reader["properties"].(map[string]interface{})
+	writerProperties, _ := writer["properties"].(map[string]interface{})
+
+	for _, name := range stringList(reader["required"]) {
+		if _, ok := writerProperties[name]; !ok {
+			problems = append(problems, fmt.Sprintf("required field %s is missing in the other version",
+				location(path+"/"+name)))
+		}
+	}
+
+	if additional, ok := reader["additionalProperties"].(bool); ok && !additional {
+		for _, name := range sortedKeys(writerProperties) {
+			if _, ok := readerProperties[name]; !ok {
+				problems = append(problems, fmt.Sprintf("field %s isn't allowed in the other version",
+					location(path+"/"+name)))
+			}
+		}
+	}
+
+	for _, name := range sortedKeys(readerProperties) {
+		readerProperty, readerOk := readerProperties[name].(map[string]interface{})
+		writerProperty, writerOk := writerProperties[name].(map[string]interface{})
+		if readerOk && writerOk {
+			problems = append(problems, readable(readerProperty, writerProperty, path+"/"+name)...)
+		}
+	}
+
+	return problems
+}
+
+// location returns a printable JSON pointer of a schema element.
+func location(path string) string {
+	if path == "" {
+		return "root"
+	}
+	return path
+}
+
+// stringList converts a JSON array of strings into a string slice.
+func stringList(value interface{}) []string {
+	values, _ := value.([]interface{})
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		if s, ok := v.(string); ok {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+// sortedKeys returns the keys of a JSON object in a deterministic order.
+func sortedKeys(object map[string]interface{}) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -22,6 +22,7 @@ import (
 	"time"
 
 	"cloud.google.com/go/storage"
+	"github.com/syntio/schema-registry/model"
 
 	"gopkg.in/yaml.v3"
 )
@@ -53,10 +54,12 @@ type Config struct {
 		PullerCleanerCsvURL        string `yaml:"pullerCleanerCsvURL"`
 	} `yaml:"functions"`
 
-	Protoparam struct {
-		TmpFilePath string `yaml:"tmpFilePath"`
-		TmpFileName string `yaml:"tmpFileName"`
-	} `yaml:"protoparam"`
+	Validators map[string]map[string]string `yaml:"validators"`
+
+	Protobuf struct {
+		DescriptorCacheSize int  `yaml:"descriptorCacheSize"`
+		StrictUnknownFields bool `yaml:"strictUnknownFields"`
+	} `yaml:"protobuf"`
 
 	PullerCleanerJSON struct {
 		TimeDurationSecondsAsync time.Duration `yaml:"timeDurationSecondsAsync"`
@@ -75,6 +78,60 @@ type Config struct {
 	ContentType             string      `yaml:"contentType"`
 	FileMode                os.FileMode `yaml:"fileMode"`
 	FirestoreCollectionName string      `yaml:"firestoreCollectionName"`
+
+	Server ServerConfig `yaml:"server"`
+
+	Tracing TracingConfig `yaml:"tracing"`
+
+	Namespaces map[string]NamespaceConfig `yaml:"namespaces"`
+
+	Retention struct {
+		AccessTrackingMinutes time.Duration `yaml:"accessTrackingMinutes"`
+	} `yaml:"retention"`
+}
+
+// ServerConfig holds the listen address, TLS files, timeouts and shutdown grace period of the registry server.
+// The server falls back to the PORT environment variable (or port 8080) if no address is set, and serves TLS only
+// if both the certificate and the key file are set.
+type ServerConfig struct {
+	Address                string        `yaml:"address"`
+	TLSCertFile            string        `yaml:"tlsCertFile"`
+	TLSKeyFile             string        `yaml:"tlsKeyFile"`
+	ReadTimeoutSeconds     time.Duration `yaml:"readTimeoutSeconds"`
+	WriteTimeoutSeconds    time.Duration `yaml:"writeTimeoutSeconds"`
+	IdleTimeoutSeconds     time.Duration `yaml:"idleTimeoutSeconds"`
+	ShutdownTimeoutSeconds time.Duration `yaml:"shutdownTimeoutSeconds"`
+}
+
+// TracingConfig holds the OTLP/HTTP collector (host:port) the spans are exported to, whether it is reached over plain
+// HTTP, and the ratio of new traces which are sampled. Nothing is exported if no endpoint is set.
+type TracingConfig struct {
+	Endpoint    string  `yaml:"endpoint"`
+	Insecure    bool    `yaml:"insecure"`
+	SampleRatio float64 `yaml:"sampleRatio"`
+}
+
+// NamespaceConfig holds the compatibility, evolution and retention defaults, the linting rules and the quotas of a
+// single namespace (tenant).
+type NamespaceConfig struct {
+	Compatibility string                `yaml:"compatibility"`
+	Evolution     model.EvolutionPolicy `yaml:"evolution"`
+	Retention     model.RetentionPolicy `yaml:"retention"`
+	Lint          []model.LintRule      `yaml:"lint"`
+
+	Quotas struct {
+		MaxSchemas           int `yaml:"maxSchemas"`
+		MaxVersionsPerSchema int `yaml:"maxVersionsPerSchema"`
+	} `yaml:"quotas"`
+}
+
+// Namespace returns the configuration of the given namespace. Namespaces that aren't listed in the config file
+// inherit the configuration of the default namespace.
+func (cfg Config) Namespace(name string) NamespaceConfig {
+	if nsCfg, ok := cfg.Namespaces[name]; ok {
+		return nsCfg
+	}
+	return cfg.Namespaces[model.DefaultNamespace]
 }
 
 // Function for obtaining configuration parameters values into an object.
@@ -0,0 +1,401 @@
+// Copyright 2020 Syntio Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conversion
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hamba/avro"
+)
+
+// avroWriter converts a JSON schema into an Avro schema.
+type avroWriter struct {
+	schema *jsonSchema
+	names  map[string]bool
+	refs   map[string]string
+}
+
+// jsonToAvro converts a JSON schema into an Avro schema. Objects become records, optional and nullable properties
+// become unions with null, and schemas without a type become strings.
+func jsonToAvro(specification []byte, name string) ([]byte, error) {
+	schema, err := parseJSONSchema(specification)
+	if err != nil {
+		return nil, err
+	}
+	if title, ok := schema.root["title"].(string); ok && title != "" {
+		name = title
+	}
+
+	writer := &avroWriter{schema: schema, names: make(map[string]bool), refs: map[string]string{}}
+	rootName := writer.uniqueName(pascalCase(name))
+	writer.refs["#"] = rootName
+
+	converted, err := writer.convert(schema.root, rootName)
+	if err != nil {
+		return nil, err
+	}
+	result, err := marshal(converted)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = avro.Parse(string(result)); err != nil {
+		return nil, fmt.Errorf("%w: converted Avro schema is invalid: %v", ErrInvalidSchema, err)
+	}
+	return result, nil
+}
+
+// uniqueName reserves a unique Avro type name.
+func (w *avroWriter) uniqueName(name string) string {
+	unique := name
+	for i := 2; w.names[unique]; i++ {
+		unique = fmt.Sprintf("%s%d", name, i)
+	}
+	w.names[unique] = true
+	return unique
+}
+
+// convert converts a JSON schema into an Avro type. Named types (records and enums) use the given name.
+func (w *avroWriter) convert(schema map[string]interface{}, name string) (interface{}, error) {
+	if ref, ok := schema["$ref"].(string); ok {
+		if named, ok := w.refs[ref]; ok {
+			return named, nil
+		}
+		resolved := w.schema.resolve(ref)
+		if resolved == nil {
+			return nil, fmt.Errorf("%w: reference %s can't be resolved", ErrInvalidSchema, ref)
+		}
+		named := w.uniqueName(pascalCase(refName(ref)))
+		w.refs[ref] = named
+		return w.convert(resolved, named)
+	}
+
+	typ, nullable := typeOf(schema)
+	converted, err := w.convertType(schema, typ, name)
+	if err != nil {
+		return nil, err
+	}
+	if nullable {
+		return []interface{}{"null", converted}, nil
+	}
+	return converted, nil
+}
+
+// convertType converts a JSON schema of a single type into an Avro type.
+func (w *avroWriter) convertType(schema map[string]interface{}, typ, name string) (interface{}, error) {
+	if symbols, ok := stringEnum(schema); ok && allValidNames(symbols) {
+		return map[string]interface{}{"type": "enum", "name": name, "symbols": symbols}, nil
+	}
+
+	switch typ {
+	case "integer":
+		return "long", nil
+	case "number":
+		return "double", nil
+	case "boolean":
+		return "boolean", nil
+	case "null":
+		return "null", nil
+	case "array":
+		items, _ := schema["items"].(map[string]interface{})
+		converted, err := w.convert(items, name+"Item")
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"type": "array", "items": converted}, nil
+	case "object":
+		properties, ok := schema["properties"].(map[string]interface{})
+		if !ok {
+			values, _ := schema["additionalProperties"].(map[string]interface{})
+			converted, err := w.convert(values, name+"Value")
+			if err != nil {
+				return nil, err
+			}
+			return map[string]interface{}{"type": "map", "values": converted}, nil
+		}
+		return w.convertRecord(schema, properties, name)
+	}
+	return "string", nil
+}
+
+// convertRecord converts an object schema into a record with a field for every property.
+func (w *avroWriter) convertRecord(schema, properties map[string]interface{}, name string) (interface{}, error) {
+	required := requiredSet(schema)
+	fields := make([]interface{}, 0, len(properties))
+	for _, property := range sortedKeys(properties) {
+		propertySchema, _ := properties[property].(map[string]interface{})
+		fieldName := property
+		if !validName(fieldName) {
+			fieldName = snakeCase(property)
+		}
+
+		converted, err := w.convert(propertySchema, w.uniqueName(name+pascalCase(property)))
+		if err != nil {
+			return nil, err
+		}
+		field := map[string]interface{}{"name": fieldName, "type": converted}
+		if !required[property] {
+			// Optional properties default to null, which has to be the first type of the union
+			if union, ok := converted.([]interface{}); !ok || union[0] != "null" {
+				field["type"] = []interface{}{"null", converted}
+			}
+			field["default"] = nil
+		}
+		if description, ok := propertySchema["description"].(string); ok {
+			field["doc"] = description
+		}
+		fields = append(fields, field)
+	}
+
+	record := map[string]interface{}{"type": "record", "name": name, "fields": fields}
+	if description, ok := schema["description"].(string); ok {
+		record["doc"] = description
+	}
+	return record, nil
+}
+
+// allValidNames checks if every enum value is a valid Avro enum symbol.
+func allValidNames(symbols []string) bool {
+	for _, symbol := range symbols {
+		if !validName(symbol) {
+			return false
+		}
+	}
+	return true
+}
+
+// jsonWriter converts an Avro schema into a JSON schema.
+type jsonWriter struct {
+	named       map[string]map[string]interface{}
+	definitions map[string]interface{}
+	root        string
+}
+
+// avroToJSON converts an Avro schema into a JSON schema. Named types besides the root record become definitions,
+// unions with null become nullable types and bytes become base64 encoded strings.
+func avroToJSON(specification []byte, _ string) ([]byte, error) {
+	if _, err := avro.Parse(string(specification)); err != nil {
+		return nil, fmt.Errorf("%w: Avro schema is invalid: %v", ErrInvalidSchema, err)
+	}
+	var schema interface{}
+	if err := json.Unmarshal(specification, &schema); err != nil {
+		return nil, fmt.Errorf("%w: Avro schema isn't valid JSON: %v", ErrInvalidSchema, err)
+	}
+
+	writer := &jsonWriter{named: make(map[string]map[string]interface{}), definitions: make(map[string]interface{})}
+	writer.collect(schema, "")
+
+	converted := writer.convert(schema, "")
+	converted["$schema"] = "http://json-schema.org/draft-07/schema#"
+	if len(writer.definitions) > 0 {
+		converted["definitions"] = writer.definitions
+	}
+	return marshal(converted)
+}
+
+// collect registers every named type of an Avro schema by its full name.
+func (w *jsonWriter) collect(schema interface{}, namespace string) {
+	switch s := schema.(type) {
+	case []interface{}:
+		for _, branch := range s {
+			w.collect(branch, namespace)
+		}
+	case map[string]interface{}:
+		switch s["type"] {
+		case "record", "error", "enum", "fixed":
+			fullName := avroFullName(s, namespace)
+			if _, ok := w.named[fullName]; ok {
+				return
+			}
+			w.named[fullName] = s
+			if w.root == "" {
+				w.root = fullName
+			}
+			namespace = fullName[:strings.LastIndex(fullName, ".")+1]
+			namespace = strings.TrimSuffix(namespace, ".")
+			fields, _ := s["fields"].([]interface{})
+			for _, field := range fields {
+				if object, ok := field.(map[string]interface{}); ok {
+					w.collect(object["type"], namespace)
+				}
+			}
+		case "array":
+			w.collect(s["items"], namespace)
+		case "map":
+			w.collect(s["values"], namespace)
+		default:
+			w.collect(s["type"], namespace)
+		}
+	}
+}
+
+// avroFullName returns the full name of a named Avro type.
+func avroFullName(schema map[string]interface{}, namespace string) string {
+	name, _ := schema["name"].(string)
+	if strings.Contains(name, ".") {
+		return name
+	}
+	if ns, ok := schema["namespace"].(string); ok {
+		namespace = ns
+	}
+	if namespace == "" {
+		return name
+	}
+	return namespace + "." + name
+}
+
+// reference returns the JSON schema reference of a named type, defining the type on its first use.
+func (w *jsonWriter) reference(fullName, namespace string) map[string]interface{} {
+	if fullName == w.root {
+		return map[string]interface{}{"$ref": "#"}
+	}
+	if _, ok := w.definitions[fullName]; !ok {
+		w.definitions[fullName] = true
+		w.definitions[fullName] = w.convertNamed(w.named[fullName], namespace)
+	}
+	return map[string]interface{}{"$ref": "#/definitions/" + fullName}
+}
+
+// convert converts an Avro type into a JSON schema.
+func (w *jsonWriter) convert(schema interface{}, namespace string) map[string]interface{} {
+	switch s := schema.(type) {
+	case string:
+		return w.convertName(s, namespace)
+	case []interface{}:
+		return w.convertUnion(s, namespace)
+	case map[string]interface{}:
+		switch s["type"] {
+		case "record", "error", "enum", "fixed":
+			fullName := avroFullName(s, namespace)
+			if fullName == w.root {
+				return w.convertNamed(s, namespace)
+			}
+			return w.reference(fullName, namespace)
+		case "array":
+			return map[string]interface{}{"type": "array", "items": w.convert(s["items"], namespace)}
+		case "map":
+			return map[string]interface{}{"type": "object", "additionalProperties": w.convert(s["values"], namespace)}
+		default:
+			return w.convert(s["type"], namespace)
+		}
+	}
+	return map[string]interface{}{}
+}
+
+// convertName converts a primitive type or a reference to a named type.
+func (w *jsonWriter) convertName(name, namespace string) map[string]interface{} {
+	switch name {
+	case "null", "string", "boolean":
+		return map[string]interface{}{"type": name}
+	case "int", "long":
+		return map[string]interface{}{"type": "integer"}
+	case "float", "double":
+		return map[string]interface{}{"type": "number"}
+	case "bytes":
+		return map[string]interface{}{"type": "string", "contentEncoding": "base64"}
+	}
+
+	fullName := name
+	if _, ok := w.named[fullName]; !ok && namespace != "" {
+		fullName = namespace + "." + name
+	}
+	return w.reference(fullName, namespace)
+}
+
+// convertUnion converts a union. A union of null and a single primitive type becomes a nullable type, any other union
+// becomes an anyOf.
+func (w *jsonWriter) convertUnion(branches []interface{}, namespace string) map[string]interface{} {
+	converted := make([]interface{}, 0, len(branches))
+	for _, branch := range branches {
+		converted = append(converted, w.convert(branch, namespace))
+	}
+	if len(branches) == 2 && (branches[0] == "null" || branches[1] == "null") {
+		other := converted[0].(map[string]interface{})
+		if branches[0] == "null" {
+			other = converted[1].(map[string]interface{})
+		}
+		if typ, ok := other["type"].(string); ok {
+			nullable := make(map[string]interface{}, len(other))
+			for key, value := range other {
+				nullable[key] = value
+			}
+			nullable["type"] = []interface{}{typ, "null"}
+			return nullable
+		}
+	}
+	return map[string]interface{}{"anyOf": converted}
+}
+
+// convertNamed converts a record, enum or fixed type.
+func (w *jsonWriter) convertNamed(schema map[string]interface{}, namespace string) map[string]interface{} {
+	converted := make(map[string]interface{})
+	if doc, ok := schema["doc"].(string); ok {
+		converted["description"] = doc
+	}
+	if name, ok := schema["name"].(string); ok {
+		converted["title"] = name
+	}
+	fullName := avroFullName(schema, namespace)
+	namespace = strings.TrimSuffix(fullName[:strings.LastIndex(fullName, ".")+1], ".")
+
+	switch schema["type"] {
+	case "enum":
+		converted["type"] = "string"
+		converted["enum"] = schema["symbols"]
+	case "fixed":
+		converted["type"] = "string"
+		converted["contentEncoding"] = "base64"
+	default:
+		properties := make(map[string]interface{})
+		required := make([]string, 0)
+		fields, _ := schema["fields"].([]interface{})
+		for _, field := range fields {
+			object, ok := field.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			name, _ := object["name"].(string)
+			property := w.convert(object["type"], namespace)
+			if doc, ok := object["doc"].(string); ok {
+				property["description"] = doc
+			}
+			properties[name] = property
+			if _, hasDefault := object["default"]; !hasDefault && !nullableUnion(object["type"]) {
+				required = append(required, name)
+			}
+		}
+		converted["type"] = "object"
+		converted["properties"] = properties
+		if len(required) > 0 {
+			converted["required"] = required
+		}
+	}
+	return converted
+}
+
+// nullableUnion checks if an Avro type is a union containing null.
+func nullableUnion(schema interface{}) bool {
+	branches, ok := schema.([]interface{})
+	if !ok {
+		return false
+	}
+	for _, branch := range branches {
+		if branch == "null" {
+			return true
+		}
+	}
+	return false
+}
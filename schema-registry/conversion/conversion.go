@@ -0,0 +1,154 @@
+// Copyright 2020 Syntio Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package conversion converts schema specifications between schema types:
+//  - JSON schema to Avro and Protobuf (proto3)
+//  - Avro to JSON schema
+//  - CSV schema to JSON schema
+package conversion
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Schema types supported by the conversion.
+const (
+	TypeJSON     = "json"
+	TypeAvro     = "avro"
+	TypeProtobuf = "protobuf"
+	TypeCSV      = "csv"
+)
+
+// ErrUnsupportedConversion is returned when there's no conversion between the requested schema types.
+var ErrUnsupportedConversion = errors.New("unsupported schema conversion")
+
+// ErrInvalidSchema is returned when the converted specification can't be parsed.
+var ErrInvalidSchema = errors.New("schema can't be converted")
+
+// converters holds the conversion of every supported pair of schema types.
+var converters = map[string]func(specification []byte, name string) ([]byte, error){
+	TypeJSON + ">" + TypeAvro:     jsonToAvro,
+	TypeAvro + ">" + TypeJSON:     avroToJSON,
+	TypeJSON + ">" + TypeProtobuf: jsonToProtobuf,
+	TypeCSV + ">" + TypeJSON:      csvToJSON,
+}
+
+// Convert converts a specification from one schema type to another. The name is used for named types of the target,
+// e.g. the Avro record or the Protobuf message, if the specification doesn't name them itself. A specification
+// converted to its own schema type is returned unchanged.
+func Convert(from, to string, specification []byte, name string) ([]byte, error) {
+	from, to = strings.ToLower(from), strings.ToLower(to)
+	if from == to {
+		return specification, nil
+	}
+	converter, ok := converters[from+">"+to]
+	if !ok {
+		return nil, fmt.Errorf("%w: from %s to %s", ErrUnsupportedConversion, from, to)
+	}
+	return converter(specification, name)
+}
+
+// Supported reports if a specification of one schema type can be converted to the other.
+func Supported(from, to string) bool {
+	from, to = strings.ToLower(from), strings.ToLower(to)
+	_, ok := converters[from+">"+to]
+	return from == to || ok
+}
+
+// words splits a name like "userName", "user_name" or "user-name" into its lower case words.
+func words(name string) []string {
+	var result []string
+	var current []rune
+	runes := []rune(name)
+	for i, r := range runes {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			if len(current) > 0 {
+				result = append(result, string(current))
+				current = nil
+			}
+			continue
+		case unicode.IsUpper(r) && len(current) > 0 &&
+			(unicode.IsLower(runes[i-1]) || i+1 < len(runes) && unicode.IsLower(runes[i+1])):
+			result = append(result, string(current))
+			current = nil
+		}
+		current = append(current, unicode.ToLower(r))
+	}
+	if len(current) > 0 {
+		result = append(result, string(current))
+	}
+	return result
+}
+
+// pascalCase converts a name into a type name valid in Avro and Protobuf, e.g. "user_name" into "UserName".
+func pascalCase(name string) string {
+	var builder strings.Builder
+	for _, word := range words(name) {
+		builder.WriteString(strings.ToUpper(word[:1]) + word[1:])
+	}
+	return identifier(builder.String(), "Record")
+}
+
+// snakeCase converts a name into a field name valid in Avro and Protobuf, e.g. "userName" into "user_name".
+func snakeCase(name string) string {
+	return identifier(strings.Join(words(name), "_"), "field")
+}
+
+// identifier makes sure a name is a valid identifier, it can't be empty or start with a digit.
+func identifier(name, fallback string) string {
+	if name == "" {
+		return fallback
+	}
+	if unicode.IsDigit(rune(name[0])) {
+		return "_" + name
+	}
+	return name
+}
+
+// validName checks if a name is a valid Avro name, e.g. an enum symbol.
+func validName(name string) bool {
+	for i, r := range name {
+		if !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || i > 0 && r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return name != ""
+}
+
+// sortedKeys returns the keys of a JSON object in a deterministic order.
+func sortedKeys(object map[string]interface{}) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// stringList converts a JSON array of strings into a string slice.
+func stringList(value interface{}) []string {
+	values, _ := value.([]interface{})
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		if s, ok := v.(string); ok {
+			result = append(result, s)
+		}
+	}
+	return result
+}
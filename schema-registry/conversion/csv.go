@@ -0,0 +1,179 @@
+// Copyright 2020 Syntio Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conversion
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// CSV schema rules which have a JSON schema counterpart.
+var (
+	csvColumnPattern = regexp.MustCompile(`^\s*("(?:[^"\\]|\\.)*"|[^:\s]+)\s*:(.*)$`)
+	csvRegexRule     = regexp.MustCompile(`regex\("((?:[^"\\]|\\.)*)"\)`)
+	csvRangeRule     = regexp.MustCompile(`range\(\s*(-?[0-9.*]+)\s*,\s*(-?[0-9.*]+)\s*\)`)
+	csvLengthRule    = regexp.MustCompile(`length\(\s*([0-9*]+)\s*(?:,\s*([0-9*]+)\s*)?\)`)
+	csvIsRule        = regexp.MustCompile(`\bis\("((?:[^"\\]|\\.)*)"\)`)
+	csvAnyRule       = regexp.MustCompile(`\bany\(([^)]*)\)`)
+	csvQuotedValue   = regexp.MustCompile(`"((?:[^"\\]|\\.)*)"`)
+	csvIntegerRule   = regexp.MustCompile(`\b(positiveInteger|integer)\b`)
+	csvBlockComment  = regexp.MustCompile(`(?s)/\*.*?\*/`)
+)
+
+// csvFormatRules map CSV schema rules to JSON schema formats.
+var csvFormatRules = map[*regexp.Regexp]string{
+	regexp.MustCompile(`\buuid4\b`):     "uuid",
+	regexp.MustCompile(`\buri\b`):       "uri",
+	regexp.MustCompile(`\bxDateTime\b`): "date-time",
+	regexp.MustCompile(`\bxDate\b`):     "date",
+	regexp.MustCompile(`\bxTime\b`):     "time",
+}
+
+// csvColumnOptional is the directive of a column which may be empty.
+const csvColumnOptional = "@optional"
+
+// csvToJSON converts a CSV schema (the CSV Schema Language used by the CSV validator) into a JSON schema of a row,
+// an object with a property for every column. Columns are required unless they have the @optional directive.
+// Rules with a JSON schema counterpart are converted (integers, ranges, lengths, patterns, enumerations and formats),
+// other rules are kept in the description of the property.
+func csvToJSON(specification []byte, name string) ([]byte, error) {
+	properties := make(map[string]interface{})
+	required := make([]string, 0)
+
+	content := csvBlockComment.ReplaceAll(specification, nil)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(stripLineComment(scanner.Text()))
+		if line == "" || strings.HasPrefix(line, "version") || strings.HasPrefix(line, "@") {
+			continue
+		}
+
+		match := csvColumnPattern.FindStringSubmatch(line)
+		if match == nil {
+			return nil, fmt.Errorf("%w: CSV schema line %q isn't a column definition", ErrInvalidSchema, line)
+		}
+		column := match[1]
+		if strings.HasPrefix(column, `"`) {
+			column, _ = strconv.Unquote(column)
+		}
+		rules := strings.TrimSpace(match[2])
+
+		properties[column] = csvColumnSchema(rules)
+		if !strings.Contains(rules, csvColumnOptional) {
+			required = append(required, column)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(properties) == 0 {
+		return nil, fmt.Errorf("%w: CSV schema defines no columns", ErrInvalidSchema)
+	}
+
+	converted := map[string]interface{}{
+		"$schema":    "http://json-schema.org/draft-07/schema#",
+		"title":      name,
+		"type":       "object",
+		"properties": properties,
+		"required":   required,
+	}
+	if len(required) == 0 {
+		delete(converted, "required")
+	}
+	return marshal(converted)
+}
+
+// csvColumnSchema converts the rules of a CSV column into the JSON schema of the column.
+func csvColumnSchema(rules string) map[string]interface{} {
+	schema := map[string]interface{}{"type": "string"}
+	if rules != "" {
+		schema["description"] = "CSV rules: " + rules
+	}
+
+	if match := csvIntegerRule.FindStringSubmatch(rules); match != nil {
+		schema["type"] = "integer"
+		if match[1] == "positiveInteger" {
+			schema["minimum"] = 0
+		}
+	}
+	if match := csvRangeRule.FindStringSubmatch(rules); match != nil {
+		if schema["type"] == "string" {
+			schema["type"] = "number"
+		}
+		if minimum, err := strconv.ParseFloat(match[1], 64); err == nil {
+			schema["minimum"] = minimum
+		}
+		if maximum, err := strconv.ParseFloat(match[2], 64); err == nil {
+			schema["maximum"] = maximum
+		}
+	}
+	if match := csvLengthRule.FindStringSubmatch(rules); match != nil && schema["type"] == "string" {
+		minimum, maximum := match[1], match[2]
+		if maximum == "" {
+			maximum = minimum
+		}
+		if length, err := strconv.Atoi(minimum); err == nil {
+			schema["minLength"] = length
+		}
+		if length, err := strconv.Atoi(maximum); err == nil {
+			schema["maxLength"] = length
+		}
+	}
+	if strings.Contains(rules, "notEmpty") && schema["type"] == "string" && schema["minLength"] == nil {
+		schema["minLength"] = 1
+	}
+	if match := csvRegexRule.FindStringSubmatch(rules); match != nil {
+		schema["pattern"] = strings.ReplaceAll(match[1], `\"`, `"`)
+	}
+	for rule, format := range csvFormatRules {
+		if rule.MatchString(rules) {
+			schema["format"] = format
+		}
+	}
+
+	values := make([]interface{}, 0)
+	for _, match := range csvIsRule.FindAllStringSubmatch(rules, -1) {
+		values = append(values, match[1])
+	}
+	for _, match := range csvAnyRule.FindAllStringSubmatch(rules, -1) {
+		for _, value := range csvQuotedValue.FindAllStringSubmatch(match[1], -1) {
+			values = append(values, value[1])
+		}
+	}
+	if len(values) > 0 && schema["type"] == "string" {
+		schema["enum"] = values
+	}
+	return schema
+}
+
+// stripLineComment removes a "//" comment from a CSV schema line, ignoring slashes in quoted values.
+func stripLineComment(line string) string {
+	quoted := false
+	for i := 0; i < len(line); i++ {
+		switch {
+		case line[i] == '\\':
+			i++
+		case line[i] == '"':
+			quoted = !quoted
+		case !quoted && strings.HasPrefix(line[i:], "//"):
+			return line[:i]
+		}
+	}
+	return line
+}
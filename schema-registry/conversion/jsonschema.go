@@ -0,0 +1,112 @@
+// Copyright 2020 Syntio Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conversion
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// jsonSchema wraps a parsed JSON schema, resolving references against its definitions.
+type jsonSchema struct {
+	root map[string]interface{}
+}
+
+// parseJSONSchema parses a JSON schema specification.
+func parseJSONSchema(specification []byte) (*jsonSchema, error) {
+	var root map[string]interface{}
+	if err := json.Unmarshal(specification, &root); err != nil {
+		return nil, fmt.Errorf("%w: JSON schema isn't valid JSON: %v", ErrInvalidSchema, err)
+	}
+	return &jsonSchema{root: root}, nil
+}
+
+// resolve returns the schema referenced by a local reference, e.g. "#/definitions/address". Nil is returned for
+// references which can't be resolved.
+func (s *jsonSchema) resolve(ref string) map[string]interface{} {
+	if ref == "#" {
+		return s.root
+	}
+	if !strings.HasPrefix(ref, "#/") {
+		return nil
+	}
+	var current interface{} = s.root
+	for _, segment := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		current = object[strings.NewReplacer("~1", "/", "~0", "~").Replace(segment)]
+	}
+	resolved, _ := current.(map[string]interface{})
+	return resolved
+}
+
+// refName returns the name of a referenced definition, the last segment of the reference.
+func refName(ref string) string {
+	return ref[strings.LastIndex(ref, "/")+1:]
+}
+
+// typeOf returns the type of a schema and if it is nullable. Schemas with several non-null types, or without a type,
+// have an empty type.
+func typeOf(schema map[string]interface{}) (string, bool) {
+	switch typ := schema["type"].(type) {
+	case string:
+		return typ, false
+	case []interface{}:
+		nullable := false
+		nonNull := make([]string, 0, len(typ))
+		for _, t := range typ {
+			if t == "null" {
+				nullable = true
+			} else if s, ok := t.(string); ok {
+				nonNull = append(nonNull, s)
+			}
+		}
+		if len(nonNull) == 1 {
+			return nonNull[0], nullable
+		}
+		return "", nullable
+	}
+	if _, ok := schema["properties"]; ok {
+		return "object", false
+	}
+	return "", false
+}
+
+// requiredSet returns the required properties of an object schema.
+func requiredSet(schema map[string]interface{}) map[string]bool {
+	required := make(map[string]bool)
+	for _, name := range stringList(schema["required"]) {
+		required[name] = true
+	}
+	return required
+}
+
+// stringEnum returns the values of an enum schema if they are all strings.
+func stringEnum(schema map[string]interface{}) ([]string, bool) {
+	values, ok := schema["enum"].([]interface{})
+	if !ok || len(values) == 0 {
+		return nil, false
+	}
+	symbols := stringList(values)
+	return symbols, len(symbols) == len(values)
+}
+
+// marshal writes a converted JSON specification in a readable form.
+func marshal(specification interface{}) ([]byte, error) {
+	return json.MarshalIndent(specification, "", "  ")
+}
@@ -0,0 +1,285 @@
+// Copyright 2020 Syntio Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conversion
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jhump/protoreflect/desc/protoparse"
+)
+
+// protoMessage is a Protobuf message being written, with its nested declarations.
+type protoMessage struct {
+	name     string
+	comment  string
+	fields   []string
+	nested   []*protoMessage
+	enums    []string
+	numbers  int
+	typeName map[string]bool
+}
+
+// protoWriter converts a JSON schema into a Protobuf (proto3) specification.
+type protoWriter struct {
+	schema   *jsonSchema
+	messages []*protoMessage
+	refs     map[string]string
+	names    map[string]bool
+}
+
+// jsonToProtobuf converts a JSON schema into a proto3 specification. Objects become messages, arrays become repeated
+// fields and objects without properties become maps. Field numbers follow the alphabetical order of the properties,
+// and the JSON names of the fields are kept with the json_name option.
+func jsonToProtobuf(specification []byte, name string) ([]byte, error) {
+	schema, err := parseJSONSchema(specification)
+	if err != nil {
+		return nil, err
+	}
+	if title, ok := schema.root["title"].(string); ok && title != "" {
+		name = title
+	}
+
+	writer := &protoWriter{schema: schema, refs: make(map[string]string), names: make(map[string]bool)}
+	rootName := writer.uniqueName(pascalCase(name))
+	writer.refs["#"] = rootName
+
+	root := &protoMessage{name: rootName, comment: description(schema.root)}
+	writer.messages = append(writer.messages, root)
+	if typ, _ := typeOf(schema.root); typ == "object" {
+		err = writer.addProperties(root, schema.root)
+	} else {
+		// Messages can't be scalars, a schema of any other type is wrapped into a value field
+		err = writer.addField(root, "value", schema.root, true)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var builder strings.Builder
+	builder.WriteString("syntax = \"proto3\";\n\n")
+	fmt.Fprintf(&builder, "package %s;\n", snakeCase(name))
+	for _, message := range writer.messages {
+		builder.WriteString("\n")
+		message.write(&builder, "")
+	}
+
+	result := []byte(builder.String())
+	parser := protoparse.Parser{
+		Accessor: protoparse.FileContentsFromMap(map[string]string{"schema.proto": builder.String()}),
+	}
+	if _, err = parser.ParseFiles("schema.proto"); err != nil {
+		return nil, fmt.Errorf("%w: converted Protobuf specification is invalid: %v", ErrInvalidSchema, err)
+	}
+	return result, nil
+}
+
+// uniqueName reserves a unique top-level message name.
+func (w *protoWriter) uniqueName(name string) string {
+	unique := name
+	for i := 2; w.names[unique]; i++ {
+		unique = fmt.Sprintf("%s%d", name, i)
+	}
+	w.names[unique] = true
+	return unique
+}
+
+// addProperties adds a field to the message for every property of an object schema.
+func (w *protoWriter) addProperties(message *protoMessage, schema map[string]interface{}) error {
+	properties, _ := schema["properties"].(map[string]interface{})
+	for _, property := range sortedKeys(properties) {
+		propertySchema, _ := properties[property].(map[string]interface{})
+		if err := w.addField(message, property, propertySchema, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addField adds a field of the given JSON schema to the message.
+func (w *protoWriter) addField(message *protoMessage, property string, schema map[string]interface{},
+	repeatedAllowed bool) error {
+	fieldName := snakeCase(property)
+	typ, err := w.fieldType(message, property, schema)
+	if err != nil {
+		return err
+	}
+
+	message.numbers++
+	field := fmt.Sprintf("%s %s = %d", typ, fieldName, message.numbers)
+	if lowerCamelCase(fieldName) != property {
+		field += fmt.Sprintf(" [json_name = %q]", property)
+	}
+	if comment := description(schema); comment != "" {
+		field = "// " + comment + "\n" + field
+	}
+	message.fields = append(message.fields, field+";")
+	return nil
+}
+
+// fieldType returns the type of a field, declaring nested messages and enums in the message.
+func (w *protoWriter) fieldType(message *protoMessage, property string, schema map[string]interface{}) (string, error) {
+	schema, named, err := w.dereference(schema)
+	if err != nil {
+		return "", err
+	}
+	if named != "" {
+		return named, nil
+	}
+
+	typ, _ := typeOf(schema)
+	switch typ {
+	case "array":
+		items, _ := schema["items"].(map[string]interface{})
+		itemType, err := w.fieldType(message, singular(property), items)
+		if err != nil {
+			return "", err
+		}
+		if strings.HasPrefix(itemType, "repeated ") || strings.HasPrefix(itemType, "map<") {
+			return "", fmt.Errorf("%w: property %s is a nested array or map, which Protobuf doesn't support",
+				ErrUnsupportedConversion, property)
+		}
+		return "repeated " + itemType, nil
+	case "object":
+		if _, ok := schema["properties"]; !ok {
+			values, _ := schema["additionalProperties"].(map[string]interface{})
+			valueType, err := w.fieldType(message, property+"Value", values)
+			if err != nil {
+				return "", err
+			}
+			if strings.HasPrefix(valueType, "repeated ") || strings.HasPrefix(valueType, "map<") {
+				return "", fmt.Errorf("%w: property %s is a map of arrays or maps, which Protobuf doesn't support",
+					ErrUnsupportedConversion, property)
+			}
+			return "map<string, " + valueType + ">", nil
+		}
+		nested := &protoMessage{name: message.uniqueNested(pascalCase(property)), comment: description(schema)}
+		message.nested = append(message.nested, nested)
+		return nested.name, w.addProperties(nested, schema)
+	}
+
+	if symbols, ok := stringEnum(schema); ok {
+		return message.declareEnum(pascalCase(property), symbols), nil
+	}
+	switch typ {
+	case "integer":
+		return "int64", nil
+	case "number":
+		return "double", nil
+	case "boolean":
+		return "bool", nil
+	}
+	return "string", nil
+}
+
+// dereference resolves a reference of a schema. Referenced objects are declared as top-level messages once, their
+// name is returned.
+func (w *protoWriter) dereference(schema map[string]interface{}) (map[string]interface{}, string, error) {
+	ref, ok := schema["$ref"].(string)
+	if !ok {
+		return schema, "", nil
+	}
+	if named, ok := w.refs[ref]; ok {
+		return nil, named, nil
+	}
+	resolved := w.schema.resolve(ref)
+	if resolved == nil {
+		return nil, "", fmt.Errorf("%w: reference %s can't be resolved", ErrInvalidSchema, ref)
+	}
+	if typ, _ := typeOf(resolved); typ != "object" || resolved["properties"] == nil {
+		return resolved, "", nil
+	}
+
+	message := &protoMessage{name: w.uniqueName(pascalCase(refName(ref))), comment: description(resolved)}
+	w.refs[ref] = message.name
+	w.messages = append(w.messages, message)
+	return nil, message.name, w.addProperties(message, resolved)
+}
+
+// uniqueNested reserves a unique name of a nested declaration in the message.
+func (m *protoMessage) uniqueNested(name string) string {
+	if m.typeName == nil {
+		m.typeName = make(map[string]bool)
+	}
+	unique := name
+	for i := 2; m.typeName[unique] || unique == m.name; i++ {
+		unique = fmt.Sprintf("%s%d", name, i)
+	}
+	m.typeName[unique] = true
+	return unique
+}
+
+// declareEnum declares a nested enum in the message. The zero value of a proto3 enum is an unspecified value.
+func (m *protoMessage) declareEnum(name string, symbols []string) string {
+	name = m.uniqueNested(name)
+	prefix := strings.ToUpper(snakeCase(name)) + "_"
+
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "enum %s {\n", name)
+	fmt.Fprintf(&builder, "  %sUNSPECIFIED = 0;\n", prefix)
+	for i, symbol := range symbols {
+		fmt.Fprintf(&builder, "  %s%s = %d;\n", prefix, strings.ToUpper(snakeCase(symbol)), i+1)
+	}
+	builder.WriteString("}")
+	m.enums = append(m.enums, builder.String())
+	return name
+}
+
+// write writes the message declaration with the given indentation.
+func (m *protoMessage) write(builder *strings.Builder, indent string) {
+	if m.comment != "" {
+		fmt.Fprintf(builder, "%s// %s\n", indent, m.comment)
+	}
+	fmt.Fprintf(builder, "%smessage %s {\n", indent, m.name)
+	for _, enum := range m.enums {
+		for _, line := range strings.Split(enum, "\n") {
+			fmt.Fprintf(builder, "%s  %s\n", indent, line)
+		}
+	}
+	for _, nested := range m.nested {
+		nested.write(builder, indent+"  ")
+	}
+	for _, field := range m.fields {
+		for _, line := range strings.Split(field, "\n") {
+			fmt.Fprintf(builder, "%s  %s\n", indent, line)
+		}
+	}
+	fmt.Fprintf(builder, "%s}\n", indent)
+}
+
+// description returns the description of a schema on a single line.
+func description(schema map[string]interface{}) string {
+	text, _ := schema["description"].(string)
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// lowerCamelCase returns the JSON name Protobuf derives from a field name, e.g. "user_name" becomes "userName".
+func lowerCamelCase(fieldName string) string {
+	parts := strings.Split(fieldName, "_")
+	for i := 1; i < len(parts); i++ {
+		if parts[i] != "" {
+			parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+// singular returns the name of an array item, used for nested item messages.
+func singular(name string) string {
+	if strings.HasSuffix(name, "s") && len(name) > 1 {
+		return strings.TrimSuffix(name, "s")
+	}
+	return name + "Item"
+}
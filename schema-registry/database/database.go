@@ -20,6 +20,7 @@ package database
 
 import (
 	"context"
+	"errors"
 
 	. "github.com/syntio/schema-registry/model"
 	"github.com/syntio/schema-registry/model/dto"
@@ -28,10 +29,35 @@ import (
 //
 // DBConnector is an interface between the REST Server and the underlying database.
 //
+// Every method is scoped to a namespace. An empty namespace matches schemas of any namespace, which keeps the
+// original (non-namespaced) routes working.
+//
 type DBExecutor interface {
-	CreateSchema(ctx context.Context, dto *dto.SchemaDTO) (*InsertInfo, bool, error)
-	GetSchemaByIdAndVersion(ctx context.Context, id string, version int32) (*Schema, bool)
-	UpdateSchemaById(ctx context.Context, id string, schema []byte, autogenerated bool) (*InsertInfo, bool, error)
-	GetSchemaVersions(ctx context.Context, id string) (*[]*SchemaDetails, error)
-	DeleteById(ctx context.Context, id string) error
+	CreateSchema(ctx context.Context, namespace string, dto *dto.SchemaDTO) (*InsertInfo, bool, error)
+	GetSchemaById(ctx context.Context, namespace, id string) (*Schema, bool)
+	GetSchemaByIdAndVersion(ctx context.Context, namespace, id string, version int32) (*Schema, error)
+	UpdateSchemaById(ctx context.Context, namespace, id string, schema []byte, autogenerated bool) (*InsertInfo, bool, error)
+	GetSchemaVersions(ctx context.Context, namespace, id string) (*[]*SchemaDetails, error)
+	CountSchemas(ctx context.Context, namespace string) (int, error)
+	GetSchemas(ctx context.Context, namespace string) ([]*Schema, error)
+	DeleteById(ctx context.Context, namespace, id string) error
+	DeleteVersions(ctx context.Context, namespace, id string, versions []int32) error
+
+	SetEvolutionPolicy(ctx context.Context, namespace, id string, policy *EvolutionPolicy) error
+	CreatePendingEvolution(ctx context.Context, namespace string, pending *PendingEvolution) (string, error)
+	GetPendingEvolutions(ctx context.Context, namespace, id string) ([]*PendingEvolution, error)
+	GetPendingEvolution(ctx context.Context, namespace, id, pendingId string) (*PendingEvolution, bool)
+	UpdatePendingEvolution(ctx context.Context, namespace string, pending *PendingEvolution) error
+
+	SetRetentionPolicy(ctx context.Context, namespace, id string, policy *RetentionPolicy) error
+
+	AddSchemaLink(ctx context.Context, namespace, id string, link SchemaLink) error
+
+	Ping(ctx context.Context) error
 }
+
+// ErrNotFound is returned when the requested schema doesn't exist in the requested namespace.
+var ErrNotFound = errors.New("schema not found")
+
+// ErrUnavailable is returned when the underlying database can't be reached.
+var ErrUnavailable = errors.New("storage backend unavailable")
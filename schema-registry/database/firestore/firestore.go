@@ -16,131 +16,321 @@ package firestore
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"sync"
+	"time"
 
 	"cloud.google.com/go/firestore"
 	"github.com/syntio/schema-registry/configuration"
+	"github.com/syntio/schema-registry/database"
 	"github.com/syntio/schema-registry/model"
 	"github.com/syntio/schema-registry/model/dto"
 	"github.com/syntio/schema-registry/util"
 	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 //
 //	Firestore implementation for database.DBExecutor interface.
 //
 type FirestoreDB struct {
-	Collection string
+	Collection             string
+	AccessTrackingInterval time.Duration
 }
 
-var client *firestore.Client
+var (
+	client     *firestore.Client
+	clientLock sync.Mutex
+)
+
+// connect creates the Firestore client on first use. A failed initialization is retried on the next call, so the
+// server keeps running and reports the storage as unavailable while Firestore can't be reached.
+//
+// Every method reaches the client through connect (directly or through getSchemaDocument) before using it.
+func connect() error {
+	clientLock.Lock()
+	defer clientLock.Unlock()
+	if client != nil {
+		return nil
+	}
 
-func init() {
-	ctx := context.Background()
 	credFilename := os.Getenv("SERVICE_ACCOUNT_KEY_FILE")
 	bucket := os.Getenv("BUCKET_NAME")
 	json := configuration.ReadFromBucket(bucket, credFilename)
 	option := option.WithCredentialsJSON(json)
 	projectId := os.Getenv("PROJECT_ID")
-	var initErr error
-	client, initErr = firestore.NewClient(ctx, projectId, option)
-	if initErr != nil {
-		log.Fatalf("Firestore client initialization failed. Server can't start properly.\nError: %s", initErr)
+	created, err := firestore.NewClient(context.Background(), projectId, option)
+	if err != nil {
+		log.Printf("Firestore client initialization failed: %s\n", err)
+		return fmt.Errorf("%w: %v", database.ErrUnavailable, err)
 	}
+	client = created
+	return nil
 }
 
-// GetSchemaByIdAndVersion retrieves a schema by its id and version. Method returns a boolean flag which defines
-// if the wanted schema exists. If the schema is found, representing SchemaInfo is retrieved.
-func (db *FirestoreDB) GetSchemaByIdAndVersion(ctx context.Context, id string, version int32) (*model.Schema, bool) {
+// Ping checks if the schema collection can be read.
+func (db *FirestoreDB) Ping(ctx context.Context) error {
+	if err := connect(); err != nil {
+		return err
+	}
+	if _, err := client.Collection(db.Collection).Limit(1).Documents(ctx).GetAll(); err != nil {
+		return fmt.Errorf("%w: %v", database.ErrUnavailable, err)
+	}
+	return nil
+}
+
+// getSchemaDocument reads the schema document with the given id. database.ErrNotFound is returned if the document
+// doesn't exist or if it belongs to another namespace, and database.ErrUnavailable if it can't be read.
+func (db *FirestoreDB) getSchemaDocument(ctx context.Context, namespace, id string) (*model.Schema, error) {
+	if err := connect(); err != nil {
+		return nil, err
+	}
 	document, err := client.Collection(db.Collection).Doc(id).Get(ctx)
 	if err != nil {
-
-		return nil, false
+		return nil, readError(err)
 	}
 	var result *model.Schema
 	if err = document.DataTo(&result); err != nil {
+		return nil, err
+	}
+	if !result.InNamespace(namespace) {
+		return nil, database.ErrNotFound
+	}
+	return result, nil
+}
 
+// readError returns the error of a failed read of a document: database.ErrNotFound if the document doesn't exist,
+// and database.ErrUnavailable wrapping the failure otherwise.
+func readError(err error) error {
+	if status.Code(err) == codes.NotFound {
+		return database.ErrNotFound
+	}
+	return fmt.Errorf("%w: %v", database.ErrUnavailable, err)
+}
+
+// versionsCollection returns the collection holding the versions of a schema. Every version is stored as a
+// separate document, so retrieving one version doesn't load the whole history of the schema.
+func (db *FirestoreDB) versionsCollection(id string) *firestore.CollectionRef {
+	return client.Collection(db.Collection).Doc(id).Collection("versions")
+}
+
+// versionDocument returns the document of a single schema version.
+func (db *FirestoreDB) versionDocument(id string, version int32) *firestore.DocumentRef {
+	return db.versionsCollection(id).Doc(strconv.FormatInt(int64(version), 10))
+}
+
+// loadVersions loads every version of a schema into its SchemaDetails, ordered by version. Schemas stored before
+// versions were moved into separate documents already hold their versions.
+func (db *FirestoreDB) loadVersions(ctx context.Context, schema *model.Schema) error {
+	if len(schema.SchemaDetails) > 0 {
+		return nil
+	}
+	details := make([]*model.SchemaDetails, 0)
+	it := db.versionsCollection(schema.Id).OrderBy("version", firestore.Asc).Documents(ctx)
+	for sh, err := it.Next(); err != iterator.Done; sh, err = it.Next() {
+		if err != nil {
+			return err
+		}
+		var d *model.SchemaDetails
+		if err = sh.DataTo(&d); err != nil {
+			return err
+		}
+		details = append(details, d)
+	}
+	schema.SchemaDetails = details
+	return nil
+}
+
+// migrateEmbeddedVersions moves the versions of a schema stored before versions were moved into separate
+// documents into the versions collection. The schema document keeps only the hashes of its versions.
+func (db *FirestoreDB) migrateEmbeddedVersions(ctx context.Context, schema *model.Schema) error {
+	if len(schema.SchemaDetails) == 0 {
+		return nil
+	}
+	batch := client.Batch()
+	schema.Hashes = make(map[string]int32, len(schema.SchemaDetails))
+	for _, d := range schema.SchemaDetails {
+		if d.CreationDate.IsZero() {
+			d.CreationDate = schema.CreationDate
+		}
+		batch.Set(db.versionDocument(schema.Id, d.Version), d)
+		schema.Hashes[d.SchemaHash] = d.Version
+		if d.Version > schema.LatestVersion {
+			schema.LatestVersion = d.Version
+		}
+	}
+	schema.SchemaDetails = nil
+	batch.Set(client.Collection(db.Collection).Doc(schema.Id), schema)
+	if _, err := batch.Commit(ctx); err != nil {
+		log.Printf("Could not migrate the versions of schema %s\n", schema.Id)
+		return err
+	}
+	return nil
+}
+
+// GetSchemaById retrieves a schema with all of its versions. Method returns a boolean flag which defines
+// if the wanted schema exists in the namespace.
+func (db *FirestoreDB) GetSchemaById(ctx context.Context, namespace, id string) (*model.Schema, bool) {
+	result, err := db.getSchemaDocument(ctx, namespace, id)
+	if err != nil {
+		return nil, false
+	}
+	if err = db.loadVersions(ctx, result); err != nil {
 		return nil, false
 	}
+	return result, true
+}
+
+// GetSchemaByIdAndVersion retrieves a schema by its id and version, with the requested version as its only
+// details. database.ErrNotFound is returned if the schema or the version doesn't exist, and
+// database.ErrUnavailable if they can't be read.
+// Only the requested version is read from the database, and its last access time is tracked for the retention.
+func (db *FirestoreDB) GetSchemaByIdAndVersion(ctx context.Context, namespace, id string,
+	version int32) (*model.Schema, error) {
+	result, err := db.getSchemaDocument(ctx, namespace, id)
+	if err != nil {
+		return nil, err
+	}
 
 	for i, v := range result.SchemaDetails {
 		if v.Version == version {
 			new_details := result.SchemaDetails[i : i+1]
 			result.SchemaDetails = new_details
-			return result, true
+			return result, nil
 		}
 	}
+	if len(result.SchemaDetails) > 0 {
+		return nil, database.ErrNotFound
+	}
 
-	return nil, false
+	document, err := db.versionDocument(id, version).Get(ctx)
+	if err != nil {
+		return nil, readError(err)
+	}
+	var details *model.SchemaDetails
+	if err = document.DataTo(&details); err != nil {
+		return nil, err
+	}
+	db.trackAccess(ctx, id, details)
+	result.SchemaDetails = []*model.SchemaDetails{details}
+
+	return result, nil
+}
+
+// trackAccess updates the last access time of a schema version. To keep reads cheap, the time is written at most
+// once per AccessTrackingInterval.
+func (db *FirestoreDB) trackAccess(ctx context.Context, id string, details *model.SchemaDetails) {
+	now := time.Now()
+	if now.Sub(details.LastAccessed) < db.AccessTrackingInterval {
+		return
+	}
+	_, err := db.versionDocument(id, details.Version).Update(ctx, []firestore.Update{
+		{Path: "last-accessed", Value: now},
+	})
+	if err != nil {
+		log.Printf("Could not track the access of schema %s version %d: %v\n", id, details.Version, err)
+		return
+	}
+	details.LastAccessed = now
 }
 
 // GetSchemaBversions returns a list of SchemaDetails from the database.
-// The input arguments are the request contex and the document/row ID.
+// The input arguments are the request contex, the namespace and the document/row ID.
 // The return value is a list of model.SchemaDetails and an error in case of a fault or failure.
-func (db *FirestoreDB) GetSchemaVersions(ctx context.Context, id string) (*[]*model.SchemaDetails, error) {
-	document, err := client.Collection(db.Collection).Doc(id).Get(ctx)
+func (db *FirestoreDB) GetSchemaVersions(ctx context.Context, namespace, id string) (*[]*model.SchemaDetails, error) {
+	result, err := db.getSchemaDocument(ctx, namespace, id)
 	if err != nil {
 		return nil, err
 	}
-	var result *model.Schema
-	if err = document.DataTo(&result); err != nil {
+	if err = db.loadVersions(ctx, result); err != nil {
 		return nil, err
 	}
 	return &result.SchemaDetails, nil
 }
 
 //UpdateSchemaById updates the schema Specification e.g. creates a new entry of SchemaDetails.
-// The input arguments are the request context, followed by the ID string of the document/row ID,
+// The input arguments are the request context, followed by the namespace and the ID string of the document/row ID,
 // specification in []byte form and a flag indicating if Schema was manully updated or dynamically evolved.
+// The new version number is read and written in one transaction, and the version document is created rather than
+// set, so concurrent updates of the same schema never get the same version; the transaction retries the update which
+// loses.
 // The output is an model.InsertInfo structure, a flag indicating if new version of schema was added and an error.
-func (db *FirestoreDB) UpdateSchemaById(ctx context.Context, id string,
+func (db *FirestoreDB) UpdateSchemaById(ctx context.Context, namespace, id string,
 	schema []byte, autogenerated bool) (*model.InsertInfo, bool, error) {
-	document, err := client.Collection(db.Collection).Doc(id).Get(ctx)
+	result, err := db.getSchemaDocument(ctx, namespace, id)
 	if err != nil {
 		return nil, false, err
 	}
-	var result *model.Schema
-	if err = document.DataTo(&result); err != nil {
+	if err = db.migrateEmbeddedVersions(ctx, result); err != nil {
 		return nil, false, err
 	}
 
 	hash := util.CalculateSchemaHash(schema)
 
-	if exists, info := db.existsByHash(hash, result); exists {
-		log.Printf("Schema %v with version %d already exists ", info.Id, info.Version)
-		return info, false, nil
-	}
+	var info *model.InsertInfo
+	var added bool
+	schemaDocument := client.Collection(db.Collection).Doc(id)
+	err = client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		document, err := tx.Get(schemaDocument)
+		if err != nil {
+			return err
+		}
+		var current *model.Schema
+		if err = document.DataTo(&current); err != nil {
+			return err
+		}
 
-	newVer := int32(len(result.SchemaDetails) + 1)
+		if exists, existing := db.existsByHash(hash, current); exists {
+			info, added = existing, false
+			return nil
+		}
 
-	d := &model.SchemaDetails{
-		Version:       newVer,
-		SchemaHash:    hash,
-		Specification: util.SchemaBase64Encode(schema),
-	}
-	details := result.SchemaDetails
-	details = append(details, d)
-	result.SchemaDetails = details
-	info := &model.InsertInfo{
-		Id:      result.Id,
-		Version: newVer,
-	}
+		newVer := current.LatestVersion + 1
+		d := &model.SchemaDetails{
+			Version:       newVer,
+			SchemaHash:    hash,
+			Specification: util.SchemaBase64Encode(schema),
+			Autogenerated: autogenerated,
+			CreationDate:  time.Now(),
+		}
+		if err = tx.Create(db.versionDocument(id, newVer), d); err != nil {
+			return err
+		}
+		if err = tx.Update(schemaDocument, []firestore.Update{
+			{Path: "latest-version", Value: newVer},
+			{FieldPath: firestore.FieldPath{"hashes", hash}, Value: newVer},
+		}); err != nil {
+			return err
+		}
 
-	if _, err := client.Collection(db.Collection).Doc(id).Set(ctx, result); err != nil {
+		info, added = &model.InsertInfo{Id: current.Id, Version: newVer}, true
+		return nil
+	})
+	if err != nil {
 		log.Println("Could not update new schema")
 		return nil, false, err
 	}
+	if !added {
+		log.Printf("Schema %v with version %d already exists ", info.Id, info.Version)
+	}
 
-	return info, true, nil
-
+	return info, added, nil
 }
 
 // CreateSchema persists a new Schema structure into the document or relational database.
-// Input arguemtns are request context, and a DTO describing the basic new schema parameters.
+// Input arguemtns are request context, the namespace of the schema and a DTO describing the basic new schema
+// parameters. Existing schemas are only looked up in the same namespace.
 // The output is an model.InsertInfo structure, a flag indicating if new version of schema was added and an error.
-func (db *FirestoreDB) CreateSchema(ctx context.Context, dto *dto.SchemaDTO) (*model.InsertInfo, bool, error) {
+func (db *FirestoreDB) CreateSchema(ctx context.Context, namespace string,
+	dto *dto.SchemaDTO) (*model.InsertInfo, bool, error) {
+	if err := connect(); err != nil {
+		return nil, false, err
+	}
 	byteSchema := []byte(dto.Specification)
 	hash := util.CalculateSchemaHash(byteSchema)
 
@@ -155,6 +345,9 @@ func (db *FirestoreDB) CreateSchema(ctx context.Context, dto *dto.SchemaDTO) (*m
 		if err != nil {
 			return nil, false, err
 		}
+		if !schema.InNamespace(namespace) {
+			continue
+		}
 		if exists, info := db.existsByHash(hash, schema); exists {
 			return info, false, err
 		}
@@ -162,8 +355,16 @@ func (db *FirestoreDB) CreateSchema(ctx context.Context, dto *dto.SchemaDTO) (*m
 	version := int32(1)
 	doc := client.Collection(db.Collection).NewDoc()
 
-	sc := util.DtoToSchema(dto, doc.ID, hash, byteSchema, version)
-	if _, err := doc.Set(ctx, sc); err != nil {
+	sc := util.DtoToSchema(dto, namespace, doc.ID, hash, byteSchema, version)
+	details := sc.SchemaDetails[0]
+	sc.SchemaDetails = nil
+	sc.LatestVersion = version
+	sc.Hashes = map[string]int32{hash: version}
+
+	batch := client.Batch()
+	batch.Set(doc, sc)
+	batch.Set(db.versionDocument(doc.ID, version), details)
+	if _, err := batch.Commit(ctx); err != nil {
 		log.Println("Could not create new schema")
 		return nil, false, err
 	}
@@ -179,6 +380,12 @@ func (db *FirestoreDB) CreateSchema(ctx context.Context, dto *dto.SchemaDTO) (*m
 // the output is a flag indicating if the schema exists, and were the flag true the corresponding model.InsertInfo
 // struct.
 func (db *FirestoreDB) existsByHash(hash string, schema *model.Schema) (bool, *model.InsertInfo) {
+	if version, ok := schema.Hashes[hash]; ok {
+		return true, &model.InsertInfo{
+			Id:      schema.Id,
+			Version: version,
+		}
+	}
 	var info *model.InsertInfo
 	for _, sd := range schema.SchemaDetails {
 		if sd.SchemaHash == hash {
@@ -192,10 +399,205 @@ func (db *FirestoreDB) existsByHash(hash string, schema *model.Schema) (bool, *m
 	return false, nil
 }
 
+// CountSchemas returns the number of schemas registered in the given namespace.
+// An error is returned if the existing schemas couldn't be read.
+func (db *FirestoreDB) CountSchemas(ctx context.Context, namespace string) (int, error) {
+	schemas, err := db.GetSchemas(ctx, namespace)
+	if err != nil {
+		return 0, err
+	}
+	return len(schemas), nil
+}
+
+// GetSchemas returns every schema registered in the given namespace, without their versions.
+// An error is returned if the existing schemas couldn't be read.
+func (db *FirestoreDB) GetSchemas(ctx context.Context, namespace string) ([]*model.Schema, error) {
+	if err := connect(); err != nil {
+		return nil, err
+	}
+	result := make([]*model.Schema, 0)
+	it := client.Collection(db.Collection).Documents(ctx)
+	for sh, err := it.Next(); err != iterator.Done; sh, err = it.Next() {
+		if err != nil {
+			return nil, err
+		}
+		var schema *model.Schema
+		if err = sh.DataTo(&schema); err != nil {
+			return nil, err
+		}
+		if schema.InNamespace(namespace) {
+			schema.SchemaDetails = nil
+			result = append(result, schema)
+		}
+	}
+	return result, nil
+}
+
+// DeleteVersions removes the given versions of a schema, together with their hashes.
+// An error is returned if the schema doesn't exist or if the versions couldn't be removed.
+func (db *FirestoreDB) DeleteVersions(ctx context.Context, namespace, id string, versions []int32) error {
+	schema, err := db.getSchemaDocument(ctx, namespace, id)
+	if err != nil {
+		return err
+	}
+	if err = db.migrateEmbeddedVersions(ctx, schema); err != nil {
+		return err
+	}
+
+	removed := make(map[int32]bool, len(versions))
+	for _, version := range versions {
+		removed[version] = true
+	}
+
+	batch := client.Batch()
+	updates := make([]firestore.Update, 0)
+	for hash, version := range schema.Hashes {
+		if removed[version] {
+			updates = append(updates, firestore.Update{FieldPath: firestore.FieldPath{"hashes", hash},
+				Value: firestore.Delete})
+		}
+	}
+	for _, version := range versions {
+		batch.Delete(db.versionDocument(id, version))
+	}
+	if len(updates) > 0 {
+		batch.Update(client.Collection(db.Collection).Doc(id), updates)
+	}
+	_, err = batch.Commit(ctx)
+	return err
+}
+
 // DeleteById deletes a schema from the document database
-// Input arguemnts are request contex and a string ID of the document.
+// Input arguemnts are request contex, the namespace and a string ID of the document.
 // An error is returned if the id were wrong or if arbitrary connection issues were at hand.
-func (db *FirestoreDB) DeleteById(ctx context.Context, id string) error {
+func (db *FirestoreDB) DeleteById(ctx context.Context, namespace, id string) error {
+	if _, err := db.getSchemaDocument(ctx, namespace, id); err != nil {
+		return err
+	}
+	for _, collection := range []*firestore.CollectionRef{db.versionsCollection(id), db.pendingCollection(id)} {
+		documents, err := collection.DocumentRefs(ctx).GetAll()
+		if err != nil {
+			return err
+		}
+		for _, document := range documents {
+			if _, err = document.Delete(ctx); err != nil {
+				return err
+			}
+		}
+	}
 	_, err := client.Collection(db.Collection).Doc(id).Delete(ctx)
 	return err
 }
+
+// SetEvolutionPolicy sets the evolution policy of a schema.
+// Input arguments are request context, the namespace, a string ID of the document and the new policy.
+// An error is returned if the schema doesn't exist or if it couldn't be updated.
+func (db *FirestoreDB) SetEvolutionPolicy(ctx context.Context, namespace, id string,
+	policy *model.EvolutionPolicy) error {
+	if _, err := db.getSchemaDocument(ctx, namespace, id); err != nil {
+		return err
+	}
+	_, err := client.Collection(db.Collection).Doc(id).Update(ctx, []firestore.Update{
+		{Path: "evolution-policy", Value: policy},
+	})
+	return err
+}
+
+// pendingCollection returns the collection holding the evolutions of a schema waiting for an approval.
+func (db *FirestoreDB) pendingCollection(id string) *firestore.CollectionRef {
+	return client.Collection(db.Collection).Doc(id).Collection("pending")
+}
+
+// CreatePendingEvolution adds an evolved schema version to the approval queue of its schema.
+// The output is the ID of the queued evolution and an error.
+func (db *FirestoreDB) CreatePendingEvolution(ctx context.Context, namespace string,
+	pending *model.PendingEvolution) (string, error) {
+	if _, err := db.getSchemaDocument(ctx, namespace, pending.SchemaId); err != nil {
+		return "", err
+	}
+	doc := db.pendingCollection(pending.SchemaId).NewDoc()
+	if _, err := doc.Set(ctx, pending); err != nil {
+		log.Println("Could not queue the evolved schema")
+		return "", err
+	}
+	pending.Id = doc.ID
+	return doc.ID, nil
+}
+
+// GetPendingEvolutions returns every queued evolution of a schema, in order of creation.
+func (db *FirestoreDB) GetPendingEvolutions(ctx context.Context, namespace,
+	id string) ([]*model.PendingEvolution, error) {
+	if _, err := db.getSchemaDocument(ctx, namespace, id); err != nil {
+		return nil, err
+	}
+	result := make([]*model.PendingEvolution, 0)
+	it := db.pendingCollection(id).OrderBy("creation-date", firestore.Asc).Documents(ctx)
+	for sh, err := it.Next(); err != iterator.Done; sh, err = it.Next() {
+		if err != nil {
+			return nil, err
+		}
+		var pending *model.PendingEvolution
+		if err = sh.DataTo(&pending); err != nil {
+			return nil, err
+		}
+		pending.Id = sh.Ref.ID
+		result = append(result, pending)
+	}
+	return result, nil
+}
+
+// GetPendingEvolution retrieves a single queued evolution of a schema. Method returns a boolean flag which defines
+// if the queued evolution exists.
+func (db *FirestoreDB) GetPendingEvolution(ctx context.Context, namespace, id,
+	pendingId string) (*model.PendingEvolution, bool) {
+	if _, err := db.getSchemaDocument(ctx, namespace, id); err != nil {
+		return nil, false
+	}
+	document, err := db.pendingCollection(id).Doc(pendingId).Get(ctx)
+	if err != nil {
+		return nil, false
+	}
+	var pending *model.PendingEvolution
+	if err = document.DataTo(&pending); err != nil {
+		return nil, false
+	}
+	pending.Id = document.Ref.ID
+	return pending, true
+}
+
+// UpdatePendingEvolution persists the changes (status and registered version) of a queued evolution.
+func (db *FirestoreDB) UpdatePendingEvolution(ctx context.Context, namespace string,
+	pending *model.PendingEvolution) error {
+	if _, err := db.getSchemaDocument(ctx, namespace, pending.SchemaId); err != nil {
+		return err
+	}
+	_, err := db.pendingCollection(pending.SchemaId).Doc(pending.Id).Set(ctx, pending)
+	return err
+}
+
+// SetRetentionPolicy sets the retention policy of a schema.
+// Input arguments are request context, the namespace, a string ID of the document and the new policy.
+// An error is returned if the schema doesn't exist or if it couldn't be updated.
+func (db *FirestoreDB) SetRetentionPolicy(ctx context.Context, namespace, id string,
+	policy *model.RetentionPolicy) error {
+	if _, err := db.getSchemaDocument(ctx, namespace, id); err != nil {
+		return err
+	}
+	_, err := client.Collection(db.Collection).Doc(id).Update(ctx, []firestore.Update{
+		{Path: "retention-policy", Value: policy},
+	})
+	return err
+}
+
+// AddSchemaLink records a relationship of a schema with another schema, an already recorded link isn't duplicated.
+// Input arguments are request context, the namespace, a string ID of the document and the link.
+// An error is returned if the schema doesn't exist or if it couldn't be updated.
+func (db *FirestoreDB) AddSchemaLink(ctx context.Context, namespace, id string, link model.SchemaLink) error {
+	if _, err := db.getSchemaDocument(ctx, namespace, id); err != nil {
+		return err
+	}
+	_, err := client.Collection(db.Collection).Doc(id).Update(ctx, []firestore.Update{
+		{Path: "links", Value: firestore.ArrayUnion(link)},
+	})
+	return err
+}
@@ -0,0 +1,151 @@
+// Copyright 2020 Syntio Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package docs renders human-readable documentation of registered schemas as HTML or Markdown.
+package docs
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/syntio/schema-registry/model"
+	"github.com/syntio/schema-registry/util"
+)
+
+// Documentation formats.
+const (
+	FormatHTML     = "html"
+	FormatMarkdown = "markdown"
+)
+
+// ErrUnsupportedFormat is returned when documentation is requested in an unknown format.
+var ErrUnsupportedFormat = errors.New("unsupported documentation format")
+
+// ErrUnsupportedSchemaType is returned when the fields of a schema type can't be extracted.
+var ErrUnsupportedSchemaType = errors.New("unsupported schema type")
+
+// Field is a documented field of a schema. Nested fields are named by their path, e.g. "address.city".
+type Field struct {
+	Path        string
+	Type        string
+	Required    bool
+	Description string
+	Examples    []string
+	Enum        []string
+}
+
+// Document describes a schema version for readers.
+type Document struct {
+	Schema        *model.Schema
+	Version       *model.SchemaDetails
+	Fields        []Field
+	Specification string
+	// Note explains why the fields of a schema couldn't be documented.
+	Note string
+}
+
+// Links build the links between documentation pages. The registry server links its own routes, a static export
+// links the exported files.
+type Links struct {
+	Catalog string
+	History func(id string) string
+	Version func(id string, version int32) string
+}
+
+// Describe documents a version of a schema. Fields are extracted from JSON, Avro, Protobuf and CSV schemas, the
+// specification of other schema types is only shown as is.
+func Describe(schema *model.Schema, version *model.SchemaDetails) (*Document, error) {
+	specification, err := util.SchemaBase64Decode(version.Specification)
+	if err != nil {
+		return nil, err
+	}
+
+	document := &Document{Schema: schema, Version: version, Specification: string(specification)}
+	var indented bytes.Buffer
+	if json.Indent(&indented, specification, "", "  ") == nil {
+		document.Specification = indented.String()
+	}
+
+	document.Fields, err = Fields(schema.SchemaType, specification)
+	if errors.Is(err, ErrUnsupportedSchemaType) {
+		document.Note = fmt.Sprintf("Fields of %s schemas aren't documented, see the specification.", schema.SchemaType)
+	} else if err != nil {
+		document.Note = fmt.Sprintf("Fields couldn't be documented: %v", err)
+	}
+	return document, nil
+}
+
+// Fields extracts the fields of a JSON, Avro, Protobuf or CSV specification. ErrUnsupportedSchemaType is returned
+// for other schema types.
+func Fields(schemaType string, specification []byte) ([]Field, error) {
+	switch strings.ToLower(schemaType) {
+	case "json":
+		return jsonFields(specification)
+	case "avro":
+		return avroFields(specification)
+	case "protobuf":
+		return protobufFields(specification)
+	case "csv":
+		return csvFields(specification)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedSchemaType, schemaType)
+	}
+}
+
+// RenderVersion renders the documentation of a schema version in the given format.
+func RenderVersion(document *Document, format string, links Links) ([]byte, error) {
+	return render(format, "version", map[string]interface{}{"Document": document, "Links": links})
+}
+
+// RenderHistory renders the version history of a schema in the given format. The versions of the schema have to be
+// loaded.
+func RenderHistory(schema *model.Schema, format string, links Links) ([]byte, error) {
+	return render(format, "history", map[string]interface{}{"Schema": schema, "Links": links})
+}
+
+// RenderCatalog renders the list of registered schemas in the given format.
+func RenderCatalog(schemas []*model.Schema, format string, links Links) ([]byte, error) {
+	return render(format, "catalog", map[string]interface{}{"Schemas": schemas, "Links": links})
+}
+
+// render executes a page template of the format.
+func render(format, page string, data interface{}) ([]byte, error) {
+	switch strings.ToLower(format) {
+	case FormatHTML, "":
+		return executeHTML(page, data)
+	case FormatMarkdown, "md":
+		return executeMarkdown(page, data)
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnsupportedFormat, format)
+}
+
+// Extension returns the file extension of a documentation format.
+func Extension(format string) string {
+	if strings.ToLower(format) == FormatMarkdown || strings.ToLower(format) == "md" {
+		return ".md"
+	}
+	return ".html"
+}
+
+// formatDate formats a date in the documentation.
+func formatDate(date time.Time) string {
+	if date.IsZero() {
+		return "-"
+	}
+	return date.UTC().Format("2006-01-02 15:04 MST")
+}
@@ -0,0 +1,471 @@
+// Copyright 2020 Syntio Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docs
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/syntio/schema-registry/conversion"
+)
+
+// jsonFields documents the properties of a JSON schema, nested objects and array items included.
+func jsonFields(specification []byte) ([]Field, error) {
+	var root map[string]interface{}
+	if err := json.Unmarshal(specification, &root); err != nil {
+		return nil, err
+	}
+	walker := &jsonWalker{root: root, visiting: make(map[string]bool)}
+	walker.walk(root, "")
+	return walker.fields, nil
+}
+
+// jsonWalker collects the fields of a JSON schema. References being visited are tracked to stop at recursion.
+type jsonWalker struct {
+	root     map[string]interface{}
+	fields   []Field
+	visiting map[string]bool
+}
+
+// walk documents the properties of an object schema and descends into nested objects and arrays.
+func (w *jsonWalker) walk(schema map[string]interface{}, path string) {
+	if ref, ok := schema["$ref"].(string); ok {
+		if w.visiting[ref] {
+			return
+		}
+		w.visiting[ref] = true
+		defer delete(w.visiting, ref)
+		schema = w.resolve(ref)
+		if schema == nil {
+			return
+		}
+	}
+
+	if items, ok := schema["items"].(map[string]interface{}); ok {
+		w.walk(items, path+"[]")
+	}
+	properties, _ := schema["properties"].(map[string]interface{})
+	required := make(map[string]bool)
+	for _, name := range stringList(schema["required"]) {
+		required[name] = true
+	}
+
+	for _, name := range sortedKeys(properties) {
+		property, _ := properties[name].(map[string]interface{})
+		fieldPath := name
+		if path != "" {
+			fieldPath = path + "." + name
+		}
+		w.fields = append(w.fields, Field{
+			Path:        fieldPath,
+			Type:        w.typeLabel(property),
+			Required:    required[name],
+			Description: w.description(property),
+			Examples:    examples(property),
+			Enum:        enumValues(property["enum"]),
+		})
+		w.walk(property, fieldPath)
+	}
+}
+
+// resolve returns the schema of a local reference, e.g. "#/definitions/address".
+func (w *jsonWalker) resolve(ref string) map[string]interface{} {
+	if ref == "#" {
+		return w.root
+	}
+	var current interface{} = w.root
+	for _, segment := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		current = object[segment]
+	}
+	resolved, _ := current.(map[string]interface{})
+	return resolved
+}
+
+// description returns the description of a property, or of the definition it references.
+func (w *jsonWalker) description(schema map[string]interface{}) string {
+	if text, ok := schema["description"].(string); ok {
+		return text
+	}
+	if ref, ok := schema["$ref"].(string); ok {
+		if resolved := w.resolve(ref); resolved != nil {
+			text, _ := resolved["description"].(string)
+			return text
+		}
+	}
+	return ""
+}
+
+// typeLabel returns a readable type of a property, e.g. "array of string" or "string (date-time)".
+func (w *jsonWalker) typeLabel(schema map[string]interface{}) string {
+	if ref, ok := schema["$ref"].(string); ok {
+		return ref[strings.LastIndex(ref, "/")+1:]
+	}
+
+	var label string
+	switch typ := schema["type"].(type) {
+	case string:
+		label = typ
+	case []interface{}:
+		label = strings.Join(stringList(typ), " | ")
+	default:
+		switch {
+		case schema["enum"] != nil:
+			label = "enum"
+		case schema["properties"] != nil:
+			label = "object"
+		default:
+			label = "any"
+		}
+	}
+
+	if items, ok := schema["items"].(map[string]interface{}); ok && label == "array" {
+		label = "array of " + w.typeLabel(items)
+	}
+	if format, ok := schema["format"].(string); ok {
+		label += " (" + format + ")"
+	}
+	return label
+}
+
+// examples returns the examples of a JSON schema property.
+func examples(schema map[string]interface{}) []string {
+	var values []interface{}
+	if list, ok := schema["examples"].([]interface{}); ok {
+		values = list
+	}
+	if example, ok := schema["example"]; ok {
+		values = append(values, example)
+	}
+
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		result = append(result, jsonValue(value))
+	}
+	return result
+}
+
+// enumValues returns the readable values of an enum.
+func enumValues(value interface{}) []string {
+	values, _ := value.([]interface{})
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		result = append(result, jsonValue(v))
+	}
+	return result
+}
+
+// jsonValue formats a JSON value, strings are shown without quotes.
+func jsonValue(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(encoded)
+}
+
+// avroFields documents the fields of an Avro record, nested records included.
+func avroFields(specification []byte) ([]Field, error) {
+	var root interface{}
+	if err := json.Unmarshal(specification, &root); err != nil {
+		return nil, err
+	}
+	walker := &avroWalker{named: make(map[string]map[string]interface{}), visiting: make(map[string]bool)}
+	walker.walk(root, "", "")
+	return walker.fields, nil
+}
+
+// avroWalker collects the fields of an Avro schema. Named types are registered on their definition, so later
+// references can be resolved.
+type avroWalker struct {
+	fields   []Field
+	named    map[string]map[string]interface{}
+	visiting map[string]bool
+}
+
+// walk documents the fields of the records reachable from an Avro type.
+func (w *avroWalker) walk(schema interface{}, path, namespace string) {
+	switch s := schema.(type) {
+	case string:
+		if named, ok := w.lookup(s, namespace); ok {
+			w.walk(named, path, namespace)
+		}
+	case []interface{}:
+		for _, branch := range s {
+			w.walk(branch, path, namespace)
+		}
+	case map[string]interface{}:
+		switch s["type"] {
+		case "record", "error":
+			w.walkRecord(s, path, namespace)
+		case "enum", "fixed":
+			w.named[w.fullName(s, namespace)] = s
+		case "array":
+			w.walk(s["items"], path+"[]", namespace)
+		case "map":
+			w.walk(s["values"], path+"{}", namespace)
+		default:
+			w.walk(s["type"], path, namespace)
+		}
+	}
+}
+
+// walkRecord documents the fields of a record.
+func (w *avroWalker) walkRecord(record map[string]interface{}, path, namespace string) {
+	fullName := w.fullName(record, namespace)
+	if w.visiting[fullName] {
+		return
+	}
+	w.named[fullName] = record
+	w.visiting[fullName] = true
+	defer delete(w.visiting, fullName)
+	if i := strings.LastIndex(fullName, "."); i >= 0 {
+		namespace = fullName[:i]
+	}
+
+	fields, _ := record["fields"].([]interface{})
+	for _, item := range fields {
+		field, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _ := field["name"].(string)
+		fieldPath := name
+		if path != "" {
+			fieldPath = path + "." + name
+		}
+		doc, _ := field["doc"].(string)
+		_, hasDefault := field["default"]
+
+		documented := Field{
+			Path:        fieldPath,
+			Type:        w.typeLabel(field["type"], namespace),
+			Required:    !hasDefault && !nullable(field["type"]),
+			Description: doc,
+			Enum:        w.symbols(field["type"], namespace),
+		}
+		if hasDefault {
+			documented.Examples = []string{"default: " + jsonValue(field["default"])}
+		}
+		w.fields = append(w.fields, documented)
+		w.walk(field["type"], fieldPath, namespace)
+	}
+}
+
+// fullName returns the full name of a named type.
+func (w *avroWalker) fullName(schema map[string]interface{}, namespace string) string {
+	name, _ := schema["name"].(string)
+	if strings.Contains(name, ".") {
+		return name
+	}
+	if ns, ok := schema["namespace"].(string); ok {
+		namespace = ns
+	}
+	if namespace == "" {
+		return name
+	}
+	return namespace + "." + name
+}
+
+// lookup returns an already defined named type.
+func (w *avroWalker) lookup(name, namespace string) (map[string]interface{}, bool) {
+	if named, ok := w.named[namespace+"."+name]; ok {
+		return named, true
+	}
+	named, ok := w.named[name]
+	return named, ok
+}
+
+// typeLabel returns a readable Avro type, e.g. "array<string>" or "string (nullable)".
+func (w *avroWalker) typeLabel(schema interface{}, namespace string) string {
+	switch s := schema.(type) {
+	case string:
+		return s
+	case []interface{}:
+		branches := make([]string, 0, len(s))
+		for _, branch := range s {
+			if branch != "null" {
+				branches = append(branches, w.typeLabel(branch, namespace))
+			}
+		}
+		label := strings.Join(branches, " | ")
+		if len(branches) < len(s) {
+			label += " (nullable)"
+		}
+		return label
+	case map[string]interface{}:
+		switch s["type"] {
+		case "record", "error", "enum", "fixed":
+			name, _ := s["name"].(string)
+			return fmt.Sprintf("%s %s", s["type"], name)
+		case "array":
+			return "array<" + w.typeLabel(s["items"], namespace) + ">"
+		case "map":
+			return "map<" + w.typeLabel(s["values"], namespace) + ">"
+		default:
+			label := w.typeLabel(s["type"], namespace)
+			if logical, ok := s["logicalType"].(string); ok {
+				label += " (" + logical + ")"
+			}
+			return label
+		}
+	}
+	return "any"
+}
+
+// symbols returns the symbols of an enum type, nullable enums included.
+func (w *avroWalker) symbols(schema interface{}, namespace string) []string {
+	switch s := schema.(type) {
+	case string:
+		if named, ok := w.lookup(s, namespace); ok {
+			return w.symbols(named, namespace)
+		}
+	case []interface{}:
+		for _, branch := range s {
+			if symbols := w.symbols(branch, namespace); len(symbols) > 0 {
+				return symbols
+			}
+		}
+	case map[string]interface{}:
+		if s["type"] == "enum" {
+			w.named[w.fullName(s, namespace)] = s
+			return stringList(s["symbols"])
+		}
+	}
+	return nil
+}
+
+// nullable checks if an Avro type is a union containing null.
+func nullable(schema interface{}) bool {
+	branches, _ := schema.([]interface{})
+	for _, branch := range branches {
+		if branch == "null" {
+			return true
+		}
+	}
+	return false
+}
+
+// protobufFields documents the fields of the messages of a Protobuf specification, named by message. Comments of the
+// fields are used as their descriptions.
+func protobufFields(specification []byte) ([]Field, error) {
+	parser := protoparse.Parser{
+		Accessor:              protoparse.FileContentsFromMap(map[string]string{"schema.proto": string(specification)}),
+		IncludeSourceCodeInfo: true,
+	}
+	descriptors, err := parser.ParseFiles("schema.proto")
+	if err != nil {
+		return nil, err
+	}
+
+	var fields []Field
+	for _, message := range descriptors[0].GetMessageTypes() {
+		fields = append(fields, messageFields(message, message.GetName(), make(map[string]bool))...)
+	}
+	return fields, nil
+}
+
+// messageFields documents the fields of a message, descending into fields of nested message types.
+func messageFields(message *desc.MessageDescriptor, path string, visiting map[string]bool) []Field {
+	if visiting[message.GetFullyQualifiedName()] {
+		return nil
+	}
+	visiting[message.GetFullyQualifiedName()] = true
+	defer delete(visiting, message.GetFullyQualifiedName())
+
+	var fields []Field
+	for _, field := range message.GetFields() {
+		fieldPath := path + "." + field.GetName()
+		documented := Field{
+			Path:        fieldPath,
+			Type:        protobufType(field),
+			Required:    field.IsRequired(),
+			Description: strings.TrimSpace(field.GetSourceInfo().GetLeadingComments()),
+		}
+		if enum := field.GetEnumType(); enum != nil {
+			for _, value := range enum.GetValues() {
+				documented.Enum = append(documented.Enum, value.GetName())
+			}
+		}
+		fields = append(fields, documented)
+
+		if nested := field.GetMessageType(); nested != nil && !field.IsMap() {
+			fields = append(fields, messageFields(nested, fieldPath, visiting)...)
+		}
+	}
+	return fields
+}
+
+// protobufType returns a readable type of a field, e.g. "repeated string" or "map<string, int64>".
+func protobufType(field *desc.FieldDescriptor) string {
+	if field.IsMap() {
+		return fmt.Sprintf("map<%s, %s>", protobufType(field.GetMapKeyType()), protobufType(field.GetMapValueType()))
+	}
+	var label string
+	switch field.GetType() {
+	case descriptor.FieldDescriptorProto_TYPE_MESSAGE, descriptor.FieldDescriptorProto_TYPE_GROUP:
+		label = field.GetMessageType().GetName()
+	case descriptor.FieldDescriptorProto_TYPE_ENUM:
+		label = field.GetEnumType().GetName()
+	default:
+		label = strings.ToLower(strings.TrimPrefix(field.GetType().String(), "TYPE_"))
+	}
+	if field.IsRepeated() {
+		return "repeated " + label
+	}
+	return label
+}
+
+// csvFields documents the columns of a CSV schema, through its JSON schema of a row.
+func csvFields(specification []byte) ([]Field, error) {
+	converted, err := conversion.Convert(conversion.TypeCSV, conversion.TypeJSON, specification, "row")
+	if err != nil {
+		return nil, err
+	}
+	return jsonFields(converted)
+}
+
+// stringList converts a JSON array of strings into a string slice.
+func stringList(value interface{}) []string {
+	values, _ := value.([]interface{})
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		if s, ok := v.(string); ok {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+// sortedKeys returns the keys of a JSON object in a deterministic order.
+func sortedKeys(object map[string]interface{}) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
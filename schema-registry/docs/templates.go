@@ -0,0 +1,237 @@
+// Copyright 2020 Syntio Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docs
+
+import (
+	"bytes"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+)
+
+// functions are the helpers available in the page templates.
+var functions = map[string]interface{}{
+	"date":  formatDate,
+	"join":  strings.Join,
+	"cell":  markdownCell,
+	"fence": func() string { return "```" },
+}
+
+// htmlPages are the HTML page templates, every page shares the layout.
+var htmlPages = htmltemplate.Must(htmltemplate.New("layout").Funcs(functions).Parse(`
+{{- define "head" -}}
+<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em auto; max-width: 70em; color: #222; }
+table { border-collapse: collapse; width: 100%; margin: 1em 0; }
+th, td { border: 1px solid #ccc; padding: .4em .6em; text-align: left; vertical-align: top; }
+th { background: #f3f3f3; }
+code, pre { background: #f6f6f6; }
+pre { padding: 1em; overflow: auto; }
+.meta td:first-child { width: 12em; font-weight: bold; }
+</style>
+</head>
+<body>
+{{- end -}}
+
+{{- define "version" -}}
+{{- $schema := .Document.Schema -}}
+{{- $version := .Document.Version -}}
+{{template "head" (printf "%s v%d" $schema.Name $version.Version)}}
+<p><a href="{{.Links.Catalog}}">Catalog</a> / <a href="{{call .Links.History $schema.Id}}">{{$schema.Name}}</a></p>
+<h1>{{$schema.Name}} <small>version {{$version.Version}}</small></h1>
+<p>{{$schema.Description}}</p>
+<table class="meta">
+<tr><td>ID</td><td><code>{{$schema.Id}}</code></td></tr>
+<tr><td>Namespace</td><td>{{$schema.Namespace}}</td></tr>
+<tr><td>Schema type</td><td>{{$schema.SchemaType}}</td></tr>
+<tr><td>Created</td><td>{{date $version.CreationDate}}</td></tr>
+<tr><td>Autogenerated</td><td>{{$version.Autogenerated}}</td></tr>
+<tr><td>Hash</td><td><code>{{$version.SchemaHash}}</code></td></tr>
+</table>
+<h2>Fields</h2>
+{{- if .Document.Note}}
+<p><em>{{.Document.Note}}</em></p>
+{{- else}}
+<table>
+<tr><th>Field</th><th>Type</th><th>Required</th><th>Description</th><th>Enum values</th><th>Examples</th></tr>
+{{- range .Document.Fields}}
+<tr><td><code>{{.Path}}</code></td><td>{{.Type}}</td><td>{{if .Required}}yes{{else}}no{{end}}</td><td>{{.Description}}</td><td>{{join .Enum ", "}}</td><td>{{join .Examples ", "}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+<h2>Specification</h2>
+<pre>{{.Document.Specification}}</pre>
+</body>
+</html>
+{{end -}}
+
+{{- define "history" -}}
+{{template "head" (printf "%s history" .Schema.Name)}}
+<p><a href="{{.Links.Catalog}}">Catalog</a></p>
+<h1>{{.Schema.Name}}</h1>
+<p>{{.Schema.Description}}</p>
+<table class="meta">
+<tr><td>ID</td><td><code>{{.Schema.Id}}</code></td></tr>
+<tr><td>Namespace</td><td>{{.Schema.Namespace}}</td></tr>
+<tr><td>Schema type</td><td>{{.Schema.SchemaType}}</td></tr>
+<tr><td>Compatibility</td><td>{{.Schema.Compatibility}}</td></tr>
+<tr><td>Created</td><td>{{date .Schema.CreationDate}}</td></tr>
+</table>
+<h2>Version history</h2>
+<table>
+<tr><th>Version</th><th>Created</th><th>Autogenerated</th><th>Hash</th></tr>
+{{- $links := .Links}}{{$id := .Schema.Id}}
+{{- range .Schema.SchemaDetails}}
+<tr><td><a href="{{call $links.Version $id .Version}}">{{.Version}}</a></td><td>{{date .CreationDate}}</td><td>{{.Autogenerated}}</td><td><code>{{.SchemaHash}}</code></td></tr>
+{{- end}}
+</table>
+{{- if .Schema.Links}}
+<h2>Related schemas</h2>
+<ul>
+{{- range .Schema.Links}}
+<li>{{.Relation}} <a href="{{call $links.Version .SchemaId .Version}}">{{.SchemaId}} version {{.Version}}</a> ({{.SchemaType}})</li>
+{{- end}}
+</ul>
+{{- end}}
+</body>
+</html>
+{{end -}}
+
+{{- define "catalog" -}}
+{{template "head" "Schema catalog"}}
+<h1>Schema catalog</h1>
+<table>
+<tr><th>Name</th><th>Namespace</th><th>Schema type</th><th>Latest version</th><th>Description</th></tr>
+{{- $links := .Links}}
+{{- range .Schemas}}
+<tr><td><a href="{{call $links.History .Id}}">{{.Name}}</a></td><td>{{.Namespace}}</td><td>{{.SchemaType}}</td><td>{{.LatestVersion}}</td><td>{{.Description}}</td></tr>
+{{- end}}
+</table>
+</body>
+</html>
+{{end -}}
+`))
+
+// markdownPages are the Markdown page templates.
+var markdownPages = texttemplate.Must(texttemplate.New("layout").Funcs(functions).Parse(`
+{{- define "version" -}}
+{{- $schema := .Document.Schema -}}
+{{- $version := .Document.Version -}}
+[Catalog]({{.Links.Catalog}}) / [{{$schema.Name}}]({{call .Links.History $schema.Id}})
+
+# {{$schema.Name}} (version {{$version.Version}})
+
+{{$schema.Description}}
+
+| | |
+|---|---|
+| ID | ` + "`{{$schema.Id}}`" + ` |
+| Namespace | {{$schema.Namespace}} |
+| Schema type | {{$schema.SchemaType}} |
+| Created | {{date $version.CreationDate}} |
+| Autogenerated | {{$version.Autogenerated}} |
+| Hash | ` + "`{{$version.SchemaHash}}`" + ` |
+
+## Fields
+
+{{if .Document.Note -}}
+_{{.Document.Note}}_
+{{- else -}}
+| Field | Type | Required | Description | Enum values | Examples |
+|---|---|---|---|---|---|
+{{- range .Document.Fields}}
+| ` + "`{{.Path}}`" + ` | {{cell .Type}} | {{if .Required}}yes{{else}}no{{end}} | {{cell .Description}} | {{cell (join .Enum ", ")}} | {{cell (join .Examples ", ")}} |
+{{- end}}
+{{- end}}
+
+## Specification
+
+{{fence}}
+{{.Document.Specification}}
+{{fence}}
+{{end -}}
+
+{{- define "history" -}}
+[Catalog]({{.Links.Catalog}})
+
+# {{.Schema.Name}}
+
+{{.Schema.Description}}
+
+| | |
+|---|---|
+| ID | ` + "`{{.Schema.Id}}`" + ` |
+| Namespace | {{.Schema.Namespace}} |
+| Schema type | {{.Schema.SchemaType}} |
+| Compatibility | {{.Schema.Compatibility}} |
+| Created | {{date .Schema.CreationDate}} |
+
+## Version history
+
+| Version | Created | Autogenerated | Hash |
+|---|---|---|---|
+{{- $links := .Links}}{{$id := .Schema.Id}}
+{{- range .Schema.SchemaDetails}}
+| [{{.Version}}]({{call $links.Version $id .Version}}) | {{date .CreationDate}} | {{.Autogenerated}} | ` + "`{{.SchemaHash}}`" + ` |
+{{- end}}
+{{- if .Schema.Links}}
+
+## Related schemas
+{{range .Schema.Links}}
+- {{.Relation}} [{{.SchemaId}} version {{.Version}}]({{call $links.Version .SchemaId .Version}}) ({{.SchemaType}})
+{{- end}}
+{{- end}}
+{{end -}}
+
+{{- define "catalog" -}}
+# Schema catalog
+
+| Name | Namespace | Schema type | Latest version | Description |
+|---|---|---|---|---|
+{{- $links := .Links}}
+{{- range .Schemas}}
+| [{{cell .Name}}]({{call $links.History .Id}}) | {{.Namespace}} | {{.SchemaType}} | {{.LatestVersion}} | {{cell .Description}} |
+{{- end}}
+{{end -}}
+`))
+
+// executeHTML renders an HTML page.
+func executeHTML(page string, data interface{}) ([]byte, error) {
+	var buffer bytes.Buffer
+	if err := htmlPages.ExecuteTemplate(&buffer, page, data); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// executeMarkdown renders a Markdown page.
+func executeMarkdown(page string, data interface{}) ([]byte, error) {
+	var buffer bytes.Buffer
+	if err := markdownPages.ExecuteTemplate(&buffer, page, data); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// markdownCell escapes a value of a Markdown table cell, which has to stay on one line.
+func markdownCell(value string) string {
+	value = strings.Join(strings.Fields(value), " ")
+	return strings.ReplaceAll(value, "|", `\|`)
+}
@@ -1,17 +1,28 @@
 module github.com/syntio/schema-registry
 
-go 1.15
+go 1.16
 
 require (
 	cloud.google.com/go v0.67.0 // indirect
 	cloud.google.com/go/firestore v1.3.0
 	cloud.google.com/go/storage v1.12.0
+	github.com/golang/protobuf v1.5.2
 	github.com/gorilla/mux v1.8.0
+	github.com/hamba/avro v1.0.0
+	github.com/jhump/protoreflect v1.6.1
+	github.com/prometheus/client_golang v1.7.1
+	github.com/syntio/janitor-common v0.0.0
 	go.mongodb.org/mongo-driver v1.4.2
-	golang.org/x/net v0.0.0-20200930145003-4acb6c075d10 // indirect
-	golang.org/x/sys v0.0.0-20200930185726-fdedc70b468f // indirect
+	go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp v0.25.0
+	go.opentelemetry.io/otel v1.0.1
+	go.opentelemetry.io/otel/trace v1.0.1
+	golang.org/x/net v0.0.0-20200930145003-4acb6c075d10
 	golang.org/x/tools v0.0.0-20201002055958-0d28ed0cbe40 // indirect
 	google.golang.org/api v0.32.0
 	google.golang.org/genproto v0.0.0-20201001141541-efaab9d3c4f7 // indirect
+	google.golang.org/grpc v1.41.0
+	google.golang.org/protobuf v1.27.1
 	gopkg.in/yaml.v3 v3.0.0-20200313102051-9f266ea9e77c
 )
+
+replace github.com/syntio/janitor-common => ../janitor-common
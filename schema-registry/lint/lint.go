@@ -0,0 +1,240 @@
+// Copyright 2020 Syntio Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package lint checks schema specifications against the conventions of a namespace before they are registered.
+package lint
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/syntio/schema-registry/docs"
+	"github.com/syntio/schema-registry/model"
+)
+
+// Names of the supported rules.
+const (
+	RuleSnakeCaseFields        = "snake-case-fields"
+	RuleFieldDescriptions      = "field-descriptions"
+	RuleNoAdditionalProperties = "no-additional-properties"
+	RuleAvroNamespace          = "avro-namespace"
+	RuleProtoPackage           = "proto-package"
+)
+
+// Violation is a breach of a lint rule. Location is the path of the offending field, or empty if the violation
+// concerns the schema as a whole.
+type Violation struct {
+	Rule     string
+	Severity string
+	Location string
+	Message  string
+}
+
+// check finds the violations of a rule in a specification. Only the Location and Message of the violations are set.
+type check func(schemaType, name string, specification []byte) []Violation
+
+var checks = map[string]check{
+	RuleSnakeCaseFields:        snakeCaseFields,
+	RuleFieldDescriptions:      fieldDescriptions,
+	RuleNoAdditionalProperties: noAdditionalProperties,
+	RuleAvroNamespace:          avroNamespace,
+	RuleProtoPackage:           protoPackage,
+}
+
+var snakeCase = regexp.MustCompile(`^[a-z][a-z0-9]*(_[a-z0-9]+)*$`)
+
+// Check checks a specification against the given rules:
+//  - snake-case-fields: field names are snake_case (JSON, Avro, Protobuf and CSV)
+//  - field-descriptions: every field has a description (JSON, Avro and Protobuf)
+//  - no-additional-properties: no JSON object schema sets "additionalProperties" to true
+//  - avro-namespace: the root type of an Avro schema has a namespace
+//  - proto-package: the package of a Protobuf schema matches the schema name
+//
+// Rules which don't apply to the schema type are skipped, and so are rules whose severity is neither "warn" nor
+// "error". A specification that can't be parsed has no violations, since it isn't the linter's job to reject it.
+func Check(rules []model.LintRule, schemaType, name string, specification []byte) []Violation {
+	violations := make([]Violation, 0)
+	for _, rule := range rules {
+		severity := strings.ToLower(rule.Severity)
+		if severity != model.LintWarn && severity != model.LintError {
+			continue
+		}
+		check, ok := checks[rule.Rule]
+		if !ok {
+			log.Printf("Unknown lint rule %s, skipping it.\n", rule.Rule)
+			continue
+		}
+		for _, violation := range check(strings.ToLower(schemaType), name, specification) {
+			violation.Rule = rule.Rule
+			violation.Severity = severity
+			violations = append(violations, violation)
+		}
+	}
+	return violations
+}
+
+// HasErrors checks if any of the violations has the "error" severity.
+func HasErrors(violations []Violation) bool {
+	for _, violation := range violations {
+		if violation.Severity == model.LintError {
+			return true
+		}
+	}
+	return false
+}
+
+// snakeCaseFields reports fields whose names aren't snake_case.
+func snakeCaseFields(schemaType, _ string, specification []byte) []Violation {
+	fields, err := docs.Fields(schemaType, specification)
+	if err != nil {
+		return nil
+	}
+	var violations []Violation
+	for _, field := range fields {
+		if name := fieldName(field.Path); !snakeCase.MatchString(name) {
+			violations = append(violations, Violation{
+				Location: field.Path,
+				Message:  fmt.Sprintf("field name %q isn't snake_case", name),
+			})
+		}
+	}
+	return violations
+}
+
+// fieldDescriptions reports fields without a description. CSV schemas can't describe their columns and are skipped.
+func fieldDescriptions(schemaType, _ string, specification []byte) []Violation {
+	if schemaType == "csv" {
+		return nil
+	}
+	fields, err := docs.Fields(schemaType, specification)
+	if err != nil {
+		return nil
+	}
+	var violations []Violation
+	for _, field := range fields {
+		if strings.TrimSpace(field.Description) == "" {
+			violations = append(violations, Violation{Location: field.Path, Message: "field has no description"})
+		}
+	}
+	return violations
+}
+
+// noAdditionalProperties reports JSON object schemas which explicitly allow additional properties.
+func noAdditionalProperties(schemaType, _ string, specification []byte) []Violation {
+	if schemaType != "json" {
+		return nil
+	}
+	var root interface{}
+	if err := json.Unmarshal(specification, &root); err != nil {
+		return nil
+	}
+	var violations []Violation
+	var walk func(node interface{}, path string)
+	walk = func(node interface{}, path string) {
+		switch value := node.(type) {
+		case map[string]interface{}:
+			if allowed, ok := value["additionalProperties"].(bool); ok && allowed {
+				violations = append(violations, Violation{
+					Location: path,
+					Message:  "additionalProperties is true",
+				})
+			}
+			for _, key := range sortedKeys(value) {
+				walk(value[key], path+"/"+key)
+			}
+		case []interface{}:
+			for i, item := range value {
+				walk(item, fmt.Sprintf("%s/%d", path, i))
+			}
+		}
+	}
+	walk(root, "#")
+	return violations
+}
+
+// avroNamespace reports an Avro schema whose root named type has no namespace.
+func avroNamespace(schemaType, _ string, specification []byte) []Violation {
+	if schemaType != "avro" {
+		return nil
+	}
+	var root map[string]interface{}
+	if err := json.Unmarshal(specification, &root); err != nil {
+		return nil
+	}
+	name, _ := root["name"].(string)
+	namespace, _ := root["namespace"].(string)
+	if name == "" || namespace != "" || strings.Contains(name, ".") {
+		return nil
+	}
+	return []Violation{{Location: name, Message: fmt.Sprintf("type %s has no namespace", name)}}
+}
+
+// protoPackage reports a Protobuf schema whose package doesn't match the schema name. The last component of the
+// package is compared with the name ignoring case and separators, so "acme.orders.order_created" matches the schema
+// name "OrderCreated".
+func protoPackage(schemaType, name string, specification []byte) []Violation {
+	if schemaType != "protobuf" {
+		return nil
+	}
+	parser := protoparse.Parser{
+		Accessor: protoparse.FileContentsFromMap(map[string]string{"schema.proto": string(specification)}),
+	}
+	descriptors, err := parser.ParseFiles("schema.proto")
+	if err != nil {
+		return nil
+	}
+
+	pkg := descriptors[0].GetPackage()
+	if pkg == "" {
+		return []Violation{{Message: fmt.Sprintf("schema has no package, expected one matching %q", name)}}
+	}
+	if normalize(pkg[strings.LastIndex(pkg, ".")+1:]) != normalize(name) {
+		return []Violation{{Message: fmt.Sprintf("package %s doesn't match the schema name %q", pkg, name)}}
+	}
+	return nil
+}
+
+// fieldName returns the name of a field from its documented path, e.g. "city" from "address.city".
+func fieldName(path string) string {
+	path = strings.TrimRight(path, "[]{}")
+	return path[strings.LastIndex(path, ".")+1:]
+}
+
+// normalize lower-cases a name and drops everything but letters and digits.
+func normalize(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r + 'a' - 'A'
+		}
+		return -1
+	}, name)
+}
+
+// sortedKeys returns the keys of a map in alphabetical order, which keeps the reported violations stable.
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,126 @@
+// Copyright 2020 Syntio Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package metrics holds the Prometheus metrics of the Schema Registry, served on "/metrics".
+package metrics
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	// Requests counts the REST requests per route, method and status code.
+	Requests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "schema_registry_http_requests_total",
+		Help: "REST requests per route, method and status code.",
+	}, []string{"route", "method", "status"})
+
+	// RequestDuration observes how long serving a REST request takes per route and method.
+	RequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "schema_registry_http_request_duration_seconds",
+		Help:    "Duration of REST requests per route and method.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"route", "method"})
+
+	// RPCs counts the gRPC calls per method and status code.
+	RPCs = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "schema_registry_grpc_requests_total",
+		Help: "gRPC calls per method and status code.",
+	}, []string{"method", "code"})
+
+	// RPCDuration observes how long serving a gRPC call takes per method. Streams are observed when they end.
+	RPCDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "schema_registry_grpc_request_duration_seconds",
+		Help:    "Duration of gRPC calls per method.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method"})
+
+	// Evolutions counts the schema evolutions per outcome, dry runs excluded.
+	Evolutions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "schema_registry_evolutions_total",
+		Help: "Schema evolutions per outcome (created, pending, exists, disabled, limit-exceeded, ...).",
+	}, []string{"outcome"})
+)
+
+func init() {
+	prometheus.MustRegister(Requests, RequestDuration, RPCs, RPCDuration, Evolutions)
+}
+
+// Middleware counts and times the requests served by the router. Requests are labeled by the path template of the
+// matched route, so the labels don't grow with the schema IDs.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := "unknown"
+		if current := mux.CurrentRoute(r); current != nil {
+			if template, err := current.GetPathTemplate(); err == nil {
+				route = template
+			}
+		}
+
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+
+		Requests.WithLabelValues(route, r.Method, strconv.Itoa(recorder.status)).Inc()
+		RequestDuration.WithLabelValues(route, r.Method).Observe(time.Since(start).Seconds())
+	})
+}
+
+// ObserveRPC counts and times a finished gRPC call.
+func ObserveRPC(method, code string, start time.Time) {
+	RPCs.WithLabelValues(method, code).Inc()
+	RPCDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+}
+
+// statusRecorder remembers the status code written by a handler. Flushing and hijacking are passed through, so
+// streamed responses keep working.
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *statusRecorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (r *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := r.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	return hijacker.Hijack()
+}
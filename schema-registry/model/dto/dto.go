@@ -14,6 +14,8 @@
 
 package dto
 
+import "time"
+
 // EvolutionDTO represents a schema evolution request. Data defines the input message from which a new schema is
 // registered (evolved).
 type EvolutionDTO struct {
@@ -28,6 +30,7 @@ type SchemaDTO struct {
 	Specification string `json:"specification"`
 	Name          string `json:"name"`
 	SchemaType    string `json:"schema-type"`
+	Compatibility string `json:"compatibility,omitempty"`
 }
 
 // Structure ReportDTO is a simple wrapper of the system's message for the user.
@@ -43,7 +46,92 @@ type SpectificationDTO struct {
 
 // InsertInfoDTO represents a schema registry/evolution response for methods other than GET.
 type InsertInfoDTO struct {
-	Id      string `json:"identification"`
-	Version int32  `json:"version"`
-	Message string `json:"message"`
+	Id      string             `json:"identification"`
+	Version int32              `json:"version"`
+	Message string             `json:"message"`
+	Lint    []LintViolationDTO `json:"lint,omitempty"`
+}
+
+// LintViolationDTO represents a breach of a linting rule of the namespace. Location is the path of the offending
+// field, if the violation concerns a single field.
+type LintViolationDTO struct {
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	Location string `json:"location,omitempty"`
+	Message  string `json:"message"`
+}
+
+// LintReportDTO represents the response to a registration refused by linting rules with the "error" severity.
+type LintReportDTO struct {
+	Message    string             `json:"message"`
+	Violations []LintViolationDTO `json:"violations"`
+}
+
+// EvolutionResultDTO represents a schema evolution response. Outcome describes what happened (or would happen, for a
+// dry run) with the evolved schema: "created", "exists", "pending" or "disabled".
+type EvolutionResultDTO struct {
+	Id            string   `json:"identification"`
+	Version       int32    `json:"version"`
+	Message       string   `json:"message"`
+	Outcome       string   `json:"outcome"`
+	DryRun        bool     `json:"dry-run"`
+	PendingId     string   `json:"pending-id,omitempty"`
+	Specification string   `json:"specification,omitempty"`
+	Diff          []string `json:"diff,omitempty"`
+}
+
+// SchemaChangeDTO represents a change of a registered schema: "created", "version-added" or "version-removed".
+type SchemaChangeDTO struct {
+	Kind       string    `json:"kind"`
+	Id         string    `json:"identification"`
+	Namespace  string    `json:"namespace"`
+	Version    int32     `json:"version"`
+	SchemaType string    `json:"schema-type"`
+	Time       time.Time `json:"time"`
+}
+
+// HealthDTO represents the report of a health or readiness probe. Storage describes the state of the storage.
+type HealthDTO struct {
+	Status  string `json:"status"`
+	Storage string `json:"storage,omitempty"`
+}
+
+// EvolutionPolicyDTO represents the evolution policy of a schema.
+type EvolutionPolicyDTO struct {
+	Mode                   string `json:"mode"`
+	MaxAutogeneratedPerDay int    `json:"max-autogenerated-per-day"`
+}
+
+// RetentionPolicyDTO represents the retention policy of a schema.
+type RetentionPolicyDTO struct {
+	KeepLast                     int  `json:"keep-last"`
+	KeepManual                   bool `json:"keep-manual"`
+	ExpireAutogeneratedAfterDays int  `json:"expire-autogenerated-after-days"`
+}
+
+// CompactionDTO represents the result of a schema compaction, the versions removed from the schema.
+type CompactionDTO struct {
+	Id              string  `json:"identification"`
+	Namespace       string  `json:"namespace"`
+	RemovedVersions []int32 `json:"removed-versions"`
+}
+
+// ValidationDTO represents the result of a payload validation against a schema version. Violations describe where
+// and why an invalid payload doesn't conform to the schema.
+type ValidationDTO struct {
+	Id         string         `json:"identification"`
+	Version    int32          `json:"version"`
+	Format     string         `json:"format"`
+	Valid      bool           `json:"valid"`
+	Violations []ViolationDTO `json:"violations,omitempty"`
+}
+
+// ViolationDTO represents a part of a payload which doesn't conform to the schema. Rule names the violated schema
+// rule, and Expected and Actual are the values it compared, if any.
+type ViolationDTO struct {
+	Location    string `json:"location,omitempty"`
+	Rule        string `json:"rule,omitempty"`
+	Expected    string `json:"expected,omitempty"`
+	Actual      string `json:"actual,omitempty"`
+	Description string `json:"description"`
 }
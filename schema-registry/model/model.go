@@ -18,21 +18,138 @@ import (
 	"time"
 )
 
+// DefaultNamespace is the namespace of schemas registered without an explicit namespace.
+const DefaultNamespace = "default"
+
+// Compatibility modes which can be enforced when a new version of a schema is registered.
+const (
+	CompatibilityNone     = "NONE"
+	CompatibilityBackward = "BACKWARD"
+	CompatibilityForward  = "FORWARD"
+	CompatibilityFull     = "FULL"
+)
+
 // Schema is a structure that defines how schemas will be saved in our Schema Registry.
 type Schema struct {
 	Id            string           `json:"id,omitempty" bson:"_id,omitempty" firestore:"ID"`
+	Namespace     string           `json:"namespace" bson:"namespace" firestore:"namespace"`
+	Compatibility string           `json:"compatibility" bson:"compatibility" firestore:"compatibility"`
 	SchemaType    string           `json:"schema-type" bson:"schema-type" firestore:"schema-type"`
 	Autogenerated bool             `json:"autogenerated" bson:"autogenerated" firestore:"autogenerated"`
 	Description   string           `json:"description" bson:"description" firestore:"description"`
 	CreationDate  time.Time        `json:"creation-date" bson:"creation-date" firestore:"creation-date"`
 	Name          string           `json:"name" bson:"name" firestore:"name"`
-	SchemaDetails []*SchemaDetails `json:"schemas" bson:"schemas" firestore:"schemas"`
+	SchemaDetails []*SchemaDetails `json:"schemas" bson:"schemas" firestore:"schemas,omitempty"`
+	LatestVersion int32            `json:"latest-version" bson:"latest-version" firestore:"latest-version"`
+	Hashes        map[string]int32 `json:"-" bson:"hashes" firestore:"hashes,omitempty"`
+
+	EvolutionPolicy *EvolutionPolicy `json:"evolution-policy,omitempty" bson:"evolution-policy,omitempty" firestore:"evolution-policy,omitempty"`
+	RetentionPolicy *RetentionPolicy `json:"retention-policy,omitempty" bson:"retention-policy,omitempty" firestore:"retention-policy,omitempty"`
+
+	Links []SchemaLink `json:"links,omitempty" bson:"links,omitempty" firestore:"links,omitempty"`
+}
+
+// Relations between a converted schema and the schema it was converted from.
+const (
+	ConvertedFromRelation = "converted-from"
+	ConvertedToRelation   = "converted-to"
+)
+
+// SchemaLink records a relationship with another schema, e.g. a sibling schema converted to another schema type.
+type SchemaLink struct {
+	Relation   string `json:"relation" bson:"relation" firestore:"relation"`
+	SchemaId   string `json:"schema-id" bson:"schema-id" firestore:"schema-id"`
+	Version    int32  `json:"version" bson:"version" firestore:"version"`
+	SchemaType string `json:"schema-type" bson:"schema-type" firestore:"schema-type"`
+}
+
+// RetentionPolicy defines which versions of a schema are removed when the schema is compacted:
+//  - KeepLast: versions older than the last KeepLast versions are removed (0 keeps every version)
+//  - KeepManual: manually registered versions are never removed
+//  - ExpireAutogeneratedAfterDays: autogenerated versions without traffic for that many days are removed
+//    (0 never expires them)
+//
+// The latest version of a schema is never removed.
+type RetentionPolicy struct {
+	KeepLast                     int  `json:"keep-last" bson:"keep-last" firestore:"keep-last" yaml:"keepLast"`
+	KeepManual                   bool `json:"keep-manual" bson:"keep-manual" firestore:"keep-manual" yaml:"keepManual"`
+	ExpireAutogeneratedAfterDays int  `json:"expire-autogenerated-after-days" bson:"expire-autogenerated-after-days" firestore:"expire-autogenerated-after-days" yaml:"expireAutogeneratedAfterDays"`
+}
+
+// Severities of a lint rule. Rules with any other severity are turned off.
+const (
+	LintWarn  = "warn"
+	LintError = "error"
+)
+
+// LintRule turns on a linting rule for the schemas of a namespace. Violations of "warn" rules are reported when a
+// schema is registered, violations of "error" rules prevent the registration.
+type LintRule struct {
+	Rule     string `json:"rule" yaml:"rule"`
+	Severity string `json:"severity" yaml:"severity"`
+}
+
+// Evolution modes define what happens with a schema inferred from a message that doesn't match any version.
+const (
+	EvolutionDisabled = "disabled"
+	EvolutionAuto     = "auto"
+	EvolutionApproval = "approval"
+)
+
+// EvolutionPolicy defines how the evolution of a schema is governed. MaxAutogeneratedPerDay limits the number of
+// autogenerated versions per (UTC) day, 0 means unlimited.
+type EvolutionPolicy struct {
+	Mode                   string `json:"mode" bson:"mode" firestore:"mode" yaml:"mode"`
+	MaxAutogeneratedPerDay int    `json:"max-autogenerated-per-day" bson:"max-autogenerated-per-day" firestore:"max-autogenerated-per-day" yaml:"maxAutogeneratedPerDay"`
+}
+
+// Statuses of an evolution waiting in the approval queue.
+const (
+	PendingStatus  = "pending"
+	ApprovedStatus = "approved"
+	RejectedStatus = "rejected"
+)
+
+// PendingEvolution is an evolved schema version that waits for an approval before it is registered.
+type PendingEvolution struct {
+	Id            string    `json:"id" bson:"_id,omitempty" firestore:"-"`
+	SchemaId      string    `json:"schema-id" bson:"schema-id" firestore:"schema-id"`
+	Format        string    `json:"format" bson:"format" firestore:"format"`
+	Specification string    `json:"specification" bson:"specification" firestore:"specification"`
+	Diff          []string  `json:"diff" bson:"diff" firestore:"diff"`
+	Status        string    `json:"status" bson:"status" firestore:"status"`
+	CreationDate  time.Time `json:"creation-date" bson:"creation-date" firestore:"creation-date"`
+	Version       int32     `json:"version,omitempty" bson:"version,omitempty" firestore:"version,omitempty"`
+}
+
+// InNamespace checks if the schema belongs to the given namespace. Schemas stored before namespaces were introduced
+// belong to the default namespace. An empty namespace matches every schema.
+func (s *Schema) InNamespace(namespace string) bool {
+	if namespace == "" {
+		return true
+	}
+	schemaNamespace := s.Namespace
+	if schemaNamespace == "" {
+		schemaNamespace = DefaultNamespace
+	}
+	return schemaNamespace == namespace
 }
 
 type SchemaDetails struct {
-	Version       int32  `json:"version" bson:"version" firestore:"version"`
-	Specification string `json:"specification" bson:"specification" firestore:"specification"`
-	SchemaHash    string `json:"schema-hash" bson:"schema-hash" firestore:"schema-hash"`
+	Version       int32     `json:"version" bson:"version" firestore:"version"`
+	Specification string    `json:"specification" bson:"specification" firestore:"specification"`
+	SchemaHash    string    `json:"schema-hash" bson:"schema-hash" firestore:"schema-hash"`
+	Autogenerated bool      `json:"autogenerated" bson:"autogenerated" firestore:"autogenerated"`
+	CreationDate  time.Time `json:"creation-date" bson:"creation-date" firestore:"creation-date"`
+	LastAccessed  time.Time `json:"last-accessed" bson:"last-accessed" firestore:"last-accessed"`
+}
+
+// LastActivity returns the last time the version was registered or retrieved.
+func (sd *SchemaDetails) LastActivity() time.Time {
+	if sd.LastAccessed.After(sd.CreationDate) {
+		return sd.LastAccessed
+	}
+	return sd.CreationDate
 }
 
 // InsertInfo is a return value from a DB used when updating the DB
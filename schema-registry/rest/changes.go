@@ -0,0 +1,61 @@
+// Copyright 2020 Syntio Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rest
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	service "github.com/syntio/schema-registry/business_logic"
+)
+
+//
+// WatchChanges streams the changes of the schemas made through this instance of the registry as newline-delimited
+// JSON, one change per line, until the client disconnects. The changes are those of the namespace of the request
+// (every namespace outside of the "/ns/{ns}" prefix), or of a single schema if the "id" query parameter is set.
+// Changes made through other instances of the registry aren't streamed, see service.SubscribeChanges.
+//
+func WatchChanges(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeInfoResponse(w, "Streaming isn't supported.", http.StatusInternalServerError)
+		return
+	}
+
+	changes, unsubscribe := service.SubscribeChanges(namespace(r), r.URL.Query().Get("id"))
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	encoder := json.NewEncoder(w)
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case change, open := <-changes:
+			if !open {
+				return
+			}
+			if err := encoder.Encode(change); err != nil {
+				log.Printf("Change couldn't be streamed: %v\n", err)
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright 2020 Syntio Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rest
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	service "github.com/syntio/schema-registry/business_logic"
+	"github.com/syntio/schema-registry/codegen"
+	"github.com/syntio/schema-registry/util"
+)
+
+//
+// GenerateCode is a GET function that generates source code of types matching the schema with the "id" and "version"
+// from the request URL. The query parameters are:
+//  - lang, the language of the generated code (only "go" is supported)
+//  - package, the package name of the generated code
+//  - type, the name of the root type, defaults to the schema name
+//
+// It currently writes back either:
+//  - status 200 with the generated source code
+//  - status 400 with error message, if the language or the schema type isn't supported
+//  - status 404 with error message, if the schema is not registered.
+//
+func GenerateCode(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	version, err := util.StringToInt32(mux.Vars(r)["version"])
+	if err != nil {
+		writeInfoResponse(w, "Bad request. Version isn't a valid number.", http.StatusBadRequest)
+		return
+	}
+
+	options := codegen.Options{
+		Language: queryParam(r, "lang", codegen.LanguageGo),
+		Package:  queryParam(r, "package", ""),
+		TypeName: queryParam(r, "type", ""),
+	}
+	source, err := service.GenerateCode(r.Context(), namespace(r), id, version, options)
+	if err != nil {
+		writeErrorResponse(w, err, "Could not generate code from the schema")
+		return
+	}
+	writeRawResponse(w, source, "text/plain; charset=utf-8", http.StatusOK)
+}
@@ -0,0 +1,54 @@
+// Copyright 2020 Syntio Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rest
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	service "github.com/syntio/schema-registry/business_logic"
+	"github.com/syntio/schema-registry/util"
+)
+
+//
+// PostConversion is a POST function that converts the schema with the "id" and "version" from the request URL to the
+// schema type of the "as" query parameter and registers it as a sibling schema, linked with the original schema.
+//
+// It currently writes back either:
+//  - status 201 with the insert info of the sibling schema
+//  - status 400 with error message, if the schema can't be converted to the requested schema type
+//  - status 404 with error message, if the schema is not registered.
+//
+func PostConversion(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	version, err := util.StringToInt32(mux.Vars(r)["version"])
+	if err != nil {
+		writeInfoResponse(w, "Bad request. Version isn't a valid number.", http.StatusBadRequest)
+		return
+	}
+	schemaType := queryParam(r, "as", "")
+	if schemaType == "" {
+		writeInfoResponse(w, "Bad request. The 'as' query parameter is required.", http.StatusBadRequest)
+		return
+	}
+
+	response, err := service.RegisterConversion(r.Context(), namespace(r), id, version, schemaType)
+	if err != nil {
+		writeErrorResponse(w, err, "Could not register the converted schema")
+		return
+	}
+	writeValidResponse(w, response, http.StatusCreated)
+}
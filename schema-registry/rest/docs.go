@@ -0,0 +1,94 @@
+// Copyright 2020 Syntio Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rest
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/gorilla/mux"
+	service "github.com/syntio/schema-registry/business_logic"
+	"github.com/syntio/schema-registry/docs"
+	"github.com/syntio/schema-registry/util"
+)
+
+//
+// GetVersionDocs writes back the documentation of the schema with the "id" and "version" from the request URL.
+// The "format" query parameter selects HTML (default) or Markdown.
+//
+func GetVersionDocs(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	version, err := util.StringToInt32(mux.Vars(r)["version"])
+	if err != nil {
+		writeInfoResponse(w, "Bad request. Version isn't a valid number.", http.StatusBadRequest)
+		return
+	}
+
+	format := queryParam(r, "format", docs.FormatHTML)
+	response, err := service.RenderVersionDocs(r.Context(), namespace(r), id, version, format, docsLinks(r, format))
+	writeDocsResponse(w, response, format, err)
+}
+
+// GetHistoryDocs writes back the version history of the schema with the ID from the request URL.
+// The "format" query parameter selects HTML (default) or Markdown.
+func GetHistoryDocs(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	format := queryParam(r, "format", docs.FormatHTML)
+	response, err := service.RenderHistoryDocs(r.Context(), namespace(r), id, format, docsLinks(r, format))
+	writeDocsResponse(w, response, format, err)
+}
+
+// GetCatalogDocs writes back the list of schemas of the request namespace as a documentation page.
+// The "format" query parameter selects HTML (default) or Markdown.
+func GetCatalogDocs(w http.ResponseWriter, r *http.Request) {
+	format := queryParam(r, "format", docs.FormatHTML)
+	response, err := service.RenderCatalogDocs(r.Context(), namespace(r), format, docsLinks(r, format))
+	writeDocsResponse(w, response, format, err)
+}
+
+// docsLinks links the documentation pages served by the registry, keeping the namespace and format of the request.
+func docsLinks(r *http.Request, format string) docs.Links {
+	prefix := ""
+	if ns := namespace(r); ns != "" {
+		prefix = "/ns/" + url.PathEscape(ns)
+	}
+	query := "?format=" + url.QueryEscape(format)
+
+	return docs.Links{
+		Catalog: prefix + "/docs" + query,
+		History: func(id string) string {
+			return fmt.Sprintf("%s/schema/%s/docs%s", prefix, url.PathEscape(id), query)
+		},
+		Version: func(id string, version int32) string {
+			return fmt.Sprintf("%s/schema/%s/version/%d/docs%s", prefix, url.PathEscape(id), version, query)
+		},
+	}
+}
+
+// writeDocsResponse writes a rendered documentation page, or the error of the rendering, into the designated writer.
+func writeDocsResponse(w http.ResponseWriter, response []byte, format string, err error) {
+	if err != nil {
+		writeErrorResponse(w, err, "Could not render the documentation")
+		return
+	}
+	contentType := "text/html; charset=utf-8"
+	if docs.Extension(format) == ".md" {
+		contentType = "text/markdown; charset=utf-8"
+	}
+	writeRawResponse(w, response, contentType, http.StatusOK)
+}
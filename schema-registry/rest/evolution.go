@@ -16,9 +16,11 @@ package rest
 
 import (
 	"encoding/json"
+	"errors"
 	"io/ioutil"
 	"log"
 	"net/http"
+	"strconv"
 
 	"github.com/gorilla/mux"
 	service "github.com/syntio/schema-registry/business_logic"
@@ -27,9 +29,19 @@ import (
 
 // EvolutionSchema registers a new schema from the input message. Evolved schema is connected with other schemas
 // by ID from the request URL. Evolved schema has an new, incremented version.
+//
+// The evolution policy of the schema decides if the evolved schema is registered (status 200), queued for an
+// approval (status 202) or refused. With the query parameter "dryRun=true" nothing is persisted, the response holds
+// the would-be schema, its diff to the latest version and the outcome the evolution would have.
 func EvolutionSchema(w http.ResponseWriter, r *http.Request) {
 	id := mux.Vars(r)["id"]
 
+	dryRun, err := strconv.ParseBool(queryParam(r, "dryRun", "false"))
+	if err != nil {
+		writeInfoResponse(w, "Bad request. dryRun must be a boolean.", http.StatusBadRequest)
+		return
+	}
+
 	requestBody, err := ioutil.ReadAll(r.Body)
 	if err != nil {
 		writeInfoResponse(w, "Connection Error, could not read data", http.StatusServiceUnavailable)
@@ -42,27 +54,98 @@ func EvolutionSchema(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	generatedSchema, isGenerated, err := service.Evolve(*evolutionRequest)
+	response, outcome, err := service.EvolveSchema(r.Context(), namespace(r), id, *evolutionRequest, dryRun)
+	if errors.Is(err, service.ErrNotInferred) {
+		writeInfoResponse(w, "Schema couldn't be generated, dead-letter message", http.StatusOK)
+		return
+	}
+	if err != nil {
+		writeErrorResponse(w, err, "Could not update schema")
+		return
+	}
 
+	log.Printf("Evolution of schema %s finished with outcome %s (dry run: %t)\n", id, outcome, dryRun)
+	if outcome == service.OutcomePending && !dryRun {
+		writeValidResponse(w, response, http.StatusAccepted)
+		return
+	}
+	writeValidResponse(w, response, http.StatusOK)
+}
+
+// GetEvolutionPolicy writes back the evolution policy in effect for the schema with the ID from the request URL.
+func GetEvolutionPolicy(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	response, err := service.GetEvolutionPolicy(r.Context(), namespace(r), id)
 	if err != nil {
-		writeInfoResponse(w, "Schema dynamic generation error!", http.StatusInternalServerError)
+		writeErrorResponse(w, err, "Could not retrieve the evolution policy")
 		return
 	}
+	writeValidResponse(w, response, http.StatusOK)
+}
 
-	if !isGenerated {
-		writeInfoResponse(w, "Schema couldn't be generated, dead-letter message", http.StatusOK)
+// PutEvolutionPolicy sets the evolution policy of the schema with the ID from the request URL.
+//
+// The expected input JSON should contain following fields:
+// - mode                      string (disabled, auto or approval)
+// - max-autogenerated-per-day int    (0 for unlimited)
+func PutEvolutionPolicy(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	requestBody, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeInfoResponse(w, "Connection Error, could not read data", http.StatusServiceUnavailable)
+		return
 	}
 
-	response, err := service.UpdateSchema(r.Context(), id,
-		&dto.SpectificationDTO{Specification: string(generatedSchema)}, true)
+	policy := dto.EvolutionPolicyDTO{}
+	if err = json.Unmarshal(requestBody, &policy); err != nil {
+		writeInfoResponse(w, "Bad request. Content-Type must be 'application/json'.", http.StatusBadRequest)
+		return
+	}
 
-	log.Println("Sucessfully updated")
+	response, err := service.SetEvolutionPolicy(r.Context(), namespace(r), id, policy)
 	if err != nil {
-		writeInfoResponse(w, "Could not update schema", http.StatusInternalServerError)
+		writeErrorResponse(w, err, "Could not update the evolution policy")
 		return
 	}
 	writeValidResponse(w, response, http.StatusOK)
+}
+
+// ListPendingEvolutions writes back the approval queue of the schema with the ID from the request URL.
+func ListPendingEvolutions(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	response, err := service.ListPendingEvolutions(r.Context(), namespace(r), id)
+	if err != nil {
+		writeErrorResponse(w, err, "Server storage error while getting pending evolutions.")
+		return
+	}
+	writeValidResponse(w, response, http.StatusOK)
+}
 
+// ApprovePendingEvolution registers the queued evolution from the request URL as a new schema version.
+func ApprovePendingEvolution(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	response, err := service.ApprovePendingEvolution(r.Context(), namespace(r), vars["id"], vars["pendingId"])
+	if err != nil {
+		writeErrorResponse(w, err, "Could not approve the evolution")
+		return
+	}
+	writeValidResponse(w, response, http.StatusOK)
+}
+
+// RejectPendingEvolution rejects the queued evolution from the request URL.
+func RejectPendingEvolution(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	response, err := service.RejectPendingEvolution(r.Context(), namespace(r), vars["id"], vars["pendingId"])
+	if err != nil {
+		writeErrorResponse(w, err, "Could not reject the evolution")
+		return
+	}
+	writeValidResponse(w, response, http.StatusOK)
 }
 
 // EvolutionRequestDeserializeJSON deserializes the request body from a byte array to the
@@ -27,9 +27,13 @@ import (
 // GetSchemaByIdAndVersion is a GET function that expects parameters "id" and "version" for
 // retrieving the schema from the underlying database.
 //
+// The optional query parameter "as" converts the schema to another schema type (e.g. "avro"), without registering it.
+//
 // It currently writes back either:
 //  - status 200 with a schema in JSON format, if the schema is registered
-//  - status 404 with error message, if the schema is not registered.
+//  - status 400 with error message, if the schema can't be converted to the requested schema type
+//  - status 404 with error message, if the schema is not registered
+//  - status 503 with error message, if the storage is unavailable.
 //
 func GetSchemaByIdAndVersion(w http.ResponseWriter, r *http.Request) {
 	id := mux.Vars(r)["id"]
@@ -40,10 +44,41 @@ func GetSchemaByIdAndVersion(w http.ResponseWriter, r *http.Request) {
 		writeInfoResponse(w, "Bad request. Version isn't a valid number.", http.StatusBadRequest)
 		return
 	}
-	schemaInfo, found := service.GetSchema(r.Context(), id, version)
-	if !found {
-		writeInfoResponse(w, "Schema not found.", http.StatusNotFound)
+	if schemaType := queryParam(r, "as", ""); schemaType != "" {
+		response, err := service.GetConvertedSchema(r.Context(), namespace(r), id, version, schemaType)
+		if err != nil {
+			writeErrorResponse(w, err, "Could not convert the schema")
+			return
+		}
+		writeValidResponse(w, response, http.StatusOK)
+		return
+	}
+	schemaInfo, err := service.GetSchema(r.Context(), namespace(r), id, version)
+	if err != nil {
+		writeErrorResponse(w, err, "Could not get the schema")
 		return
 	}
 	writeValidResponse(w, schemaInfo, http.StatusOK)
 }
+
+// GetSchema writes back the schema with the ID from the request URL, including all of its versions.
+func GetSchema(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	response, err := service.GetSchemaWithVersions(r.Context(), namespace(r), id)
+	if err != nil {
+		writeErrorResponse(w, err, "Server storage error while getting the schema.")
+		return
+	}
+	writeValidResponse(w, response, http.StatusOK)
+}
+
+// ListSchemas writes back the registered schemas of the request namespace, without their versions.
+func ListSchemas(w http.ResponseWriter, r *http.Request) {
+	response, err := service.GetSchemas(r.Context(), namespace(r))
+	if err != nil {
+		writeErrorResponse(w, err, "Server storage error while getting schemas.")
+		return
+	}
+	writeValidResponse(w, response, http.StatusOK)
+}
@@ -0,0 +1,71 @@
+// Copyright 2020 Syntio Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	service "github.com/syntio/schema-registry/business_logic"
+	"github.com/syntio/schema-registry/model/dto"
+)
+
+// storageCheckTimeout bounds the storage check of the health and readiness probes.
+const storageCheckTimeout = 5 * time.Second
+
+// Healthz is the liveness probe. It always writes back status 200, since the server can serve requests that don't
+// need the storage. The response reports "degraded" if the storage can't be reached.
+func Healthz(w http.ResponseWriter, r *http.Request) {
+	health := dto.HealthDTO{Status: "ok", Storage: "ok"}
+	if err := checkStorage(r.Context()); err != nil {
+		health.Status = "degraded"
+		health.Storage = err.Error()
+	}
+	writeHealthResponse(w, health, http.StatusOK)
+}
+
+// Readyz is the readiness probe. It writes back status 200 if the storage can be reached, and status 503 if it can't
+// or if the server is shutting down.
+func Readyz(w http.ResponseWriter, r *http.Request) {
+	if atomic.LoadInt32(&shuttingDown) == 1 {
+		writeHealthResponse(w, dto.HealthDTO{Status: "shutting down"}, http.StatusServiceUnavailable)
+		return
+	}
+	if err := checkStorage(r.Context()); err != nil {
+		writeHealthResponse(w, dto.HealthDTO{Status: "unavailable", Storage: err.Error()}, http.StatusServiceUnavailable)
+		return
+	}
+	writeHealthResponse(w, dto.HealthDTO{Status: "ok", Storage: "ok"}, http.StatusOK)
+}
+
+// checkStorage checks if the storage can be reached within the storage check timeout.
+func checkStorage(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, storageCheckTimeout)
+	defer cancel()
+	return service.CheckStorage(ctx)
+}
+
+// writeHealthResponse writes a health report into the designated writer.
+func writeHealthResponse(w http.ResponseWriter, health dto.HealthDTO, status int) {
+	response, err := json.Marshal(health)
+	if err != nil {
+		writeInfoResponse(w, health.Status, status)
+		return
+	}
+	writeValidResponse(w, response, status)
+}
@@ -0,0 +1,134 @@
+// Copyright 2020 Syntio Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rest
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/syntio/janitor-common/tracing"
+	"github.com/syntio/schema-registry/configuration"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"google.golang.org/grpc"
+)
+
+// Defaults used when the config file doesn't define them.
+const (
+	defaultPort                   = "8080"
+	defaultReadTimeoutSeconds     = 30
+	defaultIdleTimeoutSeconds     = 120
+	defaultShutdownTimeoutSeconds = 30
+)
+
+// shuttingDown is set once the server starts draining, after which it reports itself as not ready.
+var shuttingDown int32
+
+// serve serves the handler until the process receives SIGTERM or SIGINT. In-flight requests are then drained for at
+// most the configured shutdown timeout, after which the remaining connections and gRPC streams are closed.
+func serve(cfg configuration.ServerConfig, grpcServer *grpc.Server, handler http.Handler) {
+	server := newServer(cfg, handler)
+
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT)
+		received := <-signals
+
+		log.Printf("Received %v, draining in-flight requests\n", received)
+		atomic.StoreInt32(&shuttingDown, 1)
+		shutdown(server, grpcServer, durationOrDefault(cfg.ShutdownTimeoutSeconds, defaultShutdownTimeoutSeconds))
+	}()
+
+	var err error
+	if cfg.TLSCertFile != "" && cfg.TLSKeyFile != "" {
+		log.Printf("Schema registry REST and gRPC server ready on %s (TLS)\n", server.Addr)
+		err = server.ListenAndServeTLS(cfg.TLSCertFile, cfg.TLSKeyFile)
+	} else {
+		log.Printf("Schema registry REST and gRPC server ready on %s\n", server.Addr)
+		err = server.ListenAndServe()
+	}
+	if !errors.Is(err, http.ErrServerClosed) {
+		log.Fatalf("Server stopped: %v", err)
+	}
+	<-stopped
+	log.Println("Server stopped")
+}
+
+// newServer creates the HTTP server from the server configuration. Without TLS, HTTP/2 is accepted in clear text
+// (h2c), which the gRPC API needs.
+func newServer(cfg configuration.ServerConfig, handler http.Handler) *http.Server {
+	address := cfg.Address
+	if address == "" {
+		port := os.Getenv("PORT")
+		if port == "" {
+			port = defaultPort
+		}
+		address = ":" + port
+	}
+	if cfg.TLSCertFile == "" || cfg.TLSKeyFile == "" {
+		handler = h2c.NewHandler(handler, &http2.Server{})
+	}
+
+	return &http.Server{
+		Addr:         address,
+		Handler:      handler,
+		ReadTimeout:  durationOrDefault(cfg.ReadTimeoutSeconds, defaultReadTimeoutSeconds),
+		WriteTimeout: cfg.WriteTimeoutSeconds * time.Second,
+		IdleTimeout:  durationOrDefault(cfg.IdleTimeoutSeconds, defaultIdleTimeoutSeconds),
+	}
+}
+
+// shutdown stops accepting connections and waits for in-flight requests and gRPC calls to finish, for at most the
+// timeout. Connections and streams still open after the timeout (e.g. change watchers) are closed, and the remaining
+// spans are exported.
+func shutdown(server *http.Server, grpcServer *grpc.Server, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	grpcStopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(grpcStopped)
+	}()
+
+	if err := server.Shutdown(ctx); err != nil {
+		log.Printf("Requests weren't drained in %v: %v\n", timeout, err)
+		server.Close()
+	}
+	select {
+	case <-grpcStopped:
+	case <-ctx.Done():
+		grpcServer.Stop()
+	}
+	tracing.Flush(ctx)
+}
+
+// durationOrDefault converts a number of seconds from the config file into a duration, using the default if the
+// number isn't set.
+func durationOrDefault(seconds time.Duration, defaultSeconds time.Duration) time.Duration {
+	if seconds <= 0 {
+		seconds = defaultSeconds
+	}
+	return seconds * time.Second
+}
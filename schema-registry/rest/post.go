@@ -33,11 +33,16 @@ import (
 // - Type          int32
 // - Specification string
 // - Name          string
+// - Compatibility string (optional, defaults to the namespace compatibility)
 //
 // Function writes back a JSON with fields:
 // - Identification int64
 // - Version        int32
 // - Message        string
+// - Lint           list of violated "warn" linting rules (omitted if there are none)
+//
+// A schema violating a linting rule of the namespace with the "error" severity isn't registered, the violations
+// are written back with the status code 422.
 //
 func PostSchema(w http.ResponseWriter, r *http.Request) {
 
@@ -53,10 +58,10 @@ func PostSchema(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response, err := service.CreateSchema(r.Context(), *postRequest)
+	response, err := service.CreateSchema(r.Context(), namespace(r), *postRequest)
 
 	if err != nil {
-		writeInfoResponse(w, "Server storage error! Schema was not registered.", http.StatusInternalServerError)
+		writeErrorResponse(w, err, "Server storage error! Schema was not registered.")
 		return
 	}
 
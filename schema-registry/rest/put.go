@@ -41,9 +41,9 @@ func PutSchema(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response, err := service.UpdateSchema(r.Context(), id, putRequestInfo, false)
+	response, err := service.UpdateSchema(r.Context(), namespace(r), id, putRequestInfo, false)
 	if err != nil {
-		writeInfoResponse(w, "Could not update schema", http.StatusInternalServerError)
+		writeErrorResponse(w, err, "Could not update schema")
 		return
 	}
 	writeValidResponse(w, response, http.StatusOK)
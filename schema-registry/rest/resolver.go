@@ -27,9 +27,9 @@ func BackwardResolver(w http.ResponseWriter, r *http.Request) {
 	if !ok {
 		writeInfoResponse(w, "Id non existent", 400)
 	}
-	schemaVersions, err := service.ListSchemas(r.Context(), id)
+	schemaVersions, err := service.ListSchemas(r.Context(), namespace(r), id)
 	if err != nil {
-		writeInfoResponse(w, "Server storage error while getting schema versions.", http.StatusInternalServerError)
+		writeErrorResponse(w, err, "Server storage error while getting schema versions.")
 		return
 	}
 	writeValidResponse(w, schemaVersions, http.StatusOK)
@@ -0,0 +1,89 @@
+// Copyright 2020 Syntio Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rest
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	service "github.com/syntio/schema-registry/business_logic"
+	"github.com/syntio/schema-registry/model/dto"
+)
+
+// GetRetentionPolicy writes back the retention policy in effect for the schema with the ID from the request URL.
+func GetRetentionPolicy(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	response, err := service.GetRetentionPolicy(r.Context(), namespace(r), id)
+	if err != nil {
+		writeErrorResponse(w, err, "Could not retrieve the retention policy")
+		return
+	}
+	writeValidResponse(w, response, http.StatusOK)
+}
+
+// PutRetentionPolicy sets the retention policy of the schema with the ID from the request URL.
+//
+// The expected input JSON should contain following fields:
+// - keep-last                       int  (0 keeps every version)
+// - keep-manual                     bool (manually registered versions are never removed)
+// - expire-autogenerated-after-days int  (0 for never)
+func PutRetentionPolicy(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	requestBody, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeInfoResponse(w, "Connection Error, could not read data", http.StatusServiceUnavailable)
+		return
+	}
+
+	policy := dto.RetentionPolicyDTO{}
+	if err = json.Unmarshal(requestBody, &policy); err != nil {
+		writeInfoResponse(w, "Bad request. Content-Type must be 'application/json'.", http.StatusBadRequest)
+		return
+	}
+
+	response, err := service.SetRetentionPolicy(r.Context(), namespace(r), id, policy)
+	if err != nil {
+		writeErrorResponse(w, err, "Could not update the retention policy")
+		return
+	}
+	writeValidResponse(w, response, http.StatusOK)
+}
+
+// CompactSchema removes the versions of the schema with the ID from the request URL which its retention policy
+// expired.
+func CompactSchema(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	response, err := service.CompactSchema(r.Context(), namespace(r), id)
+	if err != nil {
+		writeErrorResponse(w, err, "Could not compact the schema")
+		return
+	}
+	writeValidResponse(w, response, http.StatusOK)
+}
+
+// CompactAll compacts every schema of the request namespace, or of every namespace outside of the "/ns/{ns}" prefix.
+func CompactAll(w http.ResponseWriter, r *http.Request) {
+	response, err := service.CompactAll(r.Context(), namespace(r))
+	if err != nil {
+		writeErrorResponse(w, err, "Could not compact the schemas")
+		return
+	}
+	writeValidResponse(w, response, http.StatusOK)
+}
@@ -17,35 +17,182 @@ package rest
 
 import (
 	"encoding/json"
-	"fmt"
+	"errors"
 	"log"
 	"net/http"
+	"strings"
 
 	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/syntio/janitor-common/tracing"
+	service "github.com/syntio/schema-registry/business_logic"
+	"github.com/syntio/schema-registry/codegen"
+	"github.com/syntio/schema-registry/conversion"
+	"github.com/syntio/schema-registry/database"
+	"github.com/syntio/schema-registry/docs"
+	"github.com/syntio/schema-registry/metrics"
 	"github.com/syntio/schema-registry/model/dto"
+	"github.com/syntio/schema-registry/rpc"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/trace"
 )
 
 //
-// SetupAndStartServer starts the REST server and configures it on the port localhost:8080.
+// SetupAndStartServer starts the REST server on the address from the config file (by default the port from the PORT
+// environment variable, or 8080) and serves until the process receives SIGTERM or SIGINT.
 //
 // Configuration includes listening on handles:
+//  - "/healthz" and "/readyz" for liveness and readiness probes
+//  - "/metrics" for Prometheus metrics
 //  - "/schema/{id}/version/{version}" for schema retrieval
-//  - "/schema" for schema registration
+//  - "/schema/{id}/version/{version}/validate" for payload validation
+//  - "/schema/{id}/version/{version}/codegen" for code generation
+//  - "/schema/{id}/version/{version}/conversion" for registering a schema converted to another schema type
+//  - "/schema/{id}/version/{version}/docs", "/schema/{id}/docs" and "/docs" for schema documentation
+//  - "/schema" for schema registration and schema list retrieval
+//  - "/schema/{id}" for schema retrieval with all versions
 //  - "/schema/{id} for schema versioning
 //	- "/schema/{id}/evolution for schema evolution
+//	- "/schema/{id}/evolution/policy for evolution policy retrieval and update
+//	- "/schema/{id}/evolution/pending for the approval queue of evolved schemas
+//	- "/schema/{id}/retention for retention policy retrieval and update
+//	- "/schema/{id}/compaction and "/compaction" for removing expired schema versions
+//	- "/changes" for streaming the schema changes made through this instance
 //	- "/schema/resolver/backward-transite/{id} for schema list retrieval
 //
+// Every handle is also served under the "/ns/{ns}" prefix, which scopes the request to a namespace. Expired schema
+// versions are also compacted periodically, if a compaction interval is configured.
+//
+// Requests are counted and timed per route for the "/metrics" handle, and traced in spans named after their route
+// which continue the W3C trace context of the request headers (probes and metrics scrapes aren't traced). The gRPC
+// API is served on the same port. Requests are accepted over HTTP/1.1 and HTTP/2, with or without TLS (h2c), and
+// gRPC requests are told apart by their content type.
+//
+// The storage is connected lazily: the server starts even if the storage can't be reached, reports itself as
+// degraded on "/healthz" and as not ready on "/readyz" until it can.
+//
 func SetupAndStartServer() {
+	tracingCfg := service.TracingConfig()
+	tracing.Configure("schema-registry", tracingCfg.Endpoint, tracingCfg.Insecure, tracingCfg.SampleRatio)
 
 	router := mux.NewRouter().StrictSlash(true)
+	router.Use(metrics.Middleware, nameSpan)
+	router.HandleFunc("/healthz", Healthz).Methods("GET")
+	router.HandleFunc("/readyz", Readyz).Methods("GET")
+	router.Handle("/metrics", promhttp.Handler()).Methods("GET")
+	registerSchemaRoutes(router)
+	registerSchemaRoutes(router.PathPrefix("/ns/{ns}").Subrouter())
+
+	grpcServer := rpc.NewServer()
+	traced := otelhttp.NewHandler(router, "schema-registry", otelhttp.WithFilter(func(r *http.Request) bool {
+		return r.URL.Path != "/healthz" && r.URL.Path != "/readyz" && r.URL.Path != "/metrics"
+	}))
+	serve(service.ServerConfig(), grpcServer, grpcHandler(grpcServer, traced))
+}
+
+// nameSpan names the span of a request after its method and the path template of the matched route.
+func nameSpan(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if route := mux.CurrentRoute(r); route != nil {
+			if template, err := route.GetPathTemplate(); err == nil {
+				trace.SpanFromContext(r.Context()).SetName(r.Method + " " + template)
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// grpcHandler routes gRPC requests to the gRPC server and every other request to the REST handler.
+func grpcHandler(grpcServer http.Handler, restHandler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+			grpcServer.ServeHTTP(w, r)
+			return
+		}
+		restHandler.ServeHTTP(w, r)
+	})
+}
+
+// registerSchemaRoutes registers the schema handles on the given router.
+func registerSchemaRoutes(router *mux.Router) {
 	router.HandleFunc("/schema/{id}/version/{version}", GetSchemaByIdAndVersion).Methods("GET")
+	router.HandleFunc("/schema/{id}/version/{version}/validate", ValidatePayload).Methods("POST")
+	router.HandleFunc("/schema/{id}/version/{version}/codegen", GenerateCode).Methods("GET")
+	router.HandleFunc("/schema/{id}/version/{version}/conversion", PostConversion).Methods("POST")
+	router.HandleFunc("/schema/{id}/version/{version}/docs", GetVersionDocs).Methods("GET")
+	router.HandleFunc("/schema/{id}/docs", GetHistoryDocs).Methods("GET")
+	router.HandleFunc("/docs", GetCatalogDocs).Methods("GET")
 	router.HandleFunc("/schema/", PostSchema).Methods("POST")
+	router.HandleFunc("/schema/", ListSchemas).Methods("GET")
+	router.HandleFunc("/schema/{id}", GetSchema).Methods("GET")
 	router.HandleFunc("/schema/{id}", PutSchema).Methods("PUT")
 	router.HandleFunc("/schema/{id}/evolution", EvolutionSchema).Methods("POST")
+	router.HandleFunc("/schema/{id}/evolution/policy", GetEvolutionPolicy).Methods("GET")
+	router.HandleFunc("/schema/{id}/evolution/policy", PutEvolutionPolicy).Methods("PUT")
+	router.HandleFunc("/schema/{id}/evolution/pending", ListPendingEvolutions).Methods("GET")
+	router.HandleFunc("/schema/{id}/evolution/pending/{pendingId}/approve", ApprovePendingEvolution).Methods("POST")
+	router.HandleFunc("/schema/{id}/evolution/pending/{pendingId}/reject", RejectPendingEvolution).Methods("POST")
+	router.HandleFunc("/schema/{id}/retention", GetRetentionPolicy).Methods("GET")
+	router.HandleFunc("/schema/{id}/retention", PutRetentionPolicy).Methods("PUT")
+	router.HandleFunc("/schema/{id}/compaction", CompactSchema).Methods("POST")
+	router.HandleFunc("/compaction", CompactAll).Methods("POST")
+	router.HandleFunc("/changes", WatchChanges).Methods("GET")
 	router.HandleFunc("/schema/resolver/{id}", BackwardResolver).Methods("GET")
+}
+
+// namespace returns the namespace of the request. Requests outside of the "/ns/{ns}" prefix have an empty namespace.
+func namespace(r *http.Request) string {
+	return mux.Vars(r)["ns"]
+}
+
+// queryParam returns the value of a query parameter of the request, or the default value if it isn't set.
+func queryParam(r *http.Request, name, defaultValue string) string {
+	if value := r.URL.Query().Get(name); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// writeErrorResponse writes an error returned by the business logic into the designated writer. Errors known to the
+// business logic are mapped to their status codes, for any other error the fallback message is written with the
+// status code 500.
+func writeErrorResponse(w http.ResponseWriter, err error, fallbackMessage string) {
+	var lintErr *service.LintError
+	switch {
+	case errors.As(err, &lintErr):
+		writeLintResponse(w, lintErr)
+	case errors.Is(err, database.ErrNotFound):
+		writeInfoResponse(w, "Schema not found.", http.StatusNotFound)
+	case errors.Is(err, database.ErrUnavailable):
+		writeInfoResponse(w, "Storage is unavailable.", http.StatusServiceUnavailable)
+	case errors.Is(err, service.ErrQuotaExceeded):
+		writeInfoResponse(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, service.ErrIncompatible), errors.Is(err, service.ErrNotPending):
+		writeInfoResponse(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, service.ErrEvolutionDisabled), errors.Is(err, service.ErrEvolutionRejected):
+		writeInfoResponse(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, service.ErrEvolutionLimit):
+		writeInfoResponse(w, err.Error(), http.StatusTooManyRequests)
+	case errors.Is(err, service.ErrInvalidPolicy), errors.Is(err, service.ErrInvalidRetention),
+		errors.Is(err, service.ErrUnsupportedFormat), errors.Is(err, codegen.ErrUnsupportedLanguage),
+		errors.Is(err, codegen.ErrUnsupportedSchemaType), errors.Is(err, conversion.ErrUnsupportedConversion),
+		errors.Is(err, docs.ErrUnsupportedFormat):
+		writeInfoResponse(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, conversion.ErrInvalidSchema):
+		writeInfoResponse(w, err.Error(), http.StatusUnprocessableEntity)
+	default:
+		writeInfoResponse(w, fallbackMessage, http.StatusInternalServerError)
+	}
+}
 
-	fmt.Println("Schema register REST server ready on port :8080")
-	fmt.Println(http.ListenAndServe(":8080", router))
+// writeLintResponse writes the violations of a specification refused by the linting rules into the designated writer.
+func writeLintResponse(w http.ResponseWriter, lintErr *service.LintError) {
+	response, err := json.Marshal(dto.LintReportDTO{Message: lintErr.Error(), Violations: lintErr.Violations})
+	if err != nil {
+		writeInfoResponse(w, lintErr.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	writeValidResponse(w, response, http.StatusUnprocessableEntity)
 }
 
 // wrieteValid response writes any informational or error response into the designated writer.
@@ -78,6 +225,19 @@ func writeValidResponse(w http.ResponseWriter, response []byte, status int) {
 	}
 }
 
+// writeRawResponse writes a response body which isn't JSON, e.g. generated source code, into the designated writer.
+// Input arguments are a http writer, the response body, its content type and a HTTP status code.
+func writeRawResponse(w http.ResponseWriter, response []byte, contentType string, status int) {
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
+
+	_, err := w.Write(response)
+	if err != nil {
+		log.Printf("HTTP response couldn't be send properly.\nError: %s", err)
+		return
+	}
+}
+
 //
 // InfoResponseSerializeJSON serializes the input message to a simple JSON object containing only one field  - message.
 //
@@ -0,0 +1,63 @@
+// Copyright 2020 Syntio Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rest
+
+import (
+	"io/ioutil"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	service "github.com/syntio/schema-registry/business_logic"
+	"github.com/syntio/schema-registry/util"
+)
+
+//
+// ValidatePayload is a POST function that validates the request body against the schema with the "id" and "version"
+// from the request URL. The payload format defaults to the schema type and can be set by the "format" query parameter.
+// The "messageType" query parameter chooses the message type of a Protobuf schema, and "encoding=json" reads Avro
+// payloads in the Avro JSON encoding, like the messageType and encoding attributes of the messages.
+//
+// It currently writes back either:
+//  - status 200 with the validation result, which lists the violations of an invalid payload
+//  - status 400 with error message, if the version isn't a number or the format isn't supported
+//  - status 404 with error message, if the schema is not registered.
+//
+func ValidatePayload(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	version, err := util.StringToInt32(mux.Vars(r)["version"])
+	if err != nil {
+		writeInfoResponse(w, "Bad request. Version isn't a valid number.", http.StatusBadRequest)
+		return
+	}
+
+	payload, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeInfoResponse(w, "Connection Error, could not read data", http.StatusServiceUnavailable)
+		return
+	}
+
+	attributes := map[string]string{
+		"messageType": queryParam(r, "messageType", ""),
+		"encoding":    queryParam(r, "encoding", ""),
+	}
+	response, err := service.ValidatePayload(r.Context(), namespace(r), id, version, queryParam(r, "format", ""),
+		attributes, payload)
+	if err != nil {
+		writeErrorResponse(w, err, "Could not validate the payload")
+		return
+	}
+	writeValidResponse(w, response, http.StatusOK)
+}
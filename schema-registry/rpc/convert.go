@@ -0,0 +1,120 @@
+// Copyright 2020 Syntio Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpc
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/syntio/schema-registry/api"
+	service "github.com/syntio/schema-registry/business_logic"
+	"github.com/syntio/schema-registry/model"
+	"github.com/syntio/schema-registry/model/dto"
+	"github.com/syntio/schema-registry/util"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// changeKinds maps the kinds of schema changes to their gRPC enum values.
+var changeKinds = map[string]api.SchemaChange_Kind{
+	service.ChangeCreated:        api.SchemaChange_CREATED,
+	service.ChangeVersionAdded:   api.SchemaChange_VERSION_ADDED,
+	service.ChangeVersionRemoved: api.SchemaChange_VERSION_REMOVED,
+}
+
+// schemaResponse converts a marshaled schema returned by the business logic into a gRPC message.
+func schemaResponse(response []byte) (*api.Schema, error) {
+	var schema model.Schema
+	if err := json.Unmarshal(response, &schema); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	message, err := schemaMessage(&schema)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return message, nil
+}
+
+// schemaMessage converts a schema into a gRPC message.
+func schemaMessage(schema *model.Schema) (*api.Schema, error) {
+	creationDate, err := timestampMessage(schema.CreationDate)
+	if err != nil {
+		return nil, err
+	}
+	message := &api.Schema{
+		Id:            schema.Id,
+		Namespace:     schema.Namespace,
+		Name:          schema.Name,
+		Description:   schema.Description,
+		SchemaType:    schema.SchemaType,
+		Compatibility: schema.Compatibility,
+		Autogenerated: schema.Autogenerated,
+		CreationDate:  creationDate,
+		LatestVersion: schema.LatestVersion,
+	}
+	for _, details := range schema.SchemaDetails {
+		version, err := versionMessage(details)
+		if err != nil {
+			return nil, err
+		}
+		message.Versions = append(message.Versions, version)
+	}
+	return message, nil
+}
+
+// versionMessage converts a schema version into a gRPC message. The specification is decoded from base64.
+func versionMessage(details *model.SchemaDetails) (*api.SchemaVersion, error) {
+	specification, err := util.SchemaBase64Decode(details.Specification)
+	if err != nil {
+		return nil, err
+	}
+	creationDate, err := timestampMessage(details.CreationDate)
+	if err != nil {
+		return nil, err
+	}
+	return &api.SchemaVersion{
+		Version:       details.Version,
+		Specification: specification,
+		SchemaHash:    details.SchemaHash,
+		Autogenerated: details.Autogenerated,
+		CreationDate:  creationDate,
+	}, nil
+}
+
+// changeMessage converts a schema change into a gRPC message.
+func changeMessage(change dto.SchemaChangeDTO) (*api.SchemaChange, error) {
+	changeTime, err := timestampMessage(change.Time)
+	if err != nil {
+		return nil, err
+	}
+	return &api.SchemaChange{
+		Kind:       changeKinds[change.Kind],
+		Id:         change.Id,
+		Namespace:  change.Namespace,
+		Version:    change.Version,
+		SchemaType: change.SchemaType,
+		Time:       changeTime,
+	}, nil
+}
+
+// timestampMessage converts a time into a protobuf timestamp. The zero time is left unset.
+func timestampMessage(t time.Time) (*timestamp.Timestamp, error) {
+	if t.IsZero() {
+		return nil, nil
+	}
+	return ptypes.TimestampProto(t)
+}
@@ -0,0 +1,114 @@
+// Copyright 2020 Syntio Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpc
+
+import (
+	"context"
+	"time"
+
+	"github.com/syntio/janitor-common/tracing"
+	"github.com/syntio/schema-registry/metrics"
+	"go.opentelemetry.io/otel"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// unaryTracing serves the unary calls of the gRPC server in spans continuing the trace context of the call metadata.
+func unaryTracing(ctx context.Context, request interface{}, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, span := startSpan(ctx, info.FullMethod)
+	response, err := handler(ctx, request)
+	endSpan(span, err)
+	return response, err
+}
+
+// streamTracing serves the streaming calls of the gRPC server in spans continuing the trace context of the call
+// metadata.
+func streamTracing(server interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler) error {
+	ctx, span := startSpan(stream.Context(), info.FullMethod)
+	err := handler(server, &tracedStream{ServerStream: stream, ctx: ctx})
+	endSpan(span, err)
+	return err
+}
+
+// startSpan starts the server span of a gRPC call.
+func startSpan(ctx context.Context, method string) (context.Context, trace.Span) {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		ctx = otel.GetTextMapPropagator().Extract(ctx, metadataCarrier(md))
+	}
+	return tracing.Start(ctx, method, trace.WithSpanKind(trace.SpanKindServer))
+}
+
+// endSpan ends the server span of a gRPC call with the status of the call.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.SetStatus(otelcodes.Error, status.Convert(err).Message())
+	}
+	span.End()
+}
+
+// tracedStream is a server stream whose context carries the span of the call.
+type tracedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *tracedStream) Context() context.Context {
+	return s.ctx
+}
+
+// metadataCarrier carries the trace context in the metadata of a gRPC call.
+type metadataCarrier metadata.MD
+
+func (c metadataCarrier) Get(key string) string {
+	if values := metadata.MD(c).Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func (c metadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// unaryMetrics counts and times the unary calls of the gRPC server.
+func unaryMetrics(ctx context.Context, request interface{}, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	response, err := handler(ctx, request)
+	metrics.ObserveRPC(info.FullMethod, status.Code(err).String(), start)
+	return response, err
+}
+
+// streamMetrics counts and times the streaming calls of the gRPC server.
+func streamMetrics(server interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler) error {
+	start := time.Now()
+	err := handler(server, stream)
+	metrics.ObserveRPC(info.FullMethod, status.Code(err).String(), start)
+	return err
+}
@@ -0,0 +1,211 @@
+// Copyright 2020 Syntio Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package rpc contains the gRPC API of the Schema Registry. It is backed by the same business logic as the REST API
+// and served next to it, on the same port.
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/syntio/schema-registry/api"
+	service "github.com/syntio/schema-registry/business_logic"
+	"github.com/syntio/schema-registry/database"
+	"github.com/syntio/schema-registry/model"
+	"github.com/syntio/schema-registry/model/dto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// registryServer implements the Registry gRPC service.
+type registryServer struct {
+	api.UnimplementedRegistryServer
+}
+
+// NewServer creates a gRPC server with the Registry service registered.
+func NewServer() *grpc.Server {
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(unaryTracing, unaryMetrics),
+		grpc.ChainStreamInterceptor(streamTracing, streamMetrics),
+	)
+	api.RegisterRegistryServer(server, &registryServer{})
+	return server
+}
+
+// GetSchema returns the schema with the requested ID and version.
+func (s *registryServer) GetSchema(ctx context.Context, request *api.GetSchemaRequest) (*api.Schema, error) {
+	response, err := service.GetSchema(ctx, request.Namespace, request.Id, request.Version)
+	if err != nil {
+		return nil, errorStatus(err, "could not get the schema")
+	}
+	return schemaResponse(response)
+}
+
+// GetLatest returns the schema with the requested ID and its latest version.
+func (s *registryServer) GetLatest(ctx context.Context, request *api.GetLatestRequest) (*api.Schema, error) {
+	response, err := service.GetLatestSchema(ctx, request.Namespace, request.Id)
+	if err != nil {
+		return nil, errorStatus(err, "could not get the schema")
+	}
+	return schemaResponse(response)
+}
+
+// Register registers a new schema, or a new version of the schema with the requested ID.
+func (s *registryServer) Register(ctx context.Context, request *api.RegisterRequest) (*api.RegisterResponse,
+	error) {
+	var response []byte
+	var err error
+	if request.Id == "" {
+		response, err = service.CreateSchema(ctx, request.Namespace, dto.SchemaDTO{
+			Description:   request.Description,
+			Specification: string(request.Specification),
+			Name:          request.Name,
+			SchemaType:    request.SchemaType,
+			Compatibility: request.Compatibility,
+		})
+	} else {
+		response, err = service.UpdateSchema(ctx, request.Namespace, request.Id,
+			&dto.SpectificationDTO{Specification: string(request.Specification)}, false)
+	}
+	if err != nil {
+		return nil, errorStatus(err, "schema was not registered")
+	}
+
+	var insertInfo dto.InsertInfoDTO
+	if err = json.Unmarshal(response, &insertInfo); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	registered := &api.RegisterResponse{Id: insertInfo.Id, Version: insertInfo.Version, Message: insertInfo.Message}
+	for _, violation := range insertInfo.Lint {
+		registered.Lint = append(registered.Lint, &api.LintViolation{
+			Rule:     violation.Rule,
+			Severity: violation.Severity,
+			Location: violation.Location,
+			Message:  violation.Message,
+		})
+	}
+	return registered, nil
+}
+
+// Evolve infers a new version of the schema with the requested ID from the request data.
+func (s *registryServer) Evolve(ctx context.Context, request *api.EvolveRequest) (*api.EvolveResponse, error) {
+	evolution := dto.EvolutionDTO{Data: request.Data, Format: request.Format}
+	response, _, err := service.EvolveSchema(ctx, request.Namespace, request.Id, evolution, request.DryRun)
+	if err != nil {
+		return nil, errorStatus(err, "could not evolve the schema")
+	}
+
+	var result dto.EvolutionResultDTO
+	if err = json.Unmarshal(response, &result); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &api.EvolveResponse{
+		Id:            result.Id,
+		Version:       result.Version,
+		Message:       result.Message,
+		Outcome:       result.Outcome,
+		DryRun:        result.DryRun,
+		PendingId:     result.PendingId,
+		Specification: result.Specification,
+		Diff:          result.Diff,
+	}, nil
+}
+
+// ListVersions returns every version of the schema with the requested ID.
+func (s *registryServer) ListVersions(ctx context.Context, request *api.ListVersionsRequest) (
+	*api.ListVersionsResponse, error) {
+	response, err := service.ListSchemas(ctx, request.Namespace, request.Id)
+	if err != nil {
+		return nil, errorStatus(err, "could not list the schema versions")
+	}
+
+	var versions []*model.SchemaDetails
+	if err = json.Unmarshal(response, &versions); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if len(versions) == 0 {
+		return nil, status.Error(codes.NotFound, "schema not found")
+	}
+	list := &api.ListVersionsResponse{}
+	for _, details := range versions {
+		version, err := versionMessage(details)
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		list.Versions = append(list.Versions, version)
+	}
+	return list, nil
+}
+
+// WatchChanges streams the changes of the requested namespace or schema until the client cancels the stream. Only the
+// changes made through this instance of the registry are streamed, see service.SubscribeChanges.
+func (s *registryServer) WatchChanges(request *api.WatchChangesRequest, stream api.Registry_WatchChangesServer) error {
+	changes, unsubscribe := service.SubscribeChanges(request.Namespace, request.Id)
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case change := <-changes:
+			message, err := changeMessage(change)
+			if err != nil {
+				return status.Error(codes.Internal, err.Error())
+			}
+			if err = stream.Send(message); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// errorStatus converts an error returned by the business logic into a gRPC status. Errors known to the business logic
+// are mapped to their codes, for any other error the fallback message is used with the code Internal.
+func errorStatus(err error, fallbackMessage string) error {
+	var lintErr *service.LintError
+	switch {
+	case errors.As(err, &lintErr):
+		return status.Error(codes.InvalidArgument, lintMessage(lintErr))
+	case errors.Is(err, database.ErrNotFound):
+		return status.Error(codes.NotFound, "schema not found")
+	case errors.Is(err, database.ErrUnavailable):
+		return status.Error(codes.Unavailable, "storage is unavailable")
+	case errors.Is(err, service.ErrQuotaExceeded), errors.Is(err, service.ErrEvolutionLimit):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case errors.Is(err, service.ErrIncompatible), errors.Is(err, service.ErrEvolutionDisabled),
+		errors.Is(err, service.ErrEvolutionRejected):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, service.ErrNotInferred):
+		return status.Error(codes.InvalidArgument, err.Error())
+	default:
+		return status.Error(codes.Internal, fallbackMessage)
+	}
+}
+
+// lintMessage lists the violations of a specification refused by the linting rules.
+func lintMessage(lintErr *service.LintError) string {
+	message := lintErr.Error()
+	for _, violation := range lintErr.Violations {
+		if violation.Location != "" {
+			message += fmt.Sprintf("; %s at %s: %s", violation.Rule, violation.Location, violation.Message)
+		} else {
+			message += fmt.Sprintf("; %s: %s", violation.Rule, violation.Message)
+		}
+	}
+	return message
+}
@@ -19,11 +19,13 @@ import (
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"log"
 	"path/filepath"
 	"reflect"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/syntio/schema-registry/schema_creation"
@@ -128,9 +130,12 @@ func CallFunction(name string, params ...interface{}) ([]interface{}, error) {
 }
 
 // Mapper util for converting a dto with additional data, into a model.Schema
-func DtoToSchema(dto *dto.SchemaDTO, id string, hash string, specification []byte, version int32) *model.Schema {
+func DtoToSchema(dto *dto.SchemaDTO, namespace string, id string, hash string, specification []byte,
+	version int32) *model.Schema {
 	schema := &model.Schema{
 		Id:            id,
+		Namespace:     namespace,
+		Compatibility: dto.Compatibility,
 		Autogenerated: false,
 		CreationDate:  time.Now(),
 		Description:   dto.Description,
@@ -142,7 +147,71 @@ func DtoToSchema(dto *dto.SchemaDTO, id string, hash string, specification []byt
 		Version:       version,
 		SchemaHash:    hash,
 		Specification: SchemaBase64Encode(specification),
+		CreationDate:  schema.CreationDate,
 	})
 	schema.SchemaDetails = details
 	return schema
 }
+
+// Diff returns a line based difference between two schema specifications. Lines only present in the old specification
+// are prefixed with "-", lines only present in the new one with "+" and common lines with a space.
+// JSON specifications are normalized (keys sorted and indented) before they are compared.
+func Diff(old, new []byte) []string {
+	oldLines := strings.Split(normalizeJSON(old), "\n")
+	newLines := strings.Split(normalizeJSON(new), "\n")
+
+	// lcs[i][j] holds the length of the longest common subsequence of oldLines[i:] and newLines[j:]
+	lcs := make([][]int, len(oldLines)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(newLines)+1)
+	}
+	for i := len(oldLines) - 1; i >= 0; i-- {
+		for j := len(newLines) - 1; j >= 0; j-- {
+			if oldLines[i] == newLines[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	diff := make([]string, 0, len(oldLines)+len(newLines))
+	i, j := 0, 0
+	for i < len(oldLines) && j < len(newLines) {
+		switch {
+		case oldLines[i] == newLines[j]:
+			diff = append(diff, " "+oldLines[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			diff = append(diff, "-"+oldLines[i])
+			i++
+		default:
+			diff = append(diff, "+"+newLines[j])
+			j++
+		}
+	}
+	for ; i < len(oldLines); i++ {
+		diff = append(diff, "-"+oldLines[i])
+	}
+	for ; j < len(newLines); j++ {
+		diff = append(diff, "+"+newLines[j])
+	}
+	return diff
+}
+
+// normalizeJSON returns an indented JSON specification with sorted keys. Specifications which aren't valid JSON
+// are returned unchanged.
+func normalizeJSON(specification []byte) string {
+	var value interface{}
+	if err := json.Unmarshal(specification, &value); err != nil {
+		return strings.TrimRight(string(specification), "\n")
+	}
+	normalized, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return strings.TrimRight(string(specification), "\n")
+	}
+	return string(normalized)
+}